@@ -0,0 +1,88 @@
+package events
+
+import (
+	"encoding/json"
+	"time"
+)
+
+func eventField(ev Event, key string) (interface{}, bool) {
+	data, ok := ev.GetData().(map[string]interface{})
+	if !ok {
+		return nil, false
+	}
+	val, ok := data[key]
+	return val, ok
+}
+
+// EventString returns the string stored under key in ev's map data.
+func EventString(ev Event, key string) (string, bool) {
+	val, ok := eventField(ev, key)
+	if !ok {
+		return "", false
+	}
+	s, ok := val.(string)
+	return s, ok
+}
+
+// EventFloat returns the number stored under key in ev's map data, which
+// may be of any numeric type.
+func EventFloat(ev Event, key string) (float64, bool) {
+	val, ok := eventField(ev, key)
+	if !ok {
+		return 0, false
+	}
+	return toFloat(val)
+}
+
+// EventTime returns the time stored under key in ev's map data, either as
+// a time.Time or as an RFC 3339 string as produced by JSON decoding.
+func EventTime(ev Event, key string) (time.Time, bool) {
+	val, ok := eventField(ev, key)
+	if !ok {
+		return time.Time{}, false
+	}
+	switch tval := val.(type) {
+	case time.Time:
+		return tval, true
+	case string:
+		t, err := time.Parse(time.RFC3339Nano, tval)
+		if err != nil {
+			return time.Time{}, false
+		}
+		return t, true
+	}
+	return time.Time{}, false
+}
+
+func toFloat(val interface{}) (float64, bool) {
+	switch tval := val.(type) {
+	case float64:
+		return tval, true
+	case float32:
+		return float64(tval), true
+	case int:
+		return float64(tval), true
+	case int64:
+		return float64(tval), true
+	case int32:
+		return float64(tval), true
+	case int16:
+		return float64(tval), true
+	case int8:
+		return float64(tval), true
+	case uint:
+		return float64(tval), true
+	case uint64:
+		return float64(tval), true
+	case uint32:
+		return float64(tval), true
+	case uint16:
+		return float64(tval), true
+	case uint8:
+		return float64(tval), true
+	case json.Number:
+		f, err := tval.Float64()
+		return f, err == nil
+	}
+	return 0, false
+}
@@ -0,0 +1,76 @@
+package events
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestEventAccessors(t *testing.T) {
+	when := time.Date(2024, 1, 2, 3, 4, 5, 6, time.UTC)
+	data := map[string]interface{}{
+		"room": "kitchen",
+		"temp": 21.5,
+		"count": 3,
+		"small": uint8(7),
+		"number": json.Number("1.25"),
+		"bad number": json.Number("x"),
+		"at": when,
+		"at string": when.Format(time.RFC3339Nano),
+		"bad time": "yesterday",
+		"nothing": nil,
+	}
+	ev := NewEvent("test", data)
+	tests := []struct {
+		name string
+		ev Event
+		key string
+		wantString string
+		wantStringOK bool
+		wantFloat float64
+		wantFloatOK bool
+		wantTime time.Time
+		wantTimeOK bool
+	}{
+		{"string", ev, "room", "kitchen", true, 0, false, time.Time{}, false},
+		{"float", ev, "temp", "", false, 21.5, true, time.Time{}, false},
+		{"int", ev, "count", "", false, 3, true, time.Time{}, false},
+		{"uint8", ev, "small", "", false, 7, true, time.Time{}, false},
+		{"json number", ev, "number", "", false, 1.25, true, time.Time{}, false},
+		{"bad json number", ev, "bad number", "", false, 0, false, time.Time{}, false},
+		{"time", ev, "at", "", false, 0, false, when, true},
+		{"time string", ev, "at string", when.Format(time.RFC3339Nano), true, 0, false, when, true},
+		{"bad time string", ev, "bad time", "yesterday", true, 0, false, time.Time{}, false},
+		{"nil", ev, "nothing", "", false, 0, false, time.Time{}, false},
+		{"missing", ev, "missing", "", false, 0, false, time.Time{}, false},
+		{"value event", NewEvent("test", 1.5), "value", "", false, 0, false, time.Time{}, false},
+		{"message event", NewEvent("test", "hi"), "message", "", false, 0, false, time.Time{}, false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if s, ok := EventString(tc.ev, tc.key); s != tc.wantString || ok != tc.wantStringOK {
+				t.Errorf("EventString = %q, %t, want %q, %t", s, ok, tc.wantString, tc.wantStringOK)
+			}
+			if f, ok := EventFloat(tc.ev, tc.key); f != tc.wantFloat || ok != tc.wantFloatOK {
+				t.Errorf("EventFloat = %g, %t, want %g, %t", f, ok, tc.wantFloat, tc.wantFloatOK)
+			}
+			if tm, ok := EventTime(tc.ev, tc.key); !tm.Equal(tc.wantTime) || ok != tc.wantTimeOK {
+				t.Errorf("EventTime = %s, %t, want %s, %t", tm, ok, tc.wantTime, tc.wantTimeOK)
+			}
+		})
+	}
+}
+
+func TestEventAccessorsAfterRoundTrip(t *testing.T) {
+	when := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	ev := roundTrip(t, NewEvent("test", map[string]interface{}{"room": "kitchen", "count": 3, "at": when}))
+	if s, ok := EventString(ev, "room"); s != "kitchen" || !ok {
+		t.Errorf("EventString = %q, %t after round trip", s, ok)
+	}
+	if f, ok := EventFloat(ev, "count"); f != 3 || !ok {
+		t.Errorf("EventFloat = %g, %t after round trip", f, ok)
+	}
+	if tm, ok := EventTime(ev, "at"); !tm.Equal(when) || !ok {
+		t.Errorf("EventTime = %s, %t after round trip", tm, ok)
+	}
+}
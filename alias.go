@@ -0,0 +1,42 @@
+package events
+
+// AliasEventType makes events of newType also dispatch to listeners
+// registered under oldType, so existing subscribers keep working when an
+// event is renamed. Aliases are one-way; call AliasEventType again with the
+// arguments swapped to alias in both directions. Aliases chain, so if b is
+// an alias of a and c an alias of b, firing c reaches listeners on all
+// three. Each type is visited at most once per Fire, so cycles are safe.
+func (es *basicEventSink) AliasEventType(oldType, newType string) {
+	es.mutex.Lock()
+	defer es.mutex.Unlock()
+	es.aliasLocked(oldType, newType)
+}
+
+// aliasLocked adds an alias. The caller must hold the mutex.
+func (es *basicEventSink) aliasLocked(oldType, newType string) {
+	for _, t := range es.aliases[newType] {
+		if t == oldType {
+			return
+		}
+	}
+	es.aliases[newType] = append(es.aliases[newType], oldType)
+}
+
+// aliasedTypes returns eventType followed by every type it is aliased to,
+// directly or through a chain. The caller must hold the mutex.
+func (es *basicEventSink) aliasedTypes(eventType string) []string {
+	types := []string{eventType}
+	if len(es.aliases) == 0 {
+		return types
+	}
+	seen := map[string]bool{eventType: true}
+	for i := 0; i < len(types); i++ {
+		for _, t := range es.aliases[types[i]] {
+			if !seen[t] {
+				seen[t] = true
+				types = append(types, t)
+			}
+		}
+	}
+	return types
+}
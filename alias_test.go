@@ -0,0 +1,45 @@
+package events
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestAliasEventType(t *testing.T) {
+	tests := []struct {
+		name string
+		aliases [][2]string
+		fire string
+		want []string
+	}{
+		{"no alias", nil, "new", []string{"new"}},
+		{"one way new", [][2]string{{"old", "new"}}, "new", []string{"new", "old"}},
+		{"one way old", [][2]string{{"old", "new"}}, "old", []string{"old"}},
+		{"two way new", [][2]string{{"old", "new"}, {"new", "old"}}, "new", []string{"new", "old"}},
+		{"two way old", [][2]string{{"old", "new"}, {"new", "old"}}, "old", []string{"old", "new"}},
+		{"chain", [][2]string{{"old", "mid"}, {"mid", "new"}}, "new", []string{"new", "mid", "old"}},
+		{"cycle", [][2]string{{"old", "mid"}, {"mid", "new"}, {"new", "old"}}, "mid", []string{"mid", "old", "new"}},
+		{"repeated", [][2]string{{"old", "new"}, {"old", "new"}}, "new", []string{"new", "old"}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			sink := NewSyncEventSink(time.Minute)
+			got := []string{}
+			for _, eventType := range []string{"new", "mid", "old"} {
+				listener := eventType
+				sink.AddEventListener(eventType, NewEventHandler(func(Event) error {
+					got = append(got, listener)
+					return nil
+				}))
+			}
+			for _, alias := range tc.aliases {
+				sink.(TypeManager).AliasEventType(alias[0], alias[1])
+			}
+			sink.Emit(tc.fire, 1.0)
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("listeners called %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
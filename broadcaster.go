@@ -0,0 +1,285 @@
+package events
+
+import (
+	"context"
+	"sort"
+	"time"
+)
+
+// Broadcaster fans events out to several sinks. Events fired or emitted on
+// the broadcaster reach every child sink, and event types registered on it
+// are registered on every child. Each child logs and dispatches its own
+// copy of the event, with its own sequence number.
+//
+// Listeners added through the broadcaster are attached to the primary
+// sink only. Since every broadcast event reaches the primary sink, such a
+// listener sees each event once, rather than once per child. Listeners
+// added directly to a child sink see the events broadcast to it as usual.
+// Methods not described here, such as Stats and Errors, also go to the
+// primary sink, and do nothing if it lacks them, as does reading the log,
+// which is the primary sink's log: see PrimaryLog. Pause, Resume, Close
+// and PruneExpired go to every child.
+type Broadcaster struct {
+	EventSink
+	children []EventSink
+}
+
+func NewBroadcaster(primary EventSink, others ...EventSink) *Broadcaster {
+	children := append([]EventSink{primary}, others...)
+	return &Broadcaster{primary, children}
+}
+
+func (b *Broadcaster) Children() []EventSink {
+	return append([]EventSink{}, b.children...)
+}
+
+func (b *Broadcaster) Fire(ev Event) {
+	for _, child := range b.children {
+		child.Fire(ev)
+	}
+}
+
+func (b *Broadcaster) Emit(eventType string, data interface{}) {
+	b.Fire(NewEvent(eventType, data))
+}
+
+func (b *Broadcaster) EmitContext(ctx context.Context, eventType string, data interface{}) {
+	b.Fire(newEventContext(ctx, eventType, data))
+}
+
+func (b *Broadcaster) FireMany(evs []Event) {
+	for _, child := range b.children {
+		fireMany(child, evs)
+	}
+}
+
+func (b *Broadcaster) EmitMany(eventType string, data []interface{}) {
+	b.FireMany(newEvents(eventType, data))
+}
+
+func (b *Broadcaster) RegisterEventType(ev Event) {
+	for _, child := range b.children {
+		child.RegisterEventType(ev)
+	}
+}
+
+func (b *Broadcaster) RegisterEventTypeWithValidator(ev Event, validator Validator) {
+	for _, child := range b.children {
+		if tm, ok := child.(TypeManager); ok {
+			tm.RegisterEventTypeWithValidator(ev, validator)
+		} else {
+			child.RegisterEventType(ev)
+		}
+	}
+}
+
+func (b *Broadcaster) AliasEventType(oldType, newType string) {
+	for _, child := range b.children {
+		if tm, ok := child.(TypeManager); ok {
+			tm.AliasEventType(oldType, newType)
+		}
+	}
+}
+
+func (b *Broadcaster) SetTypeTimeout(eventType string, d time.Duration) {
+	for _, child := range b.children {
+		if tm, ok := child.(TypeManager); ok {
+			tm.SetTypeTimeout(eventType, d)
+		}
+	}
+}
+
+// PrimaryLog returns the primary sink's log, in the order the events
+// arrived there. Since every broadcast event reaches the primary sink, it
+// holds them all, each once, though not events fired directly on other
+// children; their logs hold copies of the broadcast events, so merging
+// them would repeat every event once per child.
+func (b *Broadcaster) PrimaryLog() []Event {
+	return b.EventSink.Log()
+}
+
+// Log returns the primary sink's log, as PrimaryLog does.
+func (b *Broadcaster) Log() []Event {
+	return b.PrimaryLog()
+}
+
+func (b *Broadcaster) LogSortedByTime() []Event {
+	evs := b.PrimaryLog()
+	sortByTime(evs)
+	return evs
+}
+
+func (b *Broadcaster) LogForType(eventType string) []Event {
+	return logForType(b.EventSink, eventType)
+}
+
+func (b *Broadcaster) ListEventTypes() []Event {
+	seen := map[string]bool{}
+	evs := []Event{}
+	for _, child := range b.children {
+		for _, ev := range child.ListEventTypes() {
+			if !seen[ev.GetType()] {
+				seen[ev.GetType()] = true
+				evs = append(evs, ev)
+			}
+		}
+	}
+	sort.Slice(evs, func(i, j int) bool { return evs[i].GetType() < evs[j].GetType() })
+	return evs
+}
+
+func (b *Broadcaster) PruneExpired() int {
+	n := 0
+	for _, child := range b.children {
+		n += pruneExpired(child)
+	}
+	return n
+}
+
+func (b *Broadcaster) Pause() {
+	for _, child := range b.children {
+		if p, ok := child.(Pausable); ok {
+			p.Pause()
+		}
+	}
+}
+
+func (b *Broadcaster) Resume() {
+	for _, child := range b.children {
+		if p, ok := child.(Pausable); ok {
+			p.Resume()
+		}
+	}
+}
+
+// Close closes every child sink, returning the first error.
+func (b *Broadcaster) Close() error {
+	var first error
+	for _, child := range b.children {
+		c, ok := child.(Closer)
+		if !ok {
+			continue
+		}
+		err := c.Close()
+		if err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+func (b *Broadcaster) RemoveEventListenerSync(eventType string, handler EventHandler) {
+	removeEventListenerSync(b.EventSink, eventType, handler)
+}
+
+func (b *Broadcaster) AddEventListenerTagged(eventType, tag string, handler EventHandler) {
+	addEventListenerTagged(b.EventSink, eventType, tag, handler)
+}
+
+func (b *Broadcaster) AddEventListenerWithPriority(eventType string, priority int, handler EventHandler) {
+	addEventListenerWithPriority(b.EventSink, eventType, priority, handler)
+}
+
+func (b *Broadcaster) AddEventListenerIf(eventType string, pred func(Event) bool, handler EventHandler) {
+	addEventListenerIf(b.EventSink, eventType, pred, handler)
+}
+
+func (b *Broadcaster) AddEventListenerWithLabels(eventType string, selector map[string]string, handler EventHandler) {
+	addEventListenerWithLabels(b.EventSink, eventType, selector, handler)
+}
+
+func (b *Broadcaster) OnceWhen(eventType string, handler EventHandler, cond Condition) {
+	onceWhen(b.EventSink, eventType, handler, cond)
+}
+
+func (b *Broadcaster) RemoveByTag(tag string) {
+	removeByTag(b.EventSink, tag)
+}
+
+func (b *Broadcaster) Use(mw Middleware) {
+	if ms, ok := b.EventSink.(MiddlewareSink); ok {
+		ms.Use(mw)
+	}
+}
+
+func (b *Broadcaster) ListenerCount(eventType string) int {
+	if li, ok := b.EventSink.(ListenerInspector); ok {
+		return li.ListenerCount(eventType)
+	}
+	return 0
+}
+
+func (b *Broadcaster) DispatchOrder(eventType string) []ListenerMeta {
+	if li, ok := b.EventSink.(ListenerInspector); ok {
+		return li.DispatchOrder(eventType)
+	}
+	return nil
+}
+
+func (b *Broadcaster) DescribeHandlers(eventType string) []HandlerDescription {
+	if li, ok := b.EventSink.(ListenerInspector); ok {
+		return li.DescribeHandlers(eventType)
+	}
+	return nil
+}
+
+func (b *Broadcaster) OnListenerChange(fn func(ListenerMeta)) {
+	if li, ok := b.EventSink.(ListenerInspector); ok {
+		li.OnListenerChange(fn)
+	}
+}
+
+func (b *Broadcaster) Stats() map[string]EventTypeStats {
+	if ss, ok := b.EventSink.(StatsSink); ok {
+		return ss.Stats()
+	}
+	return map[string]EventTypeStats{}
+}
+
+func (b *Broadcaster) Percentiles(eventType string, ps ...float64) map[float64]float64 {
+	if ss, ok := b.EventSink.(StatsSink); ok {
+		return ss.Percentiles(eventType, ps...)
+	}
+	return map[float64]float64{}
+}
+
+func (b *Broadcaster) Counters() map[string]EventCounters {
+	if ss, ok := b.EventSink.(StatsSink); ok {
+		return ss.Counters()
+	}
+	return map[string]EventCounters{}
+}
+
+func (b *Broadcaster) Latest(eventType string) (Event, bool) {
+	if lr, ok := b.EventSink.(LatestReader); ok {
+		return lr.Latest(eventType)
+	}
+	return nil, false
+}
+
+func (b *Broadcaster) LatestAll() map[string]Event {
+	if lr, ok := b.EventSink.(LatestReader); ok {
+		return lr.LatestAll()
+	}
+	return map[string]Event{}
+}
+
+func (b *Broadcaster) Snapshot() *SinkState {
+	if s, ok := b.EventSink.(Snapshotter); ok {
+		return s.Snapshot()
+	}
+	return nil
+}
+
+func (b *Broadcaster) Restore(state *SinkState) {
+	if s, ok := b.EventSink.(Snapshotter); ok {
+		s.Restore(state)
+	}
+}
+
+func (b *Broadcaster) Errors() <-chan HandlerError {
+	if es, ok := b.EventSink.(ErrorSource); ok {
+		return es.Errors()
+	}
+	return nil
+}
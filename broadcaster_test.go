@@ -0,0 +1,169 @@
+package events
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func newTestBroadcaster() (*Broadcaster, []EventSink) {
+	children := []EventSink{
+		NewSyncEventSink(time.Minute),
+		NewSyncEventSink(time.Minute),
+		NewSyncEventSink(time.Minute),
+	}
+	return NewBroadcaster(children[0], children[1:]...), children
+}
+
+func TestBroadcasterFire(t *testing.T) {
+	tests := []struct {
+		name string
+		fire func(b *Broadcaster) Event
+	}{
+		{"fire", func(b *Broadcaster) Event {
+			ev := NewEvent("test", 1.0)
+			b.Fire(ev)
+			return ev
+		}},
+		{"emit", func(b *Broadcaster) Event {
+			b.Emit("test", 1.0)
+			return nil
+		}},
+		{"fire many", func(b *Broadcaster) Event {
+			ev := NewEvent("test", 1.0)
+			b.FireMany([]Event{ev})
+			return ev
+		}},
+		{"emit many", func(b *Broadcaster) Event {
+			b.EmitMany("test", []interface{}{1.0})
+			return nil
+		}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			b, children := newTestBroadcaster()
+			shared := RecordingHandler()
+			b.AddEventListener("test", shared)
+			recs := make([]*Recorder, len(children))
+			for i, child := range children {
+				recs[i] = RecordingHandler()
+				child.AddEventListener("test", recs[i])
+			}
+			fired := tc.fire(b)
+			if n := len(shared.Calls()); n != 1 {
+				t.Errorf("broadcaster listener called %d times, want 1", n)
+			}
+			primary := recs[0].Calls()
+			if len(primary) != 1 {
+				t.Fatalf("primary got %d events, want 1", len(primary))
+			}
+			if fired != nil && primary[0] == fired {
+				t.Errorf("primary got the caller's event, not a copy")
+			}
+			for i, rec := range recs[1:] {
+				got := rec.Calls()
+				if len(got) != 1 {
+					t.Fatalf("child %d got %d events, want 1", i + 1, len(got))
+				}
+				if got[0] == primary[0] {
+					t.Errorf("child %d got the primary's event, not a copy", i + 1)
+				}
+				if got[0].GetType() != "test" || got[0].(Valuer).GetValue() != 1 || !got[0].GetTime().Equal(primary[0].GetTime()) {
+					t.Errorf("child %d got %v, want a copy of %v", i + 1, got[0], primary[0])
+				}
+			}
+		})
+	}
+}
+
+func TestBroadcasterLog(t *testing.T) {
+	tests := []struct {
+		name string
+		fire func(b *Broadcaster, children []EventSink)
+		want []float64
+	}{
+		{"arrival order", func(b *Broadcaster, children []EventSink) {
+			b.Emit("a", 1.0)
+			b.Emit("b", 2.0)
+			b.Emit("a", 3.0)
+		}, []float64{3, 2, 1}},
+		{"fired on the primary", func(b *Broadcaster, children []EventSink) {
+			b.Emit("a", 1.0)
+			children[0].Emit("a", 2.0)
+		}, []float64{2, 1}},
+		{"fired on another child", func(b *Broadcaster, children []EventSink) {
+			b.Emit("a", 1.0)
+			children[1].Emit("a", 2.0)
+		}, []float64{1}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			b, children := newTestBroadcaster()
+			tc.fire(b, children)
+			if got := logValues(b.Log()); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("log = %v, want %v", got, tc.want)
+			}
+			if got := logValues(b.PrimaryLog()); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("primary log = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestBroadcasterRegisterEventType(t *testing.T) {
+	b, children := newTestBroadcaster()
+	b.RegisterEventType(NewEvent("temp", 1.0))
+	for i, child := range children {
+		found := false
+		for _, ev := range child.ListEventTypes() {
+			found = found || ev.GetType() == "temp"
+		}
+		if !found {
+			t.Errorf("child %d hasn't registered temp", i)
+		}
+	}
+}
+
+func TestBroadcasterPauseResume(t *testing.T) {
+	tests := []struct {
+		name string
+		resume bool
+		want int
+	}{
+		{"paused", false, 0},
+		{"resumed", true, 1},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			b, children := newTestBroadcaster()
+			recs := make([]*Recorder, len(children))
+			for i, child := range children {
+				recs[i] = RecordingHandler()
+				child.AddEventListener("test", recs[i])
+			}
+			b.Pause()
+			b.Emit("test", 1.0)
+			if tc.resume {
+				b.Resume()
+			}
+			for i, rec := range recs {
+				if n := len(rec.Calls()); n != tc.want {
+					t.Errorf("child %d got %d events, want %d", i, n, tc.want)
+				}
+			}
+		})
+	}
+}
+
+func TestBroadcasterClose(t *testing.T) {
+	b, children := newTestBroadcaster()
+	if err := b.Close(); err != nil {
+		t.Fatalf("close failed: %s", err)
+	}
+	b.Emit("test", 1.0)
+	for i, child := range children {
+		if n := len(filterLog(child.Log(), "test")); n != 0 {
+			t.Errorf("closed child %d logged %d events", i, n)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package events
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// A Broker manages a set of named topics, each backed by its own sink.
+// Topics are created on first use, and share the log TTL and options the
+// broker was created with. Listeners on one topic never see events
+// published to another.
+type Broker struct {
+	logTTL time.Duration
+	opts []SinkOption
+	topics map[string]EventSink
+	mutex *sync.Mutex
+}
+
+func NewBroker(logTTL time.Duration, opts ...SinkOption) *Broker {
+	return &Broker{
+		logTTL: logTTL,
+		opts: opts,
+		topics: map[string]EventSink{},
+		mutex: &sync.Mutex{},
+	}
+}
+
+// Topic returns the sink for the named topic, creating it if it doesn't
+// exist yet.
+func (b *Broker) Topic(name string) EventSink {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	sink, ok := b.topics[name]
+	if !ok {
+		sink = NewEventSink(b.logTTL, b.opts...)
+		b.topics[name] = sink
+	}
+	return sink
+}
+
+func (b *Broker) Publish(topic, eventType string, data interface{}) {
+	b.Topic(topic).Emit(eventType, data)
+}
+
+// Topics returns the names of the topics created so far, sorted.
+func (b *Broker) Topics() []string {
+	b.mutex.Lock()
+	names := make([]string, 0, len(b.topics))
+	for name := range b.topics {
+		names = append(names, name)
+	}
+	b.mutex.Unlock()
+	sort.Strings(names)
+	return names
+}
+
+// Close closes every topic's sink, returning the first error encountered.
+// Topics requested after Close are created afresh.
+func (b *Broker) Close() error {
+	b.mutex.Lock()
+	topics := b.topics
+	b.topics = map[string]EventSink{}
+	b.mutex.Unlock()
+	var first error
+	for _, sink := range topics {
+		err := sink.(Closer).Close()
+		if err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
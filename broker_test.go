@@ -0,0 +1,127 @@
+package events
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestBroker(t *testing.T) {
+	type publish struct {
+		topic string
+		eventType string
+		val float64
+	}
+	tests := []struct {
+		name string
+		subscribe []string
+		publishes []publish
+		want map[string][]float64
+		wantTopics []string
+	}{
+		{"empty", nil, nil, map[string][]float64{}, []string{}},
+		{
+			"independent topics",
+			[]string{"kitchen", "garage"},
+			[]publish{{"kitchen", "temp", 20}, {"garage", "temp", 5}, {"kitchen", "temp", 21}},
+			map[string][]float64{"kitchen": {20, 21}, "garage": {5}},
+			[]string{"garage", "kitchen"},
+		},
+		{
+			"published before subscribing",
+			[]string{"kitchen"},
+			[]publish{{"attic", "temp", 30}, {"kitchen", "temp", 20}},
+			map[string][]float64{"kitchen": {20}},
+			[]string{"attic", "kitchen"},
+		},
+		{
+			"other event types",
+			[]string{"kitchen"},
+			[]publish{{"kitchen", "humidity", 40}, {"kitchen", "temp", 20}},
+			map[string][]float64{"kitchen": {20}},
+			[]string{"kitchen"},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			b := NewBroker(time.Hour, SinkSync())
+			recs := map[string]*Recorder{}
+			for _, topic := range tc.subscribe {
+				recs[topic] = RecordingHandler()
+				b.Topic(topic).AddEventListener("temp", recs[topic])
+			}
+			for _, p := range tc.publishes {
+				b.Publish(p.topic, p.eventType, p.val)
+			}
+			got := map[string][]float64{}
+			for topic, rec := range recs {
+				if vals := logValues(rec.Calls()); len(vals) > 0 {
+					got[topic] = vals
+				}
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("received %v, want %v", got, tc.want)
+			}
+			if topics := b.Topics(); !reflect.DeepEqual(topics, tc.wantTopics) {
+				t.Errorf("topics = %v, want %v", topics, tc.wantTopics)
+			}
+		})
+	}
+}
+
+func TestBrokerTopicOptions(t *testing.T) {
+	c := useFakeClock(t)
+	b := NewBroker(time.Minute, SinkSync())
+	kitchen := b.Topic("kitchen")
+	if b.Topic("kitchen") != kitchen {
+		t.Error("Topic returned a new sink for an existing topic")
+	}
+	b.Publish("kitchen", "temp", 1.0)
+	c.Advance(2 * time.Minute)
+	b.Publish("kitchen", "temp", 2.0)
+	// the topic shares the broker's TTL
+	if got := logValues(kitchen.Log()); !reflect.DeepEqual(got, []float64{2}) {
+		t.Errorf("log = %v, want [2]", got)
+	}
+}
+
+func TestBrokerConcurrentTopics(t *testing.T) {
+	b := NewBroker(time.Hour)
+	wg := &sync.WaitGroup{}
+	sinks := make([]EventSink, 20)
+	for i := range sinks {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			sinks[i] = b.Topic("shared")
+		}(i)
+	}
+	wg.Wait()
+	for i, sink := range sinks {
+		if sink != sinks[0] {
+			t.Fatalf("goroutine %d got a different sink", i)
+		}
+	}
+}
+
+func TestBrokerClose(t *testing.T) {
+	b := NewBroker(time.Hour, SinkSync())
+	rec := RecordingHandler()
+	old := b.Topic("kitchen")
+	old.AddEventListener("temp", rec)
+	if err := b.Close(); err != nil {
+		t.Fatalf("close failed: %s", err)
+	}
+	if topics := b.Topics(); len(topics) != 0 {
+		t.Errorf("topics after close = %v", topics)
+	}
+	b.Publish("kitchen", "temp", 1.0)
+	old.Emit("temp", 2.0)
+	if n := len(rec.Calls()); n != 0 {
+		t.Errorf("closed topic's listener called %d times", n)
+	}
+	if b.Topic("kitchen") == old {
+		t.Error("topic wasn't created afresh after close")
+	}
+}
@@ -0,0 +1,96 @@
+package events
+
+import (
+	"time"
+)
+
+// EventHandlerBuilder composes the common decorators around a handler in
+// a fixed order, so that the result doesn't depend on the order in which
+// they are configured. An event passes through them in this order:
+// timeout, max calls, direction or threshold, range, debounce.
+//
+// Not every stateful decorator is inside the filters. Max calls wraps them
+// all, but counts only the events that reach the handler, since the
+// decorators inside it return ErrIgnored for the rest. Direction and
+// threshold wrap the range filter, so they track every event that
+// reaches them, including those the range then ignores. Only debounce is
+// inside the range, and sees just the events it passes.
+type EventHandlerBuilder struct {
+	handler EventHandler
+	debounce *time.Duration
+	min, max *float64
+	direction *Direction
+	trigger, reset *float64
+	maxCalls int
+	ttl time.Duration
+}
+
+// HandlerBuilder starts building a handler that calls fn.
+func HandlerBuilder(fn HandlerFunc) *EventHandlerBuilder {
+	return HandlerBuilderFor(NewEventHandler(fn))
+}
+
+// HandlerBuilderFor starts building a handler that wraps h.
+func HandlerBuilderFor(h EventHandler) *EventHandlerBuilder {
+	return &EventHandlerBuilder{handler: h}
+}
+
+// Debounce adds WithDebounce.
+func (b *EventHandlerBuilder) Debounce(ttl time.Duration) *EventHandlerBuilder {
+	b.debounce = &ttl
+	return b
+}
+
+// Range adds WithRange.
+func (b *EventHandlerBuilder) Range(min, max float64) *EventHandlerBuilder {
+	b.min = &min
+	b.max = &max
+	return b
+}
+
+// Direction adds WithDirection, replacing any threshold.
+func (b *EventHandlerBuilder) Direction(direction Direction) *EventHandlerBuilder {
+	b.direction = &direction
+	b.trigger = nil
+	b.reset = nil
+	return b
+}
+
+// Threshold adds WithThreshold, replacing any direction.
+func (b *EventHandlerBuilder) Threshold(direction Direction, triggerVal, resetVal float64) *EventHandlerBuilder {
+	b.direction = &direction
+	b.trigger = &triggerVal
+	b.reset = &resetVal
+	return b
+}
+
+// MaxCalls adds WithMaxCalls.
+func (b *EventHandlerBuilder) MaxCalls(maxCalls int) *EventHandlerBuilder {
+	b.maxCalls = maxCalls
+	return b
+}
+
+// Timeout adds WithTimeout.
+func (b *EventHandlerBuilder) Timeout(ttl time.Duration) *EventHandlerBuilder {
+	b.ttl = ttl
+	return b
+}
+
+// Build returns the decorated handler.
+func (b *EventHandlerBuilder) Build() EventHandler {
+	h := b.handler
+	if b.debounce != nil {
+		h = WithDebounce(h, *b.debounce)
+	}
+	if b.min != nil && b.max != nil {
+		h = WithRange(h, *b.min, *b.max)
+	}
+	if b.direction != nil {
+		if b.trigger != nil && b.reset != nil {
+			h = WithThreshold(h, *b.direction, *b.trigger, *b.reset)
+		} else {
+			h = WithDirection(h, *b.direction)
+		}
+	}
+	return WithTimeout(WithMaxCalls(h, b.maxCalls), b.ttl)
+}
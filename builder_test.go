@@ -0,0 +1,111 @@
+package events
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// timedIndexes is like passedIndexes, but the events are a second apart.
+func timedIndexes(t *testing.T, wrap func(EventHandler) EventHandler, vals ...float64) []int {
+	c := useFakeClock(t)
+	evs := make([]Event, len(vals))
+	for i, val := range vals {
+		evs[i] = NewEvent("test", val)
+		c.Advance(time.Second)
+	}
+	c.Set(evs[0].GetTime())
+	out := []int{}
+	h := wrap(NewEventHandler(func(ev Event) error {
+		for i, e := range evs {
+			if e == ev {
+				out = append(out, i)
+			}
+		}
+		return nil
+	}))
+	for _, ev := range evs {
+		c.Set(ev.GetTime())
+		h.Call(ev)
+	}
+	return out
+}
+
+func TestHandlerBuilder(t *testing.T) {
+	vals := []float64{1, 5, 12, 3, 8, 15, 9, 20, 2, 11, 6, 14}
+	tests := []struct {
+		name string
+		build func(b *EventHandlerBuilder) *EventHandlerBuilder
+		manual func(h EventHandler) EventHandler
+		want []int
+	}{
+		{
+			"nothing",
+			func(b *EventHandlerBuilder) *EventHandlerBuilder { return b },
+			func(h EventHandler) EventHandler { return h },
+			[]int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11},
+		},
+		{
+			"range",
+			func(b *EventHandlerBuilder) *EventHandlerBuilder { return b.Range(4, 12) },
+			func(h EventHandler) EventHandler { return WithRange(h, 4, 12) },
+			[]int{1, 2, 4, 6, 9, 10},
+		},
+		{
+			"threshold and max calls",
+			func(b *EventHandlerBuilder) *EventHandlerBuilder { return b.Threshold(DirectionIncreasing, 10, 4).MaxCalls(2) },
+			func(h EventHandler) EventHandler { return WithMaxCalls(WithThreshold(h, DirectionIncreasing, 10, 4), 2) },
+			[]int{2, 5},
+		},
+		{
+			"direction and range",
+			func(b *EventHandlerBuilder) *EventHandlerBuilder { return b.Range(0, 15).Direction(DirectionIncreasing) },
+			func(h EventHandler) EventHandler { return WithDirection(WithRange(h, 0, 15), DirectionIncreasing) },
+			[]int{1, 2, 4, 5, 9, 11},
+		},
+		{
+			"range and debounce",
+			func(b *EventHandlerBuilder) *EventHandlerBuilder { return b.Debounce(3 * time.Second).Range(5, 20) },
+			func(h EventHandler) EventHandler { return WithRange(WithDebounce(h, 3 * time.Second), 5, 20) },
+			[]int{1, 4, 7, 10},
+		},
+		{
+			"everything",
+			func(b *EventHandlerBuilder) *EventHandlerBuilder {
+				return b.Timeout(9 * time.Second).MaxCalls(3).Debounce(2 * time.Second).Range(2, 16).Threshold(DirectionIncreasing, 8, 4)
+			},
+			func(h EventHandler) EventHandler {
+				return WithTimeout(WithMaxCalls(WithThreshold(WithRange(WithDebounce(h, 2 * time.Second), 2, 16), DirectionIncreasing, 8, 4), 3), 9 * time.Second)
+			},
+			[]int{2, 4, 9},
+		},
+		{
+			"everything in another order",
+			func(b *EventHandlerBuilder) *EventHandlerBuilder {
+				return b.Threshold(DirectionIncreasing, 8, 4).Range(2, 16).Debounce(2 * time.Second).MaxCalls(3).Timeout(9 * time.Second)
+			},
+			func(h EventHandler) EventHandler {
+				return WithTimeout(WithMaxCalls(WithThreshold(WithRange(WithDebounce(h, 2 * time.Second), 2, 16), DirectionIncreasing, 8, 4), 3), 9 * time.Second)
+			},
+			[]int{2, 4, 9},
+		},
+		{
+			"threshold replaced by direction",
+			func(b *EventHandlerBuilder) *EventHandlerBuilder { return b.Threshold(DirectionIncreasing, 8, 4).Direction(DirectionDecreasing) },
+			func(h EventHandler) EventHandler { return WithDirection(h, DirectionDecreasing) },
+			[]int{3, 6, 8, 10},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			manual := timedIndexes(t, tc.manual, vals...)
+			if !reflect.DeepEqual(manual, tc.want) {
+				t.Errorf("manual wrapping passed %v, want %v", manual, tc.want)
+			}
+			got := timedIndexes(t, func(h EventHandler) EventHandler { return tc.build(HandlerBuilderFor(h)).Build() }, vals...)
+			if !reflect.DeepEqual(got, manual) {
+				t.Errorf("builder passed %v, manual wrapping passed %v", got, manual)
+			}
+		})
+	}
+}
@@ -0,0 +1,48 @@
+package events
+
+import (
+	"strings"
+)
+
+// OnListenerChange registers fn to be called whenever a listener is added
+// or removed, with Change set to EventTypeHandlerAdded or
+// EventTypeHandlerRemoved. Unlike the meta-events of those types, which
+// are fired asynchronously, fn is called in the goroutine making the
+// change, after it has taken effect and before AddEventListener or
+// RemoveEventListener returns, so callbacks see changes in the order
+// they happen, once each. Adding a listener that is already present
+// isn't a change, so isn't reported. fn should return quickly, since it
+// delays the caller.
+func (es *basicEventSink) OnListenerChange(fn func(ListenerMeta)) {
+	if fn == nil {
+		return
+	}
+	es.mutex.Lock()
+	// copy, since callers hold on to the old slice outside the mutex
+	fns := make([]func(ListenerMeta), len(es.changeFns), len(es.changeFns)+1)
+	copy(fns, es.changeFns)
+	es.changeFns = append(fns, fn)
+	es.mutex.Unlock()
+}
+
+func notifyChange(fns []func(ListenerMeta), meta ListenerMeta) {
+	for _, fn := range fns {
+		fn(meta)
+	}
+}
+
+func (es *PrefixedEventSource) OnListenerChange(fn func(ListenerMeta)) {
+	if fn == nil {
+		return
+	}
+	li, ok := es.EventSink.(ListenerInspector)
+	if !ok {
+		return
+	}
+	li.OnListenerChange(func(meta ListenerMeta) {
+		if strings.HasPrefix(meta.EventType, es.prefix) {
+			meta.EventType = strings.TrimPrefix(meta.EventType, es.prefix)
+			fn(meta)
+		}
+	})
+}
@@ -0,0 +1,102 @@
+package events
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestOnListenerChange(t *testing.T) {
+	a := NewEventHandlerWithID(1, func(Event) error { return nil })
+	b := NewEventHandlerWithID(2, func(Event) error { return nil })
+	added := func(eventType string, id int64) ListenerMeta {
+		return ListenerMeta{EventType: eventType, HandlerID: id, Change: EventTypeHandlerAdded}
+	}
+	removed := func(eventType string, id int64) ListenerMeta {
+		return ListenerMeta{EventType: eventType, HandlerID: id, Change: EventTypeHandlerRemoved}
+	}
+	tests := []struct {
+		name string
+		view func(sink EventSink) EventSink
+		change func(sink EventSink)
+		want []ListenerMeta
+	}{
+		{"nothing", nil, func(sink EventSink) {}, []ListenerMeta{}},
+		{"add", nil, func(sink EventSink) { sink.AddEventListener("temp", a) }, []ListenerMeta{added("temp", 1)}},
+		{"add twice", nil, func(sink EventSink) {
+			sink.AddEventListener("temp", a)
+			sink.AddEventListener("temp", a)
+		}, []ListenerMeta{added("temp", 1)}},
+		{"add to two types", nil, func(sink EventSink) {
+			sink.AddEventListener("temp", a)
+			sink.AddEventListener("door", a)
+		}, []ListenerMeta{added("temp", 1), added("door", 1)}},
+		{"add and remove", nil, func(sink EventSink) {
+			sink.AddEventListener("temp", a)
+			sink.AddEventListener("temp", b)
+			sink.RemoveEventListener("temp", a)
+		}, []ListenerMeta{added("temp", 1), added("temp", 2), removed("temp", 1)}},
+		{"remove missing", nil, func(sink EventSink) { sink.RemoveEventListener("temp", a) }, []ListenerMeta{}},
+		{"nil handler", nil, func(sink EventSink) { sink.AddEventListener("temp", nil) }, []ListenerMeta{}},
+		{"once", nil, func(sink EventSink) {
+			sink.Once("temp", a)
+			sink.Emit("temp", 1.0)
+		}, []ListenerMeta{added("temp", 1), removed("temp", 1)}},
+		{"prefixed", func(sink EventSink) EventSink { return NewPrefixedEventSource("kitchen", sink) }, func(sink EventSink) {
+			sink.AddEventListener("temp", a)
+			sink.RemoveEventListener("temp", a)
+		}, []ListenerMeta{added("temp", 1), removed("temp", 1)}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var sink EventSink = NewSyncEventSink(time.Hour)
+			if tc.view != nil {
+				sink = tc.view(sink)
+			}
+			got := []ListenerMeta{}
+			sink.(ListenerInspector).OnListenerChange(func(meta ListenerMeta) {
+				got = append(got, meta)
+			})
+			tc.change(sink)
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("changes = %+v, want %+v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestOnListenerChangeInline(t *testing.T) {
+	sink := NewEventSink(time.Hour)
+	mutex := &sync.Mutex{}
+	counts := map[int64]int{}
+	sink.(ListenerInspector).OnListenerChange(func(meta ListenerMeta) {
+		mutex.Lock()
+		counts[meta.HandlerID] += 1
+		mutex.Unlock()
+	})
+	// listeners outside its prefix aren't reported to a prefixed view
+	NewPrefixedEventSource("other", sink).(ListenerInspector).OnListenerChange(func(meta ListenerMeta) {
+		t.Errorf("prefixed callback called for %+v", meta)
+	})
+	wg := &sync.WaitGroup{}
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(id int64) {
+			defer wg.Done()
+			sink.AddEventListener("temp", NewEventHandlerWithID(id, func(Event) error { return nil }))
+		}(int64(i + 1))
+	}
+	wg.Wait()
+	// no waiting: the callbacks have run by the time the adds return
+	mutex.Lock()
+	defer mutex.Unlock()
+	if len(counts) != 50 {
+		t.Errorf("%d listeners reported, want 50", len(counts))
+	}
+	for id, n := range counts {
+		if n != 1 {
+			t.Errorf("listener %d reported %d times", id, n)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package events
+
+import (
+	"sync"
+	"time"
+)
+
+// A Clock tells the time. The package reads the time through a Clock so
+// that time-dependent behavior can be tested without real sleeps.
+type Clock interface {
+	Now() time.Time
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+var clock Clock = realClock{}
+var clockMutex = &sync.Mutex{}
+
+// SetClock replaces the clock used throughout the package. A nil clock
+// restores the real one.
+func SetClock(c Clock) {
+	if c == nil {
+		c = realClock{}
+	}
+	clockMutex.Lock()
+	clock = c
+	clockMutex.Unlock()
+}
+
+func now() time.Time {
+	clockMutex.Lock()
+	c := clock
+	clockMutex.Unlock()
+	return c.Now()
+}
+
+// FakeClock is a Clock that only moves when told to.
+type FakeClock struct {
+	t time.Time
+	mutex *sync.Mutex
+}
+
+func NewFakeClock(t time.Time) *FakeClock {
+	return &FakeClock{t: t, mutex: &sync.Mutex{}}
+}
+
+func (c *FakeClock) Now() time.Time {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.t
+}
+
+func (c *FakeClock) Advance(d time.Duration) {
+	c.mutex.Lock()
+	c.t = c.t.Add(d)
+	c.mutex.Unlock()
+}
+
+func (c *FakeClock) Set(t time.Time) {
+	c.mutex.Lock()
+	c.t = t
+	c.mutex.Unlock()
+}
+
+// SinkClock makes a sink use c, rather than the package clock, when
+// trimming its log.
+func SinkClock(c Clock) SinkOption {
+	return func(es *basicEventSink) {
+		es.clock = c
+	}
+}
+
+func (es *basicEventSink) now() time.Time {
+	if es.clock != nil {
+		return es.clock.Now()
+	}
+	return now()
+}
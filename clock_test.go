@@ -0,0 +1,104 @@
+package events
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestFakeClock(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name string
+		move func(c *FakeClock)
+		want time.Time
+	}{
+		{"still", func(c *FakeClock) {}, start},
+		{"advance", func(c *FakeClock) { c.Advance(time.Minute) }, start.Add(time.Minute)},
+		{"advance twice", func(c *FakeClock) { c.Advance(time.Minute); c.Advance(time.Second) }, start.Add(time.Minute + time.Second)},
+		{"set", func(c *FakeClock) { c.Set(start.Add(-time.Hour)) }, start.Add(-time.Hour)},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			c := NewFakeClock(start)
+			tc.move(c)
+			if got := c.Now(); !got.Equal(tc.want) {
+				t.Errorf("Now() = %s, want %s", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestSetClock(t *testing.T) {
+	c := useFakeClock(t)
+	if ev := NewEvent("test", 1.0); !ev.GetTime().Equal(c.Now()) {
+		t.Errorf("event time %s, want the fake clock's %s", ev.GetTime(), c.Now())
+	}
+	SetClock(nil)
+	if ev := NewEvent("test", 1.0); time.Since(ev.GetTime()) > time.Minute {
+		t.Errorf("event time %s after restoring the real clock", ev.GetTime())
+	}
+}
+
+func TestWithTimeoutFakeClock(t *testing.T) {
+	tests := []struct {
+		name string
+		ttl time.Duration
+		advance time.Duration
+		wantExpired bool
+		wantErr error
+	}{
+		{"before ttl", time.Minute, 30 * time.Second, false, nil},
+		{"at ttl", time.Minute, time.Minute, false, nil},
+		{"after ttl", time.Minute, time.Minute + time.Second, true, ErrExpired},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			c := useFakeClock(t)
+			sink := NewSyncEventSink(time.Hour)
+			rec := RecordingHandler()
+			h := WithTimeout(rec, tc.ttl)
+			sink.AddEventListener("test", h)
+			c.Advance(tc.advance)
+			if h.Expired() != tc.wantExpired {
+				t.Errorf("expired = %t, want %t", h.Expired(), tc.wantExpired)
+			}
+			if err := h.Call(NewEvent("test", 1.0)); err != tc.wantErr {
+				t.Errorf("call returned %v, want %v", err, tc.wantErr)
+			}
+			sink.Emit("test", 2.0)
+			wantListeners := 1
+			if tc.wantExpired {
+				wantListeners = 0
+			}
+			if n := sink.(ListenerInspector).ListenerCount("test"); n != wantListeners {
+				t.Errorf("%d listeners, want %d", n, wantListeners)
+			}
+		})
+	}
+}
+
+func TestSinkClock(t *testing.T) {
+	tests := []struct {
+		name string
+		advance time.Duration
+		want []float64
+	}{
+		{"fresh", 30 * time.Second, []float64{2, 1}},
+		{"stale", 2 * time.Minute, []float64{}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			// event times come from the package clock, which stands still
+			pkg := useFakeClock(t)
+			c := NewFakeClock(pkg.Now())
+			sink := NewSyncEventSink(time.Minute, SinkClock(c))
+			sink.Emit("test", 1.0)
+			c.Advance(tc.advance)
+			sink.Emit("test", 2.0)
+			if got := logValues(sink.Log()); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("log = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
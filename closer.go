@@ -0,0 +1,33 @@
+package events
+
+// A Closer is a handler holding resources, such as a background goroutine,
+// that must be released once it is no longer needed. The sink calls Close
+// on a handler when it is removed, including on any decorator in the
+// handler's chain that implements Closer. Sinks are Closers too.
+type Closer interface {
+	Close() error
+}
+
+type unwrapper interface {
+	Unwrap() EventHandler
+}
+
+// closeHandler closes h and every handler it wraps that implements Closer,
+// returning the first error encountered.
+func closeHandler(h EventHandler) error {
+	var first error
+	for h != nil {
+		if c, ok := h.(Closer); ok {
+			err := c.Close()
+			if err != nil && first == nil {
+				first = err
+			}
+		}
+		u, ok := h.(unwrapper)
+		if !ok {
+			break
+		}
+		h = u.Unwrap()
+	}
+	return first
+}
@@ -0,0 +1,181 @@
+package events
+
+import (
+	"errors"
+	"runtime"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// closingHandler counts the times it is closed, and fails to close with
+// err.
+type closingHandler struct {
+	EventHandler
+	closed int32
+	err error
+}
+
+func newClosingHandler(err error) *closingHandler {
+	return &closingHandler{EventHandler: NewEventHandler(func(Event) error { return nil }), err: err}
+}
+
+func (h *closingHandler) Close() error {
+	atomic.AddInt32(&h.closed, 1)
+	return h.err
+}
+
+func TestCloseOnRemoval(t *testing.T) {
+	errClose := errors.New("close failed")
+	tests := []struct {
+		name string
+		wrap func(EventHandler) EventHandler
+		remove func(sink EventSink, h EventHandler)
+		closeErr error
+	}{
+		{
+			"removed",
+			func(h EventHandler) EventHandler { return h },
+			func(sink EventSink, h EventHandler) { sink.RemoveEventListener("test", h) },
+			nil,
+		},
+		{
+			"removed by reference",
+			func(h EventHandler) EventHandler { return h },
+			func(sink EventSink, h EventHandler) { sink.RemoveEventListener("test", HandlerReference(h.ID())) },
+			nil,
+		},
+		{
+			"wrapped and expired",
+			func(h EventHandler) EventHandler { return WithMaxCalls(WithRange(h, 0, 10), 1) },
+			func(sink EventSink, h EventHandler) { sink.Emit("test", 1.0) },
+			nil,
+		},
+		{
+			"sink closed",
+			func(h EventHandler) EventHandler { return h },
+			func(sink EventSink, h EventHandler) { sink.(Closer).Close() },
+			nil,
+		},
+		{
+			"close error",
+			func(h EventHandler) EventHandler { return h },
+			func(sink EventSink, h EventHandler) { sink.RemoveEventListener("test", h) },
+			errClose,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			sink := NewSyncEventSink(time.Minute)
+			inner := newClosingHandler(tc.closeErr)
+			h := tc.wrap(inner)
+			sink.AddEventListener("test", h)
+			tc.remove(sink, h)
+			if n := atomic.LoadInt32(&inner.closed); n != 1 {
+				t.Errorf("closed %d times, want 1", n)
+			}
+			select {
+			case he := <-sink.(ErrorSource).Errors():
+				if !errors.Is(he, tc.closeErr) {
+					t.Errorf("reported %v, want %v", he, tc.closeErr)
+				}
+			default:
+				if tc.closeErr != nil {
+					t.Errorf("close error not reported")
+				}
+			}
+		})
+	}
+}
+
+// tickingHandler runs a goroutine until it is closed.
+type tickingHandler struct {
+	EventHandler
+	stop chan struct{}
+}
+
+func newTickingHandler() *tickingHandler {
+	h := &tickingHandler{NewEventHandler(func(Event) error { return nil }), make(chan struct{})}
+	go func() {
+		ticker := time.NewTicker(time.Millisecond)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+			case <-h.stop:
+				return
+			}
+		}
+	}()
+	return h
+}
+
+func (h *tickingHandler) Close() error {
+	close(h.stop)
+	return nil
+}
+
+// waitForGoroutines waits for the number of goroutines to fall to n, and
+// returns the number left.
+func waitForGoroutines(n int, timeout time.Duration) int {
+	deadline := time.Now().Add(timeout)
+	for {
+		count := runtime.NumGoroutine()
+		if count <= n || time.Now().After(deadline) {
+			return count
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestCloseLeaksNoGoroutines(t *testing.T) {
+	tests := []struct {
+		name string
+		handlers int
+		release func(sink EventSink, handlers []EventHandler)
+	}{
+		{
+			"sink closed",
+			10,
+			func(sink EventSink, handlers []EventHandler) { sink.(Closer).Close() },
+		},
+		{
+			"listeners removed",
+			10,
+			func(sink EventSink, handlers []EventHandler) {
+				for _, h := range handlers {
+					sink.RemoveEventListener("test", h)
+				}
+				sink.(Closer).Close()
+			},
+		},
+		{
+			"drained",
+			5,
+			func(sink EventSink, handlers []EventHandler) {
+				for i := 0; i < 20; i++ {
+					sink.Emit("test", float64(i))
+				}
+				sink.(Closer).Close()
+			},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			baseline := runtime.NumGoroutine()
+			sink := NewEventSink(time.Minute, SinkReapInterval(time.Millisecond))
+			handlers := make([]EventHandler, tc.handlers)
+			for i := range handlers {
+				handlers[i] = WithTrailingDebounce(newTickingHandler(), time.Hour)
+				sink.AddEventListener("test", handlers[i])
+			}
+			if n := runtime.NumGoroutine(); n <= baseline {
+				t.Fatalf("%d goroutines after adding handlers, from %d", n, baseline)
+			}
+			tc.release(sink, handlers)
+			if n := waitForGoroutines(baseline, time.Second); n > baseline {
+				t.Errorf("%d goroutines left, want %d", n, baseline)
+			}
+		})
+	}
+}
@@ -0,0 +1,151 @@
+package events
+
+import (
+	"context"
+	"math"
+	"sync"
+)
+
+// A Condition decides whether an event should be passed on to a handler.
+// A non-nil error is returned from the handler's Call as-is.
+type Condition func(Event) (bool, error)
+
+type conditionHandler struct {
+	EventHandler
+	cond Condition
+}
+
+func WithCondition(h EventHandler, cond Condition) EventHandler {
+	return &conditionHandler{h, cond}
+}
+
+func (h *conditionHandler) Call(ev Event) error {
+	return h.CallContext(context.Background(), ev)
+}
+
+func (h *conditionHandler) CallContext(ctx context.Context, ev Event) error {
+	ok, err := h.cond(ev)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ignored(ctx, "condition: not satisfied")
+	}
+	return callContext(ctx, h.EventHandler, ev)
+}
+
+func (h *conditionHandler) Unwrap() EventHandler {
+	return h.EventHandler
+}
+
+// Not inverts c: events that satisfy c fail Not(c), and vice versa.
+// Errors from c are returned unchanged.
+func Not(c Condition) Condition {
+	return func(ev Event) (bool, error) {
+		ok, err := c(ev)
+		if err != nil {
+			return false, err
+		}
+		return !ok, nil
+	}
+}
+
+// All is satisfied by events that satisfy every one of conds, checking
+// them in order and stopping at the first that fails or returns an error.
+// All with no conditions is satisfied by every event.
+func All(conds ...Condition) Condition {
+	return func(ev Event) (bool, error) {
+		for _, c := range conds {
+			ok, err := c(ev)
+			if err != nil || !ok {
+				return false, err
+			}
+		}
+		return true, nil
+	}
+}
+
+// Any is satisfied by events that satisfy at least one of conds, checking
+// them in order and stopping at the first that passes or returns an
+// error. Any with no conditions is satisfied by no event.
+func Any(conds ...Condition) Condition {
+	return func(ev Event) (bool, error) {
+		for _, c := range conds {
+			ok, err := c(ev)
+			if err != nil {
+				return false, err
+			}
+			if ok {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+}
+
+// InRange is satisfied by value events with values in [min, max], like
+// the events WithRange passes on when min <= max. NaN values don't
+// satisfy it, and other events return ErrIncompatibleEvent, so that
+// Not(InRange(min, max)) passes values outside the range.
+func InRange(min, max float64) Condition {
+	return func(ev Event) (bool, error) {
+		valEv, ok := ev.(ValueEvent)
+		if !ok {
+			return false, ErrIncompatibleEvent
+		}
+		val := valEv.GetValue()
+		if math.IsNaN(val) {
+			return false, nil
+		}
+		return val >= min && val <= max, nil
+	}
+}
+
+// WithFilter calls h only for events that satisfy pred.
+func WithFilter(h EventHandler, pred func(Event) bool) EventHandler {
+	return WithCondition(h, func(ev Event) (bool, error) {
+		return pred(ev), nil
+	})
+}
+
+type removeWhenHandler struct {
+	EventHandler
+	pred func(Event) bool
+	done bool
+	mutex *sync.Mutex
+}
+
+// WithRemoveWhen expires h once pred returns true for an event, so that
+// the sink removes it. That event is still passed to h, making it h's last
+// call; events arriving after it return ErrExpired.
+func WithRemoveWhen(h EventHandler, pred func(Event) bool) EventHandler {
+	return &removeWhenHandler{h, pred, false, &sync.Mutex{}}
+}
+
+func (h *removeWhenHandler) Call(ev Event) error {
+	return h.CallContext(context.Background(), ev)
+}
+
+func (h *removeWhenHandler) CallContext(ctx context.Context, ev Event) error {
+	h.mutex.Lock()
+	if h.done {
+		h.mutex.Unlock()
+		return ErrExpired
+	}
+	if h.pred(ev) {
+		h.done = true
+	}
+	h.mutex.Unlock()
+	return callContext(ctx, h.EventHandler, ev)
+}
+
+func (h *removeWhenHandler) Expired() bool {
+	h.mutex.Lock()
+	done := h.done
+	h.mutex.Unlock()
+	return done || h.EventHandler.Expired()
+}
+
+func (h *removeWhenHandler) Unwrap() EventHandler {
+	return h.EventHandler
+}
@@ -0,0 +1,226 @@
+package events
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestWithCondition(t *testing.T) {
+	errCond := errors.New("condition failed")
+	tests := []struct {
+		name string
+		cond Condition
+		vals []float64
+		want []float64
+		wantErr error
+	}{
+		{"always", func(Event) (bool, error) { return true, nil }, []float64{1, 2}, []float64{1, 2}, nil},
+		{"never", func(Event) (bool, error) { return false, nil }, []float64{1, 2}, []float64{}, ErrIgnored},
+		{"in range", InRange(2, 3), []float64{1, 2, 3, 4}, []float64{2, 3}, ErrIgnored},
+		{"error", func(Event) (bool, error) { return true, errCond }, []float64{1}, []float64{}, errCond},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := []float64{}
+			var lastErr error
+			h := WithCondition(NewEventHandler(func(ev Event) error {
+				got = append(got, ev.(Valuer).GetValue())
+				return nil
+			}), tc.cond)
+			for _, val := range tc.vals {
+				if err := h.Call(NewEvent("test", val)); err != nil {
+					lastErr = err
+				}
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("passed %v, want %v", got, tc.want)
+			}
+			if !errors.Is(lastErr, tc.wantErr) {
+				t.Errorf("error = %v, want %v", lastErr, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestWithFilter(t *testing.T) {
+	tests := []struct {
+		name string
+		pred func(Event) bool
+		vals []float64
+		want []float64
+	}{
+		{"all", func(Event) bool { return true }, []float64{1, 2}, []float64{1, 2}},
+		{"none", func(Event) bool { return false }, []float64{1, 2}, []float64{}},
+		{"even", func(ev Event) bool { return int(ev.(Valuer).GetValue()) % 2 == 0 }, []float64{1, 2, 3, 4}, []float64{2, 4}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			inner := NewEventHandler(func(Event) error { return nil })
+			if h := WithFilter(inner, tc.pred); h.ID() != inner.ID() {
+				t.Errorf("filtered handler ID = %d, want %d", h.ID(), inner.ID())
+			}
+			got := passedValues(func(h EventHandler) EventHandler { return WithFilter(h, tc.pred) }, tc.vals...)
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("passed %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestWithRemoveWhen(t *testing.T) {
+	above := func(limit float64) func(Event) bool {
+		return func(ev Event) bool { return ev.(Valuer).GetValue() > limit }
+	}
+	tests := []struct {
+		name string
+		wrap func(EventHandler) EventHandler
+		vals []float64
+		want []float64
+		wantListeners int
+	}{
+		{"crosses", func(h EventHandler) EventHandler { return WithRemoveWhen(h, above(10)) }, []float64{5, 8, 12, 3, 15}, []float64{5, 8, 12}, 0},
+		{"first event", func(h EventHandler) EventHandler { return WithRemoveWhen(h, above(0)) }, []float64{1, 2}, []float64{1}, 0},
+		{"never crosses", func(h EventHandler) EventHandler { return WithRemoveWhen(h, above(10)) }, []float64{5, 8, 9}, []float64{5, 8, 9}, 1},
+		{"inner expires first", func(h EventHandler) EventHandler { return WithRemoveWhen(WithMaxCalls(h, 2), above(10)) }, []float64{1, 2, 3, 20}, []float64{1, 2}, 0},
+		{"inside a filter", func(h EventHandler) EventHandler {
+			return WithFilter(WithRemoveWhen(h, above(10)), func(ev Event) bool { return ev.(Valuer).GetValue() != 12 })
+		}, []float64{5, 12, 11, 20}, []float64{5, 11}, 0},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			sink := NewSyncEventSink(time.Hour)
+			rec := RecordingHandler()
+			sink.AddEventListener("test", tc.wrap(rec))
+			for _, val := range tc.vals {
+				sink.Emit("test", val)
+			}
+			if got := logValues(rec.Calls()); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("passed %v, want %v", got, tc.want)
+			}
+			if n := sink.(ListenerInspector).ListenerCount("test"); n != tc.wantListeners {
+				t.Errorf("%d listeners left, want %d", n, tc.wantListeners)
+			}
+		})
+	}
+}
+
+func TestWithRemoveWhenDirect(t *testing.T) {
+	rec := RecordingHandler()
+	h := WithRemoveWhen(rec, func(ev Event) bool { return ev.(Valuer).GetValue() > 10 })
+	tests := []struct {
+		name string
+		val float64
+		wantErr error
+		wantExpired bool
+	}{
+		{"below", 5, nil, false},
+		{"crossing", 12, nil, true},
+		{"after", 3, ErrExpired, true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := h.Call(NewEvent("test", tc.val)); !errors.Is(err, tc.wantErr) {
+				t.Errorf("error = %v, want %v", err, tc.wantErr)
+			}
+			if h.Expired() != tc.wantExpired {
+				t.Errorf("expired = %t, want %t", h.Expired(), tc.wantExpired)
+			}
+		})
+	}
+	if got := logValues(rec.Calls()); !reflect.DeepEqual(got, []float64{5, 12}) {
+		t.Errorf("passed %v, want [5 12]", got)
+	}
+}
+
+func TestNot(t *testing.T) {
+	vals := []float64{-1, 0, 5, 10, 10.5, math.Inf(1)}
+	tests := []struct {
+		name string
+		cond Condition
+		want []float64
+	}{
+		{"in range", InRange(0, 10), []float64{0, 5, 10}},
+		{"not in range", Not(InRange(0, 10)), []float64{-1, 10.5, math.Inf(1)}},
+		{"not not", Not(Not(InRange(0, 10))), []float64{0, 5, 10}},
+		{"not all", Not(All(InRange(0, 10), InRange(5, 20))), []float64{-1, 0, 10.5, math.Inf(1)}},
+		{"not any", Not(Any(InRange(-5, 0), InRange(10, 10))), []float64{5, 10.5, math.Inf(1)}},
+		{"all of nots", All(Not(InRange(-5, 0)), Not(InRange(10, 20))), []float64{5, math.Inf(1)}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := passedValues(func(h EventHandler) EventHandler { return WithCondition(h, tc.cond) }, vals...)
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("passed %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestNotInRangeFiresWhenRangeIgnores(t *testing.T) {
+	for _, val := range []float64{-1, 0, 3, 10, 11, math.NaN()} {
+		ev := NewEvent("test", val)
+		rangeErr := WithRange(RecordingHandler(), 0, 10).Call(ev)
+		notErr := WithCondition(RecordingHandler(), Not(InRange(0, 10))).Call(ev)
+		if errors.Is(rangeErr, ErrIgnored) == errors.Is(notErr, ErrIgnored) {
+			t.Errorf("value %g: range returned %v and not in range %v", val, rangeErr, notErr)
+		}
+	}
+}
+
+func TestConditionErrors(t *testing.T) {
+	errCond := errors.New("condition failed")
+	type check struct {
+		ok bool
+		err error
+	}
+	tests := []struct {
+		name string
+		combine func(conds ...Condition) Condition
+		checks []check
+		want bool
+		wantErr error
+		wantCalls int
+	}{
+		{"not passes", func(conds ...Condition) Condition { return Not(conds[0]) }, []check{{true, nil}}, false, nil, 1},
+		{"not fails", func(conds ...Condition) Condition { return Not(conds[0]) }, []check{{false, nil}}, true, nil, 1},
+		{"not error", func(conds ...Condition) Condition { return Not(conds[0]) }, []check{{false, errCond}}, false, errCond, 1},
+		{"all empty", All, nil, true, nil, 0},
+		{"all pass", All, []check{{true, nil}, {true, nil}}, true, nil, 2},
+		{"all stops at failure", All, []check{{true, nil}, {false, nil}, {true, nil}}, false, nil, 2},
+		{"all stops at error", All, []check{{true, errCond}, {true, nil}}, false, errCond, 1},
+		{"any empty", Any, nil, false, nil, 0},
+		{"any fail", Any, []check{{false, nil}, {false, nil}}, false, nil, 2},
+		{"any stops at pass", Any, []check{{false, nil}, {true, nil}, {false, nil}}, true, nil, 2},
+		{"any stops at error", Any, []check{{false, nil}, {true, errCond}, {true, nil}}, false, errCond, 2},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			calls := 0
+			conds := make([]Condition, len(tc.checks))
+			for i, c := range tc.checks {
+				c := c
+				conds[i] = func(Event) (bool, error) {
+					calls += 1
+					return c.ok, c.err
+				}
+			}
+			ok, err := tc.combine(conds...)(NewEvent("test", 1.0))
+			if ok != tc.want || !errors.Is(err, tc.wantErr) || (err == nil) != (tc.wantErr == nil) {
+				t.Errorf("condition = %t, %v, want %t, %v", ok, err, tc.want, tc.wantErr)
+			}
+			if calls != tc.wantCalls {
+				t.Errorf("checked %d conditions, want %d", calls, tc.wantCalls)
+			}
+		})
+	}
+}
+
+func TestNotIncompatibleEvent(t *testing.T) {
+	h := WithCondition(RecordingHandler(), Not(InRange(0, 10)))
+	if err := h.Call(NewEvent("test", "offline")); !errors.Is(err, ErrIncompatibleEvent) {
+		t.Errorf("error = %v, want ErrIncompatibleEvent", err)
+	}
+}
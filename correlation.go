@@ -0,0 +1,58 @@
+package events
+
+import (
+	"context"
+)
+
+type correlationKey struct{}
+
+// ContextWithCorrelation returns a copy of ctx carrying the given
+// correlation ID.
+func ContextWithCorrelation(ctx context.Context, corrID string) context.Context {
+	return context.WithValue(ctx, correlationKey{}, corrID)
+}
+
+// CorrelationFromContext returns the correlation ID carried by ctx, or ""
+// if there is none. Handlers called by the sink through CallContext
+// receive a context carrying the correlation ID of the event being
+// handled.
+func CorrelationFromContext(ctx context.Context) string {
+	corrID, _ := ctx.Value(correlationKey{}).(string)
+	return corrID
+}
+
+// eventContext returns the context a handler for ev is called with.
+func eventContext(ev Event) context.Context {
+	ctx := context.Background()
+	corrID := CorrelationID(ev)
+	if corrID != "" {
+		ctx = ContextWithCorrelation(ctx, corrID)
+	}
+	return ctx
+}
+
+// newEventContext creates an event carrying the correlation ID from ctx,
+// if any.
+func newEventContext(ctx context.Context, eventType string, data interface{}) Event {
+	corrID := CorrelationFromContext(ctx)
+	if corrID == "" {
+		return NewEvent(eventType, data)
+	}
+	return NewEvent(eventType, data, EventCorrelation(corrID))
+}
+
+// EmitContext is like Emit, but propagates the correlation ID carried by
+// ctx to the new event. A handler that emits derived events should use the
+// context it was called with, so that they can be traced back to the
+// event that caused them.
+func (es *basicEventSink) EmitContext(ctx context.Context, eventType string, data interface{}) {
+	es.Fire(newEventContext(ctx, eventType, data))
+}
+
+func (es *PrefixedEventSource) EmitContext(ctx context.Context, eventType string, data interface{}) {
+	emitContext(es.EventSink, ctx, es.prefixed(eventType), data)
+}
+
+func (es *LoggedEventSink) EmitContext(ctx context.Context, eventType string, data interface{}) {
+	es.Fire(newEventContext(ctx, eventType, data))
+}
@@ -0,0 +1,54 @@
+package events
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestCorrelationThroughTwoHops(t *testing.T) {
+	tests := []struct {
+		name string
+		sink func() EventSink
+		corrID string
+		wrap func(EventHandler) EventHandler
+	}{
+		{"sync", func() EventSink { return NewSyncEventSink(time.Minute) }, "req-1", func(h EventHandler) EventHandler { return h }},
+		{"async", func() EventSink { return NewEventSink(time.Minute) }, "req-2", func(h EventHandler) EventHandler { return h }},
+		{"decorated", func() EventSink { return NewSyncEventSink(time.Minute) }, "req-3", func(h EventHandler) EventHandler { return WithMaxCalls(WithRange(h, 0, 10), 5) }},
+		{"uncorrelated", func() EventSink { return NewSyncEventSink(time.Minute) }, "", func(h EventHandler) EventHandler { return h }},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			sink := tc.sink()
+			relay := func(next string) EventHandler {
+				return tc.wrap(NewContextEventHandler(func(ctx context.Context, ev Event) error {
+					sink.(ContextEmitter).EmitContext(ctx, next, ev.(Valuer).GetValue() + 1)
+					return nil
+				}))
+			}
+			sink.AddEventListener("a", relay("b"))
+			sink.AddEventListener("b", relay("c"))
+			ctxIDs := make(chan string, 1)
+			rec := RecordingHandler()
+			sink.AddEventListener("c", NewContextEventHandler(func(ctx context.Context, ev Event) error {
+				ctxIDs <- CorrelationFromContext(ctx)
+				return rec.Call(ev)
+			}))
+			sink.Fire(NewEvent("a", 1.0, EventCorrelation(tc.corrID)))
+			if !rec.WaitForCalls(1, time.Second) {
+				t.Fatal("event didn't reach the second hop")
+			}
+			ev := rec.Calls()[0]
+			if v := ev.(Valuer).GetValue(); v != 3 {
+				t.Errorf("value after two hops = %g, want 3", v)
+			}
+			if id := CorrelationID(ev); id != tc.corrID {
+				t.Errorf("event correlation ID = %q, want %q", id, tc.corrID)
+			}
+			if id := <-ctxIDs; id != tc.corrID {
+				t.Errorf("context correlation ID = %q, want %q", id, tc.corrID)
+			}
+		})
+	}
+}
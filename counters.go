@@ -0,0 +1,68 @@
+package events
+
+import (
+	"strings"
+)
+
+// EventCounters counts activity on an event type since the sink was
+// created. Unlike Stats, which only covers the events still in the log,
+// Fired and Errors never decrease.
+type EventCounters struct {
+	Fired uint64 `json:"fired"`
+	Errors uint64 `json:"errors"`
+	Listeners int `json:"listeners"`
+}
+
+// ListenerCount returns the number of listeners registered for exactly the
+// given event type, not counting listeners on its ancestors or aliases.
+func (es *basicEventSink) ListenerCount(eventType string) int {
+	es.mutex.Lock()
+	defer es.mutex.Unlock()
+	return len(es.listeners[eventType])
+}
+
+// Counters returns the counters for every event type that has been fired,
+// has had a handler fail, or currently has listeners. Errors are counted
+// against the event type the failing handler was registered for.
+func (es *basicEventSink) Counters() map[string]EventCounters {
+	es.mutex.Lock()
+	defer es.mutex.Unlock()
+	out := map[string]EventCounters{}
+	for eventType, n := range es.fired {
+		c := out[eventType]
+		c.Fired = n
+		out[eventType] = c
+	}
+	for eventType, n := range es.failed {
+		c := out[eventType]
+		c.Errors = n
+		out[eventType] = c
+	}
+	for eventType, listeners := range es.listeners {
+		c := out[eventType]
+		c.Listeners = len(listeners)
+		out[eventType] = c
+	}
+	return out
+}
+
+func (es *PrefixedEventSource) ListenerCount(eventType string) int {
+	if li, ok := es.EventSink.(ListenerInspector); ok {
+		return li.ListenerCount(es.prefix+eventType)
+	}
+	return 0
+}
+
+func (es *PrefixedEventSource) Counters() map[string]EventCounters {
+	out := map[string]EventCounters{}
+	ss, ok := es.EventSink.(StatsSink)
+	if !ok {
+		return out
+	}
+	for eventType, c := range ss.Counters() {
+		if strings.HasPrefix(eventType, es.prefix) {
+			out[strings.TrimPrefix(eventType, es.prefix)] = c
+		}
+	}
+	return out
+}
@@ -0,0 +1,152 @@
+package events
+
+import (
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// ExportCSV writes the logged events of the given types, or of every type
+// if none are given, to w as CSV, oldest first. The columns are time,
+// type, value and message, with the bytes of a binary event in base64 in
+// the value column, followed by a "data.<key>" column for each
+// scalar field found in map data, and a final "data" column holding, as
+// JSON, any data that doesn't fit in those: nested maps and slices, and
+// data that isn't a map at all.
+func (es *basicEventSink) ExportCSV(w io.Writer, eventTypes ...string) error {
+	return exportCSV(w, es.Log(), eventTypes)
+}
+
+func (es *PrefixedEventSource) ExportCSV(w io.Writer, eventTypes ...string) error {
+	return exportCSV(w, es.Log(), eventTypes)
+}
+
+func (b *Broadcaster) ExportCSV(w io.Writer, eventTypes ...string) error {
+	return exportCSV(w, b.Log(), eventTypes)
+}
+
+func exportCSV(w io.Writer, log []Event, eventTypes []string) error {
+	if len(eventTypes) > 0 {
+		want := map[string]bool{}
+		for _, eventType := range eventTypes {
+			want[eventType] = true
+		}
+		filtered := []Event{}
+		for _, ev := range log {
+			if want[ev.GetType()] {
+				filtered = append(filtered, ev)
+			}
+		}
+		log = filtered
+	}
+	keySet := map[string]bool{}
+	for _, ev := range log {
+		if data, ok := ev.GetData().(map[string]interface{}); ok {
+			for k, v := range data {
+				if isScalar(v) {
+					keySet[k] = true
+				}
+			}
+		}
+	}
+	keys := make([]string, 0, len(keySet))
+	for k := range keySet {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	header := []string{"time", "type", "value", "message"}
+	for _, k := range keys {
+		header = append(header, "data."+k)
+	}
+	header = append(header, "data")
+	cw := csv.NewWriter(w)
+	err := cw.Write(header)
+	if err != nil {
+		return err
+	}
+	for i := len(log) - 1; i >= 0; i-- {
+		record, err := csvRecord(log[i], keys)
+		if err != nil {
+			return err
+		}
+		err = cw.Write(record)
+		if err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func csvRecord(ev Event, keys []string) ([]string, error) {
+	record := make([]string, 4, len(keys)+5)
+	record[0] = ev.GetTime().Format(time.RFC3339Nano)
+	record[1] = ev.GetType()
+	if vev, ok := ev.(ValueEvent); ok {
+		record[2] = strconv.FormatFloat(vev.GetValue(), 'g', -1, 64)
+	}
+	if mev, ok := ev.(MessageEvent); ok {
+		record[3] = mev.GetMessage()
+	}
+	if bev, ok := ev.(BinaryEvent); ok {
+		record[2] = base64.StdEncoding.EncodeToString(bev.GetBytes())
+	}
+	var extra interface{}
+	data, isMap := ev.GetData().(map[string]interface{})
+	if isMap {
+		rest := map[string]interface{}{}
+		for k, v := range data {
+			if !isScalar(v) {
+				rest[k] = v
+			}
+		}
+		if len(rest) > 0 {
+			extra = rest
+		}
+	} else {
+		extra = ev.GetData()
+	}
+	for _, k := range keys {
+		v, ok := data[k]
+		if !ok || !isScalar(v) {
+			record = append(record, "")
+			continue
+		}
+		record = append(record, scalarString(v))
+	}
+	if extra == nil {
+		return append(record, ""), nil
+	}
+	js, err := json.Marshal(extra)
+	if err != nil {
+		return nil, err
+	}
+	return append(record, string(js)), nil
+}
+
+func isScalar(v interface{}) bool {
+	switch v.(type) {
+	case nil, string, bool, float64, float32, int, int64, int32, int16, int8, uint, uint64, uint32, uint16, uint8:
+		return true
+	}
+	return false
+}
+
+func scalarString(v interface{}) string {
+	switch tv := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return tv
+	case float64:
+		return strconv.FormatFloat(tv, 'g', -1, 64)
+	case float32:
+		return strconv.FormatFloat(float64(tv), 'g', -1, 32)
+	}
+	js, _ := json.Marshal(v)
+	return string(js)
+}
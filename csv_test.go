@@ -0,0 +1,93 @@
+package events
+
+import (
+	"bytes"
+	"encoding/csv"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestExportCSV(t *testing.T) {
+	tests := []struct {
+		name string
+		eventTypes []string
+		want [][]string
+	}{
+		{
+			"everything",
+			nil,
+			[][]string{
+				{"time", "type", "value", "message", "data.count", "data.on", "data.room", "data"},
+				{"2024-01-01T00:00:01Z", "temp", "21.5", "", "", "", "", ""},
+				{"2024-01-01T00:00:02Z", "door", "", "open, \"wide\"\nreally", "", "", "", ""},
+				{"2024-01-01T00:00:03Z", "status", "", "", "2", "true", "kitchen", `{"list":[1,2],"nested":{"a":1}}`},
+				{"2024-01-01T00:00:04Z", "status", "", "", "", "", "garage", ""},
+				{"2024-01-01T00:00:05Z", "blob", "3q2+7w==", "", "", "", "", ""},
+			},
+		},
+		{
+			"binary",
+			[]string{"blob"},
+			[][]string{
+				{"time", "type", "value", "message", "data"},
+				{"2024-01-01T00:00:05Z", "blob", "3q2+7w==", "", ""},
+			},
+		},
+		{
+			"one type",
+			[]string{"temp"},
+			[][]string{
+				{"time", "type", "value", "message", "data"},
+				{"2024-01-01T00:00:01Z", "temp", "21.5", "", ""},
+			},
+		},
+		{
+			"two types",
+			[]string{"temp", "door"},
+			[][]string{
+				{"time", "type", "value", "message", "data"},
+				{"2024-01-01T00:00:01Z", "temp", "21.5", "", ""},
+				{"2024-01-01T00:00:02Z", "door", "", "open, \"wide\"\nreally", ""},
+			},
+		},
+		{
+			"unknown type",
+			[]string{"window"},
+			[][]string{{"time", "type", "value", "message", "data"}},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			c := useFakeClock(t)
+			sink := NewSyncEventSink(time.Hour)
+			c.Advance(time.Second)
+			sink.Emit("temp", 21.5)
+			c.Advance(time.Second)
+			sink.Emit("door", "open, \"wide\"\nreally")
+			c.Advance(time.Second)
+			sink.Emit("status", map[string]interface{}{
+				"room": "kitchen",
+				"on": true,
+				"count": 2,
+				"nested": map[string]interface{}{"a": 1},
+				"list": []int{1, 2},
+			})
+			c.Advance(time.Second)
+			sink.Emit("status", map[string]interface{}{"room": "garage"})
+			c.Advance(time.Second)
+			sink.Emit("blob", []byte{0xde, 0xad, 0xbe, 0xef})
+			buf := &bytes.Buffer{}
+			if err := sink.(CSVExporter).ExportCSV(buf, tc.eventTypes...); err != nil {
+				t.Fatalf("export failed: %s", err)
+			}
+			got, err := csv.NewReader(buf).ReadAll()
+			if err != nil {
+				t.Fatalf("can't parse %s: %s", buf, err)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("exported %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
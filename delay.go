@@ -0,0 +1,135 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+type delayedCall struct {
+	ev Event
+	ctx context.Context
+	timer *time.Timer
+}
+
+type delayHandler struct {
+	EventHandler
+	delay time.Duration
+	pending []*delayedCall
+	mutex *sync.Mutex
+}
+
+// WithDelay passes each event on to h after d has passed, from a timer.
+// Unlike WithTrailingDebounce, every event is passed on. Since h is called
+// later, Call always returns ErrIgnored; errors from the deferred call are
+// reported on the sink's Errors channel and as EventTypeHandlerError
+// events instead. Closing the sink delivers pending events immediately
+// (see Drainable), while removing the handler discards them.
+func WithDelay(h EventHandler, d time.Duration) EventHandler {
+	if d <= 0 {
+		return h
+	}
+	return &delayHandler{h, d, nil, &sync.Mutex{}}
+}
+
+func (h *delayHandler) Call(ev Event) error {
+	return h.CallContext(context.Background(), ev)
+}
+
+func (h *delayHandler) CallContext(ctx context.Context, ev Event) error {
+	dc := &delayedCall{ev: ev, ctx: detach(ctx)}
+	h.mutex.Lock()
+	h.pending = append(h.pending, dc)
+	dc.timer = time.AfterFunc(h.delay, func() { h.fire(dc) })
+	h.mutex.Unlock()
+	return ignored(ctx, "delay: deferred for %s", h.delay)
+}
+
+func (h *delayHandler) fire(dc *delayedCall) {
+	if !h.take(dc) || expired(dc.ev, now()) {
+		return
+	}
+	err := callContext(dc.ctx, h.EventHandler, dc.ev)
+	if err != nil && !errors.Is(err, ErrIgnored) && !errors.Is(err, ErrExpired) {
+		reportTo(dc.ctx, err)
+	}
+}
+
+// take removes dc from the pending calls, returning false if it was
+// already drained or discarded.
+func (h *delayHandler) take(dc *delayedCall) bool {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	for i, p := range h.pending {
+		if p == dc {
+			h.pending = append(h.pending[:i], h.pending[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Drain returns the pending events, in the order they arrived, without
+// waiting for their timers.
+func (h *delayHandler) Drain() []Event {
+	h.mutex.Lock()
+	pending := h.pending
+	h.pending = nil
+	h.mutex.Unlock()
+	evs := []Event{}
+	t := now()
+	for _, dc := range pending {
+		dc.timer.Stop()
+		if !expired(dc.ev, t) {
+			evs = append(evs, dc.ev)
+		}
+	}
+	return evs
+}
+
+func (h *delayHandler) Close() error {
+	h.mutex.Lock()
+	pending := h.pending
+	h.pending = nil
+	h.mutex.Unlock()
+	for _, dc := range pending {
+		dc.timer.Stop()
+	}
+	return nil
+}
+
+func (h *delayHandler) Unwrap() EventHandler {
+	return h.EventHandler
+}
+
+// detachedContext carries the values of the context it was made from, but
+// not its deadline or cancellation, for handlers that pass a call on after
+// the sink has finished with it. It drops the slot for the call's result,
+// which the sink has already read.
+type detachedContext struct {
+	context.Context
+}
+
+func detach(ctx context.Context) context.Context {
+	return detachedContext{ctx}
+}
+
+func (ctx detachedContext) Deadline() (time.Time, bool) {
+	return time.Time{}, false
+}
+
+func (ctx detachedContext) Done() <-chan struct{} {
+	return nil
+}
+
+func (ctx detachedContext) Err() error {
+	return nil
+}
+
+func (ctx detachedContext) Value(key interface{}) interface{} {
+	if _, ok := key.(resultKey); ok {
+		return nil
+	}
+	return ctx.Context.Value(key)
+}
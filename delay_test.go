@@ -0,0 +1,121 @@
+package events
+
+import (
+	"errors"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestWithDelay(t *testing.T) {
+	tests := []struct {
+		name string
+		delay time.Duration
+		vals []float64
+		wantErr error
+	}{
+		{"one", 20 * time.Millisecond, []float64{1}, ErrIgnored},
+		{"several", 20 * time.Millisecond, []float64{1, 2, 3}, ErrIgnored},
+		{"no delay", 0, []float64{1, 2}, nil},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := RecordingHandler()
+			h := WithDelay(rec, tc.delay)
+			start := time.Now()
+			for _, val := range tc.vals {
+				if err := h.Call(NewEvent("test", val)); !errors.Is(err, tc.wantErr) {
+					t.Errorf("error = %v, want %v", err, tc.wantErr)
+				}
+			}
+			if tc.delay > 0 && len(rec.Calls()) != 0 {
+				t.Fatal("called before the delay")
+			}
+			if !rec.WaitForCalls(len(tc.vals), time.Second) {
+				t.Fatalf("%d calls, want %d", len(rec.Calls()), len(tc.vals))
+			}
+			if elapsed := time.Since(start); elapsed < tc.delay {
+				t.Errorf("called after %s, want at least %s", elapsed, tc.delay)
+			}
+			// each event has its own timer, so they may be passed on in
+			// any order
+			got := logValues(rec.Calls())
+			sort.Float64s(got)
+			if !reflect.DeepEqual(got, tc.vals) {
+				t.Errorf("called with %v, want %v", got, tc.vals)
+			}
+		})
+	}
+}
+
+func TestWithDelayErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		err error
+		want int
+	}{
+		{"success", nil, 0},
+		{"failure", errBoom, 1},
+		{"ignored", ErrIgnored, 0},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			sink := NewSyncEventSink(time.Hour)
+			rec := RecordingHandler()
+			err := tc.err
+			sink.AddEventListener("test", WithDelay(NewEventHandler(func(ev Event) error {
+				rec.Call(ev)
+				return err
+			}), 10 * time.Millisecond))
+			// the immediate ErrIgnored isn't reported
+			sink.Emit("test", 1.0)
+			if errs := drainErrors(sink.(ErrorSource).Errors()); len(errs) != 0 {
+				t.Fatalf("errors before the delay: %v", errs)
+			}
+			rec.WaitForCalls(1, time.Second)
+			errs := []HandlerError{}
+			deadline := time.Now().Add(100 * time.Millisecond)
+			for len(errs) < tc.want && time.Now().Before(deadline) {
+				errs = append(errs, drainErrors(sink.(ErrorSource).Errors())...)
+				time.Sleep(time.Millisecond)
+			}
+			if len(errs) != tc.want {
+				t.Fatalf("%d errors, want %d", len(errs), tc.want)
+			}
+			for _, he := range errs {
+				if !errors.Is(he, tc.err) || he.EventType != "test" {
+					t.Errorf("error = %+v, want %v on test", he, tc.err)
+				}
+			}
+		})
+	}
+}
+
+func TestWithDelayCancelled(t *testing.T) {
+	tests := []struct {
+		name string
+		cancel func(sink EventSink, h EventHandler)
+		want []float64
+	}{
+		{"handler closed", func(sink EventSink, h EventHandler) { h.(Closer).Close() }, []float64{}},
+		{"listener removed", func(sink EventSink, h EventHandler) { sink.(SyncSink).RemoveEventListenerSync("test", h) }, []float64{}},
+		{"sink closed", func(sink EventSink, h EventHandler) { sink.(Closer).Close() }, []float64{1, 2}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			sink := NewSyncEventSink(time.Hour)
+			rec := RecordingHandler()
+			h := WithDelay(rec, 20 * time.Millisecond)
+			sink.AddEventListener("test", h)
+			sink.Emit("test", 1.0)
+			sink.Emit("test", 2.0)
+			tc.cancel(sink, h)
+			// sink delivers at once when closed, the others never do
+			time.Sleep(50 * time.Millisecond)
+			if got := logValues(rec.Calls()); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("called with %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
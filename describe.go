@@ -0,0 +1,189 @@
+package events
+
+import (
+	"fmt"
+	"time"
+)
+
+// A Describer is a handler that can describe its configuration, for
+// troubleshooting a chain of decorators. Describe returns one entry for
+// the handler itself followed by the entries for the handlers it wraps,
+// outermost first, such as
+//
+//	["max calls 3", "range [10, 20]", "debounce 1s", "handler"]
+//
+// The decorators in this package implement it.
+type Describer interface {
+	Describe() []string
+}
+
+type HandlerDescription struct {
+	HandlerID int64 `json:"handler_id"`
+	Chain []string `json:"chain"`
+}
+
+// Describe describes h and the handlers it wraps, outermost first. A
+// handler that isn't a Describer is described by its Go type.
+func Describe(h EventHandler) []string {
+	if h == nil {
+		return nil
+	}
+	if d, ok := h.(Describer); ok {
+		return d.Describe()
+	}
+	desc := fmt.Sprintf("%T", h)
+	if u, ok := h.(unwrapper); ok {
+		return describeChain(desc, u.Unwrap())
+	}
+	return []string{desc}
+}
+
+func describeChain(desc string, inner EventHandler) []string {
+	return append([]string{desc}, Describe(inner)...)
+}
+
+// DescribeHandlers describes the handlers for eventType, in the order
+// they are called.
+func (es *basicEventSink) DescribeHandlers(eventType string) []HandlerDescription {
+	es.mutex.Lock()
+	listeners := es.matchListeners(eventType)
+	es.mutex.Unlock()
+	out := make([]HandlerDescription, len(listeners))
+	for i, l := range listeners {
+		out[i] = HandlerDescription{l.handler.ID(), Describe(l.handler)}
+	}
+	return out
+}
+
+func (es *PrefixedEventSource) DescribeHandlers(eventType string) []HandlerDescription {
+	if li, ok := es.EventSink.(ListenerInspector); ok {
+		return li.DescribeHandlers(es.prefix+eventType)
+	}
+	return nil
+}
+
+func (es *MappedEventSource) DescribeHandlers(eventType string) []HandlerDescription {
+	if li, ok := es.EventSink.(ListenerInspector); ok {
+		return li.DescribeHandlers(es.sinkType(eventType))
+	}
+	return nil
+}
+
+func (eh *basicEventHandler) Describe() []string {
+	return []string{"handler"}
+}
+
+func (eh *resultHandler) Describe() []string {
+	return []string{"result handler"}
+}
+
+func (h *conditionHandler) Describe() []string {
+	return describeChain("condition", h.EventHandler)
+}
+
+func (h *removeWhenHandler) Describe() []string {
+	return describeChain("remove when", h.EventHandler)
+}
+
+func (h *delayHandler) Describe() []string {
+	return describeChain(fmt.Sprintf("delay %s", h.delay), h.EventHandler)
+}
+
+func (h *maxCallsHandler) Describe() []string {
+	return describeChain(fmt.Sprintf("max calls %d", h.maxCalls), h.EventHandler)
+}
+
+func (h *errorRateHandler) Describe() []string {
+	return describeChain(fmt.Sprintf("error rate limit %d per %s", h.max, h.window), h.EventHandler)
+}
+
+func (h *timeoutHandler) Describe() []string {
+	return describeChain(fmt.Sprintf("timeout at %s", h.endTime.Format(time.RFC3339)), h.EventHandler)
+}
+
+func (h *directionHandler) Describe() []string {
+	desc := fmt.Sprintf("direction %s", h.targetDirection)
+	if h.epsilon > 0 {
+		desc += fmt.Sprintf(" within %g", h.epsilon)
+	}
+	return describeChain(desc, h.EventHandler)
+}
+
+func (h *thresholdHandler) Describe() []string {
+	desc := fmt.Sprintf("threshold %s trigger %g reset %g", h.direction, h.triggerVal, h.resetVal)
+	if h.flags & ThresholdExclusiveTrigger != 0 {
+		desc += " exclusive trigger"
+	}
+	if h.flags & ThresholdExclusiveReset != 0 {
+		desc += " exclusive reset"
+	}
+	return describeChain(desc, h.EventHandler)
+}
+
+func (h *rangeHandler) Describe() []string {
+	return describeChain(fmt.Sprintf("range [%g, %g]", h.min, h.max), h.EventHandler)
+}
+
+func (h *debounceHandler) Describe() []string {
+	return describeChain(fmt.Sprintf("debounce %s", h.ttl), h.EventHandler)
+}
+
+func (h *trailingDebounceHandler) Describe() []string {
+	return describeChain(fmt.Sprintf("trailing debounce %s", h.ttl), h.EventHandler)
+}
+
+func (h *backpressureHandler) Describe() []string {
+	return describeChain(fmt.Sprintf("backpressure %d", cap(h.sem)), h.EventHandler)
+}
+
+func (h *onErrorHandler) Describe() []string {
+	return describeChain("on error", h.EventHandler)
+}
+
+func (h *hardTimeoutHandler) Describe() []string {
+	return describeChain(fmt.Sprintf("hard timeout %s", h.timeout), h.EventHandler)
+}
+
+func (h *samplingHandler) Describe() []string {
+	return describeChain(fmt.Sprintf("sampling every %d", h.n), h.EventHandler)
+}
+
+func (h *countThresholdHandler) Describe() []string {
+	desc := fmt.Sprintf("count threshold %d per %s", len(h.times), h.window)
+	if h.hold {
+		desc += " hold"
+	}
+	return describeChain(desc, h.EventHandler)
+}
+
+func (h *randomSamplingHandler) Describe() []string {
+	return describeChain(fmt.Sprintf("random sampling %g", h.p), h.EventHandler)
+}
+
+func (h *loggingHandler) Describe() []string {
+	return describeChain("logging", h.EventHandler)
+}
+
+func (h *changeOnlyHandler) Describe() []string {
+	return describeChain(fmt.Sprintf("change only %g", h.epsilon), h.EventHandler)
+}
+
+func (h *emaHandler) Describe() []string {
+	return describeChain(fmt.Sprintf("ema %g", h.alpha), h.EventHandler)
+}
+
+func (h *windowedAverageHandler) Describe() []string {
+	return describeChain(fmt.Sprintf("windowed average %s", h.window), h.EventHandler)
+}
+
+func (h *clampHandler) Describe() []string {
+	return describeChain(fmt.Sprintf("clamp [%g, %g]", h.lo, h.hi), h.EventHandler)
+}
+
+func (h *unitConversionHandler) Describe() []string {
+	return describeChain(fmt.Sprintf("convert %s to %s", h.from, h.to), h.EventHandler)
+}
+
+func (h *normalizeHandler) Describe() []string {
+	return describeChain(fmt.Sprintf("normalize [%g, %g]", h.min, h.max), h.EventHandler)
+}
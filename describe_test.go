@@ -0,0 +1,98 @@
+package events
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// opaqueWrapper is a decorator from outside the package, which doesn't
+// describe itself.
+type opaqueWrapper struct {
+	EventHandler
+}
+
+func (h *opaqueWrapper) Unwrap() EventHandler {
+	return h.EventHandler
+}
+
+func TestDescribe(t *testing.T) {
+	base := func() EventHandler { return NewEventHandler(func(Event) error { return nil }) }
+	tests := []struct {
+		name string
+		h EventHandler
+		want []string
+	}{
+		{"nil", nil, nil},
+		{"plain", base(), []string{"handler"}},
+		{"range", WithRange(base(), 10, 20), []string{"range [10, 20]", "handler"}},
+		{
+			"chain",
+			WithMaxCalls(WithRange(WithDebounce(base(), time.Second), 10, 20), 3),
+			[]string{"max calls 3", "range [10, 20]", "debounce 1s", "handler"},
+		},
+		{
+			"threshold",
+			WithThreshold(WithSampling(base(), 2), DirectionIncreasing, 10, 5),
+			[]string{"threshold increasing trigger 10 reset 5", "sampling every 2", "handler"},
+		},
+		{
+			"count threshold",
+			WithCountThresholdHold(WithClamp(base(), 0, 1), 3, time.Minute),
+			[]string{"count threshold 3 per 1m0s hold", "clamp [0, 1]", "handler"},
+		},
+		{"direction", WithDirectionSteady(base(), 0.5), []string{"direction steady within 0.5", "handler"}},
+		{"foreign decorator", &opaqueWrapper{WithRange(base(), 0, 1)}, []string{"*events.opaqueWrapper", "range [0, 1]", "handler"}},
+		{"foreign handler", WithMaxCalls(&closingHandler{}, 1), []string{"max calls 1", "*events.closingHandler"}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := Describe(tc.h); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("description = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestDescribeThresholdFlags(t *testing.T) {
+	h, err := WithThresholdFlags(RecordingHandler(), DirectionDecreasing, 5, 10, ThresholdExclusiveTrigger | ThresholdExclusiveReset)
+	if err != nil {
+		t.Fatalf("can't make threshold: %s", err)
+	}
+	want := "threshold decreasing trigger 5 reset 10 exclusive trigger exclusive reset"
+	if got := Describe(h); len(got) == 0 || got[0] != want {
+		t.Errorf("description = %q, want %q first", got, want)
+	}
+}
+
+func TestDescribeHandlers(t *testing.T) {
+	sinks := []struct {
+		name string
+		make func() EventSink
+	}{
+		{"sink", func() EventSink { return NewSyncEventSink(time.Hour) }},
+		{"prefixed", func() EventSink { return NewPrefixedEventSource("kitchen", NewSyncEventSink(time.Hour)) }},
+		{"mapped", func() EventSink { return NewMappedEventSource(map[string]string{"t": "temp"}, NewSyncEventSink(time.Hour)) }},
+	}
+	for _, sc := range sinks {
+		t.Run(sc.name, func(t *testing.T) {
+			view := sc.make()
+			first := WithRange(RecordingHandler(), 10, 20)
+			second := WithMaxCalls(WithDebounce(RecordingHandler(), time.Second), 2)
+			view.AddEventListener("temp", first)
+			view.(ListenerManager).AddEventListenerWithPriority("temp", 5, second)
+			view.AddEventListener("other", RecordingHandler())
+			got := view.(ListenerInspector).DescribeHandlers("temp")
+			want := []HandlerDescription{
+				{second.ID(), []string{"max calls 2", "debounce 1s", "*events.Recorder"}},
+				{first.ID(), []string{"range [10, 20]", "*events.Recorder"}},
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("handlers = %+v, want %+v", got, want)
+			}
+			if got := view.(ListenerInspector).DescribeHandlers("none"); len(got) != 0 {
+				t.Errorf("handlers for an unused type = %+v", got)
+			}
+		})
+	}
+}
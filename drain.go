@@ -0,0 +1,50 @@
+package events
+
+import (
+	"errors"
+)
+
+// A Drainable is a handler that holds events to pass on later, such as a
+// trailing debounce. Drain returns the held events and forgets them. When
+// a sink is closed, it drains every drainable handler in each listener's
+// chain and calls the handler it wraps with the drained events, one at a
+// time and in order, before Close returns, so that no held event is lost.
+type Drainable interface {
+	Drain() []Event
+}
+
+// drainListeners drains every listener on the sink.
+func (es *basicEventSink) drainListeners() {
+	es.mutex.Lock()
+	all := []typedListener{}
+	for eventType, listeners := range es.listeners {
+		for _, h := range listeners {
+			all = append(all, typedListener{eventType, h})
+		}
+	}
+	es.mutex.Unlock()
+	for _, l := range all {
+		es.drain(l.eventType, l.handler)
+	}
+}
+
+// drain drains each drainable handler in h's chain, from the outside in,
+// passing the drained events to the handler it wraps.
+func (es *basicEventSink) drain(eventType string, h EventHandler) {
+	for h != nil {
+		u, ok := h.(unwrapper)
+		if !ok {
+			return
+		}
+		inner := u.Unwrap()
+		if d, ok := h.(Drainable); ok && inner != nil {
+			for _, ev := range d.Drain() {
+				err := callContext(eventContext(ev), inner, ev)
+				if err != nil && !errors.Is(err, ErrIgnored) && !errors.Is(err, ErrExpired) {
+					es.reportError(eventType, h, ev, err)
+				}
+			}
+		}
+		h = inner
+	}
+}
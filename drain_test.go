@@ -0,0 +1,65 @@
+package events
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCloseDrains(t *testing.T) {
+	tests := []struct {
+		name string
+		wrap func(EventHandler) EventHandler
+		fires int
+		want []float64
+	}{
+		{"nothing held", func(h EventHandler) EventHandler { return h }, 3, []float64{0, 1, 2}},
+		{"trailing debounce", func(h EventHandler) EventHandler { return WithTrailingDebounce(h, time.Hour) }, 3, []float64{2}},
+		{"delay", func(h EventHandler) EventHandler { return WithDelay(h, time.Hour) }, 3, []float64{0, 1, 2}},
+		{"filtered debounce", func(h EventHandler) EventHandler {
+			return WithFilter(WithTrailingDebounce(h, time.Hour), func(ev Event) bool { return true })
+		}, 3, []float64{2}},
+		{"delay inside debounce", func(h EventHandler) EventHandler {
+			return WithTrailingDebounce(WithDelay(h, time.Hour), time.Hour)
+		}, 3, []float64{2}},
+		{"nothing fired", func(h EventHandler) EventHandler { return WithDelay(h, time.Hour) }, 0, []float64{}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			srv, reqs := webhookServer(t, http.StatusOK, "")
+			hook := &Webhook{Method: http.MethodPost, URL: srv.URL}
+			sink := NewSyncEventSink(time.Hour)
+			sink.AddEventListener("temp", tc.wrap(NewEventHandler(hook.Func())))
+			for i := 0; i < tc.fires; i++ {
+				sink.Emit("temp", float64(i))
+			}
+			sink.(Closer).Close()
+			got := []float64{}
+			for _, req := range reqs() {
+				ev, err := UnmarshalEvent(req.body)
+				if err != nil {
+					t.Fatalf("can't decode %s: %s", req.body, err)
+				}
+				got = append(got, ev.(Valuer).GetValue())
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("webhook got %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestCloseDrainsExpired(t *testing.T) {
+	c := useFakeClock(t)
+	sink := NewSyncEventSink(time.Hour)
+	rec := RecordingHandler()
+	sink.AddEventListener("temp", WithDelay(rec, time.Hour))
+	sink.Fire(NewEvent("temp", 1.0, EventExpiry(c.Now().Add(time.Minute))))
+	sink.Fire(NewEvent("temp", 2.0))
+	c.Advance(2 * time.Minute)
+	sink.(Closer).Close()
+	if got := logValues(rec.Calls()); len(got) != 1 || got[0] != 2 {
+		t.Errorf("drained %v, want [2]", got)
+	}
+}
@@ -0,0 +1,71 @@
+package events
+
+import (
+	"context"
+	"fmt"
+)
+
+// ErrorBufferSize is the capacity of the channel returned by a sink's
+// Errors method.
+const ErrorBufferSize = 64
+
+type HandlerError struct {
+	EventType string
+	HandlerID int64
+	Event Event
+	Err error
+}
+
+func (he HandlerError) Error() string {
+	return fmt.Sprintf("handler %d for %s: %s", he.HandlerID, he.EventType, he.Err)
+}
+
+func (he HandlerError) Unwrap() error {
+	return he.Err
+}
+
+// Errors returns a channel of handler failures, as a lighter alternative
+// to listening for EventTypeHandlerError events. The channel holds up to
+// ErrorBufferSize errors; errors arriving while it is full are dropped
+// rather than blocking dispatch. The channel is never closed.
+func (es *basicEventSink) Errors() <-chan HandlerError {
+	return es.errs
+}
+
+type reporterKey struct{}
+
+// withReporter returns a copy of ctx through which handlers that call
+// their wrapped handler later, outside of Call, can report its errors.
+func withReporter(ctx context.Context, report func(error)) context.Context {
+	return context.WithValue(ctx, reporterKey{}, report)
+}
+
+// reportTo reports err through ctx's reporter, if it has one.
+func reportTo(ctx context.Context, err error) {
+	if report, ok := ctx.Value(reporterKey{}).(func(error)); ok {
+		report(err)
+	}
+}
+
+func (es *basicEventSink) reportError(eventType string, h EventHandler, ev Event, err error) {
+	es.mutex.Lock()
+	if es.tracksType(eventType) {
+		es.failed[eventType] += 1
+	}
+	depth, ok := es.metaDepth()
+	es.mutex.Unlock()
+	select {
+	case es.errs <- HandlerError{eventType, h.ID(), ev, err}:
+	default:
+	}
+	if !ok {
+		return
+	}
+	data := &ListenerMeta{
+		EventType: eventType,
+		HandlerID: h.ID(),
+		Error: err.Error(),
+		Depth: depth,
+	}
+	es.Emit(EventTypeHandlerError, data)
+}
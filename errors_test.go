@@ -0,0 +1,59 @@
+package events
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+var errBoom = errors.New("boom")
+
+// drainErrors returns the errors waiting on ch.
+func drainErrors(ch <-chan HandlerError) []HandlerError {
+	out := []HandlerError{}
+	for {
+		select {
+		case he := <-ch:
+			out = append(out, he)
+		default:
+			return out
+		}
+	}
+}
+
+func TestErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		err error
+		fires int
+		want int
+	}{
+		{"success", nil, 3, 0},
+		{"failure", errBoom, 3, 3},
+		{"ignored", ErrIgnored, 3, 0},
+		{"stop propagation", ErrStopPropagation, 3, 0},
+		{"overflow", errBoom, ErrorBufferSize + 10, ErrorBufferSize},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			sink := NewSyncEventSink(time.Minute)
+			h := NewEventHandler(func(ev Event) error { return tc.err })
+			sink.AddEventListener("test", h)
+			for i := 0; i < tc.fires; i++ {
+				sink.Emit("test", float64(i))
+			}
+			got := drainErrors(sink.(ErrorSource).Errors())
+			if len(got) != tc.want {
+				t.Fatalf("%d errors, want %d", len(got), tc.want)
+			}
+			for i, he := range got {
+				if he.EventType != "test" || he.HandlerID != h.ID() || !errors.Is(he, tc.err) {
+					t.Errorf("error %d = %+v", i, he)
+				}
+				if v := he.Event.(Valuer).GetValue(); v != float64(i) {
+					t.Errorf("error %d for event %g", i, v)
+				}
+			}
+		})
+	}
+}
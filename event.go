@@ -2,6 +2,9 @@ package events
 
 import (
 	"fmt"
+	"math"
+	"strconv"
+	"sync/atomic"
 	"time"
 )
 
@@ -9,6 +12,10 @@ const (
 	EventTypeHandlerAdded   = "listener-add"
 	EventTypeHandlerRemoved = "listener-remove"
 	EventTypeHandlerError   = "listener-error"
+	EventTypeValidationError = "validation-error"
+	EventTypeDispatchDropped = "dispatch-dropped"
+	EventTypeStormDetected = "storm-detected"
+	EventTypeSinkClosing = "sink-closing"
 )
 
 type Valuer interface {
@@ -32,10 +39,68 @@ type MessageEvent interface {
 	GetMessage() string
 }
 
+// Correlated is implemented by events carrying a correlation ID, which
+// ties together an event and the events derived from it.
+type Correlated interface {
+	GetCorrelationID() string
+}
+
+// Sequenced is implemented by events carrying a sequence number, assigned
+// by the sink that fires them. Sequence numbers increase strictly with
+// each event a sink fires, so they order events even when their
+// timestamps are equal. A sink numbers its own copy of each event it
+// fires, which is what it logs and passes to listeners, and leaves the
+// caller's event as it was, so the same event may be fired on several
+// sinks, or fired again, each copy with its own number.
+type Sequenced interface {
+	GetSeq() uint64
+}
+
+// Expirable is implemented by events that stop being worth delivering
+// after a point in time. A sink doesn't call handlers with an event that
+// has expired by the time it is delivered, though the event is still
+// logged.
+type Expirable interface {
+	GetExpiry() time.Time
+}
+
+// Prioritized is implemented by events with a priority. A sink with a
+// bounded log keeps higher priority events longer; see
+// NewRingBufferEventSink. Events without a priority have priority 0.
+type Prioritized interface {
+	GetPriority() int
+}
+
+// Labeled is implemented by events carrying key/value labels, which
+// describe an event beyond its type, for filtering and routing.
+type Labeled interface {
+	GetLabels() map[string]string
+}
+
+// Versioned is implemented by events carrying a schema version for their
+// payload. Events without a version have version 0. See RegisterMigration.
+type Versioned interface {
+	GetVersion() int
+}
+
+type versioner interface {
+	setVersion(version int)
+}
+
+type sequencer interface {
+	setSeq(seq uint64)
+}
+
 type basicEvent struct {
 	Type    string      `json:"type"`
 	Time    time.Time   `json:"time"`
 	Data    interface{} `json:"data,omitempty"`
+	CorrelationID string `json:"correlation_id,omitempty"`
+	Seq uint64 `json:"seq,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	Priority int `json:"priority,omitempty"`
+	Labels map[string]string `json:"labels,omitempty"`
+	Version int `json:"version,omitempty"`
 }
 
 func (ev *basicEvent) GetType() string {
@@ -50,11 +115,52 @@ func (ev *basicEvent) GetData() interface{} {
 	return ev.Data
 }
 
+func (ev *basicEvent) GetCorrelationID() string {
+	return ev.CorrelationID
+}
+
+func (ev *basicEvent) GetSeq() uint64 {
+	return atomic.LoadUint64(&ev.Seq)
+}
+
+func (ev *basicEvent) GetVersion() int {
+	return ev.Version
+}
+
+func (ev *basicEvent) setVersion(version int) {
+	ev.Version = version
+}
+
+func (ev *basicEvent) GetLabels() map[string]string {
+	return ev.Labels
+}
+
+func (ev *basicEvent) GetPriority() int {
+	return ev.Priority
+}
+
+func (ev *basicEvent) GetExpiry() time.Time {
+	if ev.ExpiresAt == nil {
+		return time.Time{}
+	}
+	return *ev.ExpiresAt
+}
+
+func (ev *basicEvent) setSeq(seq uint64) {
+	atomic.StoreUint64(&ev.Seq, seq)
+}
+
 func (ev *basicEvent) As(eventType string) Event {
 	return &basicEvent{
 		Type: eventType,
 		Time: ev.Time,
 		Data: ev.Data,
+		CorrelationID: ev.CorrelationID,
+		Seq: ev.GetSeq(),
+		ExpiresAt: ev.ExpiresAt,
+		Priority: ev.Priority,
+		Labels: ev.Labels,
+		Version: ev.Version,
 	}
 }
 
@@ -71,6 +177,46 @@ func (ev *valueEvent) As(eventType string) Event {
 	return &valueEvent{ev.Event.As(eventType), ev.Value}
 }
 
+func (ev *valueEvent) GetCorrelationID() string {
+	return CorrelationID(ev.Event)
+}
+
+func (ev *valueEvent) GetSeq() uint64 {
+	return Seq(ev.Event)
+}
+
+func (ev *valueEvent) GetExpiry() time.Time {
+	return Expiry(ev.Event)
+}
+
+func (ev *valueEvent) GetPriority() int {
+	return Priority(ev.Event)
+}
+
+func (ev *valueEvent) GetLabels() map[string]string {
+	return Labels(ev.Event)
+}
+
+func (ev *valueEvent) GetVersion() int {
+	return Version(ev.Event)
+}
+
+func (ev *valueEvent) setVersion(version int) {
+	setVersion(ev.Event, version)
+}
+
+func (ev *valueEvent) setSeq(seq uint64) {
+	setSeq(ev.Event, seq)
+}
+
+// withValue returns a value event like ev, but carrying val.
+func withValue(ev Event, val float64) Event {
+	if vev, ok := ev.(*valueEvent); ok {
+		return &valueEvent{vev.Event, val}
+	}
+	return &valueEvent{ev, val}
+}
+
 type messageEvent struct {
 	Event
 	Message string      `json:"message"`
@@ -84,8 +230,305 @@ func (ev *messageEvent) As(eventType string) Event {
 	return &messageEvent{ev.Event.As(eventType), ev.Message}
 }
 
-func NewEvent(evtType string, data interface{}) Event {
-	base := &basicEvent{Type: evtType, Time: time.Now().In(time.UTC)}
+func (ev *messageEvent) GetCorrelationID() string {
+	return CorrelationID(ev.Event)
+}
+
+func (ev *messageEvent) GetSeq() uint64 {
+	return Seq(ev.Event)
+}
+
+func (ev *messageEvent) GetExpiry() time.Time {
+	return Expiry(ev.Event)
+}
+
+func (ev *messageEvent) GetPriority() int {
+	return Priority(ev.Event)
+}
+
+func (ev *messageEvent) GetLabels() map[string]string {
+	return Labels(ev.Event)
+}
+
+func (ev *messageEvent) GetVersion() int {
+	return Version(ev.Event)
+}
+
+func (ev *messageEvent) setVersion(version int) {
+	setVersion(ev.Event, version)
+}
+
+func (ev *messageEvent) setSeq(seq uint64) {
+	setSeq(ev.Event, seq)
+}
+
+// BinaryEvent is an event whose payload is raw bytes. It is created by
+// passing a []byte to NewEvent.
+type BinaryEvent interface {
+	Event
+	GetBytes() []byte
+}
+
+type binaryEvent struct {
+	Event
+	Bytes []byte `json:"bytes"`
+}
+
+func (ev *binaryEvent) GetBytes() []byte {
+	return ev.Bytes
+}
+
+func (ev *binaryEvent) As(eventType string) Event {
+	return &binaryEvent{ev.Event.As(eventType), ev.Bytes}
+}
+
+func (ev *binaryEvent) GetCorrelationID() string {
+	return CorrelationID(ev.Event)
+}
+
+func (ev *binaryEvent) GetSeq() uint64 {
+	return Seq(ev.Event)
+}
+
+func (ev *binaryEvent) GetExpiry() time.Time {
+	return Expiry(ev.Event)
+}
+
+func (ev *binaryEvent) GetPriority() int {
+	return Priority(ev.Event)
+}
+
+func (ev *binaryEvent) GetLabels() map[string]string {
+	return Labels(ev.Event)
+}
+
+func (ev *binaryEvent) GetVersion() int {
+	return Version(ev.Event)
+}
+
+func (ev *binaryEvent) setVersion(version int) {
+	setVersion(ev.Event, version)
+}
+
+func (ev *binaryEvent) setSeq(seq uint64) {
+	setSeq(ev.Event, seq)
+}
+
+// eventPayload returns what ev carries, in the form NewEvent takes it:
+// its value, message, bytes or data.
+func eventPayload(ev Event) interface{} {
+	switch tev := ev.(type) {
+	case ValueEvent:
+		return tev.GetValue()
+	case MessageEvent:
+		return tev.GetMessage()
+	case BinaryEvent:
+		return tev.GetBytes()
+	}
+	return ev.GetData()
+}
+
+// Seq returns the sequence number of ev, or 0 if it has none.
+func Seq(ev Event) uint64 {
+	if sev, ok := ev.(Sequenced); ok {
+		return sev.GetSeq()
+	}
+	return 0
+}
+
+func setSeq(ev Event, seq uint64) {
+	if sev, ok := ev.(sequencer); ok {
+		sev.setSeq(seq)
+	}
+}
+
+// numbered returns a copy of ev with the sequence number seq. The copy is
+// numbered rather than ev itself, which the caller may still hold, or
+// fire on another sink with its own numbering.
+func numbered(ev Event, seq uint64) Event {
+	ev = ev.As(ev.GetType())
+	setSeq(ev, seq)
+	return ev
+}
+
+// Expiry returns the time ev expires, or the zero time if it never does.
+func Expiry(ev Event) time.Time {
+	if eev, ok := ev.(Expirable); ok {
+		return eev.GetExpiry()
+	}
+	return time.Time{}
+}
+
+// Priority returns the priority of ev, or 0 if it has none.
+func Priority(ev Event) int {
+	if pev, ok := ev.(Prioritized); ok {
+		return pev.GetPriority()
+	}
+	return 0
+}
+
+// Labels returns the labels of ev, or nil if it has none.
+func Labels(ev Event) map[string]string {
+	if lev, ok := ev.(Labeled); ok {
+		return lev.GetLabels()
+	}
+	return nil
+}
+
+// Version returns the schema version of ev, or 0 if it has none.
+func Version(ev Event) int {
+	if vev, ok := ev.(Versioned); ok {
+		return vev.GetVersion()
+	}
+	return 0
+}
+
+func setVersion(ev Event, version int) {
+	if vev, ok := ev.(versioner); ok {
+		vev.setVersion(version)
+	}
+}
+
+// expired reports whether ev has expired as of t.
+func expired(ev Event, t time.Time) bool {
+	exp := Expiry(ev)
+	return !exp.IsZero() && !t.Before(exp)
+}
+
+// CorrelationID returns the correlation ID of ev, or "" if it has none.
+func CorrelationID(ev Event) string {
+	if c, ok := ev.(Correlated); ok {
+		return c.GetCorrelationID()
+	}
+	return ""
+}
+
+// An EventOption sets an optional attribute of an event made by NewEvent.
+type EventOption func(*basicEvent)
+
+// NewEvent returns an event of the given type carrying data, fired now.
+// The kind of event depends on data: a number gives a value event, a
+// string a message event, and so on. The event time is in UTC unless an
+// EventIn option says otherwise, and the monotonic clock reading is
+// stripped from it, since it doesn't survive marshaling and would
+// otherwise make a time compare unequal to itself after a JSON round trip.
+func NewEvent(evtType string, data interface{}, opts ...EventOption) Event {
+	base := &basicEvent{Type: evtType, Time: now().Round(0).In(time.UTC)}
+	for _, opt := range opts {
+		opt(base)
+	}
+	return newEvent(base, data)
+}
+
+// NewEventIn is like NewEvent, but with the event time in loc rather than
+// UTC. It is short for NewEvent with an EventIn option.
+func NewEventIn(loc *time.Location, evtType string, data interface{}) Event {
+	return NewEvent(evtType, data, EventIn(loc))
+}
+
+// NewEventWithCorrelation is like NewEvent, but the event carries the
+// given correlation ID. It is short for NewEvent with an EventCorrelation
+// option.
+func NewEventWithCorrelation(evtType, corrID string, data interface{}) Event {
+	return NewEvent(evtType, data, EventCorrelation(corrID))
+}
+
+// NewEventWithExpiry is like NewEvent, but the event expires at
+// expiresAt. It is short for NewEvent with an EventExpiry option.
+func NewEventWithExpiry(evtType string, expiresAt time.Time, data interface{}) Event {
+	return NewEvent(evtType, data, EventExpiry(expiresAt))
+}
+
+// NewEventWithPriority is like NewEvent, but the event has the given
+// priority. It is short for NewEvent with an EventPriority option.
+func NewEventWithPriority(evtType string, priority int, data interface{}) Event {
+	return NewEvent(evtType, data, EventPriority(priority))
+}
+
+// NewLabeledEvent is like NewEvent, but the event carries a copy of the
+// given labels. It is short for NewEvent with an EventLabels option.
+func NewLabeledEvent(evtType string, labels map[string]string, data interface{}) Event {
+	return NewEvent(evtType, data, EventLabels(labels))
+}
+
+// NewEventWithVersion is like NewEvent, but the event's payload has the
+// given schema version. It is short for NewEvent with an EventVersion
+// option.
+func NewEventWithVersion(evtType string, version int, data interface{}) Event {
+	return NewEvent(evtType, data, EventVersion(version))
+}
+
+// EventIn puts the event time in loc rather than UTC. A nil loc means UTC.
+func EventIn(loc *time.Location) EventOption {
+	return func(ev *basicEvent) {
+		if loc != nil {
+			ev.Time = ev.Time.In(loc)
+		}
+	}
+}
+
+// EventCorrelation gives the event a correlation ID.
+func EventCorrelation(corrID string) EventOption {
+	return func(ev *basicEvent) {
+		ev.CorrelationID = corrID
+	}
+}
+
+// EventExpiry makes the event expire at expiresAt. Handlers aren't called
+// with an event delivered at or after its expiry, as can happen when it
+// was queued by a paused sink or a decorator that defers calls.
+func EventExpiry(expiresAt time.Time) EventOption {
+	return func(ev *basicEvent) {
+		exp := expiresAt.Round(0).In(time.UTC)
+		ev.ExpiresAt = &exp
+	}
+}
+
+// EventPriority gives the event a priority. Routine events have priority
+// 0; give important ones, such as alerts, a higher priority so that a
+// bounded log keeps them longer.
+func EventPriority(priority int) EventOption {
+	return func(ev *basicEvent) {
+		ev.Priority = priority
+	}
+}
+
+// EventLabels gives the event labels. They are copied, so later changes to
+// the map don't affect the event.
+func EventLabels(labels map[string]string) EventOption {
+	return func(ev *basicEvent) {
+		ev.Labels = copyLabels(labels)
+	}
+}
+
+// EventVersion gives the schema version of the event's payload.
+func EventVersion(version int) EventOption {
+	return func(ev *basicEvent) {
+		ev.Version = version
+	}
+}
+
+// CloneEvent returns a new event with the same type, time, correlation
+// ID, expiry, priority, labels and version as ev, but carrying data. Like
+// NewEvent, the kind of event returned depends on data, so cloning a value
+// event with a string gives a message event. The clone has no sequence
+// number until it is fired.
+func CloneEvent(ev Event, data interface{}) Event {
+	base := &basicEvent{
+		Type: ev.GetType(),
+		Time: ev.GetTime(),
+		CorrelationID: CorrelationID(ev),
+		Priority: Priority(ev),
+		Labels: copyLabels(Labels(ev)),
+		Version: Version(ev),
+	}
+	if exp := Expiry(ev); !exp.IsZero() {
+		base.ExpiresAt = &exp
+	}
+	return newEvent(base, data)
+}
+
+func newEvent(base *basicEvent, data interface{}) Event {
 	switch tdata := data.(type) {
 	case float64:
 		return &valueEvent{base, tdata}
@@ -113,6 +556,8 @@ func NewEvent(evtType string, data interface{}) Event {
 		return &valueEvent{base, float64(tdata)}
 	case string:
 		return &messageEvent{base, tdata}
+	case []byte:
+		return &binaryEvent{base, tdata}
 	case map[string]interface{}:
 		base.Data = tdata
 		val, ok := tdata["value"]
@@ -145,6 +590,10 @@ func NewEvent(evtType string, data interface{}) Event {
 			case Valuer:
 				return &valueEvent{base, tval.GetValue()}
 			case string:
+				// a quoted JSON number, such as {"value":"42"}
+				if fval, err := strconv.ParseFloat(tval, 64); err == nil && !math.IsInf(fval, 0) && !math.IsNaN(fval) {
+					return &valueEvent{base, fval}
+				}
 				return &messageEvent{base, tval}
 			case fmt.Stringer:
 				return &messageEvent{base, tval.String()}
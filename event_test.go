@@ -0,0 +1,394 @@
+package events
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// roundTrip marshals ev to JSON and decodes it again.
+func roundTrip(t *testing.T, ev Event) Event {
+	t.Helper()
+	data, err := json.Marshal(ev)
+	if err != nil {
+		t.Fatalf("can't marshal event: %s", err)
+	}
+	out, err := UnmarshalEvent(data)
+	if err != nil {
+		t.Fatalf("can't unmarshal %s: %s", data, err)
+	}
+	return out
+}
+
+func TestNewEventIn(t *testing.T) {
+	eastern := time.FixedZone("EST", -5 * 60 * 60)
+	tests := []struct {
+		name string
+		loc *time.Location
+		data interface{}
+		want *time.Location
+	}{
+		{"nil location", nil, 1.5, time.UTC},
+		{"utc", time.UTC, "hello", time.UTC},
+		{"fixed zone", eastern, 1.5, eastern},
+		{"map data", eastern, map[string]interface{}{"value": 2.0}, eastern},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ev := NewEventIn(tc.loc, "test", tc.data)
+			if loc := ev.GetTime().Location(); loc != tc.want {
+				t.Errorf("event time in %s, want %s", loc, tc.want)
+			}
+			out := roundTrip(t, ev)
+			if !out.GetTime().Equal(ev.GetTime()) {
+				t.Errorf("time after round trip = %s, want %s", out.GetTime(), ev.GetTime())
+			}
+			if tc.want == time.UTC && out.GetTime() != ev.GetTime() {
+				t.Errorf("time after round trip = %#v, want %#v", out.GetTime(), ev.GetTime())
+			}
+		})
+	}
+}
+
+func TestNewEventStripsMonotonicClock(t *testing.T) {
+	ev := NewEvent("test", 1.0)
+	if ev.GetTime() != ev.GetTime().Round(0) {
+		t.Errorf("event time %#v has a monotonic clock reading", ev.GetTime())
+	}
+}
+
+func TestNewEventBinary(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"text", []byte("hello")},
+		{"binary", []byte{0, 1, 0xfe, 0xff, '\n'}},
+		{"single byte", []byte{0}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ev := NewEvent("blob", tc.data)
+			bev, ok := ev.(BinaryEvent)
+			if !ok {
+				t.Fatalf("NewEvent returned a %T, not a BinaryEvent", ev)
+			}
+			if !bytes.Equal(bev.GetBytes(), tc.data) {
+				t.Errorf("bytes = %v, want %v", bev.GetBytes(), tc.data)
+			}
+			out, ok := roundTrip(t, ev).(BinaryEvent)
+			if !ok {
+				t.Fatal("event isn't binary after a round trip")
+			}
+			if !bytes.Equal(out.GetBytes(), tc.data) {
+				t.Errorf("bytes after round trip = %v, want %v", out.GetBytes(), tc.data)
+			}
+			if renamed := ev.As("other").(BinaryEvent); !bytes.Equal(renamed.GetBytes(), tc.data) {
+				t.Errorf("bytes after As = %v, want %v", renamed.GetBytes(), tc.data)
+			}
+		})
+	}
+}
+
+func TestEventExpiry(t *testing.T) {
+	tests := []struct {
+		name string
+		expiry time.Duration
+		pause bool
+		advance time.Duration
+		want int
+	}{
+		{"no expiry", 0, false, time.Hour, 1},
+		{"not yet expired", time.Minute, false, 30 * time.Second, 1},
+		{"expiring now", time.Minute, false, time.Minute, 0},
+		{"expired", time.Minute, false, 2 * time.Minute, 0},
+		{"queued", time.Minute, true, 30 * time.Second, 1},
+		{"expired while queued", time.Minute, true, 2 * time.Minute, 0},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			c := useFakeClock(t)
+			sink := NewSyncEventSink(time.Hour)
+			rec := RecordingHandler()
+			sink.AddEventListener("test", rec)
+			var ev Event
+			if tc.expiry > 0 {
+				ev = NewEvent("test", 1.0, EventExpiry(c.Now().Add(tc.expiry)))
+			} else {
+				ev = NewEvent("test", 1.0)
+			}
+			if tc.pause {
+				sink.(Pausable).Pause()
+				sink.Fire(ev)
+				c.Advance(tc.advance)
+				sink.(Pausable).Resume()
+			} else {
+				c.Advance(tc.advance)
+				sink.Fire(ev)
+			}
+			if n := len(rec.Calls()); n != tc.want {
+				t.Errorf("delivered %d times, want %d", n, tc.want)
+			}
+			if n := sink.(ListenerInspector).ListenerCount("test"); n != 1 {
+				t.Errorf("%d listeners after an expired event, want 1", n)
+			}
+		})
+	}
+}
+
+func TestEventExpiryRoundTrip(t *testing.T) {
+	exp := time.Date(2024, 1, 1, 0, 1, 0, 0, time.UTC)
+	tests := []struct {
+		name string
+		data interface{}
+	}{
+		{"value", 1.0},
+		{"message", "hi"},
+		{"binary", []byte{1}},
+		{"map", map[string]interface{}{"a": "b"}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ev := NewEvent("test", tc.data, EventExpiry(exp))
+			if got := Expiry(ev); !got.Equal(exp) {
+				t.Errorf("expiry = %s, want %s", got, exp)
+			}
+			if got := Expiry(roundTrip(t, ev)); !got.Equal(exp) {
+				t.Errorf("expiry after round trip = %s, want %s", got, exp)
+			}
+		})
+	}
+}
+
+func TestEventExpiryInTrailingDebounce(t *testing.T) {
+	tests := []struct {
+		name string
+		advance time.Duration
+		want int
+	}{
+		{"fresh", 0, 1},
+		{"expired while deferred", 2 * time.Minute, 0},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			c := useFakeClock(t)
+			rec := RecordingHandler()
+			h := WithTrailingDebounce(rec, 10 * time.Millisecond)
+			h.Call(NewEvent("test", 1.0, EventExpiry(c.Now().Add(time.Minute))))
+			c.Advance(tc.advance)
+			rec.WaitForCalls(1, 100 * time.Millisecond)
+			if n := len(rec.Calls()); n != tc.want {
+				t.Errorf("delivered %d times, want %d", n, tc.want)
+			}
+		})
+	}
+}
+
+func TestEventOptions(t *testing.T) {
+	labels := map[string]string{"room": "kitchen"}
+	tests := []struct {
+		name string
+		opts []EventOption
+		wantPriority int
+		wantCorrID string
+		wantLabels map[string]string
+		wantVersion int
+	}{
+		{"none", nil, 0, "", nil, 0},
+		{"priority", []EventOption{EventPriority(5)}, 5, "", nil, 0},
+		{"negative priority", []EventOption{EventPriority(-1)}, -1, "", nil, 0},
+		{"correlation", []EventOption{EventCorrelation("req-1")}, 0, "req-1", nil, 0},
+		{"labels", []EventOption{EventLabels(labels)}, 0, "", labels, 0},
+		{"version", []EventOption{EventVersion(2)}, 0, "", nil, 2},
+		{"later option wins", []EventOption{EventPriority(1), EventPriority(3)}, 3, "", nil, 0},
+		{
+			"all",
+			[]EventOption{EventPriority(2), EventCorrelation("req-2"), EventLabels(labels), EventVersion(3)},
+			2, "req-2", labels, 3,
+		},
+	}
+	payloads := map[string]interface{}{
+		"value": 1.5,
+		"message": "hi",
+		"binary": []byte{1, 2},
+		"map": map[string]interface{}{"a": "b"},
+	}
+	for _, tc := range tests {
+		for kind, data := range payloads {
+			t.Run(tc.name + "/" + kind, func(t *testing.T) {
+				ev := NewEvent("test", data, tc.opts...)
+				for _, e := range []Event{ev, roundTrip(t, ev), ev.As("other")} {
+					if p := Priority(e); p != tc.wantPriority {
+						t.Errorf("priority = %d, want %d", p, tc.wantPriority)
+					}
+					if id := CorrelationID(e); id != tc.wantCorrID {
+						t.Errorf("correlation ID = %q, want %q", id, tc.wantCorrID)
+					}
+					if l := Labels(e); !reflect.DeepEqual(l, tc.wantLabels) && (len(l) > 0 || len(tc.wantLabels) > 0) {
+						t.Errorf("labels = %v, want %v", l, tc.wantLabels)
+					}
+					if v := Version(e); v != tc.wantVersion {
+						t.Errorf("version = %d, want %d", v, tc.wantVersion)
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestEventLabelsCopied(t *testing.T) {
+	labels := map[string]string{"room": "kitchen"}
+	ev := NewEvent("test", 1.0, EventLabels(labels))
+	labels["room"] = "garage"
+	if room := Labels(ev)["room"]; room != "kitchen" {
+		t.Errorf("room label = %q after changing the map, want kitchen", room)
+	}
+}
+
+func TestCloneEvent(t *testing.T) {
+	eastern := time.FixedZone("EST", -5 * 60 * 60)
+	tests := []struct {
+		name string
+		from interface{}
+		data interface{}
+		want interface{}
+	}{
+		{"value to message", 21.5, "hot", "hot"},
+		{"message to value", "hot", 21.5, 21.5},
+		{"int to value", 1.0, 3, 3.0},
+		{"value to binary", 1.0, []byte{1, 2}, []byte{1, 2}},
+		{"binary to map", []byte{1}, map[string]interface{}{"a": "b"}, map[string]interface{}{"a": "b"}},
+		{"map to value", map[string]interface{}{"a": "b"}, 2.0, 2.0},
+		{"value to value", 1.0, 2.0, 2.0},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			useFakeClock(t)
+			src := NewEvent("temp", tc.from,
+				EventIn(eastern),
+				EventCorrelation("req-1"),
+				EventPriority(3),
+				EventLabels(map[string]string{"room": "kitchen"}),
+				EventVersion(2),
+				EventExpiry(time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)),
+			)
+			sink := NewSyncEventSink(time.Hour)
+			sink.Fire(src)
+			fired := sink.Log()[0]
+			clone := CloneEvent(fired, tc.data)
+			if got := eventPayload(clone); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("payload = %#v, want %#v", got, tc.want)
+			}
+			if clone.GetType() != "temp" || clone.GetTime() != src.GetTime() {
+				t.Errorf("clone is %s at %s, want temp at %s", clone.GetType(), clone.GetTime(), src.GetTime())
+			}
+			if CorrelationID(clone) != "req-1" || Priority(clone) != 3 || Version(clone) != 2 {
+				t.Errorf("clone lost its attributes: %q, %d, %d", CorrelationID(clone), Priority(clone), Version(clone))
+			}
+			if !reflect.DeepEqual(Labels(clone), map[string]string{"room": "kitchen"}) {
+				t.Errorf("labels = %v", Labels(clone))
+			}
+			if !Expiry(clone).Equal(Expiry(src)) {
+				t.Errorf("expiry = %s, want %s", Expiry(clone), Expiry(src))
+			}
+			if Seq(fired) == 0 || Seq(clone) != 0 {
+				t.Errorf("seq %d cloned as %d, want 0", Seq(fired), Seq(clone))
+			}
+			// the original is untouched
+			if got := eventPayload(src); !reflect.DeepEqual(got, tc.from) {
+				t.Errorf("original payload = %#v, want %#v", got, tc.from)
+			}
+		})
+	}
+}
+
+func TestNewEventMapValue(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		wantValue bool
+		want interface{}
+	}{
+		{"number", `{"value":42}`, true, 42.0},
+		{"quoted number", `{"value":"42"}`, true, 42.0},
+		{"quoted float", `{"value":"-1.5e2"}`, true, -150.0},
+		{"word", `{"value":"foo"}`, false, "foo"},
+		{"empty string", `{"value":""}`, false, ""},
+		{"quoted infinity", `{"value":"Inf"}`, false, "Inf"},
+		{"quoted nan", `{"value":"NaN"}`, false, "NaN"},
+		{"message", `{"message":"42"}`, false, "42"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			data := map[string]interface{}{}
+			if err := json.Unmarshal([]byte(tc.data), &data); err != nil {
+				t.Fatalf("can't decode %s: %s", tc.data, err)
+			}
+			ev := NewEvent("test", data)
+			switch tev := ev.(type) {
+			case ValueEvent:
+				if !tc.wantValue {
+					t.Fatalf("%s made a value event, want a message", tc.data)
+				}
+				if v := tev.GetValue(); v != tc.want {
+					t.Errorf("value = %g, want %v", v, tc.want)
+				}
+			case MessageEvent:
+				if tc.wantValue {
+					t.Fatalf("%s made a message event, want a value", tc.data)
+				}
+				if msg := tev.GetMessage(); msg != tc.want {
+					t.Errorf("message = %q, want %q", msg, tc.want)
+				}
+			default:
+				t.Fatalf("%s made a %T", tc.data, ev)
+			}
+		})
+	}
+}
+
+func TestEventConstructors(t *testing.T) {
+	exp := time.Date(2024, 1, 1, 0, 1, 0, 0, time.UTC)
+	labels := map[string]string{"room": "kitchen"}
+	tests := []struct {
+		name string
+		make func() Event
+		want func() Event
+	}{
+		{
+			"correlation",
+			func() Event { return NewEventWithCorrelation("test", "req-1", 1.0) },
+			func() Event { return NewEvent("test", 1.0, EventCorrelation("req-1")) },
+		},
+		{
+			"expiry",
+			func() Event { return NewEventWithExpiry("test", exp, "hi") },
+			func() Event { return NewEvent("test", "hi", EventExpiry(exp)) },
+		},
+		{
+			"priority",
+			func() Event { return NewEventWithPriority("test", 5, 1.0) },
+			func() Event { return NewEvent("test", 1.0, EventPriority(5)) },
+		},
+		{
+			"labels",
+			func() Event { return NewLabeledEvent("test", labels, []byte{1}) },
+			func() Event { return NewEvent("test", []byte{1}, EventLabels(labels)) },
+		},
+		{
+			"version",
+			func() Event { return NewEventWithVersion("test", 2, map[string]interface{}{"a": "b"}) },
+			func() Event { return NewEvent("test", map[string]interface{}{"a": "b"}, EventVersion(2)) },
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			useFakeClock(t)
+			if got, want := tc.make(), tc.want(); !reflect.DeepEqual(got, want) {
+				t.Errorf("event = %#v, want %#v", got, want)
+			}
+		})
+	}
+}
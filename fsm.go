@@ -0,0 +1,69 @@
+package events
+
+import (
+	"sync"
+)
+
+// A StateMachineHandler is a handler driven by a finite state machine
+// whose transitions are triggered by event types.
+type StateMachineHandler interface {
+	EventHandler
+	CurrentState() string
+}
+
+type stateMachineHandler struct {
+	EventHandler
+	state string
+	transitions map[string]map[string]string
+	onEnter map[string]func(Event)
+	mutex *sync.Mutex
+}
+
+// NewStateMachineHandler returns a handler that starts in state initial.
+// transitions maps each state to a map from event types to the state an
+// event of that type moves to. On each transition the onEnter callback
+// for the new state, if any, is called with the event that caused it,
+// including when a transition leads back to the same state. Events with
+// no transition from the current state are ignored.
+func NewStateMachineHandler(initial string, transitions map[string]map[string]string, onEnter map[string]func(Event)) StateMachineHandler {
+	h := &stateMachineHandler{
+		state: initial,
+		transitions: map[string]map[string]string{},
+		onEnter: map[string]func(Event){},
+		mutex: &sync.Mutex{},
+	}
+	for from, edges := range transitions {
+		copied := make(map[string]string, len(edges))
+		for eventType, to := range edges {
+			copied[eventType] = to
+		}
+		h.transitions[from] = copied
+	}
+	for state, fn := range onEnter {
+		h.onEnter[state] = fn
+	}
+	h.EventHandler = NewEventHandler(h.advance)
+	return h
+}
+
+func (h *stateMachineHandler) advance(ev Event) error {
+	h.mutex.Lock()
+	next, ok := h.transitions[h.state][ev.GetType()]
+	if !ok {
+		h.mutex.Unlock()
+		return ErrIgnored
+	}
+	h.state = next
+	fn := h.onEnter[next]
+	h.mutex.Unlock()
+	if fn != nil {
+		fn(ev)
+	}
+	return nil
+}
+
+func (h *stateMachineHandler) CurrentState() string {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	return h.state
+}
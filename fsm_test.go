@@ -0,0 +1,89 @@
+package events
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestStateMachineHandler(t *testing.T) {
+	transitions := map[string]map[string]string{
+		"closed": {"open": "open", "lock": "locked"},
+		"open": {"close": "closed", "open": "open"},
+		"locked": {"unlock": "closed"},
+	}
+	tests := []struct {
+		name string
+		events []string
+		wantState string
+		wantEntered []string
+		wantIgnored int
+	}{
+		{"no events", nil, "closed", []string{}, 0},
+		{"open and close", []string{"open", "close"}, "closed", []string{"open", "closed"}, 0},
+		{"lock", []string{"lock"}, "locked", []string{}, 0},
+		{"walk", []string{"open", "close", "lock", "unlock", "open"}, "open", []string{"open", "closed", "closed", "open"}, 0},
+		{"back to the same state", []string{"open", "open"}, "open", []string{"open", "open"}, 0},
+		{"no transition", []string{"close", "unlock"}, "closed", []string{}, 2},
+		{"can't open when locked", []string{"lock", "open", "unlock", "open"}, "open", []string{"closed", "open"}, 1},
+		{"unknown event", []string{"knock"}, "closed", []string{}, 1},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			entered := []string{}
+			record := func(state string) func(Event) {
+				return func(ev Event) {
+					entered = append(entered, state)
+				}
+			}
+			// no callback for locked
+			onEnter := map[string]func(Event){"open": record("open"), "closed": record("closed")}
+			h := NewStateMachineHandler("closed", transitions, onEnter)
+			ignored := 0
+			for _, eventType := range tc.events {
+				err := h.Call(NewEvent(eventType, nil))
+				if errors.Is(err, ErrIgnored) {
+					ignored += 1
+				} else if err != nil {
+					t.Fatalf("%s: %s", eventType, err)
+				}
+			}
+			if state := h.CurrentState(); state != tc.wantState {
+				t.Errorf("state = %q, want %q", state, tc.wantState)
+			}
+			if !reflect.DeepEqual(entered, tc.wantEntered) {
+				t.Errorf("entered %v, want %v", entered, tc.wantEntered)
+			}
+			if ignored != tc.wantIgnored {
+				t.Errorf("%d events ignored, want %d", ignored, tc.wantIgnored)
+			}
+		})
+	}
+}
+
+func TestStateMachineHandlerOnSink(t *testing.T) {
+	transitions := map[string]map[string]string{
+		"idle": {"start": "running"},
+		"running": {"stop": "idle", "fail": "failed"},
+	}
+	var caused Event
+	h := NewStateMachineHandler("idle", transitions, map[string]func(Event){
+		"failed": func(ev Event) { caused = ev },
+	})
+	// changing the maps afterwards doesn't affect the handler
+	transitions["failed"] = map[string]string{"reset": "idle"}
+	sink := NewSyncEventSink(time.Hour)
+	for _, eventType := range []string{"start", "stop", "fail", "reset"} {
+		sink.AddEventListener(eventType, h)
+	}
+	for _, eventType := range []string{"start", "fail", "stop", "reset"} {
+		sink.Emit(eventType, eventType)
+	}
+	if state := h.CurrentState(); state != "failed" {
+		t.Errorf("state = %q, want failed", state)
+	}
+	if caused == nil || caused.GetType() != "fail" {
+		t.Errorf("failed entered on %v, want the fail event", caused)
+	}
+}
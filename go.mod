@@ -1,7 +1,7 @@
 module github.com/rclancey/events
 
-go 1.18
+go 1.19
 
 require github.com/rclancey/generic v0.0.2
 
-require github.com/rclancey/encoding-form v0.0.1 // indirect
+require github.com/rclancey/encoding-form v0.0.1
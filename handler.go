@@ -1,15 +1,22 @@
 package events
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"math"
 	"math/rand"
+	"sync"
 	"time"
+
+	"github.com/rclancey/generic"
 )
 
 var ErrIgnored = errors.New("ignored")
 var ErrExpired = errors.New("expired")
 var ErrIncompatibleEvent = errors.New("incompatible event")
+var ErrHandlerTimeout = errors.New("handler timed out")
+var ErrInvalidThreshold = errors.New("invalid threshold")
 
 type EventHandler interface {
 	ID() int64
@@ -27,27 +34,69 @@ const (
 	DirectionDecreasing = Direction("decreasing")
 	DirectionSteady     = Direction("steady")
 	DirectionReverse    = Direction("reverse")
+	DirectionPeak       = Direction("peak")
+	DirectionTrough     = Direction("trough")
 )
 
+// A ContextHandler is an EventHandler that can also be called with a
+// context. The sink prefers CallContext when dispatching, passing a context
+// that carries the event's correlation ID.
+type ContextHandler interface {
+	EventHandler
+	CallContext(ctx context.Context, ev Event) error
+}
+
+type ContextHandlerFunc func(context.Context, Event) error
+
+// callContext calls h with ctx if h supports it, or without ctx if not.
+func callContext(ctx context.Context, h EventHandler, ev Event) error {
+	if ch, ok := h.(ContextHandler); ok {
+		return ch.CallContext(ctx, ev)
+	}
+	return h.Call(ev)
+}
+
 type basicEventHandler struct {
 	id int64
-	handler func(Event) error
+	handler func(context.Context, Event) error
 	lastErr error
+	mutex *sync.Mutex
 }
 
+// IDGenerator produces the IDs of handlers created by NewEventHandler.
+// Replace it (before creating any handlers) with a counter to guarantee
+// unique IDs, or with a deterministic sequence in tests.
+var IDGenerator func() int64 = rand.Int63
+
 func NewEventHandler(handler HandlerFunc) EventHandler {
+	return NewEventHandlerWithID(IDGenerator(), handler)
+}
+
+func NewEventHandlerWithID(id int64, handler HandlerFunc) EventHandler {
 	return &basicEventHandler{
-		id: rand.Int63(),
+		id: id,
+		handler: func(ctx context.Context, ev Event) error {
+			return handler(ev)
+		},
+		mutex: &sync.Mutex{},
+	}
+}
+
+func NewContextEventHandler(handler ContextHandlerFunc) ContextHandler {
+	return &basicEventHandler{
+		id: IDGenerator(),
 		handler: handler,
+		mutex: &sync.Mutex{},
 	}
 }
 
 func HandlerReference(id int64) EventHandler {
 	return &basicEventHandler{
 		id: id,
-		handler: func(Event) error {
+		handler: func(context.Context, Event) error {
 			return errors.New("not a real handler")
 		},
+		mutex: &sync.Mutex{},
 	}
 }
 
@@ -60,21 +109,23 @@ func (eh *basicEventHandler) Expired() bool {
 }
 
 func (eh *basicEventHandler) Call(ev Event) error {
+	return eh.CallContext(context.Background(), ev)
+}
+
+func (eh *basicEventHandler) CallContext(ctx context.Context, ev Event) error {
 	if eh.Expired() {
 		return nil
 	}
-	err := eh.handler(ev)
+	err := eh.handler(ctx, ev)
+	eh.mutex.Lock()
 	eh.lastErr = err
-	if err != nil {
-		if errors.Is(err, ErrIgnored) {
-			return nil
-		}
-		return err
-	}
-	return nil
+	eh.mutex.Unlock()
+	return err
 }
 
 func (eh *basicEventHandler) LastError() error {
+	eh.mutex.Lock()
+	defer eh.mutex.Unlock()
 	return eh.lastErr
 }
 
@@ -82,37 +133,153 @@ type maxCallsHandler struct {
 	EventHandler
 	maxCalls int
 	calls int
+	pending int
+	released chan struct{}
+	mutex *sync.Mutex
 }
 
+// WithMaxCalls expires h after it has handled maxCalls events. Calls that
+// h ignores (by returning ErrIgnored) or fails don't count towards the
+// limit, so WithMaxCalls should wrap any filtering decorators. Their
+// errors are passed on, so the sink can tell an ignored call from a
+// handled one.
+//
+// h isn't called with the mutex held. Each call reserves one of the
+// remaining calls first, and gives it back if it doesn't count, so
+// concurrent calls never exceed the limit. An event arriving while every
+// remaining call is reserved waits for one of the calls in progress to
+// finish, and is passed on if that call didn't count; if ctx is done
+// first, it returns ctx.Err().
 func WithMaxCalls(h EventHandler, maxCalls int) EventHandler {
 	if maxCalls <= 0 {
 		return h
 	}
-	return &maxCallsHandler{h, maxCalls, 0}
+	return &maxCallsHandler{h, maxCalls, 0, 0, make(chan struct{}), &sync.Mutex{}}
 }
 
 func (h *maxCallsHandler) Call(ev Event) error {
-	if h.calls >= h.maxCalls {
-		return ErrExpired
+	return h.CallContext(context.Background(), ev)
+}
+
+func (h *maxCallsHandler) CallContext(ctx context.Context, ev Event) error {
+	if err := h.reserve(ctx); err != nil {
+		return err
 	}
-	err := h.EventHandler.Call(ev)
-	if err != nil {
-		if errors.Is(err, ErrIgnored) {
+	err := callContext(ctx, h.EventHandler, ev)
+	h.mutex.Lock()
+	h.pending -= 1
+	if err == nil {
+		h.calls += 1
+	}
+	close(h.released)
+	h.released = make(chan struct{})
+	h.mutex.Unlock()
+	return err
+}
+
+// reserve reserves one of the remaining calls, waiting for the calls in
+// progress while they hold all of them. It returns ErrExpired once the
+// limit has been reached.
+func (h *maxCallsHandler) reserve(ctx context.Context) error {
+	for {
+		h.mutex.Lock()
+		if h.calls >= h.maxCalls {
+			h.mutex.Unlock()
+			return ErrExpired
+		}
+		if h.calls + h.pending < h.maxCalls {
+			h.pending += 1
+			h.mutex.Unlock()
 			return nil
 		}
-		return err
+		released := h.released
+		h.mutex.Unlock()
+		select {
+		case <-released:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
 	}
-	h.calls += 1
-	return nil
+}
+
+func (h *maxCallsHandler) Unwrap() EventHandler {
+	return h.EventHandler
 }
 
 func (h *maxCallsHandler) Expired() bool {
-	if h.calls >= h.maxCalls {
+	h.mutex.Lock()
+	expired := h.calls >= h.maxCalls
+	h.mutex.Unlock()
+	if expired {
 		return true
 	}
 	return h.EventHandler.Expired()
 }
 
+type errorRateHandler struct {
+	EventHandler
+	max int
+	window time.Duration
+	failures []time.Time
+	tripped bool
+	mutex *sync.Mutex
+}
+
+// WithErrorRateLimit expires h once it has failed more than max times
+// within window, so that the sink removes it rather than letting a broken
+// handler, such as a webhook to a dead URL, fail on every event. The
+// failure that trips the limit is returned as usual; calls after it
+// return ErrExpired. ErrIgnored, ErrExpired and ErrStopPropagation aren't
+// failures. If max < 0 or window <= 0, h is returned unchanged.
+func WithErrorRateLimit(h EventHandler, max int, window time.Duration) EventHandler {
+	if max < 0 || window <= 0 {
+		return h
+	}
+	return &errorRateHandler{h, max, window, nil, false, &sync.Mutex{}}
+}
+
+func (h *errorRateHandler) Call(ev Event) error {
+	return h.CallContext(context.Background(), ev)
+}
+
+func (h *errorRateHandler) CallContext(ctx context.Context, ev Event) error {
+	h.mutex.Lock()
+	tripped := h.tripped
+	h.mutex.Unlock()
+	if tripped {
+		return ErrExpired
+	}
+	err := callContext(ctx, h.EventHandler, ev)
+	if err == nil || errors.Is(err, ErrIgnored) || errors.Is(err, ErrExpired) || errors.Is(err, ErrStopPropagation) {
+		return err
+	}
+	t := now()
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	cutoff := t.Add(-h.window)
+	n := 0
+	for n < len(h.failures) && !h.failures[n].After(cutoff) {
+		n += 1
+	}
+	h.failures = append(h.failures[n:], t)
+	if len(h.failures) > h.max {
+		h.tripped = true
+		h.failures = nil
+	}
+	return err
+}
+
+func (h *errorRateHandler) Expired() bool {
+	h.mutex.Lock()
+	tripped := h.tripped
+	h.mutex.Unlock()
+	return tripped || h.EventHandler.Expired()
+}
+
+func (h *errorRateHandler) Unwrap() EventHandler {
+	return h.EventHandler
+}
+
 type timeoutHandler struct {
 	EventHandler
 	endTime time.Time
@@ -122,18 +289,26 @@ func WithTimeout(h EventHandler, ttl time.Duration) EventHandler {
 	if ttl <= 0 {
 		return h
 	}
-	return &timeoutHandler{h, time.Now().Add(ttl)}
+	return &timeoutHandler{h, now().Add(ttl)}
 }
 
 func (h *timeoutHandler) Call(ev Event) error {
+	return h.CallContext(context.Background(), ev)
+}
+
+func (h *timeoutHandler) CallContext(ctx context.Context, ev Event) error {
 	if ev.GetTime().After(h.endTime) {
 		return ErrExpired
 	}
-	return h.EventHandler.Call(ev)
+	return callContext(ctx, h.EventHandler, ev)
+}
+
+func (h *timeoutHandler) Unwrap() EventHandler {
+	return h.EventHandler
 }
 
 func (h *timeoutHandler) Expired() bool {
-	if time.Now().After(h.endTime) {
+	if now().After(h.endTime) {
 		return true
 	}
 	return h.EventHandler.Expired()
@@ -144,97 +319,230 @@ type directionHandler struct {
 	targetDirection Direction
 	lastValue float64
 	currentDirection Direction
+	epsilon float64
+	extremum Event
+	mutex *sync.Mutex
 }
 
+// WithDirection calls h when the direction of a series of values matches
+// direction. DirectionReverse matches whenever the series turns around;
+// DirectionPeak and DirectionTrough match only when it turns from
+// increasing to decreasing or vice versa, and call h with the event at the
+// turning point rather than the event after it. A plateau before a turn
+// counts as part of the turn, with its first event as the turning point.
+//
+// Each value is compared with the value just before it, whether or not
+// that event was passed on to h, so the series 1, 3, 2, 4 is increasing,
+// then decreasing, then increasing.
 func WithDirection(h EventHandler, direction Direction) EventHandler {
-	return &directionHandler{h, direction, math.NaN(), DirectionNone}
+	return &directionHandler{h, direction, math.NaN(), DirectionNone, 0, nil, &sync.Mutex{}}
+}
+
+// WithDirectionSteady calls h when a value is within epsilon of the
+// previous value, rather than requiring exact equality.
+func WithDirectionSteady(h EventHandler, epsilon float64) EventHandler {
+	return &directionHandler{h, DirectionSteady, math.NaN(), DirectionNone, math.Abs(epsilon), nil, &sync.Mutex{}}
 }
 
 func (h *directionHandler) Call(ev Event) error {
+	return h.CallContext(context.Background(), ev)
+}
+
+func (h *directionHandler) CallContext(ctx context.Context, ev Event) error {
 	valEv, ok := ev.(ValueEvent)
 	if !ok {
 		return ErrIncompatibleEvent
 	}
 	val := valEv.GetValue()
 	if math.IsNaN(val) {
-		return ErrIgnored
+		return ignored(ctx, "direction: value is NaN")
+	}
+	h.mutex.Lock()
+	out := h.next(ev, val)
+	h.mutex.Unlock()
+	if out == nil {
+		return ignored(ctx, "direction: value %g isn't %s", val, h.targetDirection)
 	}
+	return callContext(ctx, h.EventHandler, out)
+}
+
+// next records val and returns the event to pass on, or nil if none. The
+// caller must hold the mutex.
+func (h *directionHandler) next(ev Event, val float64) Event {
 	if math.IsNaN(h.lastValue) {
 		h.lastValue = val
-		return ErrIgnored
+		h.extremum = ev
+		return nil
 	}
 	var dir Direction
-	if val < h.lastValue {
-		dir = DirectionDecreasing
-	} else if val > h.lastValue {
-		dir = DirectionIncreasing
-	} else {
+	last := h.lastValue
+	h.lastValue = val
+	if math.Abs(val - last) <= h.epsilon {
 		dir = DirectionSteady
 		if h.targetDirection == dir {
-			return h.EventHandler.Call(ev)
+			return ev
 		}
-		return ErrIgnored
-	}
-	if h.targetDirection == DirectionReverse {
-		if dir != h.currentDirection {
-			return h.EventHandler.Call(ev)
-		}
-		return ErrIgnored
+		return nil
+	} else if val < last {
+		dir = DirectionDecreasing
+	} else {
+		dir = DirectionIncreasing
 	}
+	prev := h.currentDirection
+	extremum := h.extremum
 	h.currentDirection = dir
-	if dir == h.targetDirection {
-		return h.EventHandler.Call(ev)
+	h.extremum = ev
+	switch h.targetDirection {
+	case DirectionReverse:
+		if prev != DirectionNone && dir != prev {
+			return ev
+		}
+	case DirectionPeak:
+		if prev == DirectionIncreasing && dir == DirectionDecreasing {
+			return extremum
+		}
+	case DirectionTrough:
+		if prev == DirectionDecreasing && dir == DirectionIncreasing {
+			return extremum
+		}
+	default:
+		if dir == h.targetDirection {
+			return ev
+		}
 	}
-	return ErrIgnored
+	return nil
 }
 
+func (h *directionHandler) Unwrap() EventHandler {
+	return h.EventHandler
+}
+
+// ThresholdFlags control whether a value exactly equal to a threshold's
+// trigger or reset value counts as crossing it. By default both bounds are
+// inclusive.
+type ThresholdFlags int
+
+const (
+	// ThresholdExclusiveTrigger requires values to pass the trigger
+	// value, not just reach it, to trigger.
+	ThresholdExclusiveTrigger ThresholdFlags = 1 << iota
+	// ThresholdExclusiveReset requires values to pass the reset value,
+	// not just reach it, to reset.
+	ThresholdExclusiveReset
+)
+
 type thresholdHandler struct {
 	EventHandler
 	direction Direction
 	triggerVal float64
 	resetVal float64
+	flags ThresholdFlags
 	triggered bool
+	mutex *sync.Mutex
 }
 
 func WithThreshold(h EventHandler, direction Direction, triggerVal, resetVal float64) EventHandler {
-	return &thresholdHandler{h, direction, triggerVal, resetVal, false}
+	return &thresholdHandler{h, direction, triggerVal, resetVal, 0, false, &sync.Mutex{}}
+}
+
+// WithThresholdFlags is like WithThreshold, with flags choosing whether
+// the trigger and reset values are inclusive, and checks the
+// configuration. It returns an error wrapping ErrInvalidThreshold if
+// direction isn't DirectionIncreasing or DirectionDecreasing, if resetVal
+// is on the triggering side of triggerVal, or if the two are equal and
+// both inclusive, which would trigger and reset on the same value.
+func WithThresholdFlags(h EventHandler, direction Direction, triggerVal, resetVal float64, flags ThresholdFlags) (EventHandler, error) {
+	switch direction {
+	case DirectionIncreasing:
+		if resetVal > triggerVal {
+			return nil, fmt.Errorf("%w: reset value %g is above trigger value %g", ErrInvalidThreshold, resetVal, triggerVal)
+		}
+	case DirectionDecreasing:
+		if resetVal < triggerVal {
+			return nil, fmt.Errorf("%w: reset value %g is below trigger value %g", ErrInvalidThreshold, resetVal, triggerVal)
+		}
+	default:
+		return nil, fmt.Errorf("%w: direction must be increasing or decreasing", ErrInvalidThreshold)
+	}
+	if resetVal == triggerVal && flags & (ThresholdExclusiveTrigger | ThresholdExclusiveReset) == 0 {
+		return nil, fmt.Errorf("%w: trigger and reset values are both %g and inclusive", ErrInvalidThreshold, triggerVal)
+	}
+	return &thresholdHandler{h, direction, triggerVal, resetVal, flags, false, &sync.Mutex{}}, nil
 }
 
 func (h *thresholdHandler) Call(ev Event) error {
+	return h.CallContext(context.Background(), ev)
+}
+
+func (h *thresholdHandler) CallContext(ctx context.Context, ev Event) error {
 	valEv, ok := ev.(ValueEvent)
 	if !ok {
 		return ErrIncompatibleEvent
 	}
 	val := valEv.GetValue()
 	if math.IsNaN(val) {
-		return ErrIgnored
+		return ignored(ctx, "threshold: value is NaN")
 	}
+	h.mutex.Lock()
+	fire := h.next(val)
+	triggered := h.triggered
+	h.mutex.Unlock()
+	if !fire {
+		if triggered {
+			return ignored(ctx, "threshold: value %g, waiting for reset at %g", val, h.resetVal)
+		}
+		return ignored(ctx, "threshold: value %g doesn't reach %g", val, h.triggerVal)
+	}
+	return callContext(ctx, h.EventHandler, ev)
+}
+
+// next records val and reports whether it triggers the threshold. The
+// caller must hold the mutex.
+func (h *thresholdHandler) next(val float64) bool {
 	if h.triggered {
 		switch h.direction {
 		case DirectionDecreasing:
-			if val >= h.resetVal {
+			if above(val, h.resetVal, h.flags & ThresholdExclusiveReset == 0) {
 				h.triggered = false
 			}
 		case DirectionIncreasing:
-			if val <= h.resetVal {
+			if below(val, h.resetVal, h.flags & ThresholdExclusiveReset == 0) {
 				h.triggered = false
 			}
 		}
-		return ErrIgnored
+		return false
 	}
 	switch h.direction {
 	case DirectionDecreasing:
-		if val <= h.triggerVal {
+		if below(val, h.triggerVal, h.flags & ThresholdExclusiveTrigger == 0) {
 			h.triggered = true
-			return h.EventHandler.Call(ev)
+			return true
 		}
 	case DirectionIncreasing:
-		if val >= h.triggerVal {
+		if above(val, h.triggerVal, h.flags & ThresholdExclusiveTrigger == 0) {
 			h.triggered = true
-			return h.EventHandler.Call(ev)
+			return true
 		}
 	}
-	return ErrIgnored
+	return false
+}
+
+func above(val, bound float64, inclusive bool) bool {
+	if inclusive {
+		return val >= bound
+	}
+	return val > bound
+}
+
+func below(val, bound float64, inclusive bool) bool {
+	if inclusive {
+		return val <= bound
+	}
+	return val < bound
+}
+
+func (h *thresholdHandler) Unwrap() EventHandler {
+	return h.EventHandler
 }
 
 type rangeHandler struct {
@@ -248,44 +556,528 @@ func WithRange(h EventHandler, min, max float64) EventHandler {
 }
 
 func (h *rangeHandler) Call(ev Event) error {
+	return h.CallContext(context.Background(), ev)
+}
+
+func (h *rangeHandler) CallContext(ctx context.Context, ev Event) error {
 	valEv, ok := ev.(ValueEvent)
 	if !ok {
 		return ErrIncompatibleEvent
 	}
 	val := valEv.GetValue()
 	if math.IsNaN(val) {
-		return ErrIgnored
+		return ignored(ctx, "range: value is NaN")
 	}
 	if h.min > h.max {
 		if val < h.min || val > h.max {
-			return h.EventHandler.Call(ev)
+			return callContext(ctx, h.EventHandler, ev)
 		}
-		return ErrIgnored
+		return ignored(ctx, "range: value %g inside [%g, %g]", val, h.max, h.min)
 	}
 	if val < h.min || val > h.max {
-		return ErrIgnored
+		return ignored(ctx, "range: value %g outside [%g, %g]", val, h.min, h.max)
 	}
-	return h.EventHandler.Call(ev)
+	return callContext(ctx, h.EventHandler, ev)
+}
+
+func (h *rangeHandler) Unwrap() EventHandler {
+	return h.EventHandler
 }
 
 type debounceHandler struct {
 	EventHandler
 	ttl time.Duration
 	last time.Time
+	mutex *sync.Mutex
 }
 
+// WithDebounce is an alias for WithLeadingDebounce.
 func WithDebounce(h EventHandler, ttl time.Duration) EventHandler {
+	return WithLeadingDebounce(h, ttl)
+}
+
+// WithLeadingDebounce passes an event on immediately, then ignores events
+// until ttl has passed since the last one it passed on:
+//
+//	events: a-b-c-------d-e---
+//	passed: a-----------d-----
+//	        |<-ttl->|   |<-ttl->|
+func WithLeadingDebounce(h EventHandler, ttl time.Duration) EventHandler {
 	if ttl <= 0 {
 		return h
 	}
-	return &debounceHandler{h, ttl, time.Now().Add(-ttl)}
+	return &debounceHandler{h, ttl, now().Add(-ttl), &sync.Mutex{}}
+}
+
+// JitterGenerator returns a random number in [0, n), for the window of
+// WithDebounceJitter. Replace it with a deterministic function in tests to
+// make windows reproducible.
+var JitterGenerator func(n int64) int64 = rand.Int63n
+
+// WithDebounceJitter is like WithLeadingDebounce, but the window is chosen
+// per handler at random between ttl and ttl+jitter, using
+// JitterGenerator, so that handlers sharing a ttl don't all pass on an
+// event from the same burst at the same moment.
+func WithDebounceJitter(h EventHandler, ttl, jitter time.Duration) EventHandler {
+	if jitter > 0 {
+		ttl += time.Duration(JitterGenerator(int64(jitter) + 1))
+	}
+	return WithLeadingDebounce(h, ttl)
 }
 
 func (h *debounceHandler) Call(ev Event) error {
+	return h.CallContext(context.Background(), ev)
+}
+
+func (h *debounceHandler) CallContext(ctx context.Context, ev Event) error {
 	t := ev.GetTime()
+	h.mutex.Lock()
 	if h.last.Add(h.ttl).After(t) {
-		return ErrIgnored
+		h.mutex.Unlock()
+		return ignored(ctx, "debounce: within %s of the last event", h.ttl)
 	}
 	h.last = t
-	return h.EventHandler.Call(ev)
+	h.mutex.Unlock()
+	return callContext(ctx, h.EventHandler, ev)
+}
+
+func (h *debounceHandler) Unwrap() EventHandler {
+	return h.EventHandler
+}
+
+type trailingDebounceHandler struct {
+	EventHandler
+	ttl time.Duration
+	pending Event
+	pendingCtx context.Context
+	timer *time.Timer
+	mutex *sync.Mutex
+}
+
+// WithTrailingDebounce waits for ttl to pass without any events, then
+// passes on the last event received:
+//
+//	events: a-b-c-------d-e-------
+//	passed: ------c(+ttl)----e(+ttl)
+//
+// Since the wrapped handler is called from a timer, Call always returns
+// ErrIgnored and errors from the deferred call are not reported.
+func WithTrailingDebounce(h EventHandler, ttl time.Duration) EventHandler {
+	if ttl <= 0 {
+		return h
+	}
+	return &trailingDebounceHandler{EventHandler: h, ttl: ttl, mutex: &sync.Mutex{}}
+}
+
+func (h *trailingDebounceHandler) Call(ev Event) error {
+	return h.CallContext(context.Background(), ev)
+}
+
+func (h *trailingDebounceHandler) CallContext(ctx context.Context, ev Event) error {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.pending = ev
+	h.pendingCtx = detach(ctx)
+	if h.timer == nil {
+		h.timer = time.AfterFunc(h.ttl, h.flush)
+	} else {
+		h.timer.Reset(h.ttl)
+	}
+	return ignored(ctx, "trailing debounce: deferred for %s", h.ttl)
+}
+
+func (h *trailingDebounceHandler) flush() {
+	h.mutex.Lock()
+	ev := h.pending
+	ctx := h.pendingCtx
+	h.pending = nil
+	h.pendingCtx = nil
+	h.timer = nil
+	h.mutex.Unlock()
+	if ev != nil && !expired(ev, now()) {
+		callContext(ctx, h.EventHandler, ev)
+	}
+}
+
+// Drain returns the pending event, if any, without waiting for the timer.
+func (h *trailingDebounceHandler) Drain() []Event {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	if h.timer != nil {
+		h.timer.Stop()
+		h.timer = nil
+	}
+	ev := h.pending
+	h.pending = nil
+	h.pendingCtx = nil
+	if ev == nil || expired(ev, now()) {
+		return nil
+	}
+	return []Event{ev}
+}
+
+func (h *trailingDebounceHandler) Close() error {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	if h.timer != nil {
+		h.timer.Stop()
+		h.timer = nil
+	}
+	h.pending = nil
+	h.pendingCtx = nil
+	return nil
+}
+
+func (h *trailingDebounceHandler) Unwrap() EventHandler {
+	return h.EventHandler
+}
+
+type backpressureHandler struct {
+	EventHandler
+	ctx context.Context
+	sem chan struct{}
+}
+
+// WithBackpressure limits h to maxConcurrent simultaneous calls. Unlike
+// WithDebounce, excess events are not dropped: Call blocks until a slot is
+// free, which throttles the producer when dispatch is synchronous.
+func WithBackpressure(h EventHandler, maxConcurrent int) EventHandler {
+	return WithBackpressureContext(context.Background(), h, maxConcurrent)
+}
+
+// WithBackpressureContext is like WithBackpressure, but a blocked Call
+// gives up and returns ctx.Err() once ctx is done.
+func WithBackpressureContext(ctx context.Context, h EventHandler, maxConcurrent int) EventHandler {
+	if maxConcurrent <= 0 {
+		return h
+	}
+	return &backpressureHandler{h, ctx, make(chan struct{}, maxConcurrent)}
+}
+
+func (h *backpressureHandler) Call(ev Event) error {
+	return h.CallContext(context.Background(), ev)
+}
+
+func (h *backpressureHandler) CallContext(ctx context.Context, ev Event) error {
+	select {
+	case h.sem <- struct{}{}:
+	case <-h.ctx.Done():
+		return h.ctx.Err()
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	defer func() { <-h.sem }()
+	// the event may have expired while waiting for a slot
+	if expired(ev, now()) {
+		return ignored(ctx, "backpressure: expired while waiting")
+	}
+	return callContext(ctx, h.EventHandler, ev)
+}
+
+func (h *backpressureHandler) Unwrap() EventHandler {
+	return h.EventHandler
+}
+
+type onErrorHandler struct {
+	EventHandler
+	onErr func(Event, error)
+}
+
+// WithOnError calls onErr whenever h fails. Ignored and expired calls are
+// not failures, and the error is always returned unchanged, so the sink
+// still removes expired handlers and emits its own error events.
+func WithOnError(h EventHandler, onErr func(Event, error)) EventHandler {
+	return &onErrorHandler{h, onErr}
+}
+
+func (h *onErrorHandler) Call(ev Event) error {
+	return h.CallContext(context.Background(), ev)
+}
+
+func (h *onErrorHandler) CallContext(ctx context.Context, ev Event) error {
+	err := callContext(ctx, h.EventHandler, ev)
+	if err != nil && !errors.Is(err, ErrIgnored) && !errors.Is(err, ErrExpired) {
+		h.onErr(ev, err)
+	}
+	return err
+}
+
+func (h *onErrorHandler) Unwrap() EventHandler {
+	return h.EventHandler
+}
+
+type hardTimeoutHandler struct {
+	EventHandler
+	timeout time.Duration
+}
+
+// WithHardTimeout makes Call return ErrHandlerTimeout if h hasn't finished
+// within timeout. The context passed to h is cancelled at the deadline,
+// but a handler that ignores its context keeps running in an abandoned
+// goroutine until it finishes on its own, so a handler that never returns
+// leaks a goroutine per call.
+func WithHardTimeout(h EventHandler, timeout time.Duration) EventHandler {
+	if timeout <= 0 {
+		return h
+	}
+	return &hardTimeoutHandler{h, timeout}
+}
+
+func (h *hardTimeoutHandler) Call(ev Event) error {
+	return h.CallContext(context.Background(), ev)
+}
+
+func (h *hardTimeoutHandler) CallContext(ctx context.Context, ev Event) error {
+	ctx, cancel := context.WithTimeout(ctx, h.timeout)
+	defer cancel()
+	// h gets a result slot of its own, which is passed on only if h
+	// finishes in time, so that an abandoned call can't set a result
+	outer, _ := ctx.Value(resultKey{}).(*resultSlot)
+	inner := &resultSlot{}
+	if outer != nil {
+		ctx = context.WithValue(ctx, resultKey{}, inner)
+	}
+	ch := make(chan error, 1)
+	go func() {
+		ch <- callContext(ctx, h.EventHandler, ev)
+	}()
+	select {
+	case err := <-ch:
+		if outer != nil {
+			outer.set(inner.get())
+		}
+		return err
+	case <-ctx.Done():
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return ErrHandlerTimeout
+		}
+		return ctx.Err()
+	}
+}
+
+func (h *hardTimeoutHandler) Unwrap() EventHandler {
+	return h.EventHandler
+}
+
+type samplingHandler struct {
+	EventHandler
+	n int
+	count int
+	mutex *sync.Mutex
+}
+
+// WithSampling passes every nth event to h, starting with the nth, and
+// ignores the rest.
+func WithSampling(h EventHandler, n int) EventHandler {
+	if n <= 1 {
+		return h
+	}
+	return &samplingHandler{h, n, 0, &sync.Mutex{}}
+}
+
+func (h *samplingHandler) Call(ev Event) error {
+	return h.CallContext(context.Background(), ev)
+}
+
+func (h *samplingHandler) CallContext(ctx context.Context, ev Event) error {
+	h.mutex.Lock()
+	h.count = (h.count + 1) % h.n
+	pass := h.count == 0
+	h.mutex.Unlock()
+	if !pass {
+		return ignored(ctx, "sampling: passing 1 in %d", h.n)
+	}
+	return callContext(ctx, h.EventHandler, ev)
+}
+
+func (h *samplingHandler) Unwrap() EventHandler {
+	return h.EventHandler
+}
+
+type countThresholdHandler struct {
+	EventHandler
+	window time.Duration
+	times []time.Time
+	head int
+	size int
+	hold bool
+	armed bool
+	mutex *sync.Mutex
+}
+
+// WithCountThreshold calls h with the event that brings the number of
+// events within a trailing window, measured by event time, up to count,
+// such as the fifth login failure within a minute. The count then starts
+// over, so h is called again after count more events within a window.
+// Events are assumed to arrive in time order.
+func WithCountThreshold(h EventHandler, count int, window time.Duration) EventHandler {
+	return newCountThresholdHandler(h, count, window, false)
+}
+
+// WithCountThresholdHold is like WithCountThreshold, but after calling h
+// it waits for the rate to drop, so that the window holds fewer than count
+// events, before h can be called again. A sustained burst therefore calls
+// h once rather than every count events.
+func WithCountThresholdHold(h EventHandler, count int, window time.Duration) EventHandler {
+	return newCountThresholdHandler(h, count, window, true)
+}
+
+func newCountThresholdHandler(h EventHandler, count int, window time.Duration, hold bool) EventHandler {
+	if count <= 0 || window <= 0 {
+		return h
+	}
+	return &countThresholdHandler{
+		EventHandler: h,
+		window: window,
+		times: make([]time.Time, count),
+		hold: hold,
+		armed: true,
+		mutex: &sync.Mutex{},
+	}
+}
+
+func (h *countThresholdHandler) Call(ev Event) error {
+	return h.CallContext(context.Background(), ev)
+}
+
+func (h *countThresholdHandler) CallContext(ctx context.Context, ev Event) error {
+	h.mutex.Lock()
+	pass := h.next(ev.GetTime())
+	h.mutex.Unlock()
+	if !pass {
+		return ignored(ctx, "count threshold: %d events in %s not reached", len(h.times), h.window)
+	}
+	return callContext(ctx, h.EventHandler, ev)
+}
+
+// next records an event at t and reports whether it reaches the
+// threshold. The caller must hold the mutex.
+func (h *countThresholdHandler) next(t time.Time) bool {
+	n := len(h.times)
+	h.times[h.head] = t
+	h.head = (h.head + 1) % n
+	if h.size < n {
+		h.size += 1
+	}
+	// with a full ring, the oldest entry is the count'th most recent event
+	reached := h.size == n && !h.times[h.head].Before(t.Add(-h.window))
+	if !reached {
+		h.armed = true
+		return false
+	}
+	if !h.armed {
+		return false
+	}
+	if h.hold {
+		h.armed = false
+	} else {
+		h.size = 0
+	}
+	return true
+}
+
+func (h *countThresholdHandler) Unwrap() EventHandler {
+	return h.EventHandler
+}
+
+type randomSamplingHandler struct {
+	EventHandler
+	p float64
+}
+
+// WithRandomSampling passes each event to h with probability p.
+func WithRandomSampling(h EventHandler, p float64) EventHandler {
+	if p >= 1 {
+		return h
+	}
+	return &randomSamplingHandler{h, p}
+}
+
+func (h *randomSamplingHandler) Call(ev Event) error {
+	return h.CallContext(context.Background(), ev)
+}
+
+func (h *randomSamplingHandler) CallContext(ctx context.Context, ev Event) error {
+	if rand.Float64() >= h.p {
+		return ignored(ctx, "random sampling: passing %g", h.p)
+	}
+	return callContext(ctx, h.EventHandler, ev)
+}
+
+func (h *randomSamplingHandler) Unwrap() EventHandler {
+	return h.EventHandler
+}
+
+type loggingHandler struct {
+	EventHandler
+	log *generic.LinkedList[Event]
+}
+
+// WithLogging records in log, most recent first, each event that h handles
+// successfully. Events that h ignores or fails on are not recorded.
+func WithLogging(h EventHandler, log *generic.LinkedList[Event]) EventHandler {
+	return &loggingHandler{h, log}
+}
+
+func (h *loggingHandler) Call(ev Event) error {
+	return h.CallContext(context.Background(), ev)
+}
+
+func (h *loggingHandler) CallContext(ctx context.Context, ev Event) error {
+	err := callContext(ctx, h.EventHandler, ev)
+	if err == nil {
+		h.log.Unshift(ev)
+	}
+	return err
+}
+
+func (h *loggingHandler) Unwrap() EventHandler {
+	return h.EventHandler
+}
+
+type changeOnlyHandler struct {
+	EventHandler
+	epsilon float64
+	last float64
+	mutex *sync.Mutex
+}
+
+// WithChangeOnly passes a value event to h only if its value differs from
+// that of the last event passed. The first value is always passed, and
+// NaN values are ignored.
+func WithChangeOnly(h EventHandler) EventHandler {
+	return WithChangeOnlyEpsilon(h, 0)
+}
+
+// WithChangeOnlyEpsilon is like WithChangeOnly, but values within epsilon
+// of the last value passed are not considered changes.
+func WithChangeOnlyEpsilon(h EventHandler, epsilon float64) EventHandler {
+	return &changeOnlyHandler{h, math.Abs(epsilon), math.NaN(), &sync.Mutex{}}
+}
+
+func (h *changeOnlyHandler) Call(ev Event) error {
+	return h.CallContext(context.Background(), ev)
+}
+
+func (h *changeOnlyHandler) CallContext(ctx context.Context, ev Event) error {
+	valEv, ok := ev.(ValueEvent)
+	if !ok {
+		return ErrIncompatibleEvent
+	}
+	val := valEv.GetValue()
+	if math.IsNaN(val) {
+		return ignored(ctx, "change only: value is NaN")
+	}
+	h.mutex.Lock()
+	if !math.IsNaN(h.last) && math.Abs(val - h.last) <= h.epsilon {
+		h.mutex.Unlock()
+		return ignored(ctx, "change only: value %g within %g of the last", val, h.epsilon)
+	}
+	h.last = val
+	h.mutex.Unlock()
+	return callContext(ctx, h.EventHandler, ev)
+}
+
+func (h *changeOnlyHandler) Unwrap() EventHandler {
+	return h.EventHandler
 }
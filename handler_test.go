@@ -0,0 +1,1125 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rclancey/generic"
+)
+
+// passedValues calls the handler made by wrap with a value event for each
+// of vals, and returns the values that reached the wrapped handler.
+func passedValues(wrap func(EventHandler) EventHandler, vals ...float64) []float64 {
+	out := []float64{}
+	h := wrap(NewEventHandler(func(ev Event) error {
+		out = append(out, ev.(Valuer).GetValue())
+		return nil
+	}))
+	for _, val := range vals {
+		h.Call(NewEvent("test", val))
+	}
+	return out
+}
+
+func TestWithDirectionSteady(t *testing.T) {
+	tests := []struct {
+		name string
+		epsilon float64
+		vals []float64
+		want []float64
+	}{
+		{"exact", 0, []float64{1, 1, 1.05, 1.05}, []float64{1, 1.05}},
+		{"within epsilon", 0.1, []float64{1, 1.05, 0.98, 1.07}, []float64{1.05, 0.98, 1.07}},
+		{"at epsilon", 0.5, []float64{1, 1.5, 1}, []float64{1.5, 1}},
+		{"beyond epsilon", 0.1, []float64{1, 1.2, 1.4, 1.45}, []float64{1.45}},
+		{"negative epsilon", -0.1, []float64{1, 1.05, 2}, []float64{1.05}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := passedValues(func(h EventHandler) EventHandler { return WithDirectionSteady(h, tc.epsilon) }, tc.vals...)
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("passed %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+// directionFromFirst reports which of vals the direction handler passed
+// on for direction before it compared each value with the previous one,
+// when it compared every value with the first.
+func directionFromFirst(direction Direction, vals ...float64) []float64 {
+	out := []float64{}
+	for _, val := range vals[1:] {
+		var dir Direction
+		switch {
+		case val > vals[0]:
+			dir = DirectionIncreasing
+		case val < vals[0]:
+			dir = DirectionDecreasing
+		default:
+			dir = DirectionSteady
+		}
+		if dir == direction {
+			out = append(out, val)
+		}
+	}
+	return out
+}
+
+func TestWithDirectionComparesWithPrevious(t *testing.T) {
+	tests := []struct {
+		name string
+		direction Direction
+		vals []float64
+		wantFromFirst []float64
+		want []float64
+	}{
+		{"increasing", DirectionIncreasing, []float64{1, 3, 2, 4}, []float64{3, 2, 4}, []float64{3, 4}},
+		{"decreasing", DirectionDecreasing, []float64{1, 3, 2, 4}, []float64{}, []float64{2}},
+		{"decreasing below first", DirectionDecreasing, []float64{5, 3, 4, 2}, []float64{3, 4, 2}, []float64{3, 2}},
+		{"steady", DirectionSteady, []float64{1, 2, 2, 1}, []float64{1}, []float64{2}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if old := directionFromFirst(tc.direction, tc.vals...); !reflect.DeepEqual(old, tc.wantFromFirst) {
+				t.Fatalf("comparing with the first value passes %v, want %v", old, tc.wantFromFirst)
+			}
+			got := passedValues(func(h EventHandler) EventHandler { return WithDirection(h, tc.direction) }, tc.vals...)
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("passed %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestWithBackpressure(t *testing.T) {
+	tests := []struct {
+		name string
+		limit int
+		calls int
+	}{
+		{"serial", 1, 10},
+		{"limited", 3, 20},
+		{"over calls", 50, 10},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var active, peak, done int32
+			h := WithBackpressure(NewEventHandler(func(Event) error {
+				n := atomic.AddInt32(&active, 1)
+				for {
+					p := atomic.LoadInt32(&peak)
+					if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+						break
+					}
+				}
+				time.Sleep(time.Millisecond)
+				atomic.AddInt32(&active, -1)
+				atomic.AddInt32(&done, 1)
+				return nil
+			}), tc.limit)
+			wg := &sync.WaitGroup{}
+			for i := 0; i < tc.calls; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					if err := h.Call(NewEvent("test", 1.0)); err != nil {
+						t.Errorf("call failed: %s", err)
+					}
+				}()
+			}
+			wg.Wait()
+			if int(peak) > tc.limit {
+				t.Errorf("%d concurrent calls, want at most %d", peak, tc.limit)
+			}
+			if int(done) != tc.calls {
+				t.Errorf("%d calls completed, want %d", done, tc.calls)
+			}
+		})
+	}
+}
+
+func TestWithBackpressureContext(t *testing.T) {
+	tests := []struct {
+		name string
+		cancelHandler bool
+		callTimeout time.Duration
+		want error
+	}{
+		{"handler context cancelled", true, 0, context.Canceled},
+		{"call context expired", false, 10 * time.Millisecond, context.DeadlineExceeded},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			release := make(chan struct{})
+			started := make(chan struct{})
+			h := WithBackpressureContext(ctx, NewEventHandler(func(Event) error {
+				close(started)
+				<-release
+				return nil
+			}), 1).(ContextHandler)
+			go h.Call(NewEvent("test", 1.0))
+			<-started
+			defer close(release)
+			callCtx := context.Background()
+			if tc.callTimeout > 0 {
+				var callCancel context.CancelFunc
+				callCtx, callCancel = context.WithTimeout(callCtx, tc.callTimeout)
+				defer callCancel()
+			}
+			if tc.cancelHandler {
+				time.AfterFunc(10 * time.Millisecond, cancel)
+			}
+			if err := h.CallContext(callCtx, NewEvent("test", 2.0)); !errors.Is(err, tc.want) {
+				t.Errorf("blocked call returned %v, want %v", err, tc.want)
+			}
+		})
+	}
+}
+
+func TestWithBackpressureUnlimited(t *testing.T) {
+	h := NewEventHandler(func(Event) error { return nil })
+	for _, limit := range []int{0, -1} {
+		if got := WithBackpressure(h, limit); got != h {
+			t.Errorf("WithBackpressure(h, %d) wrapped h", limit)
+		}
+	}
+}
+
+func TestWithMaxCallsAndFilter(t *testing.T) {
+	tests := []struct {
+		name string
+		maxCalls int
+		filter func(EventHandler) EventHandler
+		vals []float64
+		want []float64
+		wantExpired bool
+	}{
+		{"no filter", 2, func(h EventHandler) EventHandler { return h }, []float64{1, 2, 3}, []float64{1, 2}, true},
+		{"ignored calls don't count", 2, func(h EventHandler) EventHandler { return WithRange(h, 5, 10) }, []float64{1, 6, 2, 7, 8}, []float64{6, 7}, true},
+		{"limit not reached", 3, func(h EventHandler) EventHandler { return WithRange(h, 5, 10) }, []float64{1, 6, 2}, []float64{6}, false},
+		{"no limit", 0, func(h EventHandler) EventHandler { return h }, []float64{1, 2, 3}, []float64{1, 2, 3}, false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var h EventHandler
+			got := passedValues(func(inner EventHandler) EventHandler {
+				h = WithMaxCalls(tc.filter(inner), tc.maxCalls)
+				return h
+			}, tc.vals...)
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("passed %v, want %v", got, tc.want)
+			}
+			if h.Expired() != tc.wantExpired {
+				t.Errorf("expired = %t, want %t", h.Expired(), tc.wantExpired)
+			}
+		})
+	}
+}
+
+func TestWithMaxCallsDoesNotHoldLock(t *testing.T) {
+	var h EventHandler
+	h = WithMaxCalls(NewEventHandler(func(Event) error {
+		// would deadlock if the mutex were held while calling the handler
+		h.Expired()
+		return nil
+	}), 2)
+	done := make(chan struct{})
+	go func() {
+		h.Call(NewEvent("test", 1.0))
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("handler deadlocked calling Expired")
+	}
+}
+
+func TestWithMaxCallsConcurrent(t *testing.T) {
+	tests := []struct {
+		name string
+		maxCalls int
+		calls int
+	}{
+		{"one", 1, 20},
+		{"several", 5, 50},
+		{"more than calls", 100, 20},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var handled int32
+			h := WithMaxCalls(NewEventHandler(func(Event) error {
+				atomic.AddInt32(&handled, 1)
+				time.Sleep(time.Millisecond)
+				return nil
+			}), tc.maxCalls)
+			wg := &sync.WaitGroup{}
+			for i := 0; i < tc.calls; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					err := h.Call(NewEvent("test", 1.0))
+					if err != nil && !errors.Is(err, ErrIgnored) && !errors.Is(err, ErrExpired) {
+						t.Errorf("call failed: %s", err)
+					}
+				}()
+			}
+			wg.Wait()
+			if int(handled) > tc.maxCalls {
+				t.Errorf("handled %d calls, want at most %d", handled, tc.maxCalls)
+			}
+			if tc.maxCalls <= tc.calls && int(handled) == 0 {
+				t.Errorf("handled no calls")
+			}
+		})
+	}
+}
+
+func TestWithMaxCallsWaitsForCallInProgress(t *testing.T) {
+	tests := []struct {
+		name string
+		slowErr error
+		want []float64
+		wantErr error
+	}{
+		{"slow call ignored", ErrIgnored, []float64{1}, nil},
+		{"slow call failed", errBoom, []float64{1}, nil},
+		{"slow call counted", nil, []float64{}, ErrExpired},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			slowErr := tc.slowErr
+			started := make(chan struct{})
+			release := make(chan struct{})
+			mutex := &sync.Mutex{}
+			handled := []float64{}
+			h := WithMaxCalls(NewEventHandler(func(ev Event) error {
+				val := ev.(Valuer).GetValue()
+				if val == 0 {
+					close(started)
+					<-release
+					return slowErr
+				}
+				mutex.Lock()
+				handled = append(handled, val)
+				mutex.Unlock()
+				return nil
+			}), 1)
+			done := make(chan struct{})
+			go func() {
+				h.Call(NewEvent("test", 0.0))
+				close(done)
+			}()
+			<-started
+			errs := make(chan error, 1)
+			go func() {
+				errs <- h.Call(NewEvent("test", 1.0))
+			}()
+			time.Sleep(10 * time.Millisecond)
+			close(release)
+			<-done
+			err := <-errs
+			if !errors.Is(err, tc.wantErr) || (tc.wantErr == nil && err != nil) {
+				t.Errorf("overlapping call returned %v, want %v", err, tc.wantErr)
+			}
+			mutex.Lock()
+			defer mutex.Unlock()
+			if !reflect.DeepEqual(handled, tc.want) {
+				t.Errorf("handled %v, want %v", handled, tc.want)
+			}
+		})
+	}
+}
+
+func TestWithMaxCallsWaitingCancelled(t *testing.T) {
+	release := make(chan struct{})
+	started := make(chan struct{})
+	h := WithMaxCalls(NewEventHandler(func(ev Event) error {
+		if ev.(Valuer).GetValue() == 0 {
+			close(started)
+			<-release
+		}
+		return nil
+	}), 1)
+	go h.Call(NewEvent("test", 0.0))
+	<-started
+	ctx, cancel := context.WithTimeout(context.Background(), 10 * time.Millisecond)
+	defer cancel()
+	if err := h.(ContextHandler).CallContext(ctx, NewEvent("test", 1.0)); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("waiting call returned %v, want context.DeadlineExceeded", err)
+	}
+	close(release)
+}
+
+func TestWithMaxCallsThreshold(t *testing.T) {
+	tests := []struct {
+		name string
+		vals []float64
+		want []float64
+	}{
+		{"exactly three", []float64{0, 11, 12, 4, 11, 3, 11, 3, 11, 2, 15}, []float64{11, 11, 11}},
+		{"fewer crossings", []float64{0, 11, 12, 13, 4, 11}, []float64{11, 11}},
+		{"no crossings", []float64{0, 5, 9}, []float64{}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			sink := NewSyncEventSink(time.Minute)
+			rec := RecordingHandler()
+			sink.AddEventListener("test", WithMaxCalls(WithThreshold(rec, DirectionIncreasing, 10, 5), 3))
+			for _, val := range tc.vals {
+				sink.Emit("test", val)
+			}
+			if got := logValues(rec.Calls()); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("handled %v, want %v", got, tc.want)
+			}
+			wantListeners := 1
+			if len(tc.want) == 3 {
+				wantListeners = 0
+			}
+			if n := sink.(ListenerInspector).ListenerCount("test"); n != wantListeners {
+				t.Errorf("%d listeners left, want %d", n, wantListeners)
+			}
+		})
+	}
+}
+
+func TestWithMaxCallsPassesErrors(t *testing.T) {
+	errFailed := errors.New("failed")
+	tests := []struct {
+		name string
+		results []error
+		want []error
+	}{
+		{"ignored", []error{ErrIgnored, nil, ErrIgnored}, []error{ErrIgnored, nil, ErrIgnored}},
+		{"ignored reason", []error{ErrIgnoredReason("busy"), nil}, []error{ErrIgnored, nil}},
+		{"failures don't count", []error{errFailed, nil, nil, nil}, []error{errFailed, nil, nil, ErrExpired}},
+		{"expired after limit", []error{nil, nil, nil}, []error{nil, nil, ErrExpired}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			i := 0
+			h := WithMaxCalls(NewEventHandler(func(Event) error {
+				err := tc.results[i]
+				i += 1
+				return err
+			}), 2)
+			for j, want := range tc.want {
+				if err := h.Call(NewEvent("test", 1.0)); !errors.Is(err, want) || (want == nil && err != nil) {
+					t.Errorf("call %d returned %v, want %v", j, err, want)
+				}
+			}
+		})
+	}
+}
+
+// useSequentialIDs makes IDGenerator count up from 1 for the rest of the
+// test.
+func useSequentialIDs(t *testing.T) {
+	saved := IDGenerator
+	var next int64
+	IDGenerator = func() int64 {
+		return atomic.AddInt64(&next, 1)
+	}
+	t.Cleanup(func() { IDGenerator = saved })
+}
+
+func TestIDGenerator(t *testing.T) {
+	useSequentialIDs(t)
+	noop := func(Event) error { return nil }
+	tests := []struct {
+		name string
+		handler EventHandler
+		want int64
+	}{
+		{"first", NewEventHandler(noop), 1},
+		{"second", NewEventHandler(noop), 2},
+		{"explicit", NewEventHandlerWithID(100, noop), 100},
+		{"context", NewContextEventHandler(func(context.Context, Event) error { return nil }), 3},
+		{"result", NewResultHandler(func(Event) (interface{}, error) { return nil, nil }), 4},
+		{"reference", HandlerReference(7), 7},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if id := tc.handler.ID(); id != tc.want {
+				t.Errorf("ID = %d, want %d", id, tc.want)
+			}
+		})
+	}
+}
+
+func TestNewEventHandlerWithIDRemoval(t *testing.T) {
+	sink := NewSyncEventSink(time.Minute)
+	rec := RecordingHandler()
+	sink.AddEventListener("test", NewEventHandlerWithID(42, rec.Call))
+	sink.RemoveEventListener("test", HandlerReference(42))
+	sink.Emit("test", 1.0)
+	if n := len(rec.Calls()); n != 0 {
+		t.Errorf("removed handler called %d times", n)
+	}
+}
+
+func TestWithOnError(t *testing.T) {
+	errFailed := errors.New("failed")
+	tests := []struct {
+		name string
+		err error
+		wantCalled bool
+	}{
+		{"success", nil, false},
+		{"failure", errFailed, true},
+		{"wrapped failure", fmt.Errorf("sending: %w", errFailed), true},
+		{"ignored", ErrIgnored, false},
+		{"expired", ErrExpired, false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var gotEv Event
+			var gotErr error
+			h := WithOnError(NewEventHandler(func(Event) error { return tc.err }), func(ev Event, err error) {
+				gotEv = ev
+				gotErr = err
+			})
+			ev := NewEvent("test", 1.0)
+			if err := h.Call(ev); err != tc.err {
+				t.Errorf("call returned %v, want %v unchanged", err, tc.err)
+			}
+			if called := gotEv != nil; called != tc.wantCalled {
+				t.Fatalf("onErr called = %t, want %t", called, tc.wantCalled)
+			}
+			if tc.wantCalled && (gotEv != ev || gotErr != tc.err) {
+				t.Errorf("onErr(%v, %v), want (%v, %v)", gotEv, gotErr, ev, tc.err)
+			}
+		})
+	}
+}
+
+func TestWithOnErrorExpiredRemovesListener(t *testing.T) {
+	sink := NewSyncEventSink(time.Minute)
+	called := 0
+	sink.AddEventListener("test", WithOnError(WithMaxCalls(NewEventHandler(func(Event) error { return nil }), 1), func(Event, error) {
+		called += 1
+	}))
+	sink.Emit("test", 1.0)
+	sink.Emit("test", 2.0)
+	if n := sink.(ListenerInspector).ListenerCount("test"); n != 0 {
+		t.Errorf("%d listeners left, want 0", n)
+	}
+	if called != 0 {
+		t.Errorf("onErr called %d times for an expiring handler", called)
+	}
+}
+
+func TestWithLeadingDebounce(t *testing.T) {
+	tests := []struct {
+		name string
+		ttl time.Duration
+		at []time.Duration
+		want []float64
+	}{
+		{"burst", 10 * time.Second, []time.Duration{0, 2 * time.Second, 4 * time.Second}, []float64{0}},
+		{"two bursts", 10 * time.Second, []time.Duration{0, 2 * time.Second, 15 * time.Second, 17 * time.Second}, []float64{0, 2}},
+		{"exactly ttl apart", 10 * time.Second, []time.Duration{0, 10 * time.Second, 20 * time.Second}, []float64{0, 1, 2}},
+		{"quiet period measured from passed event", 10 * time.Second, []time.Duration{0, 6 * time.Second, 12 * time.Second}, []float64{0, 2}},
+		{"no ttl", 0, []time.Duration{0, 0, 0}, []float64{0, 1, 2}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			c := useFakeClock(t)
+			start := c.Now()
+			got := []float64{}
+			h := WithLeadingDebounce(NewEventHandler(func(ev Event) error {
+				got = append(got, ev.(Valuer).GetValue())
+				return nil
+			}), tc.ttl)
+			for i, at := range tc.at {
+				c.Set(start.Add(at))
+				h.Call(NewEvent("test", float64(i)))
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("passed %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestWithTrailingDebounce(t *testing.T) {
+	const ttl = 30 * time.Millisecond
+	tests := []struct {
+		name string
+		bursts [][]float64
+		want []float64
+	}{
+		{"one burst", [][]float64{{1, 2, 3}}, []float64{3}},
+		{"two bursts", [][]float64{{1, 2, 3}, {4, 5}}, []float64{3, 5}},
+		{"single events", [][]float64{{1}, {2}}, []float64{1, 2}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := RecordingHandler()
+			h := WithTrailingDebounce(rec, ttl)
+			n := 0
+			for _, burst := range tc.bursts {
+				for _, val := range burst {
+					if err := h.Call(NewEvent("test", val)); !errors.Is(err, ErrIgnored) {
+						t.Errorf("call returned %v, want ErrIgnored", err)
+					}
+				}
+				if len(rec.Calls()) != n {
+					t.Errorf("called before ttl passed")
+				}
+				n += 1
+				if !rec.WaitForCalls(n, time.Second) {
+					t.Fatalf("burst %d not passed on", n)
+				}
+			}
+			if got := logValues(rec.Calls()); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("passed %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+// passedIndexes is like passedValues, but returns the indexes in vals of
+// the events that reached the wrapped handler.
+func passedIndexes(wrap func(EventHandler) EventHandler, vals ...float64) []int {
+	evs := make([]Event, len(vals))
+	for i, val := range vals {
+		evs[i] = NewEvent("test", val)
+	}
+	out := []int{}
+	h := wrap(NewEventHandler(func(ev Event) error {
+		for i, e := range evs {
+			if e == ev {
+				out = append(out, i)
+			}
+		}
+		return nil
+	}))
+	for _, ev := range evs {
+		h.Call(ev)
+	}
+	return out
+}
+
+func TestWithDirectionTurns(t *testing.T) {
+	sawtooth := []float64{1, 2, 3, 1, 2, 3, 1}
+	tests := []struct {
+		name string
+		direction Direction
+		vals []float64
+		want []int
+	}{
+		{"sawtooth peaks", DirectionPeak, sawtooth, []int{2, 5}},
+		{"sawtooth troughs", DirectionTrough, sawtooth, []int{3}},
+		{"sawtooth reversals", DirectionReverse, sawtooth, []int{3, 4, 6}},
+		{"plateau peak", DirectionPeak, []float64{1, 2, 3, 3, 3, 2}, []int{2}},
+		{"plateau trough", DirectionTrough, []float64{3, 2, 2, 3}, []int{1}},
+		{"plateau without turn", DirectionPeak, []float64{1, 2, 2, 3}, []int{}},
+		{"monotonic", DirectionPeak, []float64{1, 2, 3, 4}, []int{}},
+		{"nan ignored", DirectionPeak, []float64{1, 3, math.NaN(), 2}, []int{1}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := passedIndexes(func(h EventHandler) EventHandler { return WithDirection(h, tc.direction) }, tc.vals...)
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("passed events %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestWithHardTimeout(t *testing.T) {
+	errFailed := errors.New("failed")
+	tests := []struct {
+		name string
+		sleep time.Duration
+		err error
+		want error
+		wantCancelled bool
+	}{
+		{"fast", 0, nil, nil, false},
+		{"fast failure", 0, errFailed, errFailed, false},
+		{"sleeping", time.Second, nil, ErrHandlerTimeout, true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			cancelled := make(chan bool, 1)
+			h := WithHardTimeout(NewContextEventHandler(func(ctx context.Context, ev Event) error {
+				select {
+				case <-time.After(tc.sleep):
+					cancelled <- false
+				case <-ctx.Done():
+					cancelled <- true
+				}
+				return tc.err
+			}), 20 * time.Millisecond)
+			start := time.Now()
+			err := h.Call(NewEvent("test", 1.0))
+			if err != tc.want {
+				t.Errorf("call returned %v, want %v", err, tc.want)
+			}
+			if elapsed := time.Since(start); tc.want == ErrHandlerTimeout && elapsed >= tc.sleep {
+				t.Errorf("call took %s, waiting for the handler to finish", elapsed)
+			}
+			if c := <-cancelled; c != tc.wantCancelled {
+				t.Errorf("handler context cancelled = %t, want %t", c, tc.wantCancelled)
+			}
+		})
+	}
+}
+
+func TestWithHardTimeoutIgnoringContext(t *testing.T) {
+	release := make(chan struct{})
+	h := WithHardTimeout(NewEventHandler(func(Event) error {
+		<-release
+		return nil
+	}), 10 * time.Millisecond)
+	if err := h.Call(NewEvent("test", 1.0)); err != ErrHandlerTimeout {
+		t.Errorf("call returned %v, want %v", err, ErrHandlerTimeout)
+	}
+	close(release)
+}
+
+func TestWithSampling(t *testing.T) {
+	vals := make([]float64, 12)
+	tests := []struct {
+		name string
+		n int
+		want []int
+	}{
+		{"every third", 3, []int{2, 5, 8, 11}},
+		{"every fifth", 5, []int{4, 9}},
+		{"every other", 2, []int{1, 3, 5, 7, 9, 11}},
+		{"more than the events", 20, []int{}},
+		{"every one", 1, []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11}},
+		{"zero", 0, []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := passedIndexes(func(h EventHandler) EventHandler { return WithSampling(h, tc.n) }, vals...)
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("passed %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestWithSamplingConcurrent(t *testing.T) {
+	tests := []struct {
+		name string
+		n int
+		workers int
+		calls int
+	}{
+		{"even split", 4, 8, 100},
+		{"uneven split", 7, 3, 70},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var passed int64
+			h := WithSampling(NewEventHandler(func(ev Event) error {
+				atomic.AddInt64(&passed, 1)
+				return nil
+			}), tc.n)
+			wg := &sync.WaitGroup{}
+			for i := 0; i < tc.workers; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for j := 0; j < tc.calls; j++ {
+						h.Call(NewEvent("test", 1.0))
+					}
+				}()
+			}
+			wg.Wait()
+			if want := int64(tc.workers * tc.calls / tc.n); passed != want {
+				t.Errorf("passed %d, want %d", passed, want)
+			}
+		})
+	}
+}
+
+func TestWithRandomSampling(t *testing.T) {
+	const events = 10000
+	tests := []struct {
+		name string
+		p float64
+		min int
+		max int
+	}{
+		{"none", 0, 0, 0},
+		{"all", 1, events, events},
+		{"half", 0.5, events * 45 / 100, events * 55 / 100},
+		{"tenth", 0.1, events * 7 / 100, events * 13 / 100},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			n := 0
+			h := WithRandomSampling(NewEventHandler(func(ev Event) error {
+				n++
+				return nil
+			}), tc.p)
+			for i := 0; i < events; i++ {
+				err := h.Call(NewEvent("test", 1.0))
+				if err != nil && !errors.Is(err, ErrIgnored) {
+					t.Fatalf("call returned %v", err)
+				}
+			}
+			if n < tc.min || n > tc.max {
+				t.Errorf("passed %d of %d, want %d to %d", n, events, tc.min, tc.max)
+			}
+		})
+	}
+}
+
+func TestWithLogging(t *testing.T) {
+	tests := []struct {
+		name string
+		wrap func(EventHandler) EventHandler
+		err error
+		vals []float64
+		want []float64
+	}{
+		{"all", func(h EventHandler) EventHandler { return h }, nil, []float64{1, 2, 3}, []float64{3, 2, 1}},
+		{"threshold", func(h EventHandler) EventHandler { return WithThreshold(h, DirectionIncreasing, 5, 2) }, nil, []float64{1, 6, 7, 1, 8, 3}, []float64{8, 6}},
+		{"range", func(h EventHandler) EventHandler { return WithRange(h, 0, 10) }, nil, []float64{-1, 5, 11, 10}, []float64{10, 5}},
+		{"failing", func(h EventHandler) EventHandler { return h }, errBoom, []float64{1, 2}, []float64{}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			log := generic.NewLinkedList[Event]()
+			h := WithLogging(tc.wrap(NewEventHandler(func(ev Event) error { return tc.err })), log)
+			for _, val := range tc.vals {
+				h.Call(NewEvent("test", val))
+			}
+			if got := logValues(log.Slice()); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("logged %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestWithChangeOnly(t *testing.T) {
+	nan := math.NaN()
+	tests := []struct {
+		name string
+		epsilon float64
+		vals []float64
+		want []float64
+	}{
+		{"repeats", 0, []float64{1, 1, 2, 2, 2, 1, 1}, []float64{1, 2, 1}},
+		{"no repeats", 0, []float64{1, 2, 3}, []float64{1, 2, 3}},
+		{"all the same", 0, []float64{5, 5, 5}, []float64{5}},
+		{"nan ignored", 0, []float64{nan, 1, nan, 1, 2}, []float64{1, 2}},
+		{"within epsilon", 0.5, []float64{1, 1.2, 1.5, 1.6, 0.4}, []float64{1, 1.6, 0.4}},
+		{"drift compares with last passed", 0.5, []float64{1, 1.25, 1.5, 1.75, 2}, []float64{1, 1.75}},
+		{"negative epsilon", -0.5, []float64{1, 1.2, 2}, []float64{1, 2}},
+		{"zero and negative zero", 0, []float64{0, math.Copysign(0, -1)}, []float64{0}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := passedValues(func(h EventHandler) EventHandler { return WithChangeOnlyEpsilon(h, tc.epsilon) }, tc.vals...)
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("passed %v, want %v", got, tc.want)
+			}
+			if tc.epsilon == 0 {
+				if got := passedValues(WithChangeOnly, tc.vals...); !reflect.DeepEqual(got, tc.want) {
+					t.Errorf("WithChangeOnly passed %v, want %v", got, tc.want)
+				}
+			}
+		})
+	}
+}
+
+func TestWithChangeOnlyMessage(t *testing.T) {
+	h := WithChangeOnly(NewEventHandler(func(Event) error { return nil }))
+	if err := h.Call(NewEvent("test", "on")); !errors.Is(err, ErrIncompatibleEvent) {
+		t.Errorf("call returned %v, want %v", err, ErrIncompatibleEvent)
+	}
+}
+
+// useJitter replaces JitterGenerator for the rest of the test.
+func useJitter(t *testing.T, gen func(n int64) int64) {
+	saved := JitterGenerator
+	JitterGenerator = gen
+	t.Cleanup(func() { JitterGenerator = saved })
+}
+
+func TestWithDebounceJitter(t *testing.T) {
+	tests := []struct {
+		name string
+		gen func(n int64) int64
+		jitter time.Duration
+		at []time.Duration
+		want []float64
+	}{
+		{"no jitter drawn", func(n int64) int64 { return 0 }, 5 * time.Second, []time.Duration{0, 10 * time.Second}, []float64{0, 1}},
+		{"most jitter", func(n int64) int64 { return n - 1 }, 5 * time.Second, []time.Duration{0, 14 * time.Second, 15 * time.Second}, []float64{0, 2}},
+		{"some jitter", func(n int64) int64 { return int64(2 * time.Second) }, 5 * time.Second, []time.Duration{0, 11 * time.Second, 12 * time.Second}, []float64{0, 2}},
+		{"zero jitter", func(n int64) int64 { panic("jitter drawn") }, 0, []time.Duration{0, 9 * time.Second, 10 * time.Second}, []float64{0, 2}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			useJitter(t, tc.gen)
+			c := useFakeClock(t)
+			start := c.Now()
+			got := []float64{}
+			h := WithDebounceJitter(NewEventHandler(func(ev Event) error {
+				got = append(got, ev.(Valuer).GetValue())
+				return nil
+			}), 10 * time.Second, tc.jitter)
+			for i, at := range tc.at {
+				c.Set(start.Add(at))
+				h.Call(NewEvent("test", float64(i)))
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("passed %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestWithDebounceJitterSpread(t *testing.T) {
+	const handlers = 1000
+	const buckets = 10
+	ttl := 10 * time.Second
+	jitter := 10 * time.Second
+	useJitter(t, rand.New(rand.NewSource(1)).Int63n)
+	counts := make([]int, buckets)
+	var sum time.Duration
+	for i := 0; i < handlers; i++ {
+		window := WithDebounceJitter(NewEventHandler(nil), ttl, jitter).(*debounceHandler).ttl
+		if window < ttl || window > ttl + jitter {
+			t.Fatalf("window %s outside [%s, %s]", window, ttl, ttl + jitter)
+		}
+		sum += window
+		b := int((window - ttl) * buckets / (jitter + 1))
+		counts[b]++
+	}
+	if mean := sum / handlers; mean < ttl + jitter * 45 / 100 || mean > ttl + jitter * 55 / 100 {
+		t.Errorf("mean window %s, want about %s", mean, ttl + jitter / 2)
+	}
+	for i, n := range counts {
+		// about 100 each
+		if n < handlers / buckets / 2 {
+			t.Errorf("bucket %d has %d windows: %v", i, n, counts)
+		}
+	}
+}
+
+func TestWithCountThreshold(t *testing.T) {
+	s := time.Second
+	tests := []struct {
+		name string
+		count int
+		window time.Duration
+		hold bool
+		offsets []time.Duration
+		want []int
+	}{
+		{"burst crosses", 3, 10 * s, false, []time.Duration{0, 1 * s, 2 * s}, []int{2}},
+		{"burst short of count", 3, 10 * s, false, []time.Duration{0, 1 * s}, []int{}},
+		{"too spread out", 3, 10 * s, false, []time.Duration{0, 6 * s, 12 * s, 18 * s}, []int{}},
+		{"window edge", 3, 10 * s, false, []time.Duration{0, 5 * s, 10 * s}, []int{2}},
+		{"just past window", 3, 10 * s, false, []time.Duration{0, 5 * s, 11 * s}, []int{}},
+		{"count starts over", 3, 10 * s, false, []time.Duration{0, 1 * s, 2 * s, 3 * s, 4 * s, 5 * s}, []int{2, 5}},
+		{"second burst", 3, 10 * s, false, []time.Duration{0, 1 * s, 2 * s, 20 * s, 21 * s, 22 * s}, []int{2, 5}},
+		{"count of one", 1, 10 * s, false, []time.Duration{0, 1 * s, 2 * s}, []int{0, 1, 2}},
+		{"hold sustained burst", 3, 10 * s, true, []time.Duration{0, 1 * s, 2 * s, 3 * s, 4 * s, 5 * s}, []int{2}},
+		{"hold re-arms", 3, 10 * s, true, []time.Duration{0, 1 * s, 2 * s, 3 * s, 30 * s, 31 * s, 32 * s}, []int{2, 6}},
+		{"hold spread out", 3, 10 * s, true, []time.Duration{0, 6 * s, 12 * s}, []int{}},
+		{"no count", 0, 10 * s, false, []time.Duration{0, 1 * s}, []int{0, 1}},
+		{"no window", 3, 0, false, []time.Duration{0, 1 * s}, []int{0, 1}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			c := useFakeClock(t)
+			start := c.Now()
+			evs := make([]Event, len(tc.offsets))
+			for i, off := range tc.offsets {
+				c.Set(start.Add(off))
+				evs[i] = NewEvent("test", 1.0)
+			}
+			// the handler goes by event time, not the clock
+			c.Set(start)
+			got := []int{}
+			h := NewEventHandler(func(ev Event) error {
+				for i, e := range evs {
+					if e == ev {
+						got = append(got, i)
+					}
+				}
+				return nil
+			})
+			if tc.hold {
+				h = WithCountThresholdHold(h, tc.count, tc.window)
+			} else {
+				h = WithCountThreshold(h, tc.count, tc.window)
+			}
+			for _, ev := range evs {
+				h.Call(ev)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("passed %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestWithErrorRateLimit(t *testing.T) {
+	type call struct {
+		gap time.Duration
+		err error
+	}
+	boom := call{time.Second, errBoom}
+	ok := call{time.Second, nil}
+	tests := []struct {
+		name string
+		max int
+		window time.Duration
+		calls []call
+		want []error
+		wantExpired bool
+	}{
+		{"under limit", 2, time.Minute, []call{boom, boom, ok}, []error{errBoom, errBoom, nil}, false},
+		{"past limit", 2, time.Minute, []call{boom, boom, boom, ok}, []error{errBoom, errBoom, errBoom, ErrExpired}, true},
+		{"successes don't reset", 1, time.Minute, []call{boom, ok, boom, ok}, []error{errBoom, nil, errBoom, ErrExpired}, true},
+		{"zero max", 0, time.Minute, []call{ok, boom, ok}, []error{nil, errBoom, ErrExpired}, true},
+		{
+			"failures age out",
+			1, time.Minute,
+			[]call{boom, {2 * time.Minute, errBoom}, {2 * time.Minute, errBoom}, ok},
+			[]error{errBoom, errBoom, errBoom, nil},
+			false,
+		},
+		{
+			"at the window edge",
+			1, time.Minute,
+			[]call{boom, {time.Minute, errBoom}, ok},
+			[]error{errBoom, errBoom, nil},
+			false,
+		},
+		{
+			"ignored isn't a failure",
+			0, time.Minute,
+			[]call{{time.Second, ErrIgnored}, {time.Second, ErrStopPropagation}, ok},
+			[]error{ErrIgnored, ErrStopPropagation, nil},
+			false,
+		},
+		{"disabled by negative max", -1, time.Minute, []call{boom, boom, boom}, []error{errBoom, errBoom, errBoom}, false},
+		{"disabled by zero window", 0, 0, []call{boom, boom, boom}, []error{errBoom, errBoom, errBoom}, false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			c := useFakeClock(t)
+			var next error
+			h := WithErrorRateLimit(NewEventHandler(func(ev Event) error { return next }), tc.max, tc.window)
+			got := []error{}
+			for _, cl := range tc.calls {
+				c.Advance(cl.gap)
+				next = cl.err
+				got = append(got, h.Call(NewEvent("test", 1.0)))
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("errors = %v, want %v", got, tc.want)
+			}
+			if h.Expired() != tc.wantExpired {
+				t.Errorf("expired = %t, want %t", h.Expired(), tc.wantExpired)
+			}
+		})
+	}
+}
+
+func TestWithErrorRateLimitRemovesListener(t *testing.T) {
+	sink := NewSyncEventSink(time.Hour)
+	var calls int
+	sink.AddEventListener("test", WithErrorRateLimit(NewEventHandler(func(ev Event) error {
+		calls += 1
+		return errBoom
+	}), 2, time.Minute))
+	for i := 0; i < 5; i++ {
+		sink.Emit("test", float64(i))
+	}
+	if calls != 3 {
+		t.Errorf("handler called %d times, want 3", calls)
+	}
+	if n := sink.(ListenerInspector).ListenerCount("test"); n != 0 {
+		t.Errorf("%d listeners after tripping the limit, want 0", n)
+	}
+}
+
+func TestWithThresholdFlags(t *testing.T) {
+	vals := []float64{5, 10, 11, 5, 4, 10, 11, 5, 10}
+	tests := []struct {
+		name string
+		direction Direction
+		trigger float64
+		reset float64
+		flags ThresholdFlags
+		vals []float64
+		want []float64
+	}{
+		{"inclusive", DirectionIncreasing, 10, 5, 0, vals, []float64{10, 10, 10}},
+		{"exclusive trigger", DirectionIncreasing, 10, 5, ThresholdExclusiveTrigger, vals, []float64{11, 11}},
+		{"exclusive reset", DirectionIncreasing, 10, 5, ThresholdExclusiveReset, vals, []float64{10, 10}},
+		{"both exclusive", DirectionIncreasing, 10, 5, ThresholdExclusiveTrigger | ThresholdExclusiveReset, vals, []float64{11, 11}},
+		{"decreasing exclusive trigger", DirectionDecreasing, 5, 10, ThresholdExclusiveTrigger, vals, []float64{4}},
+		{"decreasing exclusive reset", DirectionDecreasing, 5, 10, ThresholdExclusiveReset, []float64{5, 10, 5, 11, 5}, []float64{5, 5}},
+		{"equal, exclusive trigger", DirectionIncreasing, 10, 10, ThresholdExclusiveTrigger, []float64{10, 11, 11, 10, 11}, []float64{11, 11}},
+		{"equal, exclusive reset", DirectionIncreasing, 10, 10, ThresholdExclusiveReset, []float64{10, 10, 9, 10}, []float64{10, 10}},
+		{"equal, decreasing", DirectionDecreasing, 10, 10, ThresholdExclusiveTrigger, []float64{10, 9, 9, 10, 9}, []float64{9, 9}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := passedValues(func(h EventHandler) EventHandler {
+				th, err := WithThresholdFlags(h, tc.direction, tc.trigger, tc.reset, tc.flags)
+				if err != nil {
+					t.Fatalf("can't make threshold: %s", err)
+				}
+				return th
+			}, tc.vals...)
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("passed %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestWithThresholdFlagsInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		direction Direction
+		trigger float64
+		reset float64
+		flags ThresholdFlags
+	}{
+		{"increasing, reset above", DirectionIncreasing, 10, 15, 0},
+		{"decreasing, reset below", DirectionDecreasing, 10, 5, 0},
+		{"equal and inclusive", DirectionIncreasing, 10, 10, 0},
+		{"equal and inclusive, decreasing", DirectionDecreasing, 10, 10, 0},
+		{"no direction", "", 10, 5, 0},
+		{"steady", DirectionSteady, 10, 5, 0},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			h, err := WithThresholdFlags(RecordingHandler(), tc.direction, tc.trigger, tc.reset, tc.flags)
+			if !errors.Is(err, ErrInvalidThreshold) {
+				t.Errorf("error = %v, want ErrInvalidThreshold", err)
+			}
+			if h != nil {
+				t.Errorf("got a handler %v with the error", h)
+			}
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package events
+
+import (
+	"strings"
+	"time"
+)
+
+type BubbleOrder int
+
+const (
+	BubbleTargetFirst = BubbleOrder(iota)
+	BubbleRootFirst
+)
+
+// NewHierarchicalEventSink returns a sink where event types form a tree
+// whose levels are joined by sep. Firing an event also dispatches it, with
+// its full type preserved, to listeners on each ancestor of its type: an
+// "a.b.c" event reaches listeners on "a.b.c", "a.b" and "a". Listeners are
+// called from the event's own type up to the root unless the
+// SinkBubbleOrder(BubbleRootFirst) option is given.
+func NewHierarchicalEventSink(sep string, logTTL time.Duration, opts ...SinkOption) EventSink {
+	opts = append([]SinkOption{SinkHierarchy(sep)}, opts...)
+	return NewEventSink(logTTL, opts...)
+}
+
+// SinkHierarchy makes a sink bubble events up a hierarchy of event types
+// separated by sep. See NewHierarchicalEventSink.
+func SinkHierarchy(sep string) SinkOption {
+	return func(es *basicEventSink) {
+		es.separator = sep
+	}
+}
+
+// SinkBubbleOrder sets the order in which the ancestors of an event type
+// are dispatched to by a hierarchical sink.
+func SinkBubbleOrder(order BubbleOrder) SinkOption {
+	return func(es *basicEventSink) {
+		es.bubbleOrder = order
+	}
+}
+
+// ancestorTypes returns eventType and its ancestors, in bubbling order.
+func (es *basicEventSink) ancestorTypes(eventType string) []string {
+	if es.separator == "" {
+		return []string{eventType}
+	}
+	parts := strings.Split(eventType, es.separator)
+	paths := make([]string, len(parts))
+	for i := range parts {
+		path := strings.Join(parts[:len(parts)-i], es.separator)
+		if es.bubbleOrder == BubbleRootFirst {
+			paths[len(parts)-1-i] = path
+		} else {
+			paths[i] = path
+		}
+	}
+	return paths
+}
+
+// dispatchTypes returns every event type whose listeners should receive an
+// event of the given type, taking the hierarchy and aliases into account.
+// The caller must hold the mutex.
+func (es *basicEventSink) dispatchTypes(eventType string) []string {
+	paths := es.ancestorTypes(eventType)
+	if len(paths) == 1 {
+		return es.aliasedTypes(eventType)
+	}
+	types := make([]string, 0, len(paths))
+	seen := map[string]bool{}
+	for _, path := range paths {
+		for _, t := range es.aliasedTypes(path) {
+			if !seen[t] {
+				seen[t] = true
+				types = append(types, t)
+			}
+		}
+	}
+	return types
+}
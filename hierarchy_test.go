@@ -0,0 +1,44 @@
+package events
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestHierarchicalEventSink(t *testing.T) {
+	tests := []struct {
+		name string
+		order BubbleOrder
+		fire string
+		want []string
+	}{
+		{"leaf target first", BubbleTargetFirst, "a.b.c", []string{"a.b.c", "a.b", "a"}},
+		{"leaf root first", BubbleRootFirst, "a.b.c", []string{"a", "a.b", "a.b.c"}},
+		{"middle target first", BubbleTargetFirst, "a.b", []string{"a.b", "a"}},
+		{"middle root first", BubbleRootFirst, "a.b", []string{"a", "a.b"}},
+		{"root", BubbleTargetFirst, "a", []string{"a"}},
+		{"unlistened leaf", BubbleTargetFirst, "a.b.d", []string{"a.b", "a"}},
+		{"other tree", BubbleRootFirst, "x.b.c", []string{}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			sink := NewHierarchicalEventSink(".", time.Minute, SinkSync(), SinkBubbleOrder(tc.order))
+			got := []string{}
+			for _, eventType := range []string{"a", "a.b", "a.b.c"} {
+				listener := eventType
+				sink.AddEventListener(eventType, NewEventHandler(func(ev Event) error {
+					if ev.GetType() != tc.fire {
+						t.Errorf("listener on %q got a %q event, want %q", listener, ev.GetType(), tc.fire)
+					}
+					got = append(got, listener)
+					return nil
+				}))
+			}
+			sink.Emit(tc.fire, 1.0)
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("listeners called %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package events
+
+import (
+	"math"
+	"sort"
+	"sync"
+)
+
+// A HistogramHandler counts the values of the value events it handles
+// into buckets.
+type HistogramHandler interface {
+	EventHandler
+	// Snapshot returns the count in each bucket, keyed by the bucket's
+	// upper bound, with values above every bound counted under +Inf.
+	Snapshot() map[float64]int
+}
+
+type histogramHandler struct {
+	EventHandler
+	bounds []float64
+	counts []int
+	mutex *sync.Mutex
+}
+
+// NewHistogramHandler returns a handler that counts each value event into
+// the first bucket whose upper bound is at least its value. Bounds may be
+// given in any order, and repeated bounds are merged; an extra +Inf bucket
+// catches values above them all. Events other than value events, and NaN
+// values, are ignored.
+func NewHistogramHandler(buckets []float64) HistogramHandler {
+	bounds := []float64{}
+	for _, b := range buckets {
+		if !math.IsNaN(b) && !math.IsInf(b, 1) {
+			bounds = append(bounds, b)
+		}
+	}
+	sort.Float64s(bounds)
+	uniq := bounds[:0]
+	for _, b := range bounds {
+		if len(uniq) == 0 || b != uniq[len(uniq)-1] {
+			uniq = append(uniq, b)
+		}
+	}
+	bounds = append(uniq, math.Inf(1))
+	h := &histogramHandler{
+		bounds: bounds,
+		counts: make([]int, len(bounds)),
+		mutex: &sync.Mutex{},
+	}
+	h.EventHandler = NewEventHandler(h.observe)
+	return h
+}
+
+func (h *histogramHandler) observe(ev Event) error {
+	valEv, ok := ev.(ValueEvent)
+	if !ok {
+		return ErrIgnored
+	}
+	val := valEv.GetValue()
+	if math.IsNaN(val) {
+		return ErrIgnored
+	}
+	i := sort.SearchFloat64s(h.bounds, val)
+	h.mutex.Lock()
+	h.counts[i] += 1
+	h.mutex.Unlock()
+	return nil
+}
+
+func (h *histogramHandler) Snapshot() map[float64]int {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	out := make(map[float64]int, len(h.bounds))
+	for i, b := range h.bounds {
+		out[b] = h.counts[i]
+	}
+	return out
+}
@@ -0,0 +1,37 @@
+package events
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestHistogramHandler(t *testing.T) {
+	inf := math.Inf(1)
+	tests := []struct {
+		name string
+		buckets []float64
+		data []interface{}
+		want map[float64]int
+	}{
+		{"empty", []float64{1, 5}, nil, map[float64]int{1: 0, 5: 0, inf: 0}},
+		{"no buckets", nil, []interface{}{1.0, -3.0}, map[float64]int{inf: 2}},
+		{"bucketed", []float64{1, 5, 10}, []interface{}{0.5, 1.0, 2.0, 5.0, 7.0, 10.0, 11.0, 100.0}, map[float64]int{1: 2, 5: 2, 10: 2, inf: 2}},
+		{"unsorted buckets", []float64{10, 1, 5}, []interface{}{0.5, 7.0, 50.0}, map[float64]int{1: 1, 5: 0, 10: 1, inf: 1}},
+		{"repeated buckets", []float64{1, 5, 1}, []interface{}{0.5, 1.0, 3.0}, map[float64]int{1: 2, 5: 1, inf: 0}},
+		{"negative", []float64{-10, 0}, []interface{}{-20.0, -10.0, -5.0, 0.0, 5.0}, map[float64]int{-10: 2, 0: 2, inf: 1}},
+		{"infinite values", []float64{0, inf}, []interface{}{math.Inf(-1), inf}, map[float64]int{0: 1, inf: 1}},
+		{"ignored", []float64{1}, []interface{}{"offline", math.NaN(), map[string]interface{}{"a": "b"}, 0.0}, map[float64]int{1: 1, inf: 0}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			h := NewHistogramHandler(tc.buckets)
+			for _, data := range tc.data {
+				h.Call(NewEvent("test", data))
+			}
+			if got := h.Snapshot(); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("snapshot = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
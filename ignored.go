@@ -0,0 +1,82 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// EventTypeHandlerIgnored events are fired by a sink in debug mode (see
+// SinkDebug) when a listener ignores an event, carrying a ListenerMeta
+// with the reason in its Error field.
+const EventTypeHandlerIgnored = "listener-ignored"
+
+type ignoredError struct {
+	reason string
+}
+
+func (e *ignoredError) Error() string {
+	return ErrIgnored.Error() + ": " + e.reason
+}
+
+func (e *ignoredError) Unwrap() error {
+	return ErrIgnored
+}
+
+// ErrIgnoredReason returns an error explaining why an event was ignored,
+// for which errors.Is(err, ErrIgnored) holds.
+func ErrIgnoredReason(reason string) error {
+	return &ignoredError{reason}
+}
+
+// IgnoredReason returns the reason err gives for ignoring an event, or ""
+// if it gives none.
+func IgnoredReason(err error) string {
+	var ie *ignoredError
+	if errors.As(err, &ie) {
+		return ie.reason
+	}
+	return ""
+}
+
+type debugKey struct{}
+
+// SinkDebug puts a sink in debug mode, in which the decorators in this
+// package explain why they ignore an event, such as "range: value 5
+// outside [10, 20]", and the sink fires an EventTypeHandlerIgnored event
+// for each explained ignore. Building the explanations costs time, so
+// debug mode is meant for tuning, not production.
+func SinkDebug() SinkOption {
+	return func(es *basicEventSink) {
+		es.debug = true
+	}
+}
+
+func withDebug(ctx context.Context) context.Context {
+	return context.WithValue(ctx, debugKey{}, true)
+}
+
+// ignored returns ErrIgnored, with the formatted reason if ctx is from a
+// sink in debug mode.
+func ignored(ctx context.Context, format string, args ...interface{}) error {
+	if debug, _ := ctx.Value(debugKey{}).(bool); debug {
+		return ErrIgnoredReason(fmt.Sprintf(format, args...))
+	}
+	return ErrIgnored
+}
+
+func (es *basicEventSink) reportIgnored(eventType string, h EventHandler, reason string) {
+	es.mutex.Lock()
+	depth, ok := es.metaDepth()
+	es.mutex.Unlock()
+	if !ok {
+		return
+	}
+	data := &ListenerMeta{
+		EventType: eventType,
+		HandlerID: h.ID(),
+		Error: reason,
+		Depth: depth,
+	}
+	es.Emit(EventTypeHandlerIgnored, data)
+}
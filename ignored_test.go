@@ -0,0 +1,124 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestErrIgnoredReason(t *testing.T) {
+	tests := []struct {
+		name string
+		err error
+		wantText string
+		wantReason string
+	}{
+		{"reason", ErrIgnoredReason("range: value 5 outside [10, 20]"), ErrIgnored.Error() + ": range: value 5 outside [10, 20]", "range: value 5 outside [10, 20]"},
+		{"wrapped", fmt.Errorf("listener 3: %w", ErrIgnoredReason("sampling")), "listener 3: " + ErrIgnored.Error() + ": sampling", "sampling"},
+		{"plain", ErrIgnored, ErrIgnored.Error(), ""},
+		{"empty reason", ErrIgnoredReason(""), ErrIgnored.Error() + ": ", ""},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if !errors.Is(tc.err, ErrIgnored) {
+				t.Errorf("%v isn't ErrIgnored", tc.err)
+			}
+			if errors.Is(tc.err, ErrExpired) {
+				t.Errorf("%v is ErrExpired", tc.err)
+			}
+			if text := tc.err.Error(); text != tc.wantText {
+				t.Errorf("error = %q, want %q", text, tc.wantText)
+			}
+			if reason := IgnoredReason(tc.err); reason != tc.wantReason {
+				t.Errorf("reason = %q, want %q", reason, tc.wantReason)
+			}
+		})
+	}
+	if reason := IgnoredReason(errBoom); reason != "" {
+		t.Errorf("reason for a failure = %q", reason)
+	}
+}
+
+func TestSinkDebug(t *testing.T) {
+	tests := []struct {
+		name string
+		wrap func(EventHandler) EventHandler
+		vals []float64
+		want []string
+	}{
+		{"passed", func(h EventHandler) EventHandler { return WithRange(h, 10, 20) }, []float64{15}, []string{}},
+		{"range", func(h EventHandler) EventHandler { return WithRange(h, 10, 20) }, []float64{5, 15}, []string{"range: value 5 outside [10, 20]"}},
+		{"range NaN", func(h EventHandler) EventHandler { return WithRange(h, 10, 20) }, []float64{math.NaN()}, []string{"range: value is NaN"}},
+		{
+			"threshold",
+			func(h EventHandler) EventHandler { return WithThreshold(h, DirectionIncreasing, 10, 5) },
+			[]float64{1, 12, 11},
+			[]string{"threshold: value 1 doesn't reach 10", "threshold: value 11, waiting for reset at 5"},
+		},
+		{"sampling", func(h EventHandler) EventHandler { return WithSampling(h, 2) }, []float64{1, 2}, []string{"sampling: passing 1 in 2"}},
+		{
+			"outermost decorator explains",
+			func(h EventHandler) EventHandler { return WithRange(WithThreshold(h, DirectionIncreasing, 15, 12), 10, 20) },
+			[]float64{25, 11},
+			[]string{"range: value 25 outside [10, 20]", "threshold: value 11 doesn't reach 15"},
+		},
+	}
+	for _, tc := range tests {
+		for _, debug := range []bool{false, true} {
+			t.Run(fmt.Sprintf("%s/debug %t", tc.name, debug), func(t *testing.T) {
+				opts := []SinkOption{}
+				if debug {
+					opts = append(opts, SinkDebug())
+				}
+				sink := NewSyncEventSink(time.Hour, opts...)
+				h := tc.wrap(RecordingHandler())
+				reasons := []string{}
+				sink.AddEventListener(EventTypeHandlerIgnored, NewEventHandler(func(ev Event) error {
+					meta := ev.GetData().(*ListenerMeta)
+					if meta.EventType != "test" || meta.HandlerID != h.ID() {
+						t.Errorf("ignored event for %s listener %d, want test listener %d", meta.EventType, meta.HandlerID, h.ID())
+					}
+					reasons = append(reasons, meta.Error)
+					return nil
+				}))
+				sink.AddEventListener("test", h)
+				for _, val := range tc.vals {
+					sink.Emit("test", val)
+				}
+				want := tc.want
+				if !debug {
+					want = []string{}
+				}
+				if !reflect.DeepEqual(reasons, want) {
+					t.Errorf("reasons = %q, want %q", reasons, want)
+				}
+			})
+		}
+	}
+}
+
+func TestIgnoredInDebugContext(t *testing.T) {
+	tests := []struct {
+		name string
+		ctx context.Context
+		wantReason string
+	}{
+		{"plain", context.Background(), ""},
+		{"debug", withDebug(context.Background()), "range: value 5 outside [10, 20]"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := callContext(tc.ctx, WithRange(RecordingHandler(), 10, 20), NewEvent("test", 5.0))
+			if !errors.Is(err, ErrIgnored) {
+				t.Fatalf("error = %v, want ErrIgnored", err)
+			}
+			if reason := IgnoredReason(err); reason != tc.wantReason {
+				t.Errorf("reason = %q, want %q", reason, tc.wantReason)
+			}
+		})
+	}
+}
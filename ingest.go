@@ -0,0 +1,80 @@
+package events
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+)
+
+const maxIngestBody = 10 << 20
+
+// IngestHandler returns an http.Handler that fires POSTed events into sink.
+// The body is a JSON event, as decoded by UnmarshalEvent, or an array of
+// them. Events without a type get their type from the "type" query
+// parameter or, failing that, from the request path with slashes trimmed,
+// so the handler can be mounted under http.StripPrefix. It responds 202
+// once the events are fired, 400 if the body can't be decoded or an event
+// has no type, or 413 if the body is over 10MB.
+func IngestHandler(sink EventSink) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxIngestBody))
+		if err != nil {
+			status := http.StatusBadRequest
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				status = http.StatusRequestEntityTooLarge
+			}
+			http.Error(w, err.Error(), status)
+			return
+		}
+		defaultType := r.URL.Query().Get("type")
+		if defaultType == "" {
+			defaultType = strings.Trim(r.URL.Path, "/")
+		}
+		evs, err := decodeEvents(body, defaultType)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		fireMany(sink, evs)
+		w.WriteHeader(http.StatusAccepted)
+	})
+}
+
+func decodeEvents(body []byte, defaultType string) ([]Event, error) {
+	var items []json.RawMessage
+	body = bytes.TrimSpace(body)
+	if len(body) > 0 && body[0] == '[' {
+		err := json.Unmarshal(body, &items)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		items = []json.RawMessage{body}
+	}
+	evs := make([]Event, len(items))
+	for i, item := range items {
+		ev, err := UnmarshalEvent(item)
+		if err != nil {
+			return nil, err
+		}
+		if ev.GetType() == "" {
+			if defaultType == "" {
+				return nil, errors.New("missing event type")
+			}
+			ev = ev.As(defaultType)
+		}
+		// sequence numbers are the sink's to assign
+		setSeq(ev, 0)
+		evs[i] = ev
+	}
+	return evs, nil
+}
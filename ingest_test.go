@@ -0,0 +1,74 @@
+package events
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIngestHandler(t *testing.T) {
+	nested, err := json.Marshal(NewEvent("temp", 21.5))
+	if err != nil {
+		t.Fatalf("can't marshal event: %s", err)
+	}
+	type fired struct {
+		eventType string
+		data interface{}
+	}
+	tests := []struct {
+		name string
+		method string
+		target string
+		body string
+		wantStatus int
+		want []fired
+	}{
+		{"flat value", http.MethodPost, "/", `{"type": "temp", "value": 21.5}`, http.StatusAccepted, []fired{{"temp", 21.5}}},
+		{"flat message", http.MethodPost, "/", `{"type": "door", "message": "open"}`, http.StatusAccepted, []fired{{"door", "open"}}},
+		{"nested value", http.MethodPost, "/", string(nested), http.StatusAccepted, []fired{{"temp", 21.5}}},
+		{"array", http.MethodPost, "/", `[{"type": "temp", "value": 1}, {"type": "door", "message": "shut"}]`, http.StatusAccepted, []fired{{"temp", 1.0}, {"door", "shut"}}},
+		{"type from path", http.MethodPost, "/temp/", `{"value": 3}`, http.StatusAccepted, []fired{{"temp", 3.0}}},
+		{"type from query", http.MethodPost, "/ignored?type=door", `{"message": "open"}`, http.StatusAccepted, []fired{{"door", "open"}}},
+		{"body type wins", http.MethodPost, "/?type=door", `{"type": "temp", "value": 3}`, http.StatusAccepted, []fired{{"temp", 3.0}}},
+		{"array with path type", http.MethodPost, "/temp", `[{"value": 1}, {"value": 2}]`, http.StatusAccepted, []fired{{"temp", 1.0}, {"temp", 2.0}}},
+		{"missing type", http.MethodPost, "/", `{"value": 3}`, http.StatusBadRequest, nil},
+		{"bad json", http.MethodPost, "/", `{"type": `, http.StatusBadRequest, nil},
+		{"bad array", http.MethodPost, "/", `[{"type": "temp", "value": 1}, 7]`, http.StatusBadRequest, nil},
+		{"get", http.MethodGet, "/", "", http.StatusMethodNotAllowed, nil},
+		{"under the size limit", http.MethodPost, "/", strings.Repeat(" ", maxIngestBody - 32) + `{"type": "temp", "value": 21.5}`, http.StatusAccepted, []fired{{"temp", 21.5}}},
+		{"too large", http.MethodPost, "/", strings.Repeat(" ", maxIngestBody + 1), http.StatusRequestEntityTooLarge, nil},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			sink := NewSyncEventSink(time.Minute)
+			got := []fired{}
+			rec := NewEventHandler(func(ev Event) error {
+				switch tev := ev.(type) {
+				case ValueEvent:
+					got = append(got, fired{ev.GetType(), tev.GetValue()})
+				case MessageEvent:
+					got = append(got, fired{ev.GetType(), tev.GetMessage()})
+				}
+				return nil
+			})
+			sink.AddEventListener("temp", rec)
+			sink.AddEventListener("door", rec)
+			w := httptest.NewRecorder()
+			IngestHandler(sink).ServeHTTP(w, httptest.NewRequest(tc.method, tc.target, strings.NewReader(tc.body)))
+			if w.Code != tc.wantStatus {
+				t.Errorf("status = %d, want %d: %s", w.Code, tc.wantStatus, w.Body)
+			}
+			want := tc.want
+			if want == nil {
+				want = []fired{}
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("fired %v, want %v", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package events
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type eventJSON struct {
+	Type string `json:"type"`
+	Time time.Time `json:"time"`
+	Data interface{} `json:"data"`
+	CorrelationID string `json:"correlation_id"`
+	Seq uint64 `json:"seq"`
+	ExpiresAt *time.Time `json:"expires_at"`
+	Priority int `json:"priority"`
+	Labels map[string]string `json:"labels"`
+	Version int `json:"version"`
+	Value *float64 `json:"value"`
+	Message *string `json:"message"`
+	Bytes []byte `json:"bytes"`
+	Event *eventJSON `json:"Event"`
+}
+
+// base returns the fields of the innermost event. Value, message and
+// binary events marshal with the event they wrap nested under "Event",
+// while other producers may send one flat object.
+func (raw *eventJSON) base() *eventJSON {
+	for raw.Event != nil {
+		raw = raw.Event
+	}
+	return raw
+}
+
+// UnmarshalEvent decodes an event marshaled to JSON, either as this
+// package marshals it, with a value, message or binary event's fields
+// nested under "Event", or as a single flat object. An event with a value,
+// message or bytes field decodes to the corresponding kind of event;
+// otherwise its kind is derived from its data, as by NewEvent. A missing
+// time is taken to be now.
+func UnmarshalEvent(data []byte) (Event, error) {
+	raw := &eventJSON{}
+	err := json.Unmarshal(data, raw)
+	if err != nil {
+		return nil, err
+	}
+	inner := raw.base()
+	if inner.Time.IsZero() {
+		inner.Time = now().Round(0).In(time.UTC)
+	}
+	base := &basicEvent{
+		Type: inner.Type,
+		Time: inner.Time,
+		Data: inner.Data,
+		CorrelationID: inner.CorrelationID,
+		Seq: inner.Seq,
+		ExpiresAt: inner.ExpiresAt,
+		Priority: inner.Priority,
+		Labels: inner.Labels,
+		Version: inner.Version,
+	}
+	switch {
+	case raw.Value != nil:
+		return &valueEvent{base, *raw.Value}, nil
+	case raw.Message != nil:
+		return &messageEvent{base, *raw.Message}, nil
+	case raw.Bytes != nil:
+		return &binaryEvent{base, raw.Bytes}, nil
+	}
+	base.Data = nil
+	return newEvent(base, inner.Data), nil
+}
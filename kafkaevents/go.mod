@@ -0,0 +1,17 @@
+module github.com/rclancey/events/kafkaevents
+
+go 1.18
+
+require (
+	github.com/rclancey/events v0.0.2
+	github.com/segmentio/kafka-go v0.4.47
+)
+
+require (
+	github.com/klauspost/compress v1.15.9 // indirect
+	github.com/pierrec/lz4/v4 v4.1.15 // indirect
+	github.com/rclancey/encoding-form v0.0.1 // indirect
+	github.com/rclancey/generic v0.0.2 // indirect
+)
+
+replace github.com/rclancey/events => ../
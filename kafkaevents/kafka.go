@@ -0,0 +1,47 @@
+package kafkaevents
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/rclancey/events"
+	"github.com/segmentio/kafka-go"
+)
+
+// Writer is the part of *kafka.Writer used to produce events, so that it
+// can be replaced in tests.
+type Writer interface {
+	WriteMessages(ctx context.Context, msgs ...kafka.Message) error
+}
+
+var _ Writer = (*kafka.Writer)(nil)
+
+// NewKafkaHandler returns a handler that writes each event as a Kafka
+// message keyed by the event type. Events are JSON encoded, except for
+// binary events, whose bytes are written as-is. If topicFn is nil, messages
+// go to the writer's own topic; otherwise the writer must not have a topic
+// set. Batching is left to the writer's configuration, and produce errors
+// are returned from Call.
+func NewKafkaHandler(writer Writer, topicFn func(events.Event) string) events.ContextHandler {
+	return events.NewContextEventHandler(func(ctx context.Context, ev events.Event) error {
+		var value []byte
+		if bev, ok := ev.(events.BinaryEvent); ok {
+			value = bev.GetBytes()
+		} else {
+			var err error
+			value, err = json.Marshal(ev)
+			if err != nil {
+				return err
+			}
+		}
+		msg := kafka.Message{
+			Key: []byte(ev.GetType()),
+			Value: value,
+			Time: ev.GetTime(),
+		}
+		if topicFn != nil {
+			msg.Topic = topicFn(ev)
+		}
+		return writer.WriteMessages(ctx, msg)
+	})
+}
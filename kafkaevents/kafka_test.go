@@ -0,0 +1,106 @@
+package kafkaevents
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/rclancey/events"
+	"github.com/segmentio/kafka-go"
+)
+
+type ctxKey struct{}
+
+type mockWriter struct {
+	msgs []kafka.Message
+	ctxs []context.Context
+	err error
+}
+
+func (w *mockWriter) WriteMessages(ctx context.Context, msgs ...kafka.Message) error {
+	if w.err != nil {
+		return w.err
+	}
+	w.msgs = append(w.msgs, msgs...)
+	w.ctxs = append(w.ctxs, ctx)
+	return nil
+}
+
+func TestKafkaHandler(t *testing.T) {
+	errProduce := errors.New("leader not available")
+	tests := []struct {
+		name string
+		topicFn func(events.Event) string
+		ev events.Event
+		writeErr error
+		wantTopic string
+		wantValue []byte
+	}{
+		{"value", nil, events.NewEvent("temperature", 21.5), nil, "", nil},
+		{"topic func", func(ev events.Event) string { return "sensors-" + ev.GetType() }, events.NewEvent("temperature", 21.5), nil, "sensors-temperature", nil},
+		{"binary", nil, events.NewEvent("blob", []byte{0, 1, 2}), nil, "", []byte{0, 1, 2}},
+		{"produce error", nil, events.NewEvent("temperature", 21.5), errProduce, "", nil},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			w := &mockWriter{err: tc.writeErr}
+			ctx := context.WithValue(context.Background(), ctxKey{}, tc.name)
+			err := NewKafkaHandler(w, tc.topicFn).CallContext(ctx, tc.ev)
+			if !errors.Is(err, tc.writeErr) {
+				t.Fatalf("call returned %v, want %v", err, tc.writeErr)
+			}
+			if tc.writeErr != nil {
+				return
+			}
+			if len(w.msgs) != 1 {
+				t.Fatalf("wrote %d messages, want 1", len(w.msgs))
+			}
+			if w.ctxs[0].Value(ctxKey{}) != tc.name {
+				t.Error("writer didn't get the call's context")
+			}
+			msg := w.msgs[0]
+			if string(msg.Key) != tc.ev.GetType() {
+				t.Errorf("key = %q, want %q", msg.Key, tc.ev.GetType())
+			}
+			if msg.Topic != tc.wantTopic {
+				t.Errorf("topic = %q, want %q", msg.Topic, tc.wantTopic)
+			}
+			if !msg.Time.Equal(tc.ev.GetTime()) {
+				t.Errorf("time = %s, want %s", msg.Time, tc.ev.GetTime())
+			}
+			if tc.wantValue != nil {
+				if !bytes.Equal(msg.Value, tc.wantValue) {
+					t.Errorf("value = %v, want %v", msg.Value, tc.wantValue)
+				}
+				return
+			}
+			ev, err := events.UnmarshalEvent(msg.Value)
+			if err != nil {
+				t.Fatalf("can't decode %s: %s", msg.Value, err)
+			}
+			if ev.GetType() != tc.ev.GetType() || ev.(events.Valuer).GetValue() != tc.ev.(events.Valuer).GetValue() {
+				t.Errorf("wrote %s, want %v", msg.Value, tc.ev)
+			}
+		})
+	}
+}
+
+func TestKafkaHandlerOnSink(t *testing.T) {
+	w := &mockWriter{}
+	sink := events.NewSyncEventSink(0)
+	sink.AddEventListener("temperature", NewKafkaHandler(w, nil))
+	sink.(events.BatchSink).EmitMany("temperature", []interface{}{1.0, 2.0, 3.0})
+	if len(w.msgs) != 3 {
+		t.Fatalf("wrote %d messages, want 3", len(w.msgs))
+	}
+	for i, msg := range w.msgs {
+		ev, err := events.UnmarshalEvent(msg.Value)
+		if err != nil {
+			t.Fatalf("can't decode %s: %s", msg.Value, err)
+		}
+		if v := ev.(events.Valuer).GetValue(); v != float64(i + 1) {
+			t.Errorf("message %d has value %g", i, v)
+		}
+	}
+}
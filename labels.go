@@ -0,0 +1,45 @@
+package events
+
+// AddEventListenerWithLabels adds a listener that is only called for
+// events carrying every label in selector with the same value. Events may
+// carry other labels too. An empty selector matches every event.
+func (es *basicEventSink) AddEventListenerWithLabels(eventType string, selector map[string]string, handler EventHandler) {
+	if handler == nil {
+		return
+	}
+	selector = copyLabels(selector)
+	es.AddEventListenerIf(eventType, func(ev Event) bool { return MatchLabels(ev, selector) }, handler)
+}
+
+func (es *PrefixedEventSource) AddEventListenerWithLabels(eventType string, selector map[string]string, handler EventHandler) {
+	addEventListenerWithLabels(es.EventSink, es.prefix+eventType, selector, handler)
+}
+
+func (es *ScopedEventSink) AddEventListenerWithLabels(eventType string, selector map[string]string, handler EventHandler) {
+	if es.track(eventType, handler) {
+		addEventListenerWithLabels(es.EventSink, eventType, selector, handler)
+	}
+}
+
+// MatchLabels reports whether ev carries every label in selector with the
+// same value.
+func MatchLabels(ev Event, selector map[string]string) bool {
+	labels := Labels(ev)
+	for k, v := range selector {
+		if lv, ok := labels[k]; !ok || lv != v {
+			return false
+		}
+	}
+	return true
+}
+
+func copyLabels(labels map[string]string) map[string]string {
+	if len(labels) == 0 {
+		return nil
+	}
+	copied := make(map[string]string, len(labels))
+	for k, v := range labels {
+		copied[k] = v
+	}
+	return copied
+}
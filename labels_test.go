@@ -0,0 +1,78 @@
+package events
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestMatchLabels(t *testing.T) {
+	kitchen := map[string]string{"room": "kitchen", "floor": "1"}
+	tests := []struct {
+		name string
+		labels map[string]string
+		selector map[string]string
+		want bool
+	}{
+		{"empty selector", kitchen, nil, true},
+		{"empty selector, no labels", nil, map[string]string{}, true},
+		{"match", kitchen, map[string]string{"room": "kitchen"}, true},
+		{"match all", kitchen, map[string]string{"room": "kitchen", "floor": "1"}, true},
+		{"wrong value", kitchen, map[string]string{"room": "garage"}, false},
+		{"missing label", kitchen, map[string]string{"sensor": "temp"}, false},
+		{"one of two wrong", kitchen, map[string]string{"room": "kitchen", "floor": "2"}, false},
+		{"no labels", nil, map[string]string{"room": "kitchen"}, false},
+		{"empty value", map[string]string{"room": ""}, map[string]string{"room": ""}, true},
+		{"empty value, missing label", nil, map[string]string{"room": ""}, false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ev := NewEvent("temp", 1.0, EventLabels(tc.labels))
+			if got := MatchLabels(ev, tc.selector); got != tc.want {
+				t.Errorf("MatchLabels = %t, want %t", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestAddEventListenerWithLabels(t *testing.T) {
+	labelSets := []map[string]string{
+		{"room": "kitchen"},
+		{"room": "garage"},
+		nil,
+		{"room": "kitchen", "floor": "1"},
+	}
+	tests := []struct {
+		name string
+		view func(sink EventSink) EventSink
+		selector map[string]string
+		want []float64
+	}{
+		{"kitchen", nil, map[string]string{"room": "kitchen"}, []float64{0, 3}},
+		{"garage", nil, map[string]string{"room": "garage"}, []float64{1}},
+		{"everything", nil, nil, []float64{0, 1, 2, 3}},
+		{"nothing", nil, map[string]string{"room": "attic"}, []float64{}},
+		{"prefixed", func(sink EventSink) EventSink { return NewPrefixedEventSource("house", sink) }, map[string]string{"floor": "1"}, []float64{3}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var sink EventSink = NewSyncEventSink(time.Hour)
+			if tc.view != nil {
+				sink = tc.view(sink)
+			}
+			rec := RecordingHandler()
+			selector := copyLabels(tc.selector)
+			sink.(ListenerManager).AddEventListenerWithLabels("temp", selector, rec)
+			// the selector is copied when the listener is added
+			for k := range selector {
+				selector[k] = "changed"
+			}
+			for i, labels := range labelSets {
+				sink.Fire(NewEvent("temp", float64(i), EventLabels(labels)))
+			}
+			if got := logValues(rec.Calls()); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("called with %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
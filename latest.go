@@ -0,0 +1,53 @@
+package events
+
+import (
+	"strings"
+)
+
+// Latest returns the most recently fired event of the given type. Unlike
+// the log, it remembers the last event of each type however long ago it
+// was fired.
+func (es *basicEventSink) Latest(eventType string) (Event, bool) {
+	es.mutex.Lock()
+	defer es.mutex.Unlock()
+	ev, ok := es.latest[eventType]
+	return ev, ok
+}
+
+// LatestAll returns the most recently fired event of every type.
+func (es *basicEventSink) LatestAll() map[string]Event {
+	es.mutex.Lock()
+	defer es.mutex.Unlock()
+	out := make(map[string]Event, len(es.latest))
+	for eventType, ev := range es.latest {
+		out[eventType] = ev
+	}
+	return out
+}
+
+func (es *PrefixedEventSource) Latest(eventType string) (Event, bool) {
+	lr, ok := es.EventSink.(LatestReader)
+	if !ok {
+		return nil, false
+	}
+	ev, ok := lr.Latest(es.prefix+eventType)
+	if !ok {
+		return nil, false
+	}
+	return ev.As(eventType), true
+}
+
+func (es *PrefixedEventSource) LatestAll() map[string]Event {
+	out := map[string]Event{}
+	lr, ok := es.EventSink.(LatestReader)
+	if !ok {
+		return out
+	}
+	for eventType, ev := range lr.LatestAll() {
+		if strings.HasPrefix(eventType, es.prefix) {
+			eventType = strings.TrimPrefix(eventType, es.prefix)
+			out[eventType] = ev.As(eventType)
+		}
+	}
+	return out
+}
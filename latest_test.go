@@ -0,0 +1,102 @@
+package events
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestLatest(t *testing.T) {
+	type emit struct {
+		eventType string
+		val float64
+	}
+	tests := []struct {
+		name string
+		emits []emit
+		advance time.Duration
+		want map[string]float64
+	}{
+		{"empty", nil, 0, map[string]float64{}},
+		{"one", []emit{{"temp", 1}}, 0, map[string]float64{"temp": 1}},
+		{"most recent", []emit{{"temp", 1}, {"temp", 2}, {"temp", 3}}, 0, map[string]float64{"temp": 3}},
+		{"per type", []emit{{"temp", 1}, {"humidity", 40}, {"temp", 2}}, 0, map[string]float64{"temp": 2, "humidity": 40}},
+		{"outlives the log", []emit{{"temp", 1}, {"humidity", 40}}, 2 * time.Hour, map[string]float64{"temp": 1, "humidity": 40}},
+		{"rejected", []emit{{"temp", 1}, {"temp", -500}}, 0, map[string]float64{"temp": 1}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			c := useFakeClock(t)
+			sink := NewSyncEventSink(time.Hour)
+			sink.(TypeManager).RegisterEventTypeWithValidator(NewEvent("temp", 0.0), func(ev Event) error {
+				if ev.(Valuer).GetValue() < -273 {
+					return ErrIncompatibleEvent
+				}
+				return nil
+			})
+			for _, e := range tc.emits {
+				sink.Emit(e.eventType, e.val)
+			}
+			c.Advance(tc.advance)
+			// trims the log
+			sink.Emit("other", 0.0)
+			lr := sink.(LatestReader)
+			all := lr.LatestAll()
+			delete(all, "other")
+			delete(all, EventTypeValidationError)
+			got := map[string]float64{}
+			for eventType, ev := range all {
+				got[eventType] = ev.(Valuer).GetValue()
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("LatestAll = %v, want %v", got, tc.want)
+			}
+			for eventType, want := range tc.want {
+				ev, ok := lr.Latest(eventType)
+				if !ok || ev.(Valuer).GetValue() != want || ev.GetType() != eventType {
+					t.Errorf("Latest(%q) = %v, %t, want %g", eventType, ev, ok, want)
+				}
+			}
+			if ev, ok := lr.Latest("missing"); ok {
+				t.Errorf("Latest(missing) = %v", ev)
+			}
+		})
+	}
+}
+
+func TestPrefixedLatest(t *testing.T) {
+	sink := NewSyncEventSink(time.Hour)
+	kitchen := NewPrefixedEventSource("kitchen", sink)
+	garage := NewPrefixedEventSource("garage", sink)
+	kitchen.Emit("temp", 20.0)
+	garage.Emit("temp", 5.0)
+	kitchen.Emit("temp", 22.0)
+	sink.Emit("temp", 100.0)
+	tests := []struct {
+		name string
+		sink EventSink
+		want map[string]float64
+	}{
+		{"kitchen", kitchen, map[string]float64{"temp": 22}},
+		{"garage", garage, map[string]float64{"temp": 5}},
+		{"unprefixed", sink, map[string]float64{"temp": 100, "kitchen-temp": 22, "garage-temp": 5}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			lr := tc.sink.(LatestReader)
+			got := map[string]float64{}
+			for eventType, ev := range lr.LatestAll() {
+				if ev.GetType() != eventType {
+					t.Errorf("event under %q has type %q", eventType, ev.GetType())
+				}
+				got[eventType] = ev.(Valuer).GetValue()
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("LatestAll = %v, want %v", got, tc.want)
+			}
+			if ev, ok := lr.Latest("temp"); !ok || ev.(Valuer).GetValue() != tc.want["temp"] || ev.GetType() != "temp" {
+				t.Errorf("Latest(temp) = %v, %t, want %g", ev, ok, tc.want["temp"])
+			}
+		})
+	}
+}
@@ -0,0 +1,312 @@
+package events
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/rclancey/generic"
+)
+
+type eventLog interface {
+	Add(ev Event)
+	Trim(oldest time.Time)
+	Slice() []Event
+	Clear()
+}
+
+type listLog struct {
+	list *generic.LinkedList[Event]
+	mutex *sync.Mutex
+}
+
+func newListLog() *listLog {
+	return &listLog{
+		list: generic.NewLinkedList[Event](),
+		mutex: &sync.Mutex{},
+	}
+}
+
+func (l *listLog) Add(ev Event) {
+	l.mutex.Lock()
+	l.list.Unshift(ev)
+	l.mutex.Unlock()
+}
+
+func (l *listLog) Trim(oldest time.Time) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	stale := func(ev Event) bool { return ev.GetTime().Before(oldest) }
+	for l.list.Len() > 1 {
+		_, ok := l.list.PopIf(stale)
+		if !ok {
+			return
+		}
+	}
+	// LinkedList can't pop its only element, so start over instead
+	if l.list.Len() == 1 && stale(l.list.First()) {
+		l.list = generic.NewLinkedList[Event]()
+	}
+}
+
+func (l *listLog) Clear() {
+	l.mutex.Lock()
+	l.list = generic.NewLinkedList[Event]()
+	l.mutex.Unlock()
+}
+
+func (l *listLog) Slice() []Event {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return l.list.Slice()
+}
+
+// ringLog holds at most a fixed number of events, in arrival order. When
+// it is full, adding an event evicts the oldest of the lowest priority
+// events, counting the new one, so a new event of lower priority than
+// every held event is dropped at once.
+//
+// The events are held in a fixed array of nodes, linked in arrival order
+// and, within each priority, into a bucket in arrival order, so eviction
+// takes the head of the lowest priority bucket rather than scanning the
+// log. Every removal, whether by eviction or by Trim, is of the oldest
+// event of its priority, so only bucket heads are ever removed.
+type ringLog struct {
+	nodes []ringNode
+	free []int
+	oldest int
+	newest int
+	size int
+	buckets map[int]*ringBucket
+	priorities []int
+	mutex *sync.Mutex
+}
+
+// ringNode is a slot of a ringLog. prev and next link the events in
+// arrival order, and after links the next event of the same priority; -1
+// marks the end of a list.
+type ringNode struct {
+	ev Event
+	priority int
+	prev int
+	next int
+	after int
+}
+
+// ringBucket is the list of the events of one priority in a ringLog,
+// oldest first.
+type ringBucket struct {
+	head int
+	tail int
+}
+
+func newRingLog(capacity int) *ringLog {
+	l := &ringLog{
+		nodes: make([]ringNode, capacity),
+		free: make([]int, 0, capacity),
+		mutex: &sync.Mutex{},
+	}
+	l.reset()
+	return l
+}
+
+// reset empties the log. The caller must hold the mutex.
+func (l *ringLog) reset() {
+	for i := range l.nodes {
+		l.nodes[i] = ringNode{}
+	}
+	l.free = l.free[:0]
+	for i := len(l.nodes) - 1; i >= 0; i-- {
+		l.free = append(l.free, i)
+	}
+	l.oldest = -1
+	l.newest = -1
+	l.size = 0
+	l.buckets = map[int]*ringBucket{}
+	l.priorities = l.priorities[:0]
+}
+
+func (l *ringLog) Add(ev Event) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if len(l.nodes) == 0 {
+		return
+	}
+	p := Priority(ev)
+	if l.size == len(l.nodes) {
+		lowest := l.priorities[0]
+		if p < lowest {
+			return
+		}
+		l.remove(l.buckets[lowest].head)
+	}
+	i := l.free[len(l.free)-1]
+	l.free = l.free[:len(l.free)-1]
+	l.nodes[i] = ringNode{ev: ev, priority: p, prev: l.newest, next: -1, after: -1}
+	if l.newest >= 0 {
+		l.nodes[l.newest].next = i
+	} else {
+		l.oldest = i
+	}
+	l.newest = i
+	l.size += 1
+	b, ok := l.buckets[p]
+	if !ok {
+		l.buckets[p] = &ringBucket{i, i}
+		l.addPriority(p)
+		return
+	}
+	l.nodes[b.tail].after = i
+	b.tail = i
+}
+
+// addPriority adds p to the sorted list of priorities held. There are
+// rarely more than a few, so a linear insert is cheap. The caller must
+// hold the mutex.
+func (l *ringLog) addPriority(p int) {
+	k := sort.SearchInts(l.priorities, p)
+	l.priorities = append(l.priorities, 0)
+	copy(l.priorities[k+1:], l.priorities[k:])
+	l.priorities[k] = p
+}
+
+// remove removes node i, which must be the head of its priority's bucket.
+// The caller must hold the mutex.
+func (l *ringLog) remove(i int) {
+	n := l.nodes[i]
+	if n.prev >= 0 {
+		l.nodes[n.prev].next = n.next
+	} else {
+		l.oldest = n.next
+	}
+	if n.next >= 0 {
+		l.nodes[n.next].prev = n.prev
+	} else {
+		l.newest = n.prev
+	}
+	b := l.buckets[n.priority]
+	if n.after >= 0 {
+		b.head = n.after
+	} else {
+		delete(l.buckets, n.priority)
+		k := sort.SearchInts(l.priorities, n.priority)
+		l.priorities = append(l.priorities[:k], l.priorities[k+1:]...)
+	}
+	l.nodes[i] = ringNode{}
+	l.free = append(l.free, i)
+	l.size -= 1
+}
+
+func (l *ringLog) Trim(oldest time.Time) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	for l.size > 0 && l.nodes[l.oldest].ev.GetTime().Before(oldest) {
+		l.remove(l.oldest)
+	}
+}
+
+func (l *ringLog) Clear() {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.reset()
+}
+
+func (l *ringLog) Slice() []Event {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if l.size == 0 {
+		return nil
+	}
+	s := make([]Event, 0, l.size)
+	for i := l.newest; i >= 0; i = l.nodes[i].prev {
+		s = append(s, l.nodes[i].ev)
+	}
+	return s
+}
+
+// SinkTypeLogs makes a sink keep a separate log for each event type, in
+// addition to its main log, so that LogForType doesn't have to scan events
+// of other types. Each type's log holds events for the sink's log TTL, and
+// at most capacity events if capacity > 0.
+func SinkTypeLogs(capacity int) SinkOption {
+	return func(es *basicEventSink) {
+		es.typeLogs = map[string]eventLog{}
+		es.typeLogCapacity = capacity
+	}
+}
+
+func (es *basicEventSink) newTypeLog() eventLog {
+	if es.typeLogCapacity > 0 {
+		return newRingLog(es.typeLogCapacity)
+	}
+	return newListLog()
+}
+
+// typeLog returns the log for an event type, creating it if needed. The
+// caller must hold the mutex.
+func (es *basicEventSink) typeLog(eventType string) eventLog {
+	l, ok := es.typeLogs[eventType]
+	if !ok {
+		l = es.newTypeLog()
+		es.typeLogs[eventType] = l
+	}
+	return l
+}
+
+// LogForType returns the logged events of the given type, most recent
+// first.
+func (es *basicEventSink) LogForType(eventType string) []Event {
+	if es.typeLogs == nil {
+		return filterLog(es.Log(), eventType)
+	}
+	es.mutex.Lock()
+	l, ok := es.typeLogs[eventType]
+	tracked := es.tracksType(eventType)
+	es.mutex.Unlock()
+	if !ok {
+		if !tracked {
+			// untracked types have no log of their own
+			return filterLog(es.Log(), eventType)
+		}
+		return nil
+	}
+	l.Trim(es.now().Add(-es.logTTL))
+	return l.Slice()
+}
+
+func filterLog(log []Event, eventType string) []Event {
+	out := []Event{}
+	for _, ev := range log {
+		if ev.GetType() == eventType {
+			out = append(out, ev)
+		}
+	}
+	return out
+}
+
+func (es *PrefixedEventSource) LogForType(eventType string) []Event {
+	return es.Filter(logForType(es.EventSink, es.prefix+eventType))
+}
+
+// logForType returns the events of the given type in sink's log.
+func logForType(sink EventSink, eventType string) []Event {
+	if lr, ok := sink.(LogReader); ok {
+		return lr.LogForType(eventType)
+	}
+	return filterLog(sink.Log(), eventType)
+}
+
+// LogSortedByTime returns the events in the log sorted by event time, most
+// recent first, rather than in the order they were fired as Log does.
+// Events with equal times keep their order from Log.
+func (es *basicEventSink) LogSortedByTime() []Event {
+	evs := es.Log()
+	sortByTime(evs)
+	return evs
+}
+
+// sortByTime sorts evs by event time, most recent first, keeping the order
+// of events with equal times.
+func sortByTime(evs []Event) {
+	sort.SliceStable(evs, func(i, j int) bool { return evs[i].GetTime().After(evs[j].GetTime()) })
+}
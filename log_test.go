@@ -0,0 +1,380 @@
+package events
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// logValues returns the values of the value events in evs, in order.
+func logValues(evs []Event) []float64 {
+	out := []float64{}
+	for _, ev := range evs {
+		if vev, ok := ev.(Valuer); ok {
+			out = append(out, vev.GetValue())
+		}
+	}
+	return out
+}
+
+// useFakeClock replaces the package clock with a fake one for the rest of
+// the test.
+func useFakeClock(t *testing.T) *FakeClock {
+	c := NewFakeClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	SetClock(c)
+	t.Cleanup(func() { SetClock(nil) })
+	return c
+}
+
+func TestRingBufferEventSink(t *testing.T) {
+	tests := []struct {
+		name string
+		capacity int
+		vals []float64
+		want []float64
+	}{
+		{"empty", 3, nil, []float64{}},
+		{"under capacity", 3, []float64{1, 2}, []float64{2, 1}},
+		{"at capacity", 3, []float64{1, 2, 3}, []float64{3, 2, 1}},
+		{"wrapped", 3, []float64{1, 2, 3, 4, 5}, []float64{5, 4, 3}},
+		{"wrapped twice", 2, []float64{1, 2, 3, 4, 5, 6, 7}, []float64{7, 6}},
+		{"no capacity", 0, []float64{1, 2, 3, 4}, []float64{4, 3, 2, 1}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			sink := NewRingBufferEventSink(tc.capacity, time.Hour, SinkSync())
+			for _, val := range tc.vals {
+				sink.Emit("test", val)
+			}
+			if got := logValues(sink.Log()); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("log = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestRingLogTrim(t *testing.T) {
+	tests := []struct {
+		name string
+		capacity int
+		ages []time.Duration
+		want []float64
+	}{
+		{"all fresh", 3, []time.Duration{30 * time.Second, 20 * time.Second, 10 * time.Second}, []float64{2, 1, 0}},
+		{"oldest stale", 3, []time.Duration{2 * time.Minute, 20 * time.Second, 10 * time.Second}, []float64{2, 1}},
+		{"stale after wrapping", 2, []time.Duration{4 * time.Minute, 3 * time.Minute, 2 * time.Minute, 10 * time.Second}, []float64{3}},
+		{"all stale", 2, []time.Duration{3 * time.Minute, 2 * time.Minute}, []float64{}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			c := useFakeClock(t)
+			end := c.Now()
+			l := newRingLog(tc.capacity)
+			for i, age := range tc.ages {
+				c.Set(end.Add(-age))
+				l.Add(NewEvent("test", float64(i)))
+			}
+			l.Trim(end.Add(-time.Minute))
+			if got := logValues(l.Slice()); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("log = %v, want %v", got, tc.want)
+			}
+			// the trimmed slots are reused
+			l.Add(NewEvent("test", 9))
+			if got := logValues(l.Slice()); got[0] != 9 {
+				t.Errorf("log after adding = %v, want 9 first", got)
+			}
+		})
+	}
+}
+
+func TestRingLogPriority(t *testing.T) {
+	type add struct {
+		val float64
+		priority int
+	}
+	tests := []struct {
+		name string
+		capacity int
+		adds []add
+		want []float64
+	}{
+		{"routine", 2, []add{{1, 0}, {2, 0}, {3, 0}}, []float64{3, 2}},
+		{"old alert outlives routine", 2, []add{{1, 5}, {2, 0}, {3, 0}, {4, 0}}, []float64{4, 1}},
+		{"alerts outlive routine", 3, []add{{1, 5}, {2, 0}, {3, 5}, {4, 0}, {5, 0}, {6, 0}}, []float64{6, 3, 1}},
+		{"oldest alert evicted among alerts", 2, []add{{1, 5}, {2, 5}, {3, 5}}, []float64{3, 2}},
+		{"routine dropped when full of alerts", 2, []add{{1, 5}, {2, 5}, {3, 0}}, []float64{2, 1}},
+		{"higher priority wins", 2, []add{{1, 1}, {2, 9}, {3, 5}}, []float64{3, 2}},
+		{"negative priority evicted first", 2, []add{{1, 0}, {2, -1}, {3, 0}}, []float64{3, 1}},
+		{"under capacity", 3, []add{{1, 5}, {2, 0}}, []float64{2, 1}},
+		{"wrapped, then alert", 2, []add{{1, 0}, {2, 0}, {3, 0}, {4, 5}, {5, 0}, {6, 0}}, []float64{6, 4}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			sink := NewRingBufferEventSink(tc.capacity, time.Hour, SinkSync())
+			for _, a := range tc.adds {
+				sink.Fire(NewEvent("test", a.val, EventPriority(a.priority)))
+			}
+			if got := logValues(sink.Log()); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("log = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestRingLogPriorityTrim(t *testing.T) {
+	c := useFakeClock(t)
+	l := newRingLog(2)
+	l.Add(NewEvent("test", 1.0, EventPriority(5)))
+	c.Advance(2 * time.Minute)
+	l.Add(NewEvent("test", 2.0))
+	// an alert is trimmed when stale like any other event
+	l.Trim(c.Now().Add(-time.Minute))
+	l.Add(NewEvent("test", 3.0))
+	l.Add(NewEvent("test", 4.0))
+	if got := logValues(l.Slice()); !reflect.DeepEqual(got, []float64{4, 3}) {
+		t.Errorf("log = %v, want [4 3]", got)
+	}
+}
+
+// evictLowest is the eviction policy of ringLog done the slow way: it
+// adds ev to the full log evs, oldest first, by removing the oldest of its
+// lowest priority events, or drops ev if it has the lowest priority.
+func evictLowest(evs []Event, ev Event) []Event {
+	k := 0
+	for i := range evs {
+		if Priority(evs[i]) < Priority(evs[k]) {
+			k = i
+		}
+	}
+	if Priority(ev) < Priority(evs[k]) {
+		return evs
+	}
+	out := append(append([]Event{}, evs[:k]...), evs[k+1:]...)
+	return append(out, ev)
+}
+
+func TestRingLogMatchesScan(t *testing.T) {
+	tests := []struct {
+		name string
+		capacity int
+		priorities []int
+	}{
+		{"one priority", 4, []int{0}},
+		{"two priorities", 4, []int{0, 5}},
+		{"many priorities", 5, []int{-2, 0, 1, 3, 9}},
+		{"capacity one", 1, []int{0, 1}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			c := useFakeClock(t)
+			l := newRingLog(tc.capacity)
+			want := []Event{}
+			for i := 0; i < 200; i++ {
+				c.Advance(time.Second)
+				// a fixed but irregular sequence of priorities
+				p := tc.priorities[(i * 7 + i / 3) % len(tc.priorities)]
+				ev := NewEvent("test", float64(i), EventPriority(p))
+				l.Add(ev)
+				if len(want) < tc.capacity {
+					want = append(want, ev)
+				} else {
+					want = evictLowest(want, ev)
+				}
+				if i % 50 == 49 {
+					// trim the oldest few
+					oldest := c.Now().Add(-3 * time.Second)
+					l.Trim(oldest)
+					for len(want) > 0 && want[0].GetTime().Before(oldest) {
+						want = want[1:]
+					}
+				}
+				got := logValues(l.Slice())
+				exp := []float64{}
+				for j := len(want) - 1; j >= 0; j-- {
+					exp = append(exp, want[j].(Valuer).GetValue())
+				}
+				if !reflect.DeepEqual(got, exp) {
+					t.Fatalf("after adding %d: log = %v, want %v", i, got, exp)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkRingLogEvict(b *testing.B) {
+	for _, n := range []int{100, 10000} {
+		b.Run(fmt.Sprint(n), func(b *testing.B) {
+			l := newRingLog(n)
+			// an alert early on keeps every later add evicting among
+			// the routine events
+			l.Add(NewEvent("test", 0.0, EventPriority(5)))
+			ev := NewEvent("test", 1.0)
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				l.Add(ev)
+			}
+		})
+	}
+}
+
+func TestLogForType(t *testing.T) {
+	type emit struct {
+		eventType string
+		val float64
+	}
+	emits := []emit{{"a", 1}, {"b", 2}, {"a", 3}, {"b", 4}, {"a", 5}}
+	tests := []struct {
+		name string
+		opts []SinkOption
+		eventType string
+		want []float64
+	}{
+		{"global log", nil, "a", []float64{5, 3, 1}},
+		{"type logs", []SinkOption{SinkTypeLogs(0)}, "a", []float64{5, 3, 1}},
+		{"other type", []SinkOption{SinkTypeLogs(0)}, "b", []float64{4, 2}},
+		{"capped", []SinkOption{SinkTypeLogs(2)}, "a", []float64{5, 3}},
+		{"cap per type", []SinkOption{SinkTypeLogs(2)}, "b", []float64{4, 2}},
+		{"unknown type", []SinkOption{SinkTypeLogs(0)}, "c", []float64{}},
+		{"unknown type in global log", nil, "c", []float64{}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			sink := NewSyncEventSink(time.Hour, tc.opts...)
+			for _, e := range emits {
+				sink.Emit(e.eventType, e.val)
+			}
+			got := logValues(sink.(LogReader).LogForType(tc.eventType))
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("log for %s = %v, want %v", tc.eventType, got, tc.want)
+			}
+			if n := len(sink.Log()); n != len(emits) {
+				t.Errorf("main log has %d events, want %d", n, len(emits))
+			}
+		})
+	}
+}
+
+func TestLogForTypeTTL(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []SinkOption
+	}{
+		{"global log", nil},
+		{"type logs", []SinkOption{SinkTypeLogs(0)}},
+		{"capped type logs", []SinkOption{SinkTypeLogs(10)}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			c := useFakeClock(t)
+			sink := NewSyncEventSink(time.Minute, tc.opts...)
+			sink.Emit("a", 1.0)
+			c.Advance(45 * time.Second)
+			sink.Emit("a", 2.0)
+			c.Advance(30 * time.Second)
+			// the stale event is trimmed on reading, without another
+			// event of its type arriving
+			sink.Emit("b", 3.0)
+			if got := logValues(sink.(LogReader).LogForType("a")); !reflect.DeepEqual(got, []float64{2}) {
+				t.Errorf("log for a = %v, want [2]", got)
+			}
+		})
+	}
+}
+
+func BenchmarkLogForType(b *testing.B) {
+	sinks := []struct {
+		name string
+		opts []SinkOption
+	}{
+		{"filtered", nil},
+		{"type logs", []SinkOption{SinkTypeLogs(0)}},
+	}
+	for _, bc := range sinks {
+		b.Run(bc.name, func(b *testing.B) {
+			sink := NewSyncEventSink(time.Hour, bc.opts...)
+			for i := 0; i < 10000; i++ {
+				sink.Emit(fmt.Sprintf("noise-%d", i % 100), float64(i))
+			}
+			for i := 0; i < 10; i++ {
+				sink.Emit("quiet", float64(i))
+			}
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if n := len(sink.(LogReader).LogForType("quiet")); n != 10 {
+					b.Fatalf("%d events, want 10", n)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkFireLog(b *testing.B) {
+	sinks := []struct {
+		name string
+		sink EventSink
+	}{
+		{"list", NewEventSink(time.Millisecond)},
+		{"ring", NewRingBufferEventSink(1000, time.Millisecond)},
+	}
+	for _, bc := range sinks {
+		b.Run(bc.name, func(b *testing.B) {
+			ev := NewEvent("test", 1.0)
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				bc.sink.Fire(ev)
+			}
+		})
+	}
+}
+
+func TestLogSortedByTime(t *testing.T) {
+	tests := []struct {
+		name string
+		minutes []int
+		wantSorted []float64
+	}{
+		{"empty", nil, []float64{}},
+		{"in order", []int{1, 2, 3}, []float64{2, 1, 0}},
+		{"reversed", []int{3, 2, 1}, []float64{0, 1, 2}},
+		{"shuffled", []int{2, 5, 1, 4, 3}, []float64{1, 3, 4, 0, 2}},
+		{"equal times keep arrival order", []int{2, 1, 2, 1}, []float64{2, 0, 3, 1}},
+	}
+	sinks := []struct {
+		name string
+		make func() EventSink
+	}{
+		{"list", func() EventSink { return NewSyncEventSink(time.Hour) }},
+		{"ring", func() EventSink { return NewRingBufferEventSink(10, time.Hour, SinkSync()) }},
+		{"prefixed", func() EventSink { return NewPrefixedEventSource("kitchen", NewSyncEventSink(time.Hour)) }},
+	}
+	for _, sc := range sinks {
+		for _, tc := range tests {
+			t.Run(sc.name + "/" + tc.name, func(t *testing.T) {
+				c := useFakeClock(t)
+				start := c.Now()
+				sink := sc.make()
+				evs := []Event{}
+				for i, m := range tc.minutes {
+					c.Set(start.Add(time.Duration(m) * time.Minute))
+					evs = append(evs, NewEvent("test", float64(i)))
+				}
+				c.Set(start.Add(10 * time.Minute))
+				for _, ev := range evs {
+					sink.Fire(ev)
+				}
+				wantArrival := []float64{}
+				for i := len(tc.minutes) - 1; i >= 0; i-- {
+					wantArrival = append(wantArrival, float64(i))
+				}
+				if got := logValues(sink.Log()); !reflect.DeepEqual(got, wantArrival) {
+					t.Errorf("log = %v, want %v", got, wantArrival)
+				}
+				if got := logValues(sink.(LogReader).LogSortedByTime()); !reflect.DeepEqual(got, tc.wantSorted) {
+					t.Errorf("log by time = %v, want %v", got, tc.wantSorted)
+				}
+			})
+		}
+	}
+}
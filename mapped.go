@@ -0,0 +1,278 @@
+package events
+
+import (
+	"context"
+	"io"
+)
+
+// MappedEventSource is a view of a sink with some event types renamed.
+// Like PrefixedEventSource, it translates event types on the way in, when
+// firing events or adding listeners, and back on the way out, when reading
+// the log, registered types and statistics. Handlers are called with the
+// underlying sink's events, under their original types.
+type MappedEventSource struct {
+	EventSink
+	toSink map[string]string
+	fromSink map[string]string
+}
+
+// NewMappedEventSource returns a view of sink in which each event type in
+// mapping's keys, as used on sink, is known by the corresponding value,
+// so that mapping {"temp": "temperature"} lets listeners for
+// "temperature" on the view receive "temp" events fired on sink. Types not
+// in the mapping pass through unchanged. The mapping should be
+// one-to-one, and is copied.
+func NewMappedEventSource(mapping map[string]string, sink EventSink) EventSink {
+	es := &MappedEventSource{
+		EventSink: sink,
+		toSink: make(map[string]string, len(mapping)),
+		fromSink: make(map[string]string, len(mapping)),
+	}
+	for sinkType, viewType := range mapping {
+		es.toSink[viewType] = sinkType
+		es.fromSink[sinkType] = viewType
+	}
+	return es
+}
+
+// sinkType returns the underlying sink's name for eventType.
+func (es *MappedEventSource) sinkType(eventType string) string {
+	if mapped, ok := es.toSink[eventType]; ok {
+		return mapped
+	}
+	return eventType
+}
+
+// viewType returns the view's name for the sink's eventType.
+func (es *MappedEventSource) viewType(eventType string) string {
+	if mapped, ok := es.fromSink[eventType]; ok {
+		return mapped
+	}
+	return eventType
+}
+
+func (es *MappedEventSource) toSinkEvent(ev Event) Event {
+	if ev == nil {
+		return nil
+	}
+	if mapped, ok := es.toSink[ev.GetType()]; ok {
+		return ev.As(mapped)
+	}
+	return ev
+}
+
+func (es *MappedEventSource) toViewEvent(ev Event) Event {
+	if mapped, ok := es.fromSink[ev.GetType()]; ok {
+		return ev.As(mapped)
+	}
+	return ev
+}
+
+func (es *MappedEventSource) toView(evs []Event) []Event {
+	out := make([]Event, len(evs))
+	for i, ev := range evs {
+		out[i] = es.toViewEvent(ev)
+	}
+	return out
+}
+
+func (es *MappedEventSource) AddEventListener(eventType string, handler EventHandler) {
+	es.EventSink.AddEventListener(es.sinkType(eventType), handler)
+}
+
+func (es *MappedEventSource) RemoveEventListener(eventType string, handler EventHandler) {
+	es.EventSink.RemoveEventListener(es.sinkType(eventType), handler)
+}
+
+func (es *MappedEventSource) RemoveEventListenerSync(eventType string, handler EventHandler) {
+	removeEventListenerSync(es.EventSink, es.sinkType(eventType), handler)
+}
+
+func (es *MappedEventSource) AddEventListenerTagged(eventType, tag string, handler EventHandler) {
+	addEventListenerTagged(es.EventSink, es.sinkType(eventType), tag, handler)
+}
+
+func (es *MappedEventSource) AddEventListenerWithPriority(eventType string, priority int, handler EventHandler) {
+	addEventListenerWithPriority(es.EventSink, es.sinkType(eventType), priority, handler)
+}
+
+func (es *MappedEventSource) AddEventListenerIf(eventType string, pred func(Event) bool, handler EventHandler) {
+	addEventListenerIf(es.EventSink, es.sinkType(eventType), pred, handler)
+}
+
+func (es *MappedEventSource) AddEventListenerWithLabels(eventType string, selector map[string]string, handler EventHandler) {
+	addEventListenerWithLabels(es.EventSink, es.sinkType(eventType), selector, handler)
+}
+
+func (es *MappedEventSource) Once(eventType string, handler EventHandler) {
+	es.EventSink.Once(es.sinkType(eventType), handler)
+}
+
+func (es *MappedEventSource) OnceWhen(eventType string, handler EventHandler, cond Condition) {
+	onceWhen(es.EventSink, es.sinkType(eventType), handler, cond)
+}
+
+func (es *MappedEventSource) RemoveByTag(tag string) {
+	removeByTag(es.EventSink, tag)
+}
+
+func (es *MappedEventSource) PruneExpired() int {
+	return pruneExpired(es.EventSink)
+}
+
+func (es *MappedEventSource) AliasEventType(oldType, newType string) {
+	if tm, ok := es.EventSink.(TypeManager); ok {
+		tm.AliasEventType(es.sinkType(oldType), es.sinkType(newType))
+	}
+}
+
+func (es *MappedEventSource) Fire(ev Event) {
+	es.EventSink.Fire(es.toSinkEvent(ev))
+}
+
+func (es *MappedEventSource) Emit(eventType string, data interface{}) {
+	es.EventSink.Emit(es.sinkType(eventType), data)
+}
+
+func (es *MappedEventSource) EmitContext(ctx context.Context, eventType string, data interface{}) {
+	emitContext(es.EventSink, ctx, es.sinkType(eventType), data)
+}
+
+func (es *MappedEventSource) FireMany(evs []Event) {
+	mapped := make([]Event, len(evs))
+	for i, ev := range evs {
+		mapped[i] = es.toSinkEvent(ev)
+	}
+	fireMany(es.EventSink, mapped)
+}
+
+func (es *MappedEventSource) EmitMany(eventType string, data []interface{}) {
+	fireMany(es.EventSink, newEvents(es.sinkType(eventType), data))
+}
+
+func (es *MappedEventSource) FireCollect(ev Event) ([]interface{}, []error) {
+	return fireCollect(es.EventSink, es.toSinkEvent(ev))
+}
+
+func (es *MappedEventSource) EmitSync(eventType string, data interface{}) []error {
+	return failures(es.FireCollect(NewEvent(eventType, data)))
+}
+
+func (es *MappedEventSource) Log() []Event {
+	return es.toView(es.EventSink.Log())
+}
+
+func (es *MappedEventSource) LogSortedByTime() []Event {
+	evs := es.Log()
+	sortByTime(evs)
+	return evs
+}
+
+func (es *MappedEventSource) LogForType(eventType string) []Event {
+	return es.toView(logForType(es.EventSink, es.sinkType(eventType)))
+}
+
+func (es *MappedEventSource) Latest(eventType string) (Event, bool) {
+	lr, ok := es.EventSink.(LatestReader)
+	if !ok {
+		return nil, false
+	}
+	ev, ok := lr.Latest(es.sinkType(eventType))
+	if !ok {
+		return nil, false
+	}
+	return es.toViewEvent(ev), true
+}
+
+func (es *MappedEventSource) LatestAll() map[string]Event {
+	out := map[string]Event{}
+	lr, ok := es.EventSink.(LatestReader)
+	if !ok {
+		return out
+	}
+	for eventType, ev := range lr.LatestAll() {
+		out[es.viewType(eventType)] = es.toViewEvent(ev)
+	}
+	return out
+}
+
+func (es *MappedEventSource) ExportCSV(w io.Writer, eventTypes ...string) error {
+	return exportCSV(w, es.Log(), eventTypes)
+}
+
+func (es *MappedEventSource) RegisterEventType(ev Event) {
+	es.EventSink.RegisterEventType(es.toSinkEvent(ev))
+}
+
+func (es *MappedEventSource) RegisterEventTypeWithValidator(ev Event, validator Validator) {
+	if tm, ok := es.EventSink.(TypeManager); ok {
+		tm.RegisterEventTypeWithValidator(es.toSinkEvent(ev), validator)
+	} else {
+		es.EventSink.RegisterEventType(es.toSinkEvent(ev))
+	}
+}
+
+func (es *MappedEventSource) ListEventTypes() []Event {
+	return es.toView(es.EventSink.ListEventTypes())
+}
+
+func (es *MappedEventSource) DispatchOrder(eventType string) []ListenerMeta {
+	li, ok := es.EventSink.(ListenerInspector)
+	if !ok {
+		return nil
+	}
+	out := li.DispatchOrder(es.sinkType(eventType))
+	for i := range out {
+		out[i].EventType = es.viewType(out[i].EventType)
+	}
+	return out
+}
+
+func (es *MappedEventSource) Stats() map[string]EventTypeStats {
+	stats := map[string]EventTypeStats{}
+	ss, ok := es.EventSink.(StatsSink)
+	if !ok {
+		return stats
+	}
+	for eventType, st := range ss.Stats() {
+		stats[es.viewType(eventType)] = st
+	}
+	return stats
+}
+
+func (es *MappedEventSource) Percentiles(eventType string, ps ...float64) map[float64]float64 {
+	if ss, ok := es.EventSink.(StatsSink); ok {
+		return ss.Percentiles(es.sinkType(eventType), ps...)
+	}
+	return map[float64]float64{}
+}
+
+func (es *MappedEventSource) ListenerCount(eventType string) int {
+	if li, ok := es.EventSink.(ListenerInspector); ok {
+		return li.ListenerCount(es.sinkType(eventType))
+	}
+	return 0
+}
+
+func (es *MappedEventSource) Counters() map[string]EventCounters {
+	out := map[string]EventCounters{}
+	ss, ok := es.EventSink.(StatsSink)
+	if !ok {
+		return out
+	}
+	for eventType, c := range ss.Counters() {
+		out[es.viewType(eventType)] = c
+	}
+	return out
+}
+
+func (es *MappedEventSource) OnListenerChange(fn func(ListenerMeta)) {
+	li, ok := es.EventSink.(ListenerInspector)
+	if fn == nil || !ok {
+		return
+	}
+	li.OnListenerChange(func(meta ListenerMeta) {
+		meta.EventType = es.viewType(meta.EventType)
+		fn(meta)
+	})
+}
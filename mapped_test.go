@@ -0,0 +1,136 @@
+package events
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestMappedEventSource(t *testing.T) {
+	mapping := map[string]string{"temp": "temperature"}
+	tests := []struct {
+		name string
+		listen string
+		onView bool
+		fire func(sink EventSink, eventType string)
+		fireType string
+		wantTypes []string
+	}{
+		{"emit under external name", "temperature", true, emitOne, "temperature", []string{"temp"}},
+		{"fire under external name", "temperature", true, fireOne, "temperature", []string{"temp"}},
+		{"fire many under external name", "temperature", true, fireManyOne, "temperature", []string{"temp"}},
+		{"emit sync under external name", "temperature", true, emitSyncOne, "temperature", []string{"temp"}},
+		{"internal name on the sink", "temperature", false, emitOne, "temp", []string{"temp"}},
+		{"external name on the sink", "temperature", false, emitOne, "temperature", []string{}},
+		{"unmapped", "door", true, emitOne, "door", []string{"door"}},
+		{"unmapped on the sink", "door", false, emitOne, "door", []string{"door"}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			sink := NewSyncEventSink(time.Hour)
+			view := NewMappedEventSource(mapping, sink)
+			got := []string{}
+			view.AddEventListener(tc.listen, NewEventHandler(func(ev Event) error {
+				got = append(got, ev.GetType())
+				return nil
+			}))
+			if tc.onView {
+				tc.fire(view, tc.fireType)
+			} else {
+				tc.fire(sink, tc.fireType)
+			}
+			if !reflect.DeepEqual(got, tc.wantTypes) {
+				t.Errorf("listener got %v, want %v", got, tc.wantTypes)
+			}
+		})
+	}
+}
+
+func emitOne(sink EventSink, eventType string) {
+	sink.Emit(eventType, 1.0)
+}
+
+func fireOne(sink EventSink, eventType string) {
+	sink.Fire(NewEvent(eventType, 1.0))
+}
+
+func fireManyOne(sink EventSink, eventType string) {
+	sink.(BatchSink).FireMany([]Event{NewEvent(eventType, 1.0)})
+}
+
+func emitSyncOne(sink EventSink, eventType string) {
+	sink.(SyncSink).EmitSync(eventType, 1.0)
+}
+
+func TestMappedEventSourceReads(t *testing.T) {
+	sink := NewSyncEventSink(time.Hour)
+	view := NewMappedEventSource(map[string]string{"temp": "temperature"}, sink)
+	view.RegisterEventType(NewEvent("temperature", 0.0))
+	view.RegisterEventType(NewEvent("door", ""))
+	view.Emit("temperature", 20.0)
+	sink.Emit("temp", 21.0)
+	view.Emit("door", "open")
+	types := func(evs []Event) []string {
+		out := []string{}
+		for _, ev := range evs {
+			out = append(out, ev.GetType())
+		}
+		return out
+	}
+	tests := []struct {
+		name string
+		got []string
+		sorted bool
+		want []string
+	}{
+		{"view log", types(view.Log()), false, []string{"door", "temperature", "temperature"}},
+		{"sink log", types(sink.Log()), false, []string{"door", "temp", "temp"}},
+		{"view log by time", types(view.(LogReader).LogSortedByTime()), false, []string{"door", "temperature", "temperature"}},
+		{"view log for type", types(view.(LogReader).LogForType("temperature")), false, []string{"temperature", "temperature"}},
+		{"view types", types(view.ListEventTypes()), true, []string{"door", "temperature"}},
+		{"sink types", types(sink.ListEventTypes()), true, []string{"door", "temp"}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := append([]string{}, tc.got...)
+			if tc.sorted {
+				sort.Strings(got)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("types = %v, want %v", got, tc.want)
+			}
+		})
+	}
+	if ev, ok := view.(LatestReader).Latest("temperature"); !ok || ev.GetType() != "temperature" || ev.(Valuer).GetValue() != 21 {
+		t.Errorf("latest temperature = %v, %t", ev, ok)
+	}
+	stats := view.(StatsSink).Stats()
+	if st, ok := stats["temperature"]; !ok || st.Count != 2 {
+		t.Errorf("temperature stats = %+v, %t", st, ok)
+	}
+	if _, ok := stats["temp"]; ok {
+		t.Error("view stats include the internal name")
+	}
+	if n := view.(ListenerInspector).ListenerCount("temperature"); n != 0 {
+		t.Errorf("%d temperature listeners, want 0", n)
+	}
+}
+
+func TestMappedEventSourceRemove(t *testing.T) {
+	sink := NewSyncEventSink(time.Hour)
+	view := NewMappedEventSource(map[string]string{"temp": "temperature"}, sink)
+	rec := RecordingHandler()
+	view.AddEventListener("temperature", rec)
+	if n := sink.(ListenerInspector).ListenerCount("temp"); n != 1 {
+		t.Fatalf("%d listeners for temp on the sink, want 1", n)
+	}
+	view.RemoveEventListener("temperature", rec)
+	sink.Emit("temp", 1.0)
+	if n := len(rec.Calls()); n != 0 {
+		t.Errorf("removed listener called %d times", n)
+	}
+	if n := sink.(ListenerInspector).ListenerCount("temp"); n != 0 {
+		t.Errorf("%d listeners for temp on the sink after removing, want 0", n)
+	}
+}
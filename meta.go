@@ -0,0 +1,61 @@
+package events
+
+// MaxMetaEventDepth limits how deeply listener meta-events can cause one
+// another. A handler for EventTypeHandlerAdded that adds a listener causes
+// another EventTypeHandlerAdded event, which may call it again, and so on
+// forever. To stop such feedback, each listener meta-event records in its
+// ListenerMeta.Depth how many meta-event handlers were running, one inside
+// another, when it was caused: 0 for a listener change made by ordinary
+// code, 1 for one made by a handler for a depth 0 meta-event, and so on.
+// Listener changes and handler failures that would produce a meta-event
+// deeper than MaxMetaEventDepth still take effect, but produce no
+// meta-event.
+//
+// Since a sink can't tell which handler made a change, a change made while
+// a meta-event handler is running elsewhere is counted as caused by it.
+// Such a change only goes unreported if meta-events are already nested
+// MaxMetaEventDepth deep.
+const MaxMetaEventDepth = 8
+
+// metaEventDepth returns the depth of ev if it is a listener meta-event.
+func metaEventDepth(ev Event) (int, bool) {
+	switch ev.GetType() {
+	case EventTypeHandlerAdded, EventTypeHandlerRemoved, EventTypeHandlerError:
+	default:
+		return 0, false
+	}
+	meta, ok := ev.GetData().(*ListenerMeta)
+	if !ok {
+		return 0, false
+	}
+	return meta.Depth, true
+}
+
+// enterMeta records that a handler for a meta-event of the given depth is
+// running.
+func (es *basicEventSink) enterMeta(depth int) {
+	es.mutex.Lock()
+	es.metaActive[depth] += 1
+	es.mutex.Unlock()
+}
+
+func (es *basicEventSink) exitMeta(depth int) {
+	es.mutex.Lock()
+	es.metaActive[depth] -= 1
+	if es.metaActive[depth] <= 0 {
+		delete(es.metaActive, depth)
+	}
+	es.mutex.Unlock()
+}
+
+// metaDepth returns the depth of a meta-event caused now, and whether it
+// is within MaxMetaEventDepth. The caller must hold the mutex.
+func (es *basicEventSink) metaDepth() (int, bool) {
+	depth := 0
+	for d := range es.metaActive {
+		if d + 1 > depth {
+			depth = d + 1
+		}
+	}
+	return depth, depth <= MaxMetaEventDepth
+}
@@ -0,0 +1,102 @@
+package events
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestMetaEventFeedback(t *testing.T) {
+	tests := []struct {
+		name string
+		sink func() EventSink
+		metaType string
+		react func(sink EventSink) error
+	}{
+		{
+			"adding on add, sync",
+			func() EventSink { return NewSyncEventSink(time.Hour) },
+			EventTypeHandlerAdded,
+			func(sink EventSink) error {
+				sink.AddEventListener("test", NewEventHandler(func(ev Event) error { return nil }))
+				return nil
+			},
+		},
+		{
+			"adding on add, async",
+			func() EventSink { return NewEventSink(time.Hour) },
+			EventTypeHandlerAdded,
+			func(sink EventSink) error {
+				sink.AddEventListener("test", NewEventHandler(func(ev Event) error { return nil }))
+				return nil
+			},
+		},
+		{
+			"churning on remove",
+			func() EventSink { return NewEventSink(time.Hour) },
+			EventTypeHandlerRemoved,
+			func(sink EventSink) error {
+				h := NewEventHandler(func(ev Event) error { return nil })
+				sink.AddEventListener("test", h)
+				sink.RemoveEventListener("test", h)
+				return nil
+			},
+		},
+		{
+			"failing on error",
+			func() EventSink { return NewSyncEventSink(time.Hour) },
+			EventTypeHandlerError,
+			func(sink EventSink) error { return errBoom },
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			sink := tc.sink()
+			react := tc.react
+			depths := map[int]int{}
+			mutex := &sync.Mutex{}
+			sink.AddEventListener(tc.metaType, NewEventHandler(func(ev Event) error {
+				mutex.Lock()
+				depths[ev.GetData().(*ListenerMeta).Depth] += 1
+				mutex.Unlock()
+				return react(sink)
+			}))
+			// start the feedback off
+			sink.AddEventListener("test", NewEventHandler(func(ev Event) error { return errBoom }))
+			h := NewEventHandler(func(ev Event) error { return nil })
+			sink.AddEventListener("test", h)
+			sink.RemoveEventListener("test", h)
+			sink.Emit("test", 1.0)
+			count := func() int {
+				mutex.Lock()
+				defer mutex.Unlock()
+				n := 0
+				for _, c := range depths {
+					n += c
+				}
+				return n
+			}
+			// wait for the feedback to die down
+			deadline := time.Now().Add(5 * time.Second)
+			last := -1
+			for n := count(); n != last; n = count() {
+				if time.Now().After(deadline) {
+					t.Fatalf("still handling meta-events after %d", n)
+				}
+				last = n
+				time.Sleep(20 * time.Millisecond)
+			}
+			mutex.Lock()
+			defer mutex.Unlock()
+			for depth := range depths {
+				if depth < 0 || depth > MaxMetaEventDepth {
+					t.Errorf("meta-event at depth %d", depth)
+				}
+			}
+			// the feedback went all the way to the limit and stopped there
+			if depths[0] == 0 || depths[MaxMetaEventDepth] == 0 {
+				t.Errorf("depths seen %v, want 0 to %d", depths, MaxMetaEventDepth)
+			}
+		})
+	}
+}
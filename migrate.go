@@ -0,0 +1,100 @@
+package events
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// A MigrateFunc upgrades an event's payload from one schema version to
+// the next. It should keep the event's type and time.
+type MigrateFunc func(Event) Event
+
+type migrationKey struct {
+	eventType string
+	from int
+}
+
+type migration struct {
+	to int
+	fn MigrateFunc
+}
+
+var migrations = map[migrationKey]migration{}
+var migrationsMutex = &sync.Mutex{}
+
+// RegisterMigration registers fn to upgrade events of the given type from
+// version from to version to, which must be greater. Events loaded by
+// LoadLog or replayed by ReplayFrom are upgraded through every registered
+// migration that applies, one after another, so registering 1 -> 2 and
+// 2 -> 3 upgrades a version 1 event to version 3. The migrated event's
+// version is set to to, whatever fn returns.
+func RegisterMigration(eventType string, from, to int, fn MigrateFunc) {
+	if to <= from || fn == nil {
+		return
+	}
+	migrationsMutex.Lock()
+	migrations[migrationKey{eventType, from}] = migration{to, fn}
+	migrationsMutex.Unlock()
+}
+
+// Migrate applies the registered migrations to ev, returning the upgraded
+// event, or ev itself if no migration applies.
+func Migrate(ev Event) Event {
+	for {
+		migrationsMutex.Lock()
+		m, ok := migrations[migrationKey{ev.GetType(), Version(ev)}]
+		migrationsMutex.Unlock()
+		if !ok {
+			return ev
+		}
+		next := m.fn(ev)
+		if next == nil {
+			return ev
+		}
+		setVersion(next, m.to)
+		if Version(next) != m.to {
+			// the event can't record its version, so stop here rather
+			// than migrate it again
+			return next
+		}
+		ev = next
+	}
+}
+
+// LoadLog reads newline-delimited JSON events from r, as written by
+// NewLoggedEventSink or NewWriterHandler, upgrading each with Migrate. The
+// events are returned in the order they were read.
+func LoadLog(r io.Reader) ([]Event, error) {
+	evs := []Event{}
+	err := readEvents(r, func(ev Event) {
+		evs = append(evs, ev)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return evs, nil
+}
+
+// readEvents calls fn with each newline-delimited JSON event read from r,
+// after migrating it.
+func readEvents(r io.Reader, fn func(Event)) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxIngestBody)
+	lineno := 0
+	for scanner.Scan() {
+		lineno += 1
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		ev, err := UnmarshalEvent(line)
+		if err != nil {
+			return fmt.Errorf("line %d: %w", lineno, err)
+		}
+		fn(Migrate(ev))
+	}
+	return scanner.Err()
+}
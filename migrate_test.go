@@ -0,0 +1,89 @@
+package events
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func init() {
+	// v1 thermostat events carried fahrenheit, v2 carry celsius
+	RegisterMigration("test-thermostat", 1, 2, func(ev Event) Event {
+		f, _ := EventFloat(ev, "temp_f")
+		return CloneEvent(ev, map[string]interface{}{"temp_c": (f - 32) * 5 / 9})
+	})
+	// chained: v1 -> v2 renames, v2 -> v3 adds a field
+	RegisterMigration("test-door", 1, 2, func(ev Event) Event {
+		state, _ := EventString(ev, "open")
+		return CloneEvent(ev, map[string]interface{}{"state": state})
+	})
+	RegisterMigration("test-door", 2, 3, func(ev Event) Event {
+		state, _ := EventString(ev, "state")
+		return CloneEvent(ev, map[string]interface{}{"state": state, "source": "migrated"})
+	})
+	// refusing to migrate leaves the event as it was
+	RegisterMigration("test-refused", 1, 2, func(ev Event) Event { return nil })
+	// ignored, since it doesn't move forward
+	RegisterMigration("test-backwards", 2, 1, func(ev Event) Event { return CloneEvent(ev, "backwards") })
+}
+
+func TestLoadLogMigrates(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+		wantVersion int
+		wantData map[string]interface{}
+	}{
+		{"v1 to v2", `{"type": "test-thermostat", "version": 1, "data": {"temp_f": 212}}`, 2, map[string]interface{}{"temp_c": 100.0}},
+		{"already v2", `{"type": "test-thermostat", "version": 2, "data": {"temp_c": 20}}`, 2, map[string]interface{}{"temp_c": 20.0}},
+		{"unversioned", `{"type": "test-thermostat", "data": {"temp_f": 212}}`, 0, map[string]interface{}{"temp_f": 212.0}},
+		{"chained", `{"type": "test-door", "version": 1, "data": {"open": "yes"}}`, 3, map[string]interface{}{"state": "yes", "source": "migrated"}},
+		{"chained from the middle", `{"type": "test-door", "version": 2, "data": {"state": "no"}}`, 3, map[string]interface{}{"state": "no", "source": "migrated"}},
+		{"other type", `{"type": "test-window", "version": 1, "data": {"temp_f": 212}}`, 1, map[string]interface{}{"temp_f": 212.0}},
+		{"refused", `{"type": "test-refused", "version": 1, "data": {"a": "b"}}`, 1, map[string]interface{}{"a": "b"}},
+		{"backwards", `{"type": "test-backwards", "version": 2, "data": {"a": "b"}}`, 2, map[string]interface{}{"a": "b"}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			evs, err := LoadLog(strings.NewReader(tc.line + "\n"))
+			if err != nil {
+				t.Fatalf("can't load log: %s", err)
+			}
+			if len(evs) != 1 {
+				t.Fatalf("loaded %d events, want 1", len(evs))
+			}
+			ev := evs[0]
+			if v := Version(ev); v != tc.wantVersion {
+				t.Errorf("version = %d, want %d", v, tc.wantVersion)
+			}
+			if data, _ := ev.GetData().(map[string]interface{}); !reflect.DeepEqual(data, tc.wantData) {
+				t.Errorf("data = %v, want %v", ev.GetData(), tc.wantData)
+			}
+		})
+	}
+}
+
+func TestLoadLogMigratesInOrder(t *testing.T) {
+	lines := strings.Join([]string{
+		`{"type": "test-thermostat", "version": 1, "time": "2024-01-01T00:00:00Z", "data": {"temp_f": 32}}`,
+		`{"type": "temp", "value": 1.5}`,
+		`{"type": "test-thermostat", "version": 2, "time": "2024-01-01T00:01:00Z", "data": {"temp_c": 5}}`,
+	}, "\n")
+	evs, err := LoadLog(strings.NewReader(lines))
+	if err != nil {
+		t.Fatalf("can't load log: %s", err)
+	}
+	got := []string{}
+	for _, ev := range evs {
+		got = append(got, ev.GetType())
+	}
+	if !reflect.DeepEqual(got, []string{"test-thermostat", "temp", "test-thermostat"}) {
+		t.Fatalf("loaded %v", got)
+	}
+	if c, _ := EventFloat(evs[0], "temp_c"); c != 0 || Version(evs[0]) != 2 {
+		t.Errorf("first event = %v, version %d, want temp_c 0, version 2", evs[0].GetData(), Version(evs[0]))
+	}
+	if evs[0].GetTime().Format("15:04") != "00:00" {
+		t.Errorf("migrated event time = %s", evs[0].GetTime())
+	}
+}
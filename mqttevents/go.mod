@@ -0,0 +1,18 @@
+module github.com/rclancey/events/mqttevents
+
+go 1.18
+
+require (
+	github.com/eclipse/paho.mqtt.golang v1.4.3
+	github.com/rclancey/events v0.0.2
+)
+
+require (
+	github.com/gorilla/websocket v1.5.0 // indirect
+	github.com/rclancey/encoding-form v0.0.1 // indirect
+	github.com/rclancey/generic v0.0.2 // indirect
+	golang.org/x/net v0.16.0 // indirect
+	golang.org/x/sync v0.1.0 // indirect
+)
+
+replace github.com/rclancey/events => ../
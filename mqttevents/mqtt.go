@@ -0,0 +1,81 @@
+package mqttevents
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	"github.com/rclancey/events"
+)
+
+// NewMQTTHandler returns a handler that publishes each event to the topic
+// returned by topicFn, or to a topic named after the event type if topicFn
+// is nil. Value events are published as their bare value and message
+// events as their bare message, which suits typical IoT consumers; other
+// events are JSON encoded. Call waits for the publish to complete and
+// returns its error.
+func NewMQTTHandler(client mqtt.Client, topicFn func(events.Event) string, qos byte) events.ContextHandler {
+	return events.NewContextEventHandler(func(ctx context.Context, ev events.Event) error {
+		topic := ev.GetType()
+		if topicFn != nil {
+			topic = topicFn(ev)
+		}
+		payload, err := encodePayload(ev)
+		if err != nil {
+			return err
+		}
+		token := client.Publish(topic, qos, false, payload)
+		select {
+		case <-token.Done():
+			return token.Error()
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	})
+}
+
+func encodePayload(ev events.Event) ([]byte, error) {
+	switch tev := ev.(type) {
+	case events.BinaryEvent:
+		return tev.GetBytes(), nil
+	case events.ValueEvent:
+		return []byte(strconv.FormatFloat(tev.GetValue(), 'g', -1, 64)), nil
+	case events.MessageEvent:
+		return []byte(tev.GetMessage()), nil
+	}
+	return json.Marshal(ev)
+}
+
+// SubscribeMQTT emits every message received on topic into sink as an
+// event of the given type, or of the message's topic if eventType is
+// empty. Numeric payloads become value events, JSON objects become events
+// with map data, and anything else becomes a message event, following
+// NewEvent.
+func SubscribeMQTT(client mqtt.Client, topic string, sink events.EventSink, eventType string) error {
+	token := client.Subscribe(topic, 0, func(_ mqtt.Client, msg mqtt.Message) {
+		t := eventType
+		if t == "" {
+			t = msg.Topic()
+		}
+		sink.Emit(t, decodePayload(msg.Payload()))
+	})
+	token.Wait()
+	return token.Error()
+}
+
+func decodePayload(payload []byte) interface{} {
+	s := strings.TrimSpace(string(payload))
+	f, err := strconv.ParseFloat(s, 64)
+	if err == nil {
+		return f
+	}
+	if strings.HasPrefix(s, "{") {
+		data := map[string]interface{}{}
+		if json.Unmarshal(payload, &data) == nil {
+			return data
+		}
+	}
+	return string(payload)
+}
@@ -0,0 +1,205 @@
+package mqttevents
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	"github.com/rclancey/events"
+)
+
+// mockToken is a token that is already complete, or never completes if
+// done is nil.
+type mockToken struct {
+	done chan struct{}
+	err error
+}
+
+func completed(err error) *mockToken {
+	done := make(chan struct{})
+	close(done)
+	return &mockToken{done, err}
+}
+
+func (tok *mockToken) Wait() bool {
+	<-tok.done
+	return true
+}
+
+func (tok *mockToken) WaitTimeout(d time.Duration) bool {
+	select {
+	case <-tok.done:
+		return true
+	case <-time.After(d):
+		return false
+	}
+}
+
+func (tok *mockToken) Done() <-chan struct{} {
+	return tok.done
+}
+
+func (tok *mockToken) Error() error {
+	return tok.err
+}
+
+type mockMessage struct {
+	mqtt.Message
+	topic string
+	payload []byte
+}
+
+func (msg *mockMessage) Topic() string {
+	return msg.topic
+}
+
+func (msg *mockMessage) Payload() []byte {
+	return msg.payload
+}
+
+type publication struct {
+	topic string
+	qos byte
+	payload []byte
+}
+
+// mockClient implements the parts of mqtt.Client used by the package.
+type mockClient struct {
+	mqtt.Client
+	published []publication
+	token *mockToken
+	subscribed string
+	callback mqtt.MessageHandler
+}
+
+func (c *mockClient) Publish(topic string, qos byte, retained bool, payload interface{}) mqtt.Token {
+	c.published = append(c.published, publication{topic, qos, payload.([]byte)})
+	return c.token
+}
+
+func (c *mockClient) Subscribe(topic string, qos byte, callback mqtt.MessageHandler) mqtt.Token {
+	c.subscribed = topic
+	c.callback = callback
+	return c.token
+}
+
+// deliver passes a message to the subscribed callback.
+func (c *mockClient) deliver(topic, payload string) {
+	c.callback(c, &mockMessage{topic: topic, payload: []byte(payload)})
+}
+
+func TestMQTTHandler(t *testing.T) {
+	errPublish := errors.New("not connected")
+	tests := []struct {
+		name string
+		topicFn func(events.Event) string
+		ev events.Event
+		token *mockToken
+		wantErr error
+		wantTopic string
+		wantPayload string
+	}{
+		{"value", nil, events.NewEvent("temperature", 21.5), completed(nil), nil, "temperature", "21.5"},
+		{"message", nil, events.NewEvent("door", "open"), completed(nil), nil, "door", "open"},
+		{"binary", nil, events.NewEvent("blob", []byte("raw")), completed(nil), nil, "blob", "raw"},
+		{"map", nil, events.NewEvent("status", map[string]interface{}{"on": true}), completed(nil), nil, "status", ""},
+		{"topic func", func(ev events.Event) string { return "home/" + ev.GetType() }, events.NewEvent("temperature", 20.0), completed(nil), nil, "home/temperature", "20"},
+		{"publish error", nil, events.NewEvent("temperature", 21.5), completed(errPublish), errPublish, "temperature", "21.5"},
+		{"canceled", nil, events.NewEvent("temperature", 21.5), &mockToken{}, context.Canceled, "temperature", "21.5"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			client := &mockClient{token: tc.token}
+			ctx, cancel := context.WithCancel(context.Background())
+			if tc.token.done == nil {
+				cancel()
+			}
+			defer cancel()
+			err := NewMQTTHandler(client, tc.topicFn, 1).CallContext(ctx, tc.ev)
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("call returned %v, want %v", err, tc.wantErr)
+			}
+			if len(client.published) != 1 {
+				t.Fatalf("published %d messages, want 1", len(client.published))
+			}
+			pub := client.published[0]
+			if pub.topic != tc.wantTopic || pub.qos != 1 {
+				t.Errorf("published to %q at qos %d, want %q at qos 1", pub.topic, pub.qos, tc.wantTopic)
+			}
+			if tc.wantPayload == "" {
+				ev, err := events.UnmarshalEvent(pub.payload)
+				if err != nil {
+					t.Fatalf("can't decode %s: %s", pub.payload, err)
+				}
+				if !reflect.DeepEqual(ev.GetData(), tc.ev.GetData()) {
+					t.Errorf("published %s, want %v", pub.payload, tc.ev.GetData())
+				}
+			} else if string(pub.payload) != tc.wantPayload {
+				t.Errorf("payload = %q, want %q", pub.payload, tc.wantPayload)
+			}
+		})
+	}
+}
+
+func TestSubscribeMQTT(t *testing.T) {
+	tests := []struct {
+		name string
+		eventType string
+		topic string
+		payload string
+		wantType string
+		wantData interface{}
+	}{
+		{"number", "temperature", "home/kitchen/temp", "21.5", "temperature", 21.5},
+		{"padded number", "temperature", "home/kitchen/temp", " 21.5\n", "temperature", 21.5},
+		{"string", "door", "home/door", "open", "door", "open"},
+		{"json", "status", "home/status", `{"on": true}`, "status", map[string]interface{}{"on": true}},
+		{"broken json", "status", "home/status", `{"on": `, "status", `{"on": `},
+		{"topic as type", "", "home/door", "open", "home/door", "open"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			client := &mockClient{token: completed(nil)}
+			sink := events.NewSyncEventSink(time.Minute)
+			var got events.Event
+			sink.AddEventListener(tc.wantType, events.NewEventHandler(func(ev events.Event) error {
+				got = ev
+				return nil
+			}))
+			if err := SubscribeMQTT(client, "home/#", sink, tc.eventType); err != nil {
+				t.Fatalf("subscribe failed: %s", err)
+			}
+			if client.subscribed != "home/#" {
+				t.Errorf("subscribed to %q, want home/#", client.subscribed)
+			}
+			client.deliver(tc.topic, tc.payload)
+			if got == nil {
+				t.Fatalf("no %s event emitted", tc.wantType)
+			}
+			var data interface{}
+			switch tev := got.(type) {
+			case events.ValueEvent:
+				data = tev.GetValue()
+			case events.MessageEvent:
+				data = tev.GetMessage()
+			default:
+				data = got.GetData()
+			}
+			if !reflect.DeepEqual(data, tc.wantData) {
+				t.Errorf("emitted %#v, want %#v", data, tc.wantData)
+			}
+		})
+	}
+}
+
+func TestSubscribeMQTTError(t *testing.T) {
+	errSubscribe := errors.New("not authorized")
+	client := &mockClient{token: completed(errSubscribe)}
+	err := SubscribeMQTT(client, "home/#", events.NewSyncEventSink(time.Minute), "")
+	if !errors.Is(err, errSubscribe) {
+		t.Errorf("subscribe returned %v, want %v", err, errSubscribe)
+	}
+}
@@ -0,0 +1,20 @@
+module github.com/rclancey/events/natsevents
+
+go 1.20
+
+require (
+	github.com/nats-io/nats.go v1.31.0
+	github.com/rclancey/events v0.0.2
+)
+
+require (
+	github.com/klauspost/compress v1.17.0 // indirect
+	github.com/nats-io/nkeys v0.4.5 // indirect
+	github.com/nats-io/nuid v1.0.1 // indirect
+	github.com/rclancey/encoding-form v0.0.1 // indirect
+	github.com/rclancey/generic v0.0.2 // indirect
+	golang.org/x/crypto v0.14.0 // indirect
+	golang.org/x/sys v0.13.0 // indirect
+)
+
+replace github.com/rclancey/events => ../
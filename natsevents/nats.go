@@ -0,0 +1,41 @@
+package natsevents
+
+import (
+	"encoding/json"
+
+	"github.com/nats-io/nats.go"
+	"github.com/rclancey/events"
+)
+
+// Publisher is the part of *nats.Conn used to publish events, so that it
+// can be replaced in tests.
+type Publisher interface {
+	Publish(subject string, data []byte) error
+}
+
+var _ Publisher = (*nats.Conn)(nil)
+
+// NewNATSHandler returns a handler that publishes each event to the
+// subject returned by subjectFn, or to a subject named after the event
+// type if subjectFn is nil. Events are JSON encoded, except for binary
+// events, whose bytes are published as-is. Publish errors are returned
+// from Call.
+func NewNATSHandler(conn Publisher, subjectFn func(events.Event) string) events.EventHandler {
+	return events.NewEventHandler(func(ev events.Event) error {
+		subject := ev.GetType()
+		if subjectFn != nil {
+			subject = subjectFn(ev)
+		}
+		var data []byte
+		if bev, ok := ev.(events.BinaryEvent); ok {
+			data = bev.GetBytes()
+		} else {
+			var err error
+			data, err = json.Marshal(ev)
+			if err != nil {
+				return err
+			}
+		}
+		return conn.Publish(subject, data)
+	})
+}
@@ -0,0 +1,104 @@
+package natsevents
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/rclancey/events"
+)
+
+type published struct {
+	subject string
+	data []byte
+}
+
+type mockConn struct {
+	msgs []published
+	err error
+}
+
+func (c *mockConn) Publish(subject string, data []byte) error {
+	if c.err != nil {
+		return c.err
+	}
+	c.msgs = append(c.msgs, published{subject, data})
+	return nil
+}
+
+func TestNATSHandler(t *testing.T) {
+	errPublish := errors.New("connection closed")
+	tests := []struct {
+		name string
+		subjectFn func(events.Event) string
+		ev events.Event
+		pubErr error
+		wantSubject string
+		wantData []byte
+	}{
+		{
+			"value",
+			nil,
+			events.NewEvent("temperature", 21.5),
+			nil,
+			"temperature",
+			nil,
+		},
+		{
+			"subject func",
+			func(ev events.Event) string { return "sensors." + ev.GetType() },
+			events.NewEvent("temperature", 21.5),
+			nil,
+			"sensors.temperature",
+			nil,
+		},
+		{
+			"binary",
+			nil,
+			events.NewEvent("blob", []byte{0, 1, 2}),
+			nil,
+			"blob",
+			[]byte{0, 1, 2},
+		},
+		{
+			"publish error",
+			nil,
+			events.NewEvent("temperature", 21.5),
+			errPublish,
+			"",
+			nil,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			conn := &mockConn{err: tc.pubErr}
+			err := NewNATSHandler(conn, tc.subjectFn).Call(tc.ev)
+			if !errors.Is(err, tc.pubErr) {
+				t.Fatalf("call returned %v, want %v", err, tc.pubErr)
+			}
+			if tc.pubErr != nil {
+				return
+			}
+			if len(conn.msgs) != 1 {
+				t.Fatalf("published %d messages, want 1", len(conn.msgs))
+			}
+			msg := conn.msgs[0]
+			if msg.subject != tc.wantSubject {
+				t.Errorf("subject = %q, want %q", msg.subject, tc.wantSubject)
+			}
+			if tc.wantData != nil {
+				if !bytes.Equal(msg.data, tc.wantData) {
+					t.Errorf("data = %v, want %v", msg.data, tc.wantData)
+				}
+				return
+			}
+			ev, err := events.UnmarshalEvent(msg.data)
+			if err != nil {
+				t.Fatalf("can't decode %s: %s", msg.data, err)
+			}
+			if ev.GetType() != tc.ev.GetType() || ev.(events.Valuer).GetValue() != tc.ev.(events.Valuer).GetValue() {
+				t.Errorf("published %s, want %v", msg.data, tc.ev)
+			}
+		})
+	}
+}
@@ -0,0 +1,19 @@
+module github.com/rclancey/events/oauth2events
+
+go 1.18
+
+require (
+	github.com/rclancey/events v0.0.2
+	golang.org/x/oauth2 v0.13.0
+)
+
+require (
+	github.com/golang/protobuf v1.5.3 // indirect
+	github.com/rclancey/encoding-form v0.0.1 // indirect
+	github.com/rclancey/generic v0.0.2 // indirect
+	golang.org/x/net v0.16.0 // indirect
+	google.golang.org/appengine v1.6.7 // indirect
+	google.golang.org/protobuf v1.31.0 // indirect
+)
+
+replace github.com/rclancey/events => ../
@@ -0,0 +1,26 @@
+package oauth2events
+
+import (
+	"github.com/rclancey/events"
+	"golang.org/x/oauth2"
+)
+
+type tokenSource struct {
+	ts oauth2.TokenSource
+}
+
+// NewTokenSource adapts ts for use as a Webhook's TokenSource, sending
+// each token as "<type> <access token>", with a type of Bearer if the
+// token doesn't give one. Wrap ts in oauth2.ReuseTokenSource to refresh
+// the token only when it expires.
+func NewTokenSource(ts oauth2.TokenSource) events.TokenSource {
+	return &tokenSource{ts}
+}
+
+func (s *tokenSource) AuthHeader() (string, error) {
+	token, err := s.ts.Token()
+	if err != nil {
+		return "", err
+	}
+	return token.Type() + " " + token.AccessToken, nil
+}
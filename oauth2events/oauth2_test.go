@@ -0,0 +1,83 @@
+package oauth2events
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/rclancey/events"
+	"golang.org/x/oauth2"
+)
+
+// mockTokenSource hands out its tokens in turn, returning err instead
+// once they run out.
+type mockTokenSource struct {
+	tokens []*oauth2.Token
+	err error
+}
+
+func (ts *mockTokenSource) Token() (*oauth2.Token, error) {
+	if len(ts.tokens) == 0 {
+		return nil, ts.err
+	}
+	tok := ts.tokens[0]
+	ts.tokens = ts.tokens[1:]
+	return tok, nil
+}
+
+func TestTokenSource(t *testing.T) {
+	errToken := errors.New("token endpoint down")
+	tests := []struct {
+		name string
+		token *oauth2.Token
+		want string
+		wantErr error
+	}{
+		{"bearer", &oauth2.Token{AccessToken: "a", TokenType: "Bearer"}, "Bearer a", nil},
+		{"default type", &oauth2.Token{AccessToken: "a"}, "Bearer a", nil},
+		{"lower case bearer", &oauth2.Token{AccessToken: "a", TokenType: "bearer"}, "Bearer a", nil},
+		{"other type", &oauth2.Token{AccessToken: "a", TokenType: "MAC"}, "MAC a", nil},
+		{"fetch fails", nil, "", errToken},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mock := &mockTokenSource{err: errToken}
+			if tc.token != nil {
+				mock.tokens = []*oauth2.Token{tc.token}
+			}
+			got, err := NewTokenSource(mock).AuthHeader()
+			if !errors.Is(err, tc.wantErr) {
+				t.Errorf("error = %v, want %v", err, tc.wantErr)
+			}
+			if got != tc.want {
+				t.Errorf("header = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestWebhookTokenSource(t *testing.T) {
+	auth := make(chan string, 3)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth <- r.Header.Get("Authorization")
+	}))
+	defer srv.Close()
+	mock := &mockTokenSource{tokens: []*oauth2.Token{
+		{AccessToken: "a", TokenType: "Bearer", Expiry: time.Now().Add(-time.Minute)},
+		{AccessToken: "b", TokenType: "Bearer", Expiry: time.Now().Add(time.Hour)},
+	}}
+	hook := &events.Webhook{Method: http.MethodPost, URL: srv.URL, TokenSource: NewTokenSource(oauth2.ReuseTokenSource(nil, mock))}
+	fn := hook.Func()
+	// the first token has expired, so it is refreshed once and then reused
+	want := []string{"Bearer a", "Bearer b", "Bearer b"}
+	for i, w := range want {
+		if err := fn(events.NewEvent("temp", float64(i))); err != nil {
+			t.Fatalf("webhook failed: %s", err)
+		}
+		if got := <-auth; got != w {
+			t.Errorf("call %d: Authorization = %q, want %q", i, got, w)
+		}
+	}
+}
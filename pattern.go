@@ -0,0 +1,98 @@
+package events
+
+import (
+	"path"
+	"sort"
+)
+
+// AddPatternListener adds a listener on a pattern of event types, as for
+// path.Match, which receives every event whose type matches it, so
+// "sensor.*" matches "sensor.temp" and "sensor.humidity". A malformed
+// pattern matches nothing. Listeners added by AddEventListener always
+// match their type exactly, even one containing "*", "?" or "[". Listeners
+// on patterns are called after those on the event's exact type; see
+// AddEventListenerWithPriority. Remove a pattern listener with
+// RemoveEventListener, passing the pattern.
+func (es *basicEventSink) AddPatternListener(pattern string, handler EventHandler) {
+	es.addEventListener(pattern, "", 0, true, handler)
+}
+
+// AddPatternListenerWithPriority adds a pattern listener that is called
+// before the lower priority pattern listeners matching the same event.
+func (es *basicEventSink) AddPatternListenerWithPriority(pattern string, priority int, handler EventHandler) {
+	es.addEventListener(pattern, "", priority, true, handler)
+}
+
+// isPatternListener reports whether h was added on eventType as a pattern,
+// rather than as an exact type. The caller must hold the mutex.
+func (es *basicEventSink) isPatternListener(eventType string, h EventHandler) bool {
+	_, ok := es.patternOrder[listenerKey{eventType, h.ID()}]
+	return ok
+}
+
+// addPattern records a listener added under a pattern. The caller must
+// hold the mutex.
+func (es *basicEventSink) addPattern(pattern string, id int64) {
+	es.patterns[pattern] = true
+	es.patternSeq += 1
+	es.patternOrder[listenerKey{pattern, id}] = es.patternSeq
+}
+
+// matchPatterns returns the listeners on patterns matching eventType,
+// higher priority first, then in the order they were added, regardless
+// of pattern. The caller must hold the mutex.
+func (es *basicEventSink) matchPatterns(eventType string) []typedListener {
+	out := []typedListener{}
+	if len(es.patterns) == 0 {
+		return out
+	}
+	for pattern := range es.patterns {
+		if ok, _ := path.Match(pattern, eventType); !ok {
+			continue
+		}
+		for _, h := range es.listeners[pattern] {
+			if es.isPatternListener(pattern, h) {
+				out = append(out, typedListener{pattern, h})
+			}
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		ki := listenerKey{out[i].eventType, out[i].handler.ID()}
+		kj := listenerKey{out[j].eventType, out[j].handler.ID()}
+		if es.priorities[ki] != es.priorities[kj] {
+			return es.priorities[ki] > es.priorities[kj]
+		}
+		return es.patternOrder[ki] < es.patternOrder[kj]
+	})
+	return out
+}
+
+// AddPatternListener adds a listener to the underlying sink on the prefix
+// followed by pattern, so it matches the prefixed types whose remainder
+// matches pattern. Characters of the prefix special to path.Match keep
+// their meaning there.
+func (es *PrefixedEventSource) AddPatternListener(pattern string, handler EventHandler) {
+	es.AddPatternListenerWithPriority(pattern, 0, handler)
+}
+
+func (es *PrefixedEventSource) AddPatternListenerWithPriority(pattern string, priority int, handler EventHandler) {
+	addPatternListener(es.EventSink, es.prefix+pattern, priority, handler)
+}
+
+func (es *ScopedEventSink) AddPatternListener(pattern string, handler EventHandler) {
+	es.AddPatternListenerWithPriority(pattern, 0, handler)
+}
+
+func (es *ScopedEventSink) AddPatternListenerWithPriority(pattern string, priority int, handler EventHandler) {
+	if es.track(pattern, handler) {
+		addPatternListener(es.EventSink, pattern, priority, handler)
+	}
+}
+
+func (b *Broadcaster) AddPatternListener(pattern string, handler EventHandler) {
+	addPatternListener(b.EventSink, pattern, 0, handler)
+}
+
+func (b *Broadcaster) AddPatternListenerWithPriority(pattern string, priority int, handler EventHandler) {
+	addPatternListener(b.EventSink, pattern, priority, handler)
+}
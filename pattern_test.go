@@ -0,0 +1,97 @@
+package events
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestPatternListener(t *testing.T) {
+	fired := []string{"sensor.temp", "sensor.*", "door", "sensor.temp.max"}
+	tests := []struct {
+		name string
+		exact []string
+		patterns []string
+		wantExact []string
+		wantPattern []string
+	}{
+		{"exact type with a star", []string{"sensor.*"}, nil, []string{"sensor.*"}, []string{}},
+		{"pattern", nil, []string{"sensor.*"}, []string{}, []string{"sensor.temp", "sensor.*", "sensor.temp.max"}},
+		{"both on the same type", []string{"sensor.*"}, []string{"sensor.*"}, []string{"sensor.*"}, []string{"sensor.temp", "sensor.*", "sensor.temp.max"}},
+		{"question mark", []string{"doo?"}, []string{"doo?"}, []string{}, []string{"door"}},
+		{"malformed", []string{"sensor.["}, []string{"sensor.["}, []string{}, []string{}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			sink := NewSyncEventSink(time.Hour)
+			exact := RecordingHandler()
+			pattern := RecordingHandler()
+			for _, eventType := range tc.exact {
+				sink.AddEventListener(eventType, exact)
+			}
+			for _, p := range tc.patterns {
+				sink.(PatternSink).AddPatternListener(p, pattern)
+			}
+			for _, eventType := range fired {
+				sink.Emit(eventType, 1.0)
+			}
+			if got := eventTypes(exact.Calls()); !reflect.DeepEqual(got, tc.wantExact) {
+				t.Errorf("exact listener got %v, want %v", got, tc.wantExact)
+			}
+			if got := eventTypes(pattern.Calls()); !reflect.DeepEqual(got, tc.wantPattern) {
+				t.Errorf("pattern listener got %v, want %v", got, tc.wantPattern)
+			}
+		})
+	}
+}
+
+func eventTypes(evs []Event) []string {
+	out := []string{}
+	for _, ev := range evs {
+		out = append(out, ev.GetType())
+	}
+	return out
+}
+
+func TestPatternListenerRemove(t *testing.T) {
+	sink := NewSyncEventSink(time.Hour)
+	exact := RecordingHandler()
+	pattern := RecordingHandler()
+	sink.AddEventListener("sensor.*", exact)
+	sink.(PatternSink).AddPatternListener("sensor.*", pattern)
+	sink.RemoveEventListener("sensor.*", pattern)
+	sink.Emit("sensor.temp", 1.0)
+	sink.Emit("sensor.*", 1.0)
+	if n := len(pattern.Calls()); n != 0 {
+		t.Errorf("removed pattern listener called %d times", n)
+	}
+	if got := eventTypes(exact.Calls()); !reflect.DeepEqual(got, []string{"sensor.*"}) {
+		t.Errorf("exact listener got %v, want [sensor.*]", got)
+	}
+}
+
+func TestPatternListenerViews(t *testing.T) {
+	tests := []struct {
+		name string
+		view func(sink EventSink) EventSink
+		pattern string
+		fire string
+	}{
+		{"prefixed", func(sink EventSink) EventSink { return NewPrefixedEventSource("kitchen", sink) }, "*", "kitchen-temp"},
+		{"scoped", func(sink EventSink) EventSink { return NewScopedSink(context.Background(), sink) }, "sensor.*", "sensor.temp"},
+		{"broadcaster", func(sink EventSink) EventSink { return NewBroadcaster(sink, NewSyncEventSink(time.Hour)) }, "sensor.*", "sensor.temp"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			sink := NewSyncEventSink(time.Hour)
+			rec := RecordingHandler()
+			tc.view(sink).(PatternSink).AddPatternListener(tc.pattern, rec)
+			sink.Emit(tc.fire, 1.0)
+			sink.Emit("other", 1.0)
+			if got := eventTypes(rec.Calls()); !reflect.DeepEqual(got, []string{tc.fire}) {
+				t.Errorf("pattern listener got %v, want [%s]", got, tc.fire)
+			}
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package events
+
+// DefaultMaxPending is the number of events a paused sink holds for
+// delivery on Resume, unless set otherwise with SinkPauseQueue.
+const DefaultMaxPending = 1024
+
+// SinkPauseQueue sets the number of events a paused sink holds for
+// delivery on Resume.
+func SinkPauseQueue(maxPending int) SinkOption {
+	return func(es *basicEventSink) {
+		es.maxPending = maxPending
+	}
+}
+
+// Pause stops the sink from delivering events to listeners. Events fired
+// while paused are still logged, and are queued for delivery when Resume
+// is called. If more than the queue's capacity are fired, the oldest
+// queued events are dropped to make room, so they are logged but never
+// delivered.
+func (es *basicEventSink) Pause() {
+	es.mutex.Lock()
+	es.paused = true
+	es.mutex.Unlock()
+}
+
+// Resume restarts delivery of events, first delivering the events queued
+// while the sink was paused, in the order they were fired.
+func (es *basicEventSink) Resume() {
+	es.mutex.Lock()
+	if !es.paused {
+		es.mutex.Unlock()
+		return
+	}
+	es.paused = false
+	pending := es.pending
+	es.pending = nil
+	batches := make([][]typedListener, len(pending))
+	for i, ev := range pending {
+		batches[i] = es.matchListeners(ev.GetType())
+	}
+	es.mutex.Unlock()
+	es.dispatch(pending, batches)
+}
+
+// queue holds ev for delivery on Resume. The caller must hold the mutex.
+func (es *basicEventSink) queue(ev Event) {
+	if es.maxPending <= 0 {
+		return
+	}
+	if len(es.pending) >= es.maxPending {
+		n := len(es.pending) - es.maxPending + 1
+		es.pending = append(es.pending[:0], es.pending[n:]...)
+	}
+	es.pending = append(es.pending, ev)
+}
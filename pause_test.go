@@ -0,0 +1,88 @@
+package events
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestPause(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []SinkOption
+		before []float64
+		paused []float64
+		after []float64
+		wantPaused []float64
+		want []float64
+	}{
+		{"not paused", nil, []float64{1, 2}, nil, []float64{3}, []float64{1, 2}, []float64{1, 2, 3}},
+		{"fired while paused", nil, []float64{1}, []float64{2, 3}, []float64{4}, []float64{1}, []float64{1, 2, 3, 4}},
+		{"queue overflow", []SinkOption{SinkPauseQueue(2)}, nil, []float64{1, 2, 3, 4}, []float64{5}, []float64{}, []float64{3, 4, 5}},
+		{"no queue", []SinkOption{SinkPauseQueue(0)}, []float64{1}, []float64{2, 3}, []float64{4}, []float64{1}, []float64{1, 4}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			sink := NewSyncEventSink(time.Hour, tc.opts...)
+			rec := RecordingHandler()
+			sink.AddEventListener("test", rec)
+			for _, val := range tc.before {
+				sink.Emit("test", val)
+			}
+			if tc.paused != nil {
+				sink.(Pausable).Pause()
+				for _, val := range tc.paused {
+					sink.Emit("test", val)
+				}
+			}
+			if got := logValues(rec.Calls()); !reflect.DeepEqual(got, tc.wantPaused) {
+				t.Errorf("handled %v while paused, want %v", got, tc.wantPaused)
+			}
+			sink.(Pausable).Resume()
+			for _, val := range tc.after {
+				sink.Emit("test", val)
+			}
+			if got := logValues(rec.Calls()); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("handled %v, want %v", got, tc.want)
+			}
+			// everything is logged, delivered or not
+			n := len(tc.before) + len(tc.paused) + len(tc.after)
+			if got := logValues(filterLog(sink.Log(), "test")); len(got) != n {
+				t.Errorf("logged %v, want %d events", got, n)
+			}
+		})
+	}
+}
+
+func TestPauseDefaultQueue(t *testing.T) {
+	sink := NewSyncEventSink(time.Hour)
+	rec := RecordingHandler()
+	sink.AddEventListener("test", rec)
+	sink.(Pausable).Pause()
+	for i := 0; i < DefaultMaxPending + 10; i++ {
+		sink.Emit("test", float64(i))
+	}
+	sink.(Pausable).Resume()
+	calls := rec.Calls()
+	if len(calls) != DefaultMaxPending {
+		t.Fatalf("delivered %d events, want %d", len(calls), DefaultMaxPending)
+	}
+	if first := calls[0].(Valuer).GetValue(); first != 10 {
+		t.Errorf("first delivered event is %g, want 10", first)
+	}
+}
+
+func TestPauseAsync(t *testing.T) {
+	sink := NewEventSink(time.Hour)
+	rec := RecordingHandler()
+	sink.AddEventListener("test", rec)
+	sink.(Pausable).Pause()
+	sink.Emit("test", 1.0)
+	if rec.WaitForCalls(1, 20 * time.Millisecond) {
+		t.Fatal("paused sink delivered an event")
+	}
+	sink.(Pausable).Resume()
+	if !rec.WaitForCalls(1, time.Second) {
+		t.Fatal("queued event wasn't delivered on resume")
+	}
+}
@@ -0,0 +1,143 @@
+package events
+
+import (
+	"sync"
+)
+
+// SinkMaxConcurrentDispatch caps the number of handler calls a sink runs
+// at once. Rather than starting a goroutine per handler per event, the
+// sink runs at most n goroutines, and calls arriving while they are all
+// busy wait in a queue, in the order they were dispatched. A cap <= 0
+// means no cap. Synchronous sinks call handlers inline and ignore it.
+func SinkMaxConcurrentDispatch(n int) SinkOption {
+	return func(es *basicEventSink) {
+		es.maxDispatch = n
+	}
+}
+
+// SinkDropOnSaturation makes a sink with a SinkMaxConcurrentDispatch cap
+// drop handler calls that arrive while every slot is busy, instead of
+// queueing them. Dropped calls are reported by EventTypeDispatchDropped
+// events carrying a ListenerMeta, whose listeners are called outside the
+// cap. Drops are coalesced: each report counts, in Dropped, the calls to
+// one listener dropped since the last report for it, so a burst of drops
+// produces a few reports rather than one per drop.
+func SinkDropOnSaturation() SinkOption {
+	return func(es *basicEventSink) {
+		es.dropOnSaturation = true
+	}
+}
+
+func newDispatchPool(size int, drop bool) *dispatchPool {
+	return &dispatchPool{size: size, drop: drop, mutex: &sync.Mutex{}}
+}
+
+type dispatchPool struct {
+	size int
+	drop bool
+	busy int
+	queue []func()
+	dropped map[listenerKey]int
+	reporting bool
+	mutex *sync.Mutex
+}
+
+// submit runs fn on the pool, reporting false if it was dropped.
+func (p *dispatchPool) submit(fn func()) bool {
+	p.mutex.Lock()
+	if p.busy < p.size {
+		p.busy += 1
+		p.mutex.Unlock()
+		go p.run(fn)
+		return true
+	}
+	if p.drop {
+		p.mutex.Unlock()
+		return false
+	}
+	p.queue = append(p.queue, fn)
+	p.mutex.Unlock()
+	return true
+}
+
+// recordDrop counts a dropped call, reporting true if no report of drops
+// is under way, so the caller should start one.
+func (p *dispatchPool) recordDrop(key listenerKey) bool {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if p.dropped == nil {
+		p.dropped = map[listenerKey]int{}
+	}
+	p.dropped[key] += 1
+	if p.reporting {
+		return false
+	}
+	p.reporting = true
+	return true
+}
+
+// takeDrops returns and clears the drops counted since it was last called.
+// When there are none, the report under way is over.
+func (p *dispatchPool) takeDrops() map[listenerKey]int {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	drops := p.dropped
+	p.dropped = nil
+	if len(drops) == 0 {
+		p.reporting = false
+	}
+	return drops
+}
+
+// run calls fn, then works through the queue until it is empty.
+func (p *dispatchPool) run(fn func()) {
+	for {
+		fn()
+		p.mutex.Lock()
+		if len(p.queue) == 0 {
+			p.busy -= 1
+			p.mutex.Unlock()
+			return
+		}
+		fn = p.queue[0]
+		p.queue[0] = nil
+		p.queue = p.queue[1:]
+		p.mutex.Unlock()
+	}
+}
+
+// spawn calls es.call in the background, through the pool if the sink has
+// one.
+func (es *basicEventSink) spawn(eventType string, h EventHandler, ev Event) {
+	// reports of dropped calls bypass the pool, since they would otherwise
+	// be dropped along with the calls they report
+	if es.pool == nil || ev.GetType() == EventTypeDispatchDropped {
+		go es.call(eventType, h, ev)
+		return
+	}
+	if es.pool.submit(func() { es.call(eventType, h, ev) }) {
+		return
+	}
+	if es.pool.recordDrop(listenerKey{eventType, h.ID()}) {
+		go es.reportDrops()
+	}
+}
+
+// reportDrops emits an EventTypeDispatchDropped event for each listener
+// with dropped calls, then again for the calls dropped meanwhile, until
+// there are none left.
+func (es *basicEventSink) reportDrops() {
+	for {
+		drops := es.pool.takeDrops()
+		if len(drops) == 0 {
+			return
+		}
+		for key, n := range drops {
+			es.Emit(EventTypeDispatchDropped, &ListenerMeta{
+				EventType: key.eventType,
+				HandlerID: key.id,
+				Dropped: n,
+			})
+		}
+	}
+}
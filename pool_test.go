@@ -0,0 +1,173 @@
+package events
+
+import (
+	"reflect"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// blockingHandler returns a handler that counts its calls and the most
+// calls it has been in at once, and blocks each call until release is
+// closed.
+func blockingHandler(release chan bool, calls, active, peak *int32) EventHandler {
+	return NewEventHandler(func(ev Event) error {
+		n := atomic.AddInt32(active, 1)
+		for {
+			p := atomic.LoadInt32(peak)
+			if n <= p || atomic.CompareAndSwapInt32(peak, p, n) {
+				break
+			}
+		}
+		<-release
+		atomic.AddInt32(active, -1)
+		atomic.AddInt32(calls, 1)
+		return nil
+	})
+}
+
+func TestSinkMaxConcurrentDispatch(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []SinkOption
+		fires int
+		wantPeak int32
+		wantCalls int32
+		wantDropped int
+	}{
+		{"no cap", nil, 10, 10, 10, 0},
+		{"cap of one", []SinkOption{SinkMaxConcurrentDispatch(1)}, 10, 1, 10, 0},
+		{"cap of three", []SinkOption{SinkMaxConcurrentDispatch(3)}, 10, 3, 10, 0},
+		{"cap above load", []SinkOption{SinkMaxConcurrentDispatch(20)}, 10, 10, 10, 0},
+		{"drop", []SinkOption{SinkMaxConcurrentDispatch(2), SinkDropOnSaturation()}, 10, 2, 2, 8},
+		{"drop without cap", []SinkOption{SinkDropOnSaturation()}, 10, 10, 10, 0},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			sink := NewEventSink(time.Hour, tc.opts...)
+			release := make(chan bool)
+			var calls, active, peak int32
+			sink.AddEventListener("test", blockingHandler(release, &calls, &active, &peak))
+			dropped := RecordingHandler()
+			sink.AddEventListener(EventTypeDispatchDropped, dropped)
+			for i := 0; i < tc.fires; i++ {
+				sink.Emit("test", float64(i))
+			}
+			deadline := time.Now().Add(time.Second)
+			for atomic.LoadInt32(&peak) < tc.wantPeak && time.Now().Before(deadline) {
+				time.Sleep(time.Millisecond)
+			}
+			// give any calls over the cap a chance to start
+			time.Sleep(10 * time.Millisecond)
+			if p := atomic.LoadInt32(&peak); p != tc.wantPeak {
+				t.Errorf("%d calls at once, want %d", p, tc.wantPeak)
+			}
+			close(release)
+			for atomic.LoadInt32(&calls) < tc.wantCalls && time.Now().Before(deadline) {
+				time.Sleep(time.Millisecond)
+			}
+			if n := atomic.LoadInt32(&calls); n != tc.wantCalls {
+				t.Errorf("%d calls, want %d", n, tc.wantCalls)
+			}
+			n := 0
+			for n < tc.wantDropped && time.Now().Before(deadline) {
+				n = 0
+				for _, ev := range dropped.Calls() {
+					meta, ok := ev.GetData().(*ListenerMeta)
+					if !ok || meta.EventType != "test" || meta.Dropped < 1 {
+						t.Fatalf("dropped call reported with %#v", ev.GetData())
+					}
+					n += meta.Dropped
+				}
+				time.Sleep(time.Millisecond)
+			}
+			if n != tc.wantDropped {
+				t.Errorf("%d dropped calls reported, want %d", n, tc.wantDropped)
+			}
+			if reports := len(dropped.Calls()); reports > tc.wantDropped {
+				t.Errorf("%d reports of %d dropped calls", reports, tc.wantDropped)
+			}
+		})
+	}
+}
+
+func TestDispatchPoolDrops(t *testing.T) {
+	p := newDispatchPool(1, true)
+	a := listenerKey{"test", 1}
+	b := listenerKey{"test", 2}
+	if !p.recordDrop(a) {
+		t.Error("first drop didn't start a report")
+	}
+	for i := 0; i < 4; i++ {
+		if p.recordDrop(a) {
+			t.Error("drop started a second report")
+		}
+	}
+	p.recordDrop(b)
+	if got := p.takeDrops(); !reflect.DeepEqual(got, map[listenerKey]int{a: 5, b: 1}) {
+		t.Errorf("drops = %v, want 5 for a and 1 for b", got)
+	}
+	if p.recordDrop(a) {
+		t.Error("drop started a report while one is under way")
+	}
+	if got := p.takeDrops(); !reflect.DeepEqual(got, map[listenerKey]int{a: 1}) {
+		t.Errorf("drops = %v, want 1 for a", got)
+	}
+	if got := p.takeDrops(); len(got) != 0 {
+		t.Errorf("drops = %v, want none", got)
+	}
+	if !p.recordDrop(a) {
+		t.Error("drop after the report ended didn't start a new one")
+	}
+}
+
+func TestSinkMaxConcurrentDispatchSync(t *testing.T) {
+	sink := NewSyncEventSink(time.Hour, SinkMaxConcurrentDispatch(1), SinkDropOnSaturation())
+	rec := RecordingHandler()
+	sink.AddEventListener("test", rec)
+	sink.AddEventListener("test", NewEventHandler(func(ev Event) error { return nil }))
+	for i := 0; i < 5; i++ {
+		sink.Emit("test", float64(i))
+	}
+	if n := len(rec.Calls()); n != 5 {
+		t.Errorf("%d calls on a synchronous sink, want 5", n)
+	}
+}
+
+func BenchmarkDispatchPool(b *testing.B) {
+	sinks := []struct {
+		name string
+		opts []SinkOption
+	}{
+		{"unbounded", nil},
+		{"pool of 4", []SinkOption{SinkMaxConcurrentDispatch(4)}},
+		{"pool of 64", []SinkOption{SinkMaxConcurrentDispatch(64)}},
+	}
+	for _, bc := range sinks {
+		b.Run(bc.name, func(b *testing.B) {
+			sink := NewEventSink(time.Millisecond, bc.opts...)
+			wg := &sync.WaitGroup{}
+			for i := 0; i < 10; i++ {
+				sink.AddEventListener("test", NewEventHandler(func(ev Event) error {
+					time.Sleep(10 * time.Microsecond)
+					wg.Done()
+					return nil
+				}))
+			}
+			peak := runtime.NumGoroutine()
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				wg.Add(10)
+				sink.Emit("test", float64(i))
+				if n := runtime.NumGoroutine(); n > peak {
+					peak = n
+				}
+			}
+			wg.Wait()
+			b.ReportMetric(float64(peak), "peak-goroutines")
+		})
+	}
+}
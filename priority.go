@@ -0,0 +1,94 @@
+package events
+
+import (
+	"errors"
+	"strings"
+)
+
+// ErrStopPropagation can be returned by a handler to keep the listeners
+// after it in the dispatch order from being called with the event. It is
+// honored by synchronous sinks and by FireCollect, which call listeners
+// one at a time, and isn't reported as a failure there. A sink that calls
+// listeners concurrently starts them all before any can return, so it
+// can't honor ErrStopPropagation; it reports it as a failure instead, on
+// Errors and as an EventTypeHandlerError event, so the mistake is seen.
+var ErrStopPropagation = errors.New("stop propagation")
+
+// AddEventListenerWithPriority adds a listener that is called before the
+// lower priority listeners on the same event type. AddEventListener adds
+// listeners with priority 0.
+//
+// Listeners receive an event in a fixed order. They are grouped by the
+// type they were registered under: the event's own type, then the types it
+// is aliased to. In a hierarchical sink, each ancestor type follows in the
+// same way, in bubbling order, so with BubbleRootFirst the root's group
+// comes first. Within a group, higher priority listeners come first, and
+// listeners of equal priority come in the order they were added. Exact
+// listeners come before pattern listeners (see AddPatternListener): once
+// every group of exact listeners has been called, the listeners on
+// matching patterns follow, higher priority first, then in the order they
+// were added, whatever their pattern. DispatchOrder reports this order,
+// and a listener returning ErrStopPropagation on a synchronous sink stops
+// the rest of it, so an exact listener can keep an event from pattern
+// listeners.
+func (es *basicEventSink) AddEventListenerWithPriority(eventType string, priority int, handler EventHandler) {
+	es.addEventListener(eventType, "", priority, false, handler)
+}
+
+// insertListener adds handler after every listener on eventType with at
+// least its priority. The listener slice is replaced rather than modified
+// in place. The caller must hold the mutex.
+func (es *basicEventSink) insertListener(eventType string, priority int, handler EventHandler) {
+	old := es.listeners[eventType]
+	i := len(old)
+	for i > 0 && es.priorities[listenerKey{eventType, old[i-1].ID()}] < priority {
+		i -= 1
+	}
+	if i == len(old) {
+		es.listeners[eventType] = append(old, handler)
+	} else {
+		listeners := make([]EventHandler, 0, len(old)+1)
+		listeners = append(listeners, old[:i]...)
+		listeners = append(listeners, handler)
+		listeners = append(listeners, old[i:]...)
+		es.listeners[eventType] = listeners
+	}
+	if priority != 0 {
+		es.priorities[listenerKey{eventType, handler.ID()}] = priority
+	}
+}
+
+// DispatchOrder returns the listeners an event of the given type would be
+// dispatched to, in the order they would be called.
+func (es *basicEventSink) DispatchOrder(eventType string) []ListenerMeta {
+	es.mutex.Lock()
+	listeners := es.matchListeners(eventType)
+	es.mutex.Unlock()
+	out := make([]ListenerMeta, len(listeners))
+	for i, l := range listeners {
+		out[i] = ListenerMeta{EventType: l.eventType, HandlerID: l.handler.ID()}
+	}
+	return out
+}
+
+func (es *PrefixedEventSource) AddEventListenerWithPriority(eventType string, priority int, handler EventHandler) {
+	addEventListenerWithPriority(es.EventSink, es.prefix+eventType, priority, handler)
+}
+
+func (es *PrefixedEventSource) DispatchOrder(eventType string) []ListenerMeta {
+	li, ok := es.EventSink.(ListenerInspector)
+	if !ok {
+		return nil
+	}
+	out := li.DispatchOrder(es.prefix+eventType)
+	for i := range out {
+		out[i].EventType = strings.TrimPrefix(out[i].EventType, es.prefix)
+	}
+	return out
+}
+
+func (es *ScopedEventSink) AddEventListenerWithPriority(eventType string, priority int, handler EventHandler) {
+	if es.track(eventType, handler) {
+		addEventListenerWithPriority(es.EventSink, eventType, priority, handler)
+	}
+}
@@ -0,0 +1,172 @@
+package events
+
+import (
+	"errors"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type prioritizedListener struct {
+	name string
+	eventType string
+	priority int
+}
+
+// orderedSink adds a listener to sink for each of listeners, returning a
+// function listing the names of the listeners called so far, in the order
+// they were called. The listener named stop returns
+// ErrStopPropagation. Listener types containing "*" are added as patterns.
+func orderedSink(sink EventSink, listeners []prioritizedListener, stop string) func() []string {
+	called := []string{}
+	mutex := &sync.Mutex{}
+	for _, l := range listeners {
+		name := l.name
+		h := NewEventHandler(func(ev Event) error {
+			mutex.Lock()
+			called = append(called, name)
+			mutex.Unlock()
+			if name == stop {
+				return ErrStopPropagation
+			}
+			return nil
+		})
+		if strings.Contains(l.eventType, "*") {
+			sink.(PatternSink).AddPatternListenerWithPriority(l.eventType, l.priority, h)
+		} else {
+			sink.(ListenerManager).AddEventListenerWithPriority(l.eventType, l.priority, h)
+		}
+	}
+	return func() []string {
+		mutex.Lock()
+		defer mutex.Unlock()
+		return append([]string{}, called...)
+	}
+}
+
+func TestDispatchOrder(t *testing.T) {
+	tests := []struct {
+		name string
+		listeners []prioritizedListener
+		want []string
+	}{
+		{
+			"registration order",
+			[]prioritizedListener{{"a", "sensor.temp", 0}, {"b", "sensor.temp", 0}, {"c", "sensor.temp", 0}},
+			[]string{"a", "b", "c"},
+		},
+		{
+			"priority",
+			[]prioritizedListener{{"a", "sensor.temp", 0}, {"b", "sensor.temp", 5}, {"c", "sensor.temp", -1}, {"d", "sensor.temp", 5}},
+			[]string{"b", "d", "a", "c"},
+		},
+		{
+			"exact before pattern",
+			[]prioritizedListener{{"p", "sensor.*", 10}, {"e", "sensor.temp", 0}},
+			[]string{"e", "p"},
+		},
+		{
+			"patterns by priority then registration",
+			[]prioritizedListener{{"p1", "sensor.*", 0}, {"p2", "*.temp", 5}, {"p3", "sensor.*", 5}, {"p4", "*.*", 0}},
+			[]string{"p2", "p3", "p1", "p4"},
+		},
+		{
+			"overlapping",
+			[]prioritizedListener{
+				{"any", "*.*", 0},
+				{"low", "sensor.temp", -5},
+				{"sensors", "sensor.*", 1},
+				{"high", "sensor.temp", 5},
+				{"mid", "sensor.temp", 0},
+				{"other", "sensor.humidity", 10},
+			},
+			[]string{"high", "mid", "low", "sensors", "any"},
+		},
+		{
+			"no match",
+			[]prioritizedListener{{"other", "door", 0}, {"p", "door.*", 0}},
+			[]string{},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			useSequentialIDs(t)
+			sink := NewSyncEventSink(time.Hour)
+			called := orderedSink(sink, tc.listeners, "")
+			names := map[int64]string{}
+			for i, l := range tc.listeners {
+				// sequential IDs start at 1, in the order added
+				names[int64(i + 1)] = l.name
+			}
+			order := []string{}
+			for _, meta := range sink.(ListenerInspector).DispatchOrder("sensor.temp") {
+				order = append(order, names[meta.HandlerID])
+			}
+			if !reflect.DeepEqual(order, tc.want) {
+				t.Errorf("dispatch order = %v, want %v", order, tc.want)
+			}
+			sink.Emit("sensor.temp", 1.0)
+			if got := called(); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("called %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestDispatchOrderStopPropagation(t *testing.T) {
+	listeners := []prioritizedListener{
+		{"pattern", "sensor.*", 10},
+		{"low", "sensor.temp", 0},
+		{"high", "sensor.temp", 5},
+	}
+	tests := []struct {
+		name string
+		sink func() EventSink
+		stop string
+		want []string
+		wantErrors int
+	}{
+		{"sync, no stop", func() EventSink { return NewSyncEventSink(time.Hour) }, "", []string{"high", "low", "pattern"}, 0},
+		{"sync, exact stops pattern", func() EventSink { return NewSyncEventSink(time.Hour) }, "low", []string{"high", "low"}, 0},
+		{"sync, first stops all", func() EventSink { return NewSyncEventSink(time.Hour) }, "high", []string{"high"}, 0},
+		{"sync, last stops nothing", func() EventSink { return NewSyncEventSink(time.Hour) }, "pattern", []string{"high", "low", "pattern"}, 0},
+		{"async", func() EventSink { return NewEventSink(time.Hour) }, "high", []string{"high", "low", "pattern"}, 1},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			sink := tc.sink()
+			called := orderedSink(sink, listeners, tc.stop)
+			sink.Emit("sensor.temp", 1.0)
+			deadline := time.Now().Add(time.Second)
+			for len(called()) < len(tc.want) && time.Now().Before(deadline) {
+				time.Sleep(time.Millisecond)
+			}
+			got := called()
+			want := append([]string{}, tc.want...)
+			if tc.wantErrors > 0 {
+				// an asynchronous sink calls them in no particular order
+				sort.Strings(got)
+				sort.Strings(want)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("called %v, want %v", got, want)
+			}
+			errs := []HandlerError{}
+			for len(errs) < tc.wantErrors && time.Now().Before(deadline) {
+				errs = append(errs, drainErrors(sink.(ErrorSource).Errors())...)
+				time.Sleep(time.Millisecond)
+			}
+			if len(errs) != tc.wantErrors {
+				t.Fatalf("%d errors, want %d", len(errs), tc.wantErrors)
+			}
+			for _, he := range errs {
+				if !errors.Is(he, ErrStopPropagation) {
+					t.Errorf("error = %s, want %s", he, ErrStopPropagation)
+				}
+			}
+		})
+	}
+}
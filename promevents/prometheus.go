@@ -0,0 +1,55 @@
+package promevents
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/rclancey/events"
+)
+
+var (
+	firedDesc = prometheus.NewDesc(
+		"events_fired_total",
+		"Number of events fired, by event type.",
+		[]string{"event_type"}, nil,
+	)
+	errorsDesc = prometheus.NewDesc(
+		"events_handler_errors_total",
+		"Number of handler failures, by the event type the handler listens for.",
+		[]string{"event_type"}, nil,
+	)
+	listenersDesc = prometheus.NewDesc(
+		"events_listeners",
+		"Number of listeners currently registered, by event type.",
+		[]string{"event_type"}, nil,
+	)
+)
+
+type collector struct {
+	sink events.EventSink
+}
+
+// NewPrometheusCollector returns a collector exposing a sink's counters
+// from StatsSink.Counters: events fired and handler errors per event type,
+// and the current number of listeners per event type. Register it with a
+// prometheus.Registerer to export them. A sink that isn't a StatsSink
+// exports no metrics.
+func NewPrometheusCollector(sink events.EventSink) prometheus.Collector {
+	return &collector{sink}
+}
+
+func (c *collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- firedDesc
+	ch <- errorsDesc
+	ch <- listenersDesc
+}
+
+func (c *collector) Collect(ch chan<- prometheus.Metric) {
+	ss, ok := c.sink.(events.StatsSink)
+	if !ok {
+		return
+	}
+	for eventType, counts := range ss.Counters() {
+		ch <- prometheus.MustNewConstMetric(firedDesc, prometheus.CounterValue, float64(counts.Fired), eventType)
+		ch <- prometheus.MustNewConstMetric(errorsDesc, prometheus.CounterValue, float64(counts.Errors), eventType)
+		ch <- prometheus.MustNewConstMetric(listenersDesc, prometheus.GaugeValue, float64(counts.Listeners), eventType)
+	}
+}
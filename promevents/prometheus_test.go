@@ -0,0 +1,96 @@
+package promevents
+
+import (
+	"errors"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/rclancey/events"
+)
+
+// scrape gathers the metrics from a registry holding only a collector for
+// sink, as metric name to event type to value.
+func scrape(t *testing.T, sink events.EventSink) map[string]map[string]float64 {
+	t.Helper()
+	reg := prometheus.NewPedanticRegistry()
+	if err := reg.Register(NewPrometheusCollector(sink)); err != nil {
+		t.Fatalf("can't register collector: %s", err)
+	}
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatalf("can't gather metrics: %s", err)
+	}
+	out := map[string]map[string]float64{}
+	for _, mf := range families {
+		vals := map[string]float64{}
+		for _, m := range mf.GetMetric() {
+			eventType := ""
+			for _, label := range m.GetLabel() {
+				if label.GetName() == "event_type" {
+					eventType = label.GetValue()
+				}
+			}
+			if m.GetCounter() != nil {
+				vals[eventType] = m.GetCounter().GetValue()
+			} else {
+				vals[eventType] = m.GetGauge().GetValue()
+			}
+		}
+		out[mf.GetName()] = vals
+	}
+	return out
+}
+
+type plainSink struct {
+	events.EventSink
+}
+
+func TestPrometheusCollector(t *testing.T) {
+	sink := events.NewSyncEventSink(time.Minute)
+	sink.AddEventListener("temp", events.NewEventHandler(func(events.Event) error { return nil }))
+	sink.AddEventListener("door", events.NewEventHandler(func(events.Event) error { return errors.New("stuck") }))
+	sink.AddEventListener("door", events.NewEventHandler(func(events.Event) error { return nil }))
+	sink.Emit("temp", 1.0)
+	sink.Emit("temp", 2.0)
+	sink.Emit("door", "open")
+	tests := []struct {
+		name string
+		sink events.EventSink
+		want map[string]map[string]float64
+	}{
+		{"stats sink", sink, map[string]map[string]float64{
+			"events_fired_total": {"temp": 2, "door": 1},
+			"events_handler_errors_total": {"temp": 0, "door": 1},
+			"events_listeners": {"temp": 1, "door": 2},
+		}},
+		{"plain sink", plainSink{sink}, map[string]map[string]float64{}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := scrape(t, tc.sink)
+			names := []string{}
+			for name := range got {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			for name, want := range tc.want {
+				for eventType, val := range want {
+					if got[name][eventType] != val {
+						t.Errorf("%s{event_type=%q} = %g, want %g", name, eventType, got[name][eventType], val)
+					}
+				}
+			}
+			wantNames := []string{}
+			for name := range tc.want {
+				wantNames = append(wantNames, name)
+			}
+			sort.Strings(wantNames)
+			if !reflect.DeepEqual(names, wantNames) {
+				t.Errorf("metric names = %v, want %v", names, wantNames)
+			}
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package events
+
+import (
+	"errors"
+	"time"
+)
+
+// SinkReapInterval makes a sink call PruneExpired every interval, so that
+// expired handlers are removed even if no more events of their type are
+// fired. The reaper runs until the sink is closed.
+func SinkReapInterval(interval time.Duration) SinkOption {
+	return func(es *basicEventSink) {
+		es.reapInterval = interval
+	}
+}
+
+func (es *basicEventSink) reap() {
+	ticker := time.NewTicker(es.reapInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			es.PruneExpired()
+		case <-es.done:
+			return
+		}
+	}
+}
+
+// Close shuts the sink down, giving its listeners a last chance to act.
+// In order, it:
+//
+//  1. fires an EventTypeSinkClosing event, calling its listeners in the
+//     calling goroutine, so that they can flush their state;
+//  2. marks the sink closed;
+//  3. stops the sink's background work and drains its listeners (see
+//     Drainable);
+//  4. removes every remaining listener, passing an EventTypeHandlerRemoved
+//     event for each to the removal listeners and closing it, all in the
+//     calling goroutine. Listeners for EventTypeHandlerRemoved are removed
+//     last, so that they see the other removals.
+//
+// Once the closing listeners return, the sink is closed for good: events
+// fired on it, even by listeners being drained or removed, are dropped
+// without being logged or dispatched, and listeners added to it are
+// ignored. It is safe to call Close more than once; calls after the first
+// do nothing.
+func (es *basicEventSink) Close() error {
+	es.closeOnce.Do(func() {
+		es.FireCollect(NewEvent(EventTypeSinkClosing, nil))
+		es.mutex.Lock()
+		es.closed = true
+		es.mutex.Unlock()
+		close(es.done)
+		es.drainListeners()
+		es.removeAll()
+	})
+	return nil
+}
+
+// fireRemoved passes ev, an EventTypeHandlerRemoved event, to its
+// listeners in the calling goroutine. On an open sink that's FireCollect;
+// once the sink is closed, the event skips the log and goes straight to the
+// listeners Close hasn't removed yet.
+func (es *basicEventSink) fireRemoved(ev Event) {
+	es.mutex.Lock()
+	closed := es.closed
+	var listeners []typedListener
+	if closed {
+		listeners = es.matchListeners(ev.GetType())
+	}
+	es.mutex.Unlock()
+	if !closed {
+		es.FireCollect(ev)
+		return
+	}
+	for _, l := range listeners {
+		_, err := es.callResult(l.eventType, l.handler, ev)
+		if errors.Is(err, ErrStopPropagation) {
+			break
+		}
+	}
+}
+
+// removeAll removes every listener inline, leaving listeners for
+// EventTypeHandlerRemoved until last.
+func (es *basicEventSink) removeAll() {
+	es.mutex.Lock()
+	all := []typedListener{}
+	last := []typedListener{}
+	for eventType, listeners := range es.listeners {
+		for _, h := range listeners {
+			if eventType == EventTypeHandlerRemoved {
+				last = append(last, typedListener{eventType, h})
+			} else {
+				all = append(all, typedListener{eventType, h})
+			}
+		}
+	}
+	es.mutex.Unlock()
+	for _, l := range append(all, last...) {
+		es.removeEventListener(l.eventType, l.handler, true)
+	}
+}
@@ -0,0 +1,181 @@
+package events
+
+import (
+	"reflect"
+	"runtime"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+// waitForListeners waits for sink to have n listeners for eventType, and
+// returns the number it has.
+func waitForListeners(sink EventSink, eventType string, n int, timeout time.Duration) int {
+	deadline := time.Now().Add(timeout)
+	for {
+		count := sink.(ListenerInspector).ListenerCount(eventType)
+		if count == n || time.Now().After(deadline) {
+			return count
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestSinkReapInterval(t *testing.T) {
+	tests := []struct {
+		name string
+		ttls []time.Duration
+		advance time.Duration
+		want int
+	}{
+		{"expired", []time.Duration{time.Minute}, 2 * time.Minute, 0},
+		{"not expired", []time.Duration{time.Hour}, time.Minute, 1},
+		{"some expired", []time.Duration{time.Second, time.Minute, time.Hour}, 2 * time.Minute, 1},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			c := useFakeClock(t)
+			sink := NewEventSink(time.Minute, SinkReapInterval(time.Millisecond))
+			defer sink.(Closer).Close()
+			for _, ttl := range tc.ttls {
+				sink.AddEventListener("quiet", WithTimeout(NewEventHandler(func(Event) error { return nil }), ttl))
+			}
+			c.Advance(tc.advance)
+			if n := waitForListeners(sink, "quiet", tc.want, time.Second); n != tc.want {
+				t.Errorf("%d listeners left, want %d", n, tc.want)
+			}
+			// give the reaper a few more rounds to remove too many
+			time.Sleep(10 * time.Millisecond)
+			if n := sink.(ListenerInspector).ListenerCount("quiet"); n != tc.want {
+				t.Errorf("%d listeners left, want %d", n, tc.want)
+			}
+		})
+	}
+}
+
+func TestSinkReapIntervalStopsOnClose(t *testing.T) {
+	baseline := runtime.NumGoroutine()
+	sink := NewEventSink(time.Minute, SinkReapInterval(time.Millisecond))
+	if n := runtime.NumGoroutine(); n <= baseline {
+		t.Fatalf("%d goroutines with a reaper, from %d", n, baseline)
+	}
+	sink.(Closer).Close()
+	if n := waitForGoroutines(baseline, time.Second); n > baseline {
+		t.Errorf("%d goroutines after Close, want %d", n, baseline)
+	}
+}
+
+func TestCloseOrder(t *testing.T) {
+	tests := []struct {
+		name string
+		sink EventSink
+	}{
+		{"sync", NewSyncEventSink(time.Minute)},
+		{"async", NewEventSink(time.Minute)},
+		{"ring", NewRingBufferEventSink(10, time.Minute)},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			sink := tc.sink
+			got := []string{}
+			mutex := &sync.Mutex{}
+			record := func(s string) {
+				mutex.Lock()
+				got = append(got, s)
+				mutex.Unlock()
+			}
+			sink.AddEventListener(EventTypeSinkClosing, NewEventHandler(func(ev Event) error {
+				record("closing")
+				// the sink still dispatches while closing
+				sink.(SyncSink).EmitSync("flush", 1.0)
+				return nil
+			}))
+			sink.AddEventListener("flush", NewEventHandler(func(ev Event) error {
+				record("flush")
+				return nil
+			}))
+			sink.AddEventListener("test", NewEventHandler(func(ev Event) error {
+				record("test")
+				return nil
+			}))
+			sink.AddEventListener(EventTypeHandlerRemoved, NewEventHandler(func(ev Event) error {
+				record("removed " + ev.GetData().(*ListenerMeta).EventType)
+				return nil
+			}))
+			sink.(Closer).Close()
+			mutex.Lock()
+			closed := append([]string{}, got...)
+			mutex.Unlock()
+			if len(closed) < 2 || closed[0] != "closing" || closed[1] != "flush" {
+				t.Fatalf("close called %v, want closing and flush first", closed)
+			}
+			removed := closed[2:]
+			sort.Strings(removed)
+			want := []string{"removed flush", "removed " + EventTypeSinkClosing, "removed test"}
+			if !reflect.DeepEqual(removed, want) {
+				t.Errorf("removals = %v, want %v", removed, want)
+			}
+			sink.AddEventListener("test", NewEventHandler(func(ev Event) error {
+				record("late")
+				return nil
+			}))
+			sink.Emit("test", 2.0)
+			time.Sleep(10 * time.Millisecond)
+			mutex.Lock()
+			after := got[len(closed):]
+			mutex.Unlock()
+			if len(after) > 0 {
+				t.Errorf("closed sink called %v", after)
+			}
+			if n := sink.(ListenerInspector).ListenerCount("test"); n != 0 {
+				t.Errorf("closed sink has %d listeners", n)
+			}
+			for _, ev := range sink.Log() {
+				if ev.GetType() == "test" {
+					t.Errorf("closed sink logged %v", ev)
+				}
+			}
+			if err := sink.(Closer).Close(); err != nil {
+				t.Errorf("second Close failed: %s", err)
+			}
+		})
+	}
+}
+
+func TestCloseIgnoresListenersAddedDuringTeardown(t *testing.T) {
+	tests := []struct {
+		name string
+		sink EventSink
+	}{
+		{"sync", NewSyncEventSink(time.Minute)},
+		{"async", NewEventSink(time.Minute)},
+		{"ring", NewRingBufferEventSink(10, time.Minute)},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			sink := tc.sink
+			removed := []string{}
+			mutex := &sync.Mutex{}
+			sink.AddEventListener("test", RecordingHandler())
+			sink.AddEventListener(EventTypeHandlerRemoved, NewEventHandler(func(ev Event) error {
+				mutex.Lock()
+				removed = append(removed, ev.GetData().(*ListenerMeta).EventType)
+				mutex.Unlock()
+				// a listener added while the sink tears down must not outlive it
+				sink.AddEventListener("late", RecordingHandler())
+				return nil
+			}))
+			sink.(Closer).Close()
+			if n := sink.(ListenerInspector).ListenerCount("late"); n != 0 {
+				t.Errorf("closed sink has %d late listeners", n)
+			}
+			mutex.Lock()
+			got := append([]string{}, removed...)
+			mutex.Unlock()
+			if !reflect.DeepEqual(got, []string{"test"}) {
+				t.Errorf("removals = %v, want [test]", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package events
+
+import (
+	"sync"
+	"time"
+)
+
+// A Recorder is a handler that records every event it is called with, for
+// use in tests of asynchronous dispatch.
+type Recorder struct {
+	EventHandler
+	calls []Event
+	changed chan struct{}
+	mutex *sync.Mutex
+}
+
+// RecordingHandler returns a handler that records the events it is called
+// with, in the order the calls arrive.
+func RecordingHandler() *Recorder {
+	r := &Recorder{
+		changed: make(chan struct{}),
+		mutex: &sync.Mutex{},
+	}
+	r.EventHandler = NewEventHandler(r.record)
+	return r
+}
+
+func (r *Recorder) record(ev Event) error {
+	r.mutex.Lock()
+	r.calls = append(r.calls, ev)
+	close(r.changed)
+	r.changed = make(chan struct{})
+	r.mutex.Unlock()
+	return nil
+}
+
+// Calls returns the events recorded so far.
+func (r *Recorder) Calls() []Event {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return append([]Event{}, r.calls...)
+}
+
+// WaitForCalls waits until at least n events have been recorded, and
+// reports whether they were before timeout passed.
+func (r *Recorder) WaitForCalls(n int, timeout time.Duration) bool {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	for {
+		r.mutex.Lock()
+		count := len(r.calls)
+		changed := r.changed
+		r.mutex.Unlock()
+		if count >= n {
+			return true
+		}
+		select {
+		case <-changed:
+		case <-timer.C:
+			return false
+		}
+	}
+}
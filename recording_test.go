@@ -0,0 +1,93 @@
+package events
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRecordingHandler(t *testing.T) {
+	tests := []struct {
+		name string
+		vals []float64
+	}{
+		{"none", nil},
+		{"one", []float64{1}},
+		{"in order", []float64{3, 1, 2, 1}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := RecordingHandler()
+			want := []float64{}
+			for _, val := range tc.vals {
+				rec.Call(NewEvent("test", val))
+				want = append(want, val)
+			}
+			calls := rec.Calls()
+			if got := logValues(calls); !reflect.DeepEqual(got, want) {
+				t.Errorf("calls = %v, want %v", got, want)
+			}
+			// the result is a copy
+			if len(calls) > 0 {
+				calls[0] = nil
+				if rec.Calls()[0] == nil {
+					t.Error("changing the result of Calls changed the recording")
+				}
+			}
+		})
+	}
+}
+
+func TestRecordingHandlerWaitForCalls(t *testing.T) {
+	tests := []struct {
+		name string
+		fires int
+		wait int
+		want bool
+	}{
+		{"nothing to wait for", 0, 0, true},
+		{"reached", 3, 3, true},
+		{"passed", 3, 2, true},
+		{"timed out", 2, 3, false},
+		{"none arrive", 0, 1, false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			sink := NewEventSink(time.Hour)
+			rec := RecordingHandler()
+			sink.AddEventListener("test", rec)
+			for i := 0; i < tc.fires; i++ {
+				sink.Emit("test", float64(i))
+			}
+			start := time.Now()
+			if got := rec.WaitForCalls(tc.wait, 50 * time.Millisecond); got != tc.want {
+				t.Errorf("WaitForCalls(%d) = %t, want %t", tc.wait, got, tc.want)
+			}
+			if elapsed := time.Since(start); !tc.want && elapsed < 50 * time.Millisecond {
+				t.Errorf("gave up after %s, before the timeout", elapsed)
+			}
+			if tc.want && len(rec.Calls()) < tc.wait {
+				t.Errorf("%d calls after waiting for %d", len(rec.Calls()), tc.wait)
+			}
+		})
+	}
+}
+
+func TestRecordingHandlerConcurrent(t *testing.T) {
+	rec := RecordingHandler()
+	wg := &sync.WaitGroup{}
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				rec.Call(NewEvent("test", float64(j)))
+			}
+		}()
+	}
+	if !rec.WaitForCalls(1000, time.Second) {
+		t.Errorf("%d calls recorded, want 1000", len(rec.Calls()))
+	}
+	wg.Wait()
+}
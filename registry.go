@@ -0,0 +1,59 @@
+package events
+
+// SinkAutoRegister sets whether a sink registers the type of each event
+// it fires, as if by RegisterEventType, the first time it sees it. It
+// does by default. With auto-registration off, ListEventTypes only lists
+// explicitly registered types, and the sink keeps per-type state (the
+// latest event, counters, storm detection and the log of SinkTypeLogs)
+// only for registered types, which keeps a sink firing many dynamic
+// types, such as one per user, from remembering every one of them. Events
+// of unregistered types are still logged and dispatched as usual.
+func SinkAutoRegister(enabled bool) SinkOption {
+	return func(es *basicEventSink) {
+		es.autoRegister = enabled
+	}
+}
+
+// SinkAutoRegisterLimit caps the number of event types a sink registers
+// automatically. Once the cap is reached, new types are no longer
+// registered when fired, though they can still be registered explicitly,
+// and the sink keeps no per-type state for them, as if auto-registration
+// were off. A limit <= 0 means no cap.
+func SinkAutoRegisterLimit(limit int) SinkOption {
+	return func(es *basicEventSink) {
+		es.autoRegisterLimit = limit
+	}
+}
+
+// autoRegisterType registers the type of ev if it is new and the sink's
+// auto-registration settings allow it. The caller must hold the mutex.
+func (es *basicEventSink) autoRegisterType(ev Event) {
+	if !es.autoRegister {
+		return
+	}
+	eventType := ev.GetType()
+	if _, ok := es.eventTypes[eventType]; ok {
+		return
+	}
+	if es.autoRegisterLimit > 0 && len(es.autoTypes) >= es.autoRegisterLimit {
+		return
+	}
+	es.eventTypes[eventType] = ev
+	es.autoTypes[eventType] = true
+}
+
+// tracksType reports whether the sink keeps per-type state for
+// eventType: the latest event, counters, storm state and type log. It
+// does for registered types, which with auto-registration on and no cap
+// is every type fired. The caller must hold the mutex.
+func (es *basicEventSink) tracksType(eventType string) bool {
+	_, ok := es.eventTypes[eventType]
+	return ok
+}
+
+// registerLocked registers the type of ev explicitly. The caller must hold
+// the mutex.
+func (es *basicEventSink) registerLocked(ev Event) {
+	es.eventTypes[ev.GetType()] = ev
+	delete(es.autoTypes, ev.GetType())
+}
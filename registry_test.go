@@ -0,0 +1,109 @@
+package events
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAutoRegister(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []SinkOption
+		wantUsers int
+	}{
+		{"default", nil, 100},
+		{"on", []SinkOption{SinkAutoRegister(true)}, 100},
+		{"off", []SinkOption{SinkAutoRegister(false)}, 0},
+		{"limited", []SinkOption{SinkAutoRegisterLimit(10)}, 10},
+		{"limit above count", []SinkOption{SinkAutoRegisterLimit(1000)}, 100},
+		{"no limit", []SinkOption{SinkAutoRegisterLimit(0)}, 100},
+		{"off with limit", []SinkOption{SinkAutoRegister(false), SinkAutoRegisterLimit(10)}, 0},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			sink := NewSyncEventSink(time.Hour, tc.opts...)
+			sink.RegisterEventType(NewEvent("login", ""))
+			for i := 0; i < 100; i++ {
+				sink.Emit(fmt.Sprintf("user-%d", i), float64(i))
+			}
+			// explicit registration isn't limited
+			sink.RegisterEventType(NewEvent("logout", ""))
+			users := 0
+			explicit := 0
+			for _, ev := range sink.ListEventTypes() {
+				switch {
+				case strings.HasPrefix(ev.GetType(), "user-"):
+					users += 1
+				case ev.GetType() == "login" || ev.GetType() == "logout":
+					explicit += 1
+				}
+			}
+			if users != tc.wantUsers {
+				t.Errorf("%d user types registered, want %d", users, tc.wantUsers)
+			}
+			if explicit != 2 {
+				t.Errorf("%d explicit types registered, want 2", explicit)
+			}
+			if n := len(sink.Log()); n != 100 {
+				t.Errorf("logged %d events, want 100", n)
+			}
+		})
+	}
+}
+
+func TestAutoRegisterLimitExplicit(t *testing.T) {
+	sink := NewSyncEventSink(time.Hour, SinkAutoRegisterLimit(2))
+	sink.Emit("a", 1.0)
+	sink.Emit("b", 1.0)
+	// registering an auto-registered type explicitly frees its slot
+	sink.RegisterEventType(NewEvent("a", 0.0))
+	sink.Emit("c", 1.0)
+	sink.Emit("d", 1.0)
+	got := []string{}
+	for _, ev := range sink.ListEventTypes() {
+		got = append(got, ev.GetType())
+	}
+	if strings.Join(got, ",") != "a,b,c" {
+		t.Errorf("registered types = %v, want [a b c]", got)
+	}
+}
+
+func TestAutoRegisterPerTypeState(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []SinkOption
+		wantTracked int
+	}{
+		{"default", nil, 101},
+		{"off", []SinkOption{SinkAutoRegister(false)}, 1},
+		{"limited", []SinkOption{SinkAutoRegisterLimit(10)}, 11},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			opts := append([]SinkOption{SinkTypeLogs(10), SinkStormDetection(1000, time.Minute)}, tc.opts...)
+			sink := NewSyncEventSink(time.Hour, opts...)
+			sink.RegisterEventType(NewEvent("login", ""))
+			sink.Emit("login", "alice")
+			for i := 0; i < 100; i++ {
+				sink.Emit(fmt.Sprintf("user-%d", i), float64(i))
+			}
+			es := sink.(*basicEventSink)
+			es.mutex.Lock()
+			sizes := []int{len(es.latest), len(es.fired), len(es.storms), len(es.typeLogs)}
+			es.mutex.Unlock()
+			for i, n := range sizes {
+				if n > tc.wantTracked {
+					t.Errorf("per-type map %d has %d entries, want at most %d", i, n, tc.wantTracked)
+				}
+			}
+			if _, ok := sink.(LatestReader).Latest("login"); !ok {
+				t.Error("no latest event for a registered type")
+			}
+			if n := len(sink.(LogReader).LogForType("user-99")); n != 1 {
+				t.Errorf("%d user-99 events logged, want 1", n)
+			}
+		})
+	}
+}
@@ -0,0 +1,31 @@
+package events
+
+import (
+	"io"
+	"time"
+)
+
+// ReplayFrom reads newline-delimited JSON events from r, as written by
+// NewLoggedEventSink or NewWriterHandler, and fires them into sink in
+// order, after upgrading them with Migrate. The events keep their
+// recorded times, so time-based handlers behave as they did when the
+// events were recorded, though the sink's log drops any older than its
+// TTL.
+//
+// If speed > 0, ReplayFrom waits between events for the time that passed
+// between them when recorded, divided by speed, so 1 replays in real time
+// and 10 ten times as fast. If speed <= 0, events are fired as fast as
+// possible. ReplayFrom stops at the first line that isn't a valid event.
+func ReplayFrom(r io.Reader, sink EventSink, speed float64) error {
+	var last time.Time
+	return readEvents(r, func(ev Event) {
+		t := ev.GetTime()
+		if speed > 0 && !last.IsZero() && t.After(last) {
+			time.Sleep(time.Duration(float64(t.Sub(last)) / speed))
+		}
+		last = t
+		// the recorded sequence numbers belong to the recording sink
+		setSeq(ev, 0)
+		sink.Fire(ev)
+	})
+}
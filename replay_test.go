@@ -0,0 +1,141 @@
+package events
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// recording returns newline-delimited JSON for value events of the given
+// type, spaced gap apart starting at the fake clock's time.
+func recording(t *testing.T, c *FakeClock, eventType string, gap time.Duration, vals ...float64) string {
+	t.Helper()
+	buf := &bytes.Buffer{}
+	for _, val := range vals {
+		data, err := json.Marshal(NewEvent(eventType, val))
+		if err != nil {
+			t.Fatalf("can't marshal event: %s", err)
+		}
+		buf.Write(data)
+		buf.WriteString("\n")
+		c.Advance(gap)
+	}
+	return buf.String()
+}
+
+func TestReplayFrom(t *testing.T) {
+	tests := []struct {
+		name string
+		wrap func(EventHandler) EventHandler
+		gap time.Duration
+		vals []float64
+		trailer string
+		wantErr bool
+		want []float64
+	}{
+		{
+			"threshold",
+			func(h EventHandler) EventHandler { return WithThreshold(h, DirectionIncreasing, 20, 14) },
+			time.Minute,
+			[]float64{10, 15, 25, 30, 12, 28},
+			"",
+			false,
+			[]float64{25, 28},
+		},
+		{
+			"count threshold by recorded time",
+			func(h EventHandler) EventHandler { return WithCountThreshold(h, 3, time.Minute) },
+			20 * time.Second,
+			[]float64{1, 2, 3, 4, 5, 6, 7},
+			"",
+			false,
+			[]float64{3, 6},
+		},
+		{
+			"count threshold too slow",
+			func(h EventHandler) EventHandler { return WithCountThreshold(h, 3, time.Minute) },
+			time.Minute,
+			[]float64{1, 2, 3, 4},
+			"",
+			false,
+			[]float64{},
+		},
+		{
+			"blank lines",
+			func(h EventHandler) EventHandler { return h },
+			time.Second,
+			[]float64{1, 2},
+			"\n\n",
+			false,
+			[]float64{1, 2},
+		},
+		{
+			"stops at a bad line",
+			func(h EventHandler) EventHandler { return h },
+			time.Second,
+			[]float64{1, 2},
+			"not json\n" + `{"type": "temp", "value": 3}` + "\n",
+			true,
+			[]float64{1, 2},
+		},
+		{
+			"empty",
+			func(h EventHandler) EventHandler { return h },
+			time.Second,
+			nil,
+			"",
+			false,
+			[]float64{},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			c := useFakeClock(t)
+			lines := recording(t, c, "temp", tc.gap, tc.vals...) + tc.trailer
+			sink := NewSyncEventSink(time.Hour)
+			rec := RecordingHandler()
+			sink.AddEventListener("temp", tc.wrap(rec))
+			err := ReplayFrom(strings.NewReader(lines), sink, 0)
+			if tc.wantErr != (err != nil) {
+				t.Errorf("error = %v, want error %t", err, tc.wantErr)
+			}
+			if got := logValues(rec.Calls()); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("handler called with %v, want %v", got, tc.want)
+			}
+			if n := len(filterLog(sink.Log(), "temp")); n != len(tc.vals) {
+				t.Errorf("logged %d events, want %d", n, len(tc.vals))
+			}
+		})
+	}
+}
+
+func TestReplayFromSpeed(t *testing.T) {
+	tests := []struct {
+		name string
+		gap time.Duration
+		speed float64
+		min time.Duration
+		max time.Duration
+	}{
+		{"as fast as possible", time.Hour, 0, 0, time.Second},
+		{"negative speed", time.Hour, -1, 0, time.Second},
+		{"scaled", time.Second, 50, 40 * time.Millisecond, time.Second},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			c := useFakeClock(t)
+			lines := recording(t, c, "temp", tc.gap, 1, 2, 3)
+			sink := NewSyncEventSink(time.Hour)
+			start := time.Now()
+			if err := ReplayFrom(strings.NewReader(lines), sink, tc.speed); err != nil {
+				t.Fatalf("replay failed: %s", err)
+			}
+			if elapsed := time.Since(start); elapsed < tc.min || elapsed > tc.max {
+				t.Errorf("replay took %s, want %s to %s", elapsed, tc.min, tc.max)
+			}
+		})
+	}
+}
@@ -0,0 +1,31 @@
+package events
+
+import (
+	"context"
+)
+
+// NewRepublishHandler returns a handler that emits a derived event to sink
+// for each event it receives, with a type given by deriveType and data
+// given by transform. If transform is nil, the original event's value,
+// message, bytes or data is used. The derived event carries the
+// original's correlation ID.
+//
+// An event is ignored if its derived type is empty or the same as its own
+// type, so a handler listening for the type it emits can't republish
+// forever. Longer cycles, like a -> b -> a, are not detected.
+func NewRepublishHandler(sink EventSink, deriveType func(Event) string, transform func(Event) interface{}) EventHandler {
+	return NewContextEventHandler(func(ctx context.Context, ev Event) error {
+		eventType := deriveType(ev)
+		if eventType == "" || eventType == ev.GetType() {
+			return ErrIgnored
+		}
+		var data interface{}
+		if transform != nil {
+			data = transform(ev)
+		} else {
+			data = eventPayload(ev)
+		}
+		emitContext(sink, ctx, eventType, data)
+		return nil
+	})
+}
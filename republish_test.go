@@ -0,0 +1,78 @@
+package events
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRepublishHandler(t *testing.T) {
+	alert := func(ev Event) string { return ev.GetType() + "-alert" }
+	same := func(ev Event) string { return ev.GetType() }
+	tests := []struct {
+		name string
+		deriveType func(Event) string
+		transform func(Event) interface{}
+		data interface{}
+		wantType string
+		wantData interface{}
+	}{
+		{"value", alert, nil, 35.0, "temperature-alert", 35.0},
+		{"message", alert, nil, "open", "temperature-alert", "open"},
+		{"map", alert, nil, map[string]interface{}{"room": "kitchen"}, "temperature-alert", map[string]interface{}{"room": "kitchen"}},
+		{"transformed", alert, func(ev Event) interface{} { return "too hot" }, 35.0, "temperature-alert", "too hot"},
+		{"same type", same, nil, 35.0, "", nil},
+		{"empty type", func(Event) string { return "" }, nil, 35.0, "", nil},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			sink := NewSyncEventSink(time.Minute)
+			h := NewRepublishHandler(sink, tc.deriveType, tc.transform)
+			sink.AddEventListener("temperature", h)
+			derived := RecordingHandler()
+			sink.AddEventListener("temperature-alert", derived)
+			sink.Fire(NewEvent("temperature", tc.data, EventCorrelation("req-1")))
+			calls := derived.Calls()
+			if tc.wantType == "" {
+				if len(calls) != 0 {
+					t.Errorf("republished %v", calls)
+				}
+				if n := len(filterLog(sink.Log(), "temperature")); n != 1 {
+					t.Errorf("logged %d temperature events, want 1", n)
+				}
+				return
+			}
+			if len(calls) != 1 {
+				t.Fatalf("republished %d events, want 1", len(calls))
+			}
+			if got := eventPayload(calls[0]); calls[0].GetType() != tc.wantType || !reflect.DeepEqual(got, tc.wantData) {
+				t.Errorf("republished %s %#v, want %s %#v", calls[0].GetType(), got, tc.wantType, tc.wantData)
+			}
+			if id := CorrelationID(calls[0]); id != "req-1" {
+				t.Errorf("correlation ID = %q, want req-1", id)
+			}
+		})
+	}
+}
+
+func TestRepublishHandlerLoop(t *testing.T) {
+	sink := NewSyncEventSink(time.Minute)
+	h := NewRepublishHandler(sink, func(ev Event) string { return "temperature" }, nil)
+	sink.AddEventListener("temperature", h)
+	done := make(chan struct{})
+	go func() {
+		sink.Emit("temperature", 1.0)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("republishing to the same type didn't terminate")
+	}
+	if n := len(filterLog(sink.Log(), "temperature")); n != 1 {
+		t.Errorf("logged %d temperature events, want 1", n)
+	}
+	if err := h.Call(NewEvent("temperature", 1.0)); err != ErrIgnored {
+		t.Errorf("call returned %v, want %v", err, ErrIgnored)
+	}
+}
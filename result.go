@@ -0,0 +1,183 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// A ResultHandler is an EventHandler that produces a result as well as an
+// error, for request/response patterns over a sink. Results are collected
+// by FireCollect.
+type ResultHandler interface {
+	EventHandler
+	CallResult(ev Event) (interface{}, error)
+}
+
+type ResultHandlerFunc func(Event) (interface{}, error)
+
+type resultKey struct{}
+
+// resultSlot receives the result of a call from a result handler. It is
+// read once, when the call returns; a result set after that, by a handler
+// abandoned by WithHardTimeout for example, is discarded.
+type resultSlot struct {
+	val interface{}
+	read bool
+	mutex sync.Mutex
+}
+
+func (slot *resultSlot) set(val interface{}) {
+	slot.mutex.Lock()
+	if !slot.read {
+		slot.val = val
+	}
+	slot.mutex.Unlock()
+}
+
+func (slot *resultSlot) get() interface{} {
+	slot.mutex.Lock()
+	defer slot.mutex.Unlock()
+	slot.read = true
+	return slot.val
+}
+
+type resultHandler struct {
+	id int64
+	handler ResultHandlerFunc
+	lastErr error
+	mutex *sync.Mutex
+}
+
+// NewResultHandler returns a handler whose results are collected by
+// FireCollect. Its results survive being wrapped in the decorators in this
+// package, which pass on the context they are called with; when it is
+// called through Call, or through Fire, its result is discarded.
+func NewResultHandler(handler ResultHandlerFunc) ResultHandler {
+	return &resultHandler{IDGenerator(), handler, nil, &sync.Mutex{}}
+}
+
+func (eh *resultHandler) ID() int64 {
+	return eh.id
+}
+
+func (eh *resultHandler) Expired() bool {
+	return false
+}
+
+func (eh *resultHandler) LastError() error {
+	eh.mutex.Lock()
+	defer eh.mutex.Unlock()
+	return eh.lastErr
+}
+
+func (eh *resultHandler) Call(ev Event) error {
+	_, err := eh.CallResult(ev)
+	return err
+}
+
+func (eh *resultHandler) CallContext(ctx context.Context, ev Event) error {
+	res, err := eh.CallResult(ev)
+	if slot, ok := ctx.Value(resultKey{}).(*resultSlot); ok {
+		slot.set(res)
+	}
+	return err
+}
+
+func (eh *resultHandler) CallResult(ev Event) (interface{}, error) {
+	res, err := eh.handler(ev)
+	eh.mutex.Lock()
+	eh.lastErr = err
+	eh.mutex.Unlock()
+	return res, err
+}
+
+// callResult calls h with ctx, returning its result if it produces one.
+func callResult(ctx context.Context, h EventHandler, ev Event) (interface{}, error) {
+	if rh, ok := h.(ResultHandler); ok {
+		return rh.CallResult(ev)
+	}
+	slot := &resultSlot{}
+	err := callContext(context.WithValue(ctx, resultKey{}, slot), h, ev)
+	return slot.get(), err
+}
+
+// FireCollect fires ev like Fire, but calls its listeners one at a time in
+// the calling goroutine and returns what they produced. The i'th result
+// and error come from the i'th listener to handle the event; result
+// handlers produce results, and other listeners produce nil. Listeners
+// that ignore the event, or have expired, are left out. A listener that
+// returns ErrStopPropagation contributes its result, with a nil error, and
+// no further listeners are called. If the sink is paused, the event is
+// queued as usual and nothing is returned.
+func (es *basicEventSink) FireCollect(ev Event) ([]interface{}, []error) {
+	valid, batches, notices := es.accept([]Event{ev})
+	es.FireMany(notices)
+	if len(valid) == 0 {
+		return nil, nil
+	}
+	var results []interface{}
+	var errs []error
+	for _, l := range batches[0] {
+		res, err := es.callResult(l.eventType, l.handler, valid[0])
+		if errors.Is(err, ErrIgnored) || errors.Is(err, ErrExpired) {
+			continue
+		}
+		if errors.Is(err, ErrStopPropagation) {
+			results = append(results, res)
+			errs = append(errs, nil)
+			break
+		}
+		results = append(results, res)
+		errs = append(errs, err)
+	}
+	return results, errs
+}
+
+// EmitSync emits an event like Emit, but calls its listeners in the
+// calling goroutine, as FireCollect does, and returns the errors of those
+// that failed. Emit is unaffected and remains asynchronous.
+func (es *basicEventSink) EmitSync(eventType string, data interface{}) []error {
+	return failures(es.FireCollect(NewEvent(eventType, data)))
+}
+
+func (es *PrefixedEventSource) EmitSync(eventType string, data interface{}) []error {
+	return failures(es.FireCollect(NewEvent(eventType, data)))
+}
+
+func (es *LoggedEventSink) EmitSync(eventType string, data interface{}) []error {
+	return failures(es.FireCollect(NewEvent(eventType, data)))
+}
+
+func (b *Broadcaster) EmitSync(eventType string, data interface{}) []error {
+	return failures(b.FireCollect(NewEvent(eventType, data)))
+}
+
+// failures returns the non-nil errors from FireCollect.
+func failures(_ []interface{}, errs []error) []error {
+	var out []error
+	for _, err := range errs {
+		if err != nil {
+			out = append(out, err)
+		}
+	}
+	return out
+}
+
+func (es *PrefixedEventSource) FireCollect(ev Event) ([]interface{}, []error) {
+	return fireCollect(es.EventSink, es.As(ev))
+}
+
+func (es *LoggedEventSink) FireCollect(ev Event) ([]interface{}, []error) {
+	es.write(ev)
+	return fireCollect(es.EventSink, ev)
+}
+
+// FireCollect fires ev on every child, collecting results from the
+// primary sink's listeners.
+func (b *Broadcaster) FireCollect(ev Event) ([]interface{}, []error) {
+	for _, child := range b.children[1:] {
+		child.Fire(ev)
+	}
+	return fireCollect(b.EventSink, ev)
+}
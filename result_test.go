@@ -0,0 +1,185 @@
+package events
+
+import (
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestFireCollect(t *testing.T) {
+	result := func(val interface{}) EventHandler {
+		return NewResultHandler(func(Event) (interface{}, error) { return val, nil })
+	}
+	failing := NewResultHandler(func(Event) (interface{}, error) { return "partial", errBoom })
+	plain := NewEventHandler(func(Event) error { return nil })
+	ignoring := NewEventHandler(func(Event) error { return ErrIgnored })
+	stopping := NewResultHandler(func(Event) (interface{}, error) { return "last", ErrStopPropagation })
+	tests := []struct {
+		name string
+		handlers []EventHandler
+		wantResults []interface{}
+		wantErrs []error
+	}{
+		{"none", nil, nil, nil},
+		{"results in order", []EventHandler{result(1), result("two"), result(3.0)}, []interface{}{1, "two", 3.0}, []error{nil, nil, nil}},
+		{"plain handler", []EventHandler{result(1), plain, result(2)}, []interface{}{1, nil, 2}, []error{nil, nil, nil}},
+		{"ignoring handler", []EventHandler{result(1), ignoring, result(2)}, []interface{}{1, 2}, []error{nil, nil}},
+		{"failing handler", []EventHandler{failing, result(2)}, []interface{}{"partial", 2}, []error{errBoom, nil}},
+		{"stop propagation", []EventHandler{result(1), stopping, result(2)}, []interface{}{1, "last"}, []error{nil, nil}},
+		{"decorated", []EventHandler{WithMaxCalls(WithRange(result("ranged"), 0, 10), 5), result(2)}, []interface{}{"ranged", 2}, []error{nil, nil}},
+		{"decorated ignoring", []EventHandler{WithRange(result("ranged"), 5, 10), result(2)}, []interface{}{2}, []error{nil}},
+	}
+	sinks := []struct {
+		name string
+		sink func() EventSink
+	}{
+		{"sync", func() EventSink { return NewSyncEventSink(time.Minute) }},
+		{"async", func() EventSink { return NewEventSink(time.Minute) }},
+	}
+	for _, sc := range sinks {
+		for _, tc := range tests {
+			t.Run(sc.name + "/" + tc.name, func(t *testing.T) {
+				sink := sc.sink()
+				for _, h := range tc.handlers {
+					sink.AddEventListener("test", h)
+				}
+				results, errs := sink.(SyncSink).FireCollect(NewEvent("test", 1.0))
+				if !reflect.DeepEqual(results, tc.wantResults) {
+					t.Errorf("results = %v, want %v", results, tc.wantResults)
+				}
+				if len(errs) != len(tc.wantErrs) {
+					t.Fatalf("errors = %v, want %v", errs, tc.wantErrs)
+				}
+				for i, err := range errs {
+					if !errors.Is(err, tc.wantErrs[i]) {
+						t.Errorf("error %d = %v, want %v", i, err, tc.wantErrs[i])
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestFireCollectPaused(t *testing.T) {
+	sink := NewSyncEventSink(time.Minute)
+	sink.AddEventListener("test", NewResultHandler(func(Event) (interface{}, error) { return 1, nil }))
+	sink.(Pausable).Pause()
+	results, errs := sink.(SyncSink).FireCollect(NewEvent("test", 1.0))
+	if results != nil || errs != nil {
+		t.Errorf("paused sink returned %v, %v", results, errs)
+	}
+}
+
+func TestEmitSync(t *testing.T) {
+	errOther := errors.New("other")
+	failing := func(err error) EventHandler {
+		return NewEventHandler(func(Event) error { return err })
+	}
+	ok := func() EventHandler {
+		return NewEventHandler(func(Event) error { return nil })
+	}
+	tests := []struct {
+		name string
+		handlers []EventHandler
+		want []error
+	}{
+		{"no listeners", nil, nil},
+		{"all succeed", []EventHandler{ok(), ok()}, nil},
+		{"one fails", []EventHandler{ok(), failing(errBoom), ok()}, []error{errBoom}},
+		{"several fail", []EventHandler{failing(errBoom), ok(), failing(errOther)}, []error{errBoom, errOther}},
+		{"ignored isn't a failure", []EventHandler{failing(ErrIgnored), failing(errBoom)}, []error{errBoom}},
+		{"stop propagation", []EventHandler{failing(ErrStopPropagation), failing(errBoom)}, nil},
+		{"range rejects a message", []EventHandler{WithRange(ok(), 0, 10)}, []error{ErrIncompatibleEvent}},
+	}
+	sinks := []struct {
+		name string
+		sink func() EventSink
+	}{
+		{"sync", func() EventSink { return NewSyncEventSink(time.Minute) }},
+		{"async", func() EventSink { return NewEventSink(time.Minute) }},
+		{"prefixed", func() EventSink { return NewPrefixedEventSource("p", NewEventSink(time.Minute)) }},
+		{"logged", func() EventSink { return NewLoggedEventSink(NewEventSink(time.Minute), io.Discard) }},
+	}
+	for _, sc := range sinks {
+		for _, tc := range tests {
+			t.Run(sc.name + "/" + tc.name, func(t *testing.T) {
+				sink := sc.sink()
+				for _, h := range tc.handlers {
+					sink.AddEventListener("test", h)
+				}
+				errs := sink.(SyncSink).EmitSync("test", "hello")
+				if len(errs) != len(tc.want) {
+					t.Fatalf("errors = %v, want %v", errs, tc.want)
+				}
+				for i, err := range errs {
+					if !errors.Is(err, tc.want[i]) {
+						t.Errorf("error %d = %v, want %v", i, err, tc.want[i])
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestEmitStaysAsync(t *testing.T) {
+	sink := NewEventSink(time.Minute)
+	release := make(chan bool)
+	sink.AddEventListener("test", NewEventHandler(func(Event) error {
+		<-release
+		return errBoom
+	}))
+	done := make(chan bool)
+	go func() {
+		sink.Emit("test", 1.0)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Emit waited for its listener")
+	}
+	close(release)
+}
+
+func TestFireCollectAbandonedResult(t *testing.T) {
+	tests := []struct {
+		name string
+		wrap func(EventHandler) EventHandler
+		delay time.Duration
+		wantResults []interface{}
+		wantErrs []error
+	}{
+		{"in time", func(h EventHandler) EventHandler { return WithHardTimeout(h, time.Second) }, 0, []interface{}{"late"}, []error{nil}},
+		{"timed out", func(h EventHandler) EventHandler { return WithHardTimeout(h, 5 * time.Millisecond) }, 20 * time.Millisecond, []interface{}{nil}, []error{ErrHandlerTimeout}},
+		{"delayed", func(h EventHandler) EventHandler { return WithDelay(h, 5 * time.Millisecond) }, 0, nil, nil},
+		{"trailing debounce", func(h EventHandler) EventHandler { return WithTrailingDebounce(h, 5 * time.Millisecond) }, 0, nil, nil},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			delay := tc.delay
+			done := make(chan struct{})
+			sink := NewSyncEventSink(time.Hour)
+			sink.AddEventListener("test", tc.wrap(NewResultHandler(func(Event) (interface{}, error) {
+				defer close(done)
+				time.Sleep(delay)
+				return "late", nil
+			})))
+			results, errs := sink.(SyncSink).FireCollect(NewEvent("test", 1.0))
+			if !reflect.DeepEqual(results, tc.wantResults) {
+				t.Errorf("results = %v, want %v", results, tc.wantResults)
+			}
+			if len(errs) != len(tc.wantErrs) {
+				t.Fatalf("errors = %v, want %v", errs, tc.wantErrs)
+			}
+			for i, err := range errs {
+				if !errors.Is(err, tc.wantErrs[i]) || (tc.wantErrs[i] == nil && err != nil) {
+					t.Errorf("error %d = %v, want %v", i, err, tc.wantErrs[i])
+				}
+			}
+			// the handler finishes after FireCollect has returned
+			<-done
+		})
+	}
+}
@@ -0,0 +1,152 @@
+package events
+
+import (
+	"context"
+	"sync"
+)
+
+// ScopedEventSink is a view of a sink whose listeners last only as long as
+// a context. Everything but listener management is passed through to the
+// parent sink. Besides EventSink, it is a BatchSink, ContextEmitter,
+// SyncSink and ListenerManager; use the parent directly for the rest.
+type ScopedEventSink struct {
+	EventSink
+	ctx context.Context
+	keys map[listenerKey]bool
+	done bool
+	stop chan bool
+	mutex *sync.Mutex
+}
+
+// NewScopedSink returns a view of parent that removes every listener
+// added through it once ctx is done, such as at the end of an HTTP
+// request. Listeners added after that are dropped. Closing the scoped sink
+// removes its listeners early, but doesn't close parent.
+func NewScopedSink(ctx context.Context, parent EventSink) EventSink {
+	es := &ScopedEventSink{
+		EventSink: parent,
+		ctx: ctx,
+		keys: map[listenerKey]bool{},
+		stop: make(chan bool),
+		mutex: &sync.Mutex{},
+	}
+	if ctx.Done() != nil {
+		go func() {
+			select {
+			case <-ctx.Done():
+				es.Close()
+			case <-es.stop:
+			}
+		}()
+	}
+	return es
+}
+
+// track records a listener, reporting false if the scope has ended.
+func (es *ScopedEventSink) track(eventType string, handler EventHandler) bool {
+	if handler == nil {
+		return false
+	}
+	es.mutex.Lock()
+	defer es.mutex.Unlock()
+	if es.done {
+		return false
+	}
+	es.keys[listenerKey{eventType, handler.ID()}] = true
+	return true
+}
+
+func (es *ScopedEventSink) AddEventListener(eventType string, handler EventHandler) {
+	if es.track(eventType, handler) {
+		es.EventSink.AddEventListener(eventType, handler)
+	}
+}
+
+func (es *ScopedEventSink) AddEventListenerTagged(eventType, tag string, handler EventHandler) {
+	if es.track(eventType, handler) {
+		addEventListenerTagged(es.EventSink, eventType, tag, handler)
+	}
+}
+
+func (es *ScopedEventSink) AddEventListenerIf(eventType string, pred func(Event) bool, handler EventHandler) {
+	if es.track(eventType, handler) {
+		addEventListenerIf(es.EventSink, eventType, pred, handler)
+	}
+}
+
+func (es *ScopedEventSink) Once(eventType string, handler EventHandler) {
+	if es.track(eventType, handler) {
+		es.EventSink.Once(eventType, handler)
+	}
+}
+
+func (es *ScopedEventSink) OnceWhen(eventType string, handler EventHandler, cond Condition) {
+	if es.track(eventType, handler) {
+		onceWhen(es.EventSink, eventType, handler, cond)
+	}
+}
+
+func (es *ScopedEventSink) RemoveEventListener(eventType string, handler EventHandler) {
+	if handler == nil {
+		return
+	}
+	es.mutex.Lock()
+	delete(es.keys, listenerKey{eventType, handler.ID()})
+	es.mutex.Unlock()
+	es.EventSink.RemoveEventListener(eventType, handler)
+}
+
+func (es *ScopedEventSink) RemoveEventListenerSync(eventType string, handler EventHandler) {
+	if handler == nil {
+		return
+	}
+	es.mutex.Lock()
+	delete(es.keys, listenerKey{eventType, handler.ID()})
+	es.mutex.Unlock()
+	removeEventListenerSync(es.EventSink, eventType, handler)
+}
+
+func (es *ScopedEventSink) RemoveByTag(tag string) {
+	removeByTag(es.EventSink, tag)
+}
+
+func (es *ScopedEventSink) PruneExpired() int {
+	return pruneExpired(es.EventSink)
+}
+
+func (es *ScopedEventSink) FireMany(evs []Event) {
+	fireMany(es.EventSink, evs)
+}
+
+func (es *ScopedEventSink) EmitMany(eventType string, data []interface{}) {
+	fireMany(es.EventSink, newEvents(eventType, data))
+}
+
+func (es *ScopedEventSink) EmitContext(ctx context.Context, eventType string, data interface{}) {
+	emitContext(es.EventSink, ctx, eventType, data)
+}
+
+func (es *ScopedEventSink) FireCollect(ev Event) ([]interface{}, []error) {
+	return fireCollect(es.EventSink, ev)
+}
+
+func (es *ScopedEventSink) EmitSync(eventType string, data interface{}) []error {
+	return failures(es.FireCollect(NewEvent(eventType, data)))
+}
+
+// Close removes every listener added through the scoped sink, and stops
+// watching its context.
+func (es *ScopedEventSink) Close() error {
+	es.mutex.Lock()
+	keys := es.keys
+	es.keys = map[listenerKey]bool{}
+	if !es.done {
+		es.done = true
+		close(es.stop)
+	}
+	es.mutex.Unlock()
+	for key := range keys {
+		es.EventSink.RemoveEventListener(key.eventType, HandlerReference(key.id))
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package events
+
+import (
+	"context"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestScopedSink(t *testing.T) {
+	tests := []struct {
+		name string
+		end func(scoped EventSink, cancel context.CancelFunc)
+	}{
+		{"cancelled", func(scoped EventSink, cancel context.CancelFunc) { cancel() }},
+		{"closed", func(scoped EventSink, cancel context.CancelFunc) { scoped.(Closer).Close() }},
+		{"closed twice", func(scoped EventSink, cancel context.CancelFunc) {
+			scoped.(Closer).Close()
+			scoped.(Closer).Close()
+			cancel()
+		}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			parent := NewSyncEventSink(time.Hour)
+			own := RecordingHandler()
+			parent.AddEventListener("test", own)
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			scoped := NewScopedSink(ctx, parent)
+			recs := []*Recorder{RecordingHandler(), RecordingHandler(), RecordingHandler()}
+			scoped.AddEventListener("test", recs[0])
+			scoped.Once("test", recs[1])
+			scoped.(ListenerManager).AddEventListenerTagged("other", "req", recs[2])
+			scoped.Emit("test", 1.0)
+			if n := parent.(ListenerInspector).ListenerCount("test"); n != 2 {
+				t.Errorf("%d listeners during the scope, want 2", n)
+			}
+			tc.end(scoped, cancel)
+			for _, eventType := range []string{"test", "other"} {
+				want := 0
+				if eventType == "test" {
+					want = 1
+				}
+				if n := waitForListeners(parent, eventType, want, time.Second); n != want {
+					t.Errorf("%d %s listeners after the scope, want %d", n, eventType, want)
+				}
+			}
+			// listeners added once the scope is over are dropped
+			late := RecordingHandler()
+			scoped.AddEventListener("test", late)
+			scoped.Emit("test", 2.0)
+			scoped.Emit("other", 2.0)
+			if n := len(own.Calls()); n != 2 {
+				t.Errorf("parent's listener called %d times, want 2", n)
+			}
+			if n := len(recs[0].Calls()); n != 1 {
+				t.Errorf("scoped listener called %d times, want 1", n)
+			}
+			if n := len(recs[2].Calls()) + len(late.Calls()); n != 0 {
+				t.Errorf("listeners called %d times after the scope, want 0", n)
+			}
+		})
+	}
+}
+
+func TestScopedSinkRemove(t *testing.T) {
+	parent := NewSyncEventSink(time.Hour)
+	ctx, cancel := context.WithCancel(context.Background())
+	scoped := NewScopedSink(ctx, parent)
+	h := RecordingHandler()
+	scoped.AddEventListener("test", h)
+	scoped.RemoveEventListener("test", h)
+	// re-added directly, so the scope no longer owns it
+	parent.AddEventListener("test", h)
+	cancel()
+	time.Sleep(10 * time.Millisecond)
+	if n := parent.(ListenerInspector).ListenerCount("test"); n != 1 {
+		t.Errorf("%d listeners after the scope, want 1", n)
+	}
+}
+
+func TestScopedSinkCloseStopsWatching(t *testing.T) {
+	before := runtime.NumGoroutine()
+	parent := NewSyncEventSink(time.Hour)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	for i := 0; i < 10; i++ {
+		scoped := NewScopedSink(ctx, parent)
+		scoped.AddEventListener("test", RecordingHandler())
+		scoped.(Closer).Close()
+	}
+	if n := waitForGoroutines(before, time.Second); n > before {
+		t.Errorf("%d goroutines after closing, want %d", n, before)
+	}
+}
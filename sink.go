@@ -1,23 +1,48 @@
 package events
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
-
-	"github.com/rclancey/generic"
 )
 
 type ListenerMeta struct {
 	EventType string `json:"event_type"`
 	HandlerID int64 `json:"handler_id"`
 	Error string `json:"error,omitempty"`
+	Depth int `json:"depth,omitempty"`
+	// Dropped is the number of calls an EventTypeDispatchDropped event
+	// reports as dropped.
+	Dropped int `json:"dropped,omitempty"`
+	// Change is EventTypeHandlerAdded or EventTypeHandlerRemoved in the
+	// ListenerMeta passed to an OnListenerChange callback.
+	Change string `json:"-"`
 }
 
+// EventSink dispatches events to listeners. Adding a nil handler is a
+// no-op, as is firing a nil event or one with an empty type, so Emit with
+// an empty event type emits nothing.
+//
+// The sinks in this package do more than EventSink requires. Each further
+// capability has its own small interface, such as BatchSink or Pausable,
+// to check for with a type assertion:
+//
+//	if p, ok := sink.(Pausable); ok {
+//		p.Pause()
+//	}
+//
+// Views of a sink, such as NewPrefixedEventSource, implement the
+// capabilities that concern event types, translating them. When the
+// sink they wrap lacks one, they fall back to the core methods where they
+// can, and otherwise do nothing. Capabilities of the sink as a whole, such
+// as Pausable and Closer, are used on the sink itself.
 type EventSink interface {
 	AddEventListener(eventType string, handler EventHandler)
 	RemoveEventListener(eventType string, handler EventHandler)
@@ -29,110 +54,792 @@ type EventSink interface {
 	ListEventTypes() []Event
 }
 
+// BatchSink fires several events at once.
+type BatchSink interface {
+	FireMany(evs []Event)
+	EmitMany(eventType string, data []interface{})
+}
+
+// ContextEmitter emits events carrying values from a context, such as a
+// correlation ID.
+type ContextEmitter interface {
+	EmitContext(ctx context.Context, eventType string, data interface{})
+}
+
+// SyncSink calls listeners in the calling goroutine, even when the sink is
+// asynchronous.
+type SyncSink interface {
+	FireCollect(ev Event) ([]interface{}, []error)
+	EmitSync(eventType string, data interface{}) []error
+	RemoveEventListenerSync(eventType string, handler EventHandler)
+}
+
+// LogReader reads the log other than in the order of Log.
+type LogReader interface {
+	LogSortedByTime() []Event
+	LogForType(eventType string) []Event
+}
+
+// LatestReader reads the most recent event of each type.
+type LatestReader interface {
+	Latest(eventType string) (Event, bool)
+	LatestAll() map[string]Event
+}
+
+// CSVExporter writes the log as CSV.
+type CSVExporter interface {
+	ExportCSV(w io.Writer, eventTypes ...string) error
+}
+
+// TypeManager configures event types beyond registering them.
+type TypeManager interface {
+	RegisterEventTypeWithValidator(ev Event, validator Validator)
+	AliasEventType(oldType, newType string)
+	SetTypeTimeout(eventType string, d time.Duration)
+}
+
+// MiddlewareSink wraps the handlers added to it in middleware.
+type MiddlewareSink interface {
+	Use(mw Middleware)
+}
+
+// ListenerManager adds listeners with options, and removes them in bulk.
+type ListenerManager interface {
+	AddEventListenerTagged(eventType, tag string, handler EventHandler)
+	AddEventListenerWithPriority(eventType string, priority int, handler EventHandler)
+	AddEventListenerIf(eventType string, pred func(Event) bool, handler EventHandler)
+	AddEventListenerWithLabels(eventType string, selector map[string]string, handler EventHandler)
+	OnceWhen(eventType string, handler EventHandler, cond Condition)
+	RemoveByTag(tag string)
+	PruneExpired() int
+}
+
+// PatternSink adds listeners on patterns of event types.
+type PatternSink interface {
+	AddPatternListener(pattern string, handler EventHandler)
+	AddPatternListenerWithPriority(pattern string, priority int, handler EventHandler)
+}
+
+// ListenerInspector describes the listeners for an event type.
+type ListenerInspector interface {
+	ListenerCount(eventType string) int
+	DispatchOrder(eventType string) []ListenerMeta
+	DescribeHandlers(eventType string) []HandlerDescription
+	OnListenerChange(fn func(ListenerMeta))
+}
+
+// StatsSink reports statistics on the values of the events it has fired.
+type StatsSink interface {
+	Stats() map[string]EventTypeStats
+	Percentiles(eventType string, ps ...float64) map[float64]float64
+	Counters() map[string]EventCounters
+}
+
+// Pausable queues events, rather than dispatching them, while paused.
+type Pausable interface {
+	Pause()
+	Resume()
+}
+
+// Snapshotter saves and restores a sink's registered types and log.
+type Snapshotter interface {
+	Snapshot() *SinkState
+	Restore(state *SinkState)
+}
+
+// ErrorSource reports handler failures on a channel.
+type ErrorSource interface {
+	Errors() <-chan HandlerError
+}
+
+var (
+	_ BatchSink = (*basicEventSink)(nil)
+	_ ContextEmitter = (*basicEventSink)(nil)
+	_ SyncSink = (*basicEventSink)(nil)
+	_ LogReader = (*basicEventSink)(nil)
+	_ LatestReader = (*basicEventSink)(nil)
+	_ CSVExporter = (*basicEventSink)(nil)
+	_ TypeManager = (*basicEventSink)(nil)
+	_ MiddlewareSink = (*basicEventSink)(nil)
+	_ ListenerManager = (*basicEventSink)(nil)
+	_ ListenerInspector = (*basicEventSink)(nil)
+	_ StatsSink = (*basicEventSink)(nil)
+	_ Pausable = (*basicEventSink)(nil)
+	_ Snapshotter = (*basicEventSink)(nil)
+	_ ErrorSource = (*basicEventSink)(nil)
+	_ Closer = (*basicEventSink)(nil)
+)
+
+// fireMany fires evs on sink, as one batch if it is a BatchSink.
+func fireMany(sink EventSink, evs []Event) {
+	if bs, ok := sink.(BatchSink); ok {
+		bs.FireMany(evs)
+		return
+	}
+	for _, ev := range evs {
+		sink.Fire(ev)
+	}
+}
+
+// emitContext emits an event on sink carrying the values in ctx.
+func emitContext(sink EventSink, ctx context.Context, eventType string, data interface{}) {
+	if ce, ok := sink.(ContextEmitter); ok {
+		ce.EmitContext(ctx, eventType, data)
+		return
+	}
+	sink.Fire(newEventContext(ctx, eventType, data))
+}
+
+// fireCollect calls the listeners for ev on sink and collects their
+// results, if sink is a SyncSink. Otherwise it just fires ev.
+func fireCollect(sink EventSink, ev Event) ([]interface{}, []error) {
+	if ss, ok := sink.(SyncSink); ok {
+		return ss.FireCollect(ev)
+	}
+	sink.Fire(ev)
+	return nil, nil
+}
+
+// removeEventListenerSync removes handler from sink, waiting for the
+// removal's meta-events if sink is a SyncSink.
+func removeEventListenerSync(sink EventSink, eventType string, handler EventHandler) {
+	if ss, ok := sink.(SyncSink); ok {
+		ss.RemoveEventListenerSync(eventType, handler)
+		return
+	}
+	sink.RemoveEventListener(eventType, handler)
+}
+
+// addEventListenerTagged adds handler to sink with a tag, or without one
+// if sink isn't a ListenerManager. The same goes for the other
+// addEventListener helpers below, which fall back to plain listeners.
+func addEventListenerTagged(sink EventSink, eventType, tag string, handler EventHandler) {
+	if lm, ok := sink.(ListenerManager); ok {
+		lm.AddEventListenerTagged(eventType, tag, handler)
+		return
+	}
+	sink.AddEventListener(eventType, handler)
+}
+
+func addEventListenerWithPriority(sink EventSink, eventType string, priority int, handler EventHandler) {
+	if lm, ok := sink.(ListenerManager); ok {
+		lm.AddEventListenerWithPriority(eventType, priority, handler)
+		return
+	}
+	sink.AddEventListener(eventType, handler)
+}
+
+// addPatternListener adds handler to sink on pattern, or does nothing if
+// sink isn't a PatternSink, since a pattern taken as an exact type would
+// match only itself.
+func addPatternListener(sink EventSink, pattern string, priority int, handler EventHandler) {
+	if ps, ok := sink.(PatternSink); ok {
+		ps.AddPatternListenerWithPriority(pattern, priority, handler)
+	}
+}
+
+func addEventListenerIf(sink EventSink, eventType string, pred func(Event) bool, handler EventHandler) {
+	if lm, ok := sink.(ListenerManager); ok {
+		lm.AddEventListenerIf(eventType, pred, handler)
+		return
+	}
+	if handler != nil {
+		sink.AddEventListener(eventType, WithFilter(handler, pred))
+	}
+}
+
+func addEventListenerWithLabels(sink EventSink, eventType string, selector map[string]string, handler EventHandler) {
+	if lm, ok := sink.(ListenerManager); ok {
+		lm.AddEventListenerWithLabels(eventType, selector, handler)
+		return
+	}
+	selector = copyLabels(selector)
+	addEventListenerIf(sink, eventType, func(ev Event) bool { return MatchLabels(ev, selector) }, handler)
+}
+
+func onceWhen(sink EventSink, eventType string, handler EventHandler, cond Condition) {
+	if lm, ok := sink.(ListenerManager); ok {
+		lm.OnceWhen(eventType, handler, cond)
+		return
+	}
+	if handler != nil {
+		sink.AddEventListener(eventType, WithCondition(WithMaxCalls(handler, 1), cond))
+	}
+}
+
+func removeByTag(sink EventSink, tag string) {
+	if lm, ok := sink.(ListenerManager); ok {
+		lm.RemoveByTag(tag)
+	}
+}
+
+func pruneExpired(sink EventSink) int {
+	if lm, ok := sink.(ListenerManager); ok {
+		return lm.PruneExpired()
+	}
+	return 0
+}
+
+type Middleware func(EventHandler) EventHandler
+
 type basicEventSink struct {
 	listeners map[string][]EventHandler
 	eventTypes map[string]Event
 	mutex *sync.Mutex
-	log *generic.LinkedList[Event]
+	log eventLog
 	logTTL time.Duration
+	middleware []Middleware
+	tags map[string]map[listenerKey]bool
+	aliases map[string][]string
+	validators map[string]Validator
+	separator string
+	bubbleOrder BubbleOrder
+	sync bool
+	reapInterval time.Duration
+	done chan struct{}
+	closeOnce *sync.Once
+	closed bool
+	clock Clock
+	errs chan HandlerError
+	typeLogs map[string]eventLog
+	typeLogCapacity int
+	seq uint64
+	paused bool
+	pending []Event
+	maxPending int
+	fired map[string]uint64
+	failed map[string]uint64
+	autoRegister bool
+	autoRegisterLimit int
+	autoTypes map[string]bool
+	priorities map[listenerKey]int
+	patterns map[string]bool
+	patternOrder map[listenerKey]uint64
+	patternSeq uint64
+	maxDispatch int
+	dropOnSaturation bool
+	pool *dispatchPool
+	metaActive map[int]int
+	latest map[string]Event
+	changeFns []func(ListenerMeta)
+	stormRate float64
+	stormWindow time.Duration
+	stormCooldown time.Duration
+	storms map[string]*stormState
+	handlerTimeout time.Duration
+	typeTimeouts map[string]time.Duration
+	debug bool
+}
+
+type listenerKey struct {
+	eventType string
+	id int64
 }
 
-func NewEventSink(logTTL time.Duration) EventSink {
-	return &basicEventSink{
+type SinkOption func(*basicEventSink)
+
+func NewEventSink(logTTL time.Duration, opts ...SinkOption) EventSink {
+	return newBasicEventSink(newListLog(), logTTL, opts)
+}
+
+// NewRingBufferEventSink returns a sink whose log is kept in a preallocated
+// circular buffer holding at most capacity events (and no events older than
+// logTTL). Prefer it over NewEventSink for high-throughput sinks, where a
+// bounded log without per-event allocations matters more than retaining
+// every event within logTTL. A capacity <= 0 falls back to NewEventSink.
+//
+// When the buffer is full, a new event evicts the oldest of the events
+// with the lowest priority (see Prioritized), so important events outlive
+// routine ones. If the new event has a lower priority than every event in
+// the buffer, it is not logged.
+func NewRingBufferEventSink(capacity int, logTTL time.Duration, opts ...SinkOption) EventSink {
+	if capacity <= 0 {
+		return NewEventSink(logTTL, opts...)
+	}
+	return newBasicEventSink(newRingLog(capacity), logTTL, opts)
+}
+
+// NewSyncEventSink returns a sink that calls handlers in the goroutine
+// that fires the event, one at a time in registration order, instead of
+// each in its own goroutine. Meta-events, logging and removal of expired
+// handlers behave as in NewEventSink, but have all happened by the time
+// Fire returns. It is intended for tests and single-threaded programs; a
+// slow handler blocks whoever fires the event.
+func NewSyncEventSink(logTTL time.Duration, opts ...SinkOption) EventSink {
+	opts = append([]SinkOption{SinkSync()}, opts...)
+	return NewEventSink(logTTL, opts...)
+}
+
+// SinkSync makes a sink call handlers synchronously. See NewSyncEventSink.
+func SinkSync() SinkOption {
+	return func(es *basicEventSink) {
+		es.sync = true
+	}
+}
+
+// async runs fn in a new goroutine, or inline for a synchronous sink.
+func (es *basicEventSink) async(fn func()) {
+	if es.sync {
+		fn()
+	} else {
+		go fn()
+	}
+}
+
+func newBasicEventSink(log eventLog, logTTL time.Duration, opts []SinkOption) *basicEventSink {
+	es := &basicEventSink{
 		listeners: map[string][]EventHandler{},
 		eventTypes: map[string]Event{},
 		mutex: &sync.Mutex{},
-		log: generic.NewLinkedList[Event](),
+		log: log,
+		tags: map[string]map[listenerKey]bool{},
+		aliases: map[string][]string{},
+		validators: map[string]Validator{},
+		done: make(chan struct{}),
+		errs: make(chan HandlerError, ErrorBufferSize),
+		maxPending: DefaultMaxPending,
+		fired: map[string]uint64{},
+		failed: map[string]uint64{},
+		autoRegister: true,
+		autoTypes: map[string]bool{},
+		priorities: map[listenerKey]int{},
+		patterns: map[string]bool{},
+		patternOrder: map[listenerKey]uint64{},
+		metaActive: map[int]int{},
+		latest: map[string]Event{},
+		stormCooldown: DefaultStormCooldown,
+		storms: map[string]*stormState{},
+		typeTimeouts: map[string]time.Duration{},
+		closeOnce: &sync.Once{},
 		logTTL: logTTL,
 	}
+	for _, opt := range opts {
+		opt(es)
+	}
+	if es.maxDispatch > 0 {
+		es.pool = newDispatchPool(es.maxDispatch, es.dropOnSaturation)
+	}
+	if es.reapInterval > 0 {
+		go es.reap()
+	}
+	return es
+}
+
+// Use registers middleware that wraps every handler subsequently added to
+// the sink. As with net/http middleware chains, the first middleware
+// registered is the outermost wrapper, so it runs first on each call,
+// before the middleware registered after it.
+func (es *basicEventSink) Use(mw Middleware) {
+	es.mutex.Lock()
+	es.middleware = append(es.middleware, mw)
+	es.mutex.Unlock()
 }
 
 func (es *basicEventSink) AddEventListener(eventType string, handler EventHandler) {
+	es.addEventListener(eventType, "", 0, false, handler)
+}
+
+// AddEventListenerTagged adds a listener that can later be removed, along
+// with every other listener sharing its tag, by RemoveByTag.
+func (es *basicEventSink) AddEventListenerTagged(eventType, tag string, handler EventHandler) {
+	es.addEventListener(eventType, tag, 0, false, handler)
+}
+
+func (es *basicEventSink) addEventListener(eventType, tag string, priority int, pattern bool, handler EventHandler) {
+	if handler == nil {
+		return
+	}
 	es.mutex.Lock()
-	defer es.mutex.Unlock()
-	es.listeners[eventType] = append(es.listeners[eventType], handler)
-	go func() {
+	if es.closed {
+		es.mutex.Unlock()
+		return
+	}
+	id := handler.ID()
+	for _, eh := range es.listeners[eventType] {
+		if eh.ID() == id {
+			// already listening; adding it again would call it twice
+			es.mutex.Unlock()
+			return
+		}
+	}
+	for i := len(es.middleware) - 1; i >= 0; i-- {
+		handler = es.middleware[i](handler)
+	}
+	if handler == nil {
+		es.mutex.Unlock()
+		return
+	}
+	es.insertListener(eventType, priority, handler)
+	if pattern {
+		es.addPattern(eventType, handler.ID())
+	}
+	if tag != "" {
+		keys, ok := es.tags[tag]
+		if !ok {
+			keys = map[listenerKey]bool{}
+			es.tags[tag] = keys
+		}
+		keys[listenerKey{eventType, handler.ID()}] = true
+	}
+	depth, ok := es.metaDepth()
+	fns := es.changeFns
+	es.mutex.Unlock()
+	notifyChange(fns, ListenerMeta{
+		EventType: eventType,
+		HandlerID: handler.ID(),
+		Change: EventTypeHandlerAdded,
+	})
+	if !ok {
+		return
+	}
+	es.async(func() {
 		data := &ListenerMeta{
 			EventType: eventType,
 			HandlerID: handler.ID(),
+			Depth: depth,
 		}
 		es.Emit(EventTypeHandlerAdded, data)
-	}()
+	})
 }
 
 func (es *basicEventSink) RemoveEventListener(eventType string, handler EventHandler) {
+	es.removeEventListener(eventType, handler, false)
+}
+
+// RemoveEventListenerSync is like RemoveEventListener, but calls the
+// listeners for the EventTypeHandlerRemoved event, and closes the removed
+// handler, in the calling goroutine before returning, rather than in the
+// background. The mutex is released first, so those listeners may add and
+// remove listeners themselves. The caller is blocked until they are done,
+// and as with FireCollect, they run even on an asynchronous sink.
+func (es *basicEventSink) RemoveEventListenerSync(eventType string, handler EventHandler) {
+	es.removeEventListener(eventType, handler, true)
+}
+
+// removeEventListener removes handler. If inline is set, its
+// EventTypeHandlerRemoved events are dispatched and the removed handlers
+// closed before it returns, rather than in the background.
+func (es *basicEventSink) removeEventListener(eventType string, handler EventHandler, inline bool) {
+	if handler == nil {
+		return
+	}
 	es.mutex.Lock()
-	defer es.mutex.Unlock()
 	out := make([]EventHandler, 0, len(es.listeners[eventType]))
 	id := handler.ID()
 	evts := make([]Event, 0, 1)
+	removed := make([]EventHandler, 0, 1)
+	depth, notify := es.metaDepth()
 	for _, eh := range es.listeners[eventType] {
 		if eh.ID() != id {
 			out = append(out, eh)
 		} else {
+			removed = append(removed, eh)
 			data := &ListenerMeta{
 				EventType: eventType,
 				HandlerID: id,
+				Depth: depth,
 			}
 			evts = append(evts, NewEvent(EventTypeHandlerRemoved, data))
 		}
 	}
 	if len(out) == 0 {
 		delete(es.listeners, eventType)
+		delete(es.patterns, eventType)
 	} else {
 		es.listeners[eventType] = out
 	}
 	if len(evts) > 0 {
+		key := listenerKey{eventType, id}
+		delete(es.priorities, key)
+		delete(es.patternOrder, key)
+		for tag, keys := range es.tags {
+			delete(keys, key)
+			if len(keys) == 0 {
+				delete(es.tags, tag)
+			}
+		}
+	}
+	fns := es.changeFns
+	es.mutex.Unlock()
+	if len(removed) > 0 {
+		notifyChange(fns, ListenerMeta{
+			EventType: eventType,
+			HandlerID: id,
+			Change: EventTypeHandlerRemoved,
+		})
+	}
+	if notify {
 		for _, ev := range evts {
 			xev := ev
-			go func() {
-				es.Fire(xev)
-			}()
+			if inline {
+				es.fireRemoved(xev)
+			} else {
+				es.async(func() {
+					es.Fire(xev)
+				})
+			}
 		}
 	}
+	for _, eh := range removed {
+		xeh := eh
+		closeFn := func() {
+			err := closeHandler(xeh)
+			if err != nil {
+				es.reportError(eventType, xeh, nil, err)
+			}
+		}
+		if inline {
+			closeFn()
+		} else {
+			es.async(closeFn)
+		}
+	}
+}
+
+// AddEventListenerIf adds a listener that is only called for events that
+// satisfy pred. The filtered listener keeps handler's ID, so it can be
+// removed by passing handler to RemoveEventListener.
+func (es *basicEventSink) AddEventListenerIf(eventType string, pred func(Event) bool, handler EventHandler) {
+	if handler == nil {
+		return
+	}
+	es.AddEventListener(eventType, WithFilter(handler, pred))
+}
+
+// RemoveByTag removes every listener added with the given tag, regardless
+// of event type.
+func (es *basicEventSink) RemoveByTag(tag string) {
+	es.mutex.Lock()
+	keys := make([]listenerKey, 0, len(es.tags[tag]))
+	for key := range es.tags[tag] {
+		keys = append(keys, key)
+	}
+	es.mutex.Unlock()
+	for _, key := range keys {
+		es.RemoveEventListener(key.eventType, HandlerReference(key.id))
+	}
+}
+
+// PruneExpired removes every expired listener, without waiting for another
+// event to reach it, and returns the number removed. This lets timeout and
+// max calls handlers on event types that have gone quiet be cleaned up.
+func (es *basicEventSink) PruneExpired() int {
+	es.mutex.Lock()
+	all := []typedListener{}
+	for eventType, listeners := range es.listeners {
+		for _, h := range listeners {
+			all = append(all, typedListener{eventType, h})
+		}
+	}
+	es.mutex.Unlock()
+	n := 0
+	for _, l := range all {
+		if l.handler.Expired() {
+			es.RemoveEventListener(l.eventType, l.handler)
+			n += 1
+		}
+	}
+	return n
 }
 
 func (es *basicEventSink) Once(eventType string, handler EventHandler) {
+	if handler == nil {
+		return
+	}
 	es.AddEventListener(eventType, WithMaxCalls(handler, 1))
 }
 
+// OnceWhen calls handler for the first event of the given type that
+// satisfies cond, then removes it. Events failing cond don't count. cond
+// is checked before the call is reserved, so an event failing it never
+// holds up a concurrent event that satisfies it.
+func (es *basicEventSink) OnceWhen(eventType string, handler EventHandler, cond Condition) {
+	if handler == nil {
+		return
+	}
+	es.AddEventListener(eventType, WithCondition(WithMaxCalls(handler, 1), cond))
+}
+
 func (es *basicEventSink) Fire(ev Event) {
-	eventType := ev.GetType()
-	es.log.Unshift(ev)
-	oldest := time.Now().Add(-es.logTTL)
-	es.log.PopIf(func(ev Event) bool { return ev.GetTime().Before(oldest) })
+	es.FireMany([]Event{ev})
+}
+
+// FireMany fires a batch of events in order, trimming the log and taking
+// the mutex once for the whole batch.
+//
+// The listeners for each event are copied while the mutex is held, and the
+// event is dispatched to that copy after it is released. Listeners added
+// or removed while an event is being dispatched, including by its own
+// handlers, therefore don't affect who receives that event: a listener
+// removed mid-dispatch may still be called with it, and one added
+// mid-dispatch first sees the next event. Handlers may be called
+// concurrently with one another, and with themselves, so any state they
+// keep must be synchronized; the decorators in this package do so.
+func (es *basicEventSink) FireMany(evs []Event) {
+	if len(evs) == 0 {
+		return
+	}
+	valid, batches, notices := es.accept(evs)
+	es.dispatch(valid, batches)
+	es.FireMany(notices)
+}
+
+// accept validates, numbers and logs evs, returning the valid events, the
+// listeners to dispatch each of them to, and the meta-events, such as
+// validation errors, to fire once they have been dispatched.
+func (es *basicEventSink) accept(evs []Event) ([]Event, [][]typedListener, []Event) {
+	valid := make([]Event, 0, len(evs))
+	batches := make([][]typedListener, 0, len(evs))
+	notices := []Event{}
+	typeLogs := []eventLog{}
+	// validators run before taking the mutex, so a slow validator doesn't
+	// hold up the sink, and one may read the sink without deadlocking
+	checked := make([]Event, 0, len(evs))
+	for _, ev := range evs {
+		if ev == nil || ev.GetType() == "" {
+			continue
+		}
+		if err := es.validate(ev); err != nil {
+			notices = append(notices, NewEvent(EventTypeValidationError, &ValidationErrorMeta{ev.GetType(), err.Error()}))
+			continue
+		}
+		checked = append(checked, ev)
+	}
 	es.mutex.Lock()
-	listeners := es.listeners[eventType]
-	if _, ok := es.eventTypes[eventType]; !ok {
-		es.eventTypes[eventType] = ev
+	if es.closed {
+		es.mutex.Unlock()
+		return valid, batches, []Event{}
+	}
+	for _, ev := range checked {
+		eventType := ev.GetType()
+		ev = numbered(ev, atomic.AddUint64(&es.seq, 1))
+		es.autoRegisterType(ev)
+		tracked := es.tracksType(eventType)
+		if tracked {
+			es.fired[eventType] += 1
+			es.latest[eventType] = ev
+			if storm := es.detectStorm(eventType); storm != nil {
+				notices = append(notices, storm)
+			}
+		}
+		valid = append(valid, ev)
+		if es.paused {
+			es.queue(ev)
+			batches = append(batches, nil)
+		} else {
+			batches = append(batches, es.matchListeners(eventType))
+		}
+		if es.typeLogs != nil {
+			var l eventLog
+			if tracked {
+				l = es.typeLog(eventType)
+			}
+			typeLogs = append(typeLogs, l)
+		}
 	}
 	es.mutex.Unlock()
-	if len(listeners) == 0 {
-		return
+	oldest := es.now().Add(-es.logTTL)
+	for i, ev := range valid {
+		es.log.Add(ev)
+		if len(typeLogs) > 0 && typeLogs[i] != nil {
+			typeLogs[i].Add(ev)
+			typeLogs[i].Trim(oldest)
+		}
 	}
-	for _, h := range listeners {
-		xh := h
-		go func() {
-			err := xh.Call(ev)
-			if err != nil {
-				if errors.Is(err, ErrExpired) {
-					es.RemoveEventListener(eventType, xh)
-					return
-				}
-				if !errors.Is(err, ErrIgnored) {
-					data := &ListenerMeta{
-						EventType: eventType,
-						HandlerID: xh.ID(),
-						Error: err.Error(),
-					}
-					es.Emit(EventTypeHandlerError, data)
+	es.log.Trim(oldest)
+	return valid, batches, notices
+}
+
+// dispatch calls the listeners in batches[i] with evs[i]. A synchronous
+// sink stops calling the listeners for an event when one of them returns
+// ErrStopPropagation.
+func (es *basicEventSink) dispatch(evs []Event, batches [][]typedListener) {
+	for i, listeners := range batches {
+		ev := evs[i]
+		for _, l := range listeners {
+			if es.sync {
+				_, err := es.callResult(l.eventType, l.handler, ev)
+				if errors.Is(err, ErrStopPropagation) {
+					break
 				}
+				continue
 			}
-			if xh.Expired() {
-				es.RemoveEventListener(eventType, xh)
+			es.spawn(l.eventType, l.handler, ev)
+		}
+	}
+}
+
+type typedListener struct {
+	eventType string
+	handler EventHandler
+}
+
+// matchListeners returns the listeners that should receive an event of the
+// given type, tagged with the event type they were registered under. The
+// result is a fresh slice, safe to use after the mutex is released; the
+// listener slices themselves are never modified in place, only replaced or
+// appended to. The caller must hold the mutex.
+func (es *basicEventSink) matchListeners(eventType string) []typedListener {
+	out := []typedListener{}
+	for _, t := range es.dispatchTypes(eventType) {
+		for _, h := range es.listeners[t] {
+			if es.patterns[t] && es.isPatternListener(t, h) {
+				// matched as a pattern below
+				continue
 			}
-		}()
+			out = append(out, typedListener{t, h})
+		}
 	}
+	return append(out, es.matchPatterns(eventType)...)
+}
+
+// call calls h in the background, for an asynchronous sink. The other
+// listeners for ev have already been started by then, so a handler that
+// returns ErrStopPropagation can't stop them, and is reported as failing.
+func (es *basicEventSink) call(eventType string, h EventHandler, ev Event) {
+	_, err := es.callResult(eventType, h, ev)
+	if errors.Is(err, ErrStopPropagation) {
+		es.reportError(eventType, h, ev, fmt.Errorf("%w: not supported by an asynchronous sink", err))
+	}
+}
+
+// callResult calls h with ev, handling its expiry and reporting its
+// failure, and returns its result and error.
+func (es *basicEventSink) callResult(eventType string, h EventHandler, ev Event) (interface{}, error) {
+	if expired(ev, es.now()) {
+		return nil, ErrIgnored
+	}
+	if depth, ok := metaEventDepth(ev); ok {
+		es.enterMeta(depth)
+		defer es.exitMeta(depth)
+	}
+	ctx := withReporter(eventContext(ev), func(err error) {
+		es.reportError(eventType, h, ev, err)
+	})
+	if es.debug {
+		ctx = withDebug(ctx)
+	}
+	if timeout := es.timeoutFor(eventType); timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	res, err := callResult(ctx, h, ev)
+	if err != nil {
+		if errors.Is(err, ErrExpired) {
+			es.RemoveEventListener(eventType, h)
+			return nil, err
+		}
+		if !errors.Is(err, ErrIgnored) && !errors.Is(err, ErrStopPropagation) {
+			es.reportError(eventType, h, ev, err)
+		} else if reason := IgnoredReason(err); reason != "" && eventType != EventTypeHandlerIgnored {
+			es.reportIgnored(eventType, h, reason)
+		}
+	}
+	if h.Expired() {
+		es.RemoveEventListener(eventType, h)
+	}
+	return res, err
 }
 
 func (es *basicEventSink) Emit(eventType string, data interface{}) {
@@ -140,13 +847,28 @@ func (es *basicEventSink) Emit(eventType string, data interface{}) {
 	es.Fire(ev)
 }
 
+func (es *basicEventSink) EmitMany(eventType string, data []interface{}) {
+	es.FireMany(newEvents(eventType, data))
+}
+
+func newEvents(eventType string, data []interface{}) []Event {
+	evs := make([]Event, len(data))
+	for i, d := range data {
+		evs[i] = NewEvent(eventType, d)
+	}
+	return evs
+}
+
+// Log returns the events in the log in the order they were fired, most
+// recent first. For replayed or backfilled events this can differ from
+// the order of their event times; see LogSortedByTime.
 func (es *basicEventSink) Log() []Event {
 	return es.log.Slice()
 }
 
 func (es *basicEventSink) RegisterEventType(ev Event) {
 	es.mutex.Lock()
-	es.eventTypes[ev.GetType()] = ev
+	es.registerLocked(ev)
 	es.mutex.Unlock()
 }
 
@@ -168,7 +890,19 @@ type PrefixedEventSource struct {
 	prefix string
 }
 
+// NewPrefixedEventSource returns a view of sink in which event types are
+// prefixed with prefix and a "-". Wrapping a prefixed source in another
+// yields a single source with the compound prefix, so that "inner" wrapped
+// in "outer" fires "x" as "inner-outer-x" and reads it back as "x". An
+// empty prefix returns sink unchanged.
 func NewPrefixedEventSource(prefix string, sink EventSink) EventSink {
+	prefix = strings.TrimSuffix(prefix, "-")
+	if prefix == "" {
+		return sink
+	}
+	if inner, ok := sink.(*PrefixedEventSource); ok {
+		return &PrefixedEventSource{inner.EventSink, inner.prefix+prefix+"-"}
+	}
 	return &PrefixedEventSource{sink, prefix+"-"}
 }
 
@@ -177,23 +911,77 @@ func (es *PrefixedEventSource) AddEventListener(eventType string, handler EventH
 }
 
 func (es *PrefixedEventSource) RemoveEventListener(eventType string, handler EventHandler) {
-	es.RemoveEventListener(es.prefix+eventType, handler)
+	es.EventSink.RemoveEventListener(es.prefix+eventType, handler)
+}
+
+func (es *PrefixedEventSource) RemoveEventListenerSync(eventType string, handler EventHandler) {
+	removeEventListenerSync(es.EventSink, es.prefix+eventType, handler)
+}
+
+func (es *PrefixedEventSource) AddEventListenerTagged(eventType, tag string, handler EventHandler) {
+	addEventListenerTagged(es.EventSink, es.prefix+eventType, tag, handler)
+}
+
+func (es *PrefixedEventSource) AddEventListenerIf(eventType string, pred func(Event) bool, handler EventHandler) {
+	addEventListenerIf(es.EventSink, es.prefix+eventType, pred, handler)
 }
 
 func (es *PrefixedEventSource) Once(eventType string, handler EventHandler) {
-	es.Once(es.prefix+eventType, handler)
+	es.EventSink.Once(es.prefix+eventType, handler)
+}
+
+func (es *PrefixedEventSource) OnceWhen(eventType string, handler EventHandler, cond Condition) {
+	onceWhen(es.EventSink, es.prefix+eventType, handler, cond)
+}
+
+func (es *PrefixedEventSource) RemoveByTag(tag string) {
+	removeByTag(es.EventSink, tag)
+}
+
+func (es *PrefixedEventSource) PruneExpired() int {
+	return pruneExpired(es.EventSink)
+}
+
+func (es *PrefixedEventSource) AliasEventType(oldType, newType string) {
+	if tm, ok := es.EventSink.(TypeManager); ok {
+		tm.AliasEventType(es.prefix+oldType, es.prefix+newType)
+	}
 }
 
 func (es *PrefixedEventSource) As(ev Event) Event {
+	if ev == nil || ev.GetType() == "" {
+		return nil
+	}
 	return ev.As(es.prefix+ev.GetType())
 }
 
+// prefixed adds the prefix to eventType, unless it is empty, so that an
+// event without a type is still rejected by the sink.
+func (es *PrefixedEventSource) prefixed(eventType string) string {
+	if eventType == "" {
+		return ""
+	}
+	return es.prefix+eventType
+}
+
 func (es *PrefixedEventSource) Fire(ev Event) {
 	es.EventSink.Fire(es.As(ev))
 }
 
 func (es *PrefixedEventSource) Emit(eventType string, data interface{}) {
-	es.EventSink.Emit(es.prefix+eventType, data)
+	es.EventSink.Emit(es.prefixed(eventType), data)
+}
+
+func (es *PrefixedEventSource) FireMany(evs []Event) {
+	prefixed := make([]Event, len(evs))
+	for i, ev := range evs {
+		prefixed[i] = es.As(ev)
+	}
+	fireMany(es.EventSink, prefixed)
+}
+
+func (es *PrefixedEventSource) EmitMany(eventType string, data []interface{}) {
+	fireMany(es.EventSink, newEvents(es.prefixed(eventType), data))
 }
 
 func (es *PrefixedEventSource) Filter(all []Event) []Event {
@@ -210,10 +998,24 @@ func (es *PrefixedEventSource) Log() []Event {
 	return es.Filter(es.EventSink.Log())
 }
 
+func (es *PrefixedEventSource) LogSortedByTime() []Event {
+	evs := es.Log()
+	sortByTime(evs)
+	return evs
+}
+
 func (es *PrefixedEventSource) RegisterEventType(ev Event) {
 	es.EventSink.RegisterEventType(es.As(ev))
 }
 
+func (es *PrefixedEventSource) RegisterEventTypeWithValidator(ev Event, validator Validator) {
+	if tm, ok := es.EventSink.(TypeManager); ok {
+		tm.RegisterEventTypeWithValidator(es.As(ev), validator)
+	} else {
+		es.EventSink.RegisterEventType(es.As(ev))
+	}
+}
+
 func (es *PrefixedEventSource) ListEventTypes() []Event {
 	return es.Filter(es.EventSink.ListEventTypes())
 }
@@ -223,20 +1025,45 @@ type LoggedEventSink struct {
 	w io.Writer
 }
 
+// NewLoggedEventSink returns a view of sink that writes each event fired
+// through it to w as a line of JSON. Besides EventSink, it is a BatchSink,
+// ContextEmitter and SyncSink; use sink directly for the rest.
 func NewLoggedEventSink(sink EventSink, w io.Writer) EventSink {
 	return &LoggedEventSink{sink, w}
 }
 
-func (es *LoggedEventSink) Fire(ev Event) {
+func (es *LoggedEventSink) RemoveEventListenerSync(eventType string, handler EventHandler) {
+	removeEventListenerSync(es.EventSink, eventType, handler)
+}
+
+func (es *LoggedEventSink) write(ev Event) {
+	if ev == nil {
+		return
+	}
 	data, err := json.Marshal(ev)
 	if err == nil {
 		data = append(data, '\n')
 		es.w.Write(data)
 	}
+}
+
+func (es *LoggedEventSink) Fire(ev Event) {
+	es.write(ev)
 	es.EventSink.Fire(ev)
 }
 
+func (es *LoggedEventSink) FireMany(evs []Event) {
+	for _, ev := range evs {
+		es.write(ev)
+	}
+	fireMany(es.EventSink, evs)
+}
+
 func (es *LoggedEventSink) Emit(eventType string, data interface{}) {
 	ev := NewEvent(eventType, data)
 	es.Fire(ev)
 }
+
+func (es *LoggedEventSink) EmitMany(eventType string, data []interface{}) {
+	es.FireMany(newEvents(eventType, data))
+}
@@ -0,0 +1,1030 @@
+package events
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// coreSink hides every method of the sink it wraps beyond EventSink, for
+// testing how views fall back when a sink lacks an optional capability.
+type coreSink struct {
+	EventSink
+}
+
+func TestUse(t *testing.T) {
+	tests := []struct {
+		name string
+		before []string
+		after []string
+		want []string
+	}{
+		{"none", nil, nil, []string{"handler"}},
+		{"one", []string{"a"}, nil, []string{"a", "handler"}},
+		{"registration order", []string{"a", "b", "c"}, nil, []string{"a", "b", "c", "handler"}},
+		{"added after handler", []string{"a"}, []string{"b"}, []string{"a", "handler"}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			sink := NewSyncEventSink(time.Minute)
+			calls := []string{}
+			named := func(name string) Middleware {
+				return func(h EventHandler) EventHandler {
+					return NewEventHandlerWithID(h.ID(), func(ev Event) error {
+						calls = append(calls, name)
+						return h.Call(ev)
+					})
+				}
+			}
+			for _, name := range tc.before {
+				sink.(MiddlewareSink).Use(named(name))
+			}
+			sink.AddEventListener("test", NewEventHandler(func(Event) error {
+				calls = append(calls, "handler")
+				return nil
+			}))
+			for _, name := range tc.after {
+				sink.(MiddlewareSink).Use(named(name))
+			}
+			sink.Emit("test", 1.0)
+			if !reflect.DeepEqual(calls, tc.want) {
+				t.Errorf("calls = %v, want %v", calls, tc.want)
+			}
+		})
+	}
+}
+
+func TestUseWrapsEveryHandler(t *testing.T) {
+	tests := []struct {
+		name string
+		listeners map[string]int
+		fire []string
+		want int
+	}{
+		{"no listeners", nil, []string{"a"}, 0},
+		{"one type", map[string]int{"a": 3}, []string{"a"}, 3},
+		{"two types", map[string]int{"a": 2, "b": 1}, []string{"a", "b", "b"}, 4},
+		{"unfired type", map[string]int{"a": 2, "b": 1}, []string{"a"}, 2},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			sink := NewSyncEventSink(time.Minute)
+			count := 0
+			sink.(MiddlewareSink).Use(func(h EventHandler) EventHandler {
+				return NewEventHandlerWithID(h.ID(), func(ev Event) error {
+					count += 1
+					return h.Call(ev)
+				})
+			})
+			for eventType, n := range tc.listeners {
+				for i := 0; i < n; i++ {
+					sink.AddEventListener(eventType, NewEventHandler(func(Event) error { return nil }))
+				}
+			}
+			for _, eventType := range tc.fire {
+				sink.Emit(eventType, 1.0)
+			}
+			if count != tc.want {
+				t.Errorf("middleware called %d times, want %d", count, tc.want)
+			}
+		})
+	}
+}
+
+func TestViewCapabilityFallbacks(t *testing.T) {
+	views := []struct {
+		name string
+		view func(EventSink) EventSink
+	}{
+		{"prefixed", func(sink EventSink) EventSink { return NewPrefixedEventSource("p", sink) }},
+		{"mapped", func(sink EventSink) EventSink { return NewMappedEventSource(map[string]string{"x": "a"}, sink) }},
+		{"scoped", func(sink EventSink) EventSink { return NewScopedSink(context.Background(), sink) }},
+	}
+	tests := []struct {
+		name string
+		add func(view EventSink, h EventHandler)
+		fire []interface{}
+		want int
+	}{
+		{
+			"tagged",
+			func(view EventSink, h EventHandler) { view.(ListenerManager).AddEventListenerTagged("a", "tag", h) },
+			[]interface{}{1.0, 2.0},
+			2,
+		},
+		{
+			"priority",
+			func(view EventSink, h EventHandler) { view.(ListenerManager).AddEventListenerWithPriority("a", 5, h) },
+			[]interface{}{1.0},
+			1,
+		},
+		{
+			"if",
+			func(view EventSink, h EventHandler) {
+				view.(ListenerManager).AddEventListenerIf("a", func(ev Event) bool { return ev.(Valuer).GetValue() > 1 }, h)
+			},
+			[]interface{}{1.0, 2.0, 3.0},
+			2,
+		},
+		{
+			"labels",
+			func(view EventSink, h EventHandler) {
+				view.(ListenerManager).AddEventListenerWithLabels("a", map[string]string{"room": "kitchen"}, h)
+			},
+			[]interface{}{1.0},
+			0,
+		},
+		{
+			"once when",
+			func(view EventSink, h EventHandler) { view.(ListenerManager).OnceWhen("a", h, InRange(2, 3)) },
+			[]interface{}{1.0, 2.0, 3.0},
+			1,
+		},
+	}
+	for _, v := range views {
+		for _, tc := range tests {
+			t.Run(v.name+"/"+tc.name, func(t *testing.T) {
+				view := v.view(coreSink{NewSyncEventSink(time.Minute)})
+				rec := RecordingHandler()
+				tc.add(view, rec)
+				view.(BatchSink).EmitMany("a", tc.fire)
+				if n := len(rec.Calls()); n != tc.want {
+					t.Errorf("handler called %d times, want %d", n, tc.want)
+				}
+			})
+		}
+	}
+}
+
+func TestViewFireCollectFallback(t *testing.T) {
+	tests := []struct {
+		name string
+		sink func() EventSink
+		wantResults int
+	}{
+		{"sync sink", func() EventSink { return NewSyncEventSink(time.Minute) }, 1},
+		{"core sink", func() EventSink { return coreSink{NewSyncEventSink(time.Minute)} }, 0},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			view := NewPrefixedEventSource("p", tc.sink())
+			rec := RecordingHandler()
+			view.AddEventListener("a", rec)
+			results, errs := view.(SyncSink).FireCollect(NewEvent("a", 1.0))
+			if len(results) != tc.wantResults || len(failures(results, errs)) != 0 {
+				t.Errorf("FireCollect = %v, %v, want %d results and no failures", results, errs, tc.wantResults)
+			}
+			if n := len(rec.Calls()); n != 1 {
+				t.Errorf("handler called %d times, want 1", n)
+			}
+		})
+	}
+}
+
+func TestRemoveByTag(t *testing.T) {
+	type listener struct {
+		eventType string
+		tag string
+	}
+	tests := []struct {
+		name string
+		listeners []listener
+		remove string
+		wantCounts map[string]int
+		wantRemoved []string
+	}{
+		{
+			"across types",
+			[]listener{{"a", "x"}, {"b", "x"}, {"b", "y"}, {"c", ""}},
+			"x",
+			map[string]int{"a": 0, "b": 1, "c": 1},
+			[]string{"a", "b"},
+		},
+		{
+			"unknown tag",
+			[]listener{{"a", "x"}},
+			"y",
+			map[string]int{"a": 1},
+			[]string{},
+		},
+		{
+			"untagged only",
+			[]listener{{"a", ""}, {"b", ""}},
+			"",
+			map[string]int{"a": 1, "b": 1},
+			[]string{},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			sink := NewSyncEventSink(time.Minute)
+			lm := sink.(ListenerManager)
+			ids := map[int64]string{}
+			for _, l := range tc.listeners {
+				h := NewEventHandler(func(Event) error { return nil })
+				ids[h.ID()] = l.eventType
+				if l.tag == "" {
+					sink.AddEventListener(l.eventType, h)
+				} else {
+					lm.AddEventListenerTagged(l.eventType, l.tag, h)
+				}
+			}
+			removed := RecordingHandler()
+			sink.AddEventListener(EventTypeHandlerRemoved, removed)
+			lm.RemoveByTag(tc.remove)
+			for eventType, want := range tc.wantCounts {
+				if n := sink.(ListenerInspector).ListenerCount(eventType); n != want {
+					t.Errorf("%d listeners for %q, want %d", n, eventType, want)
+				}
+			}
+			got := []string{}
+			for _, ev := range removed.Calls() {
+				meta := ev.GetData().(*ListenerMeta)
+				if ids[meta.HandlerID] != meta.EventType {
+					t.Errorf("removal of handler %d from %q, which wasn't added there", meta.HandlerID, meta.EventType)
+				}
+				got = append(got, meta.EventType)
+			}
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, tc.wantRemoved) {
+				t.Errorf("removal events for %v, want %v", got, tc.wantRemoved)
+			}
+		})
+	}
+}
+
+func TestOnceWhen(t *testing.T) {
+	tests := []struct {
+		name string
+		cond Condition
+		vals []float64
+		want []float64
+	}{
+		{"first event", InRange(0, 10), []float64{1, 2, 3}, []float64{1}},
+		{"failing events don't count", InRange(2, 3), []float64{1, 5, 3, 2}, []float64{3}},
+		{"never satisfied", InRange(10, 20), []float64{1, 2}, []float64{}},
+		{"not", Not(InRange(0, 1)), []float64{0.5, 1, 7, 8}, []float64{7}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			sink := NewSyncEventSink(time.Minute)
+			rec := RecordingHandler()
+			sink.(ListenerManager).OnceWhen("test", rec, tc.cond)
+			for _, val := range tc.vals {
+				sink.Emit("test", val)
+			}
+			if got := logValues(rec.Calls()); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("handled %v, want %v", got, tc.want)
+			}
+			wantListeners := 1
+			if len(tc.want) > 0 {
+				wantListeners = 0
+			}
+			if n := sink.(ListenerInspector).ListenerCount("test"); n != wantListeners {
+				t.Errorf("%d listeners left, want %d", n, wantListeners)
+			}
+		})
+	}
+}
+
+func TestOnceWhenAsync(t *testing.T) {
+	tests := []struct {
+		name string
+		vals []float64
+	}{
+		{"passing after failing", []float64{1, 10}},
+		{"interleaved", []float64{1, 10, 2, 11, 3, 12}},
+		{"failing after passing", []float64{10, 1, 2, 3}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			sink := NewEventSink(time.Minute)
+			defer sink.(Closer).Close()
+			rec := RecordingHandler()
+			// events failing the condition are slow to check, so that they
+			// are still in flight when the passing ones arrive
+			cond := func(ev Event) (bool, error) {
+				if ev.(Valuer).GetValue() < 10 {
+					time.Sleep(20 * time.Millisecond)
+					return false, nil
+				}
+				return true, nil
+			}
+			sink.(ListenerManager).OnceWhen("test", rec, cond)
+			for _, val := range tc.vals {
+				sink.Emit("test", val)
+			}
+			if !rec.WaitForCalls(1, time.Second) {
+				t.Fatal("handler never called")
+			}
+			if n := waitForListeners(sink, "test", 0, time.Second); n != 0 {
+				t.Errorf("%d listeners left, want 0", n)
+			}
+			time.Sleep(30 * time.Millisecond)
+			calls := logValues(rec.Calls())
+			if len(calls) != 1 || calls[0] < 10 {
+				t.Errorf("handled %v, want one passing value", calls)
+			}
+		})
+	}
+}
+
+func TestFireMany(t *testing.T) {
+	tests := []struct {
+		name string
+		sink func() EventSink
+		listeners int
+		data []interface{}
+		wantLogged int
+	}{
+		{"sync empty", func() EventSink { return NewSyncEventSink(time.Minute) }, 2, nil, 0},
+		{"sync", func() EventSink { return NewSyncEventSink(time.Minute) }, 3, []interface{}{1.0, 2.0, 3.0}, 3},
+		{"async", func() EventSink { return NewEventSink(time.Minute) }, 3, []interface{}{1.0, 2.0, 3.0, 4.0}, 4},
+		{"ring", func() EventSink { return NewRingBufferEventSink(2, time.Minute, SinkSync()) }, 1, []interface{}{1.0, 2.0, 3.0}, 2},
+	}
+	for _, tc := range tests {
+		for _, emit := range []bool{false, true} {
+			name := tc.name + "/FireMany"
+			if emit {
+				name = tc.name + "/EmitMany"
+			}
+			t.Run(name, func(t *testing.T) {
+				sink := tc.sink()
+				recs := make([]*Recorder, tc.listeners)
+				for i := range recs {
+					recs[i] = RecordingHandler()
+					sink.AddEventListener("test", recs[i])
+				}
+				if emit {
+					sink.(BatchSink).EmitMany("test", tc.data)
+				} else {
+					sink.(BatchSink).FireMany(newEvents("test", tc.data))
+				}
+				for i, rec := range recs {
+					if !rec.WaitForCalls(len(tc.data), time.Second) {
+						t.Fatalf("listener %d got %d events, want %d", i, len(rec.Calls()), len(tc.data))
+					}
+					got := logValues(rec.Calls())
+					sort.Float64s(got)
+					if want := logValues(newEvents("test", tc.data)); !reflect.DeepEqual(got, want) {
+						t.Errorf("listener %d got %v, want %v", i, got, want)
+					}
+				}
+				if n := len(filterLog(sink.Log(), "test")); n != tc.wantLogged {
+					t.Errorf("%d events logged, want %d", n, tc.wantLogged)
+				}
+			})
+		}
+	}
+}
+
+func BenchmarkEmitMany(b *testing.B) {
+	data := make([]interface{}, 100)
+	for i := range data {
+		data[i] = float64(i)
+	}
+	b.Run("Emit", func(b *testing.B) {
+		sink := NewSyncEventSink(time.Millisecond)
+		sink.AddEventListener("test", NewEventHandler(func(Event) error { return nil }))
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			for _, d := range data {
+				sink.Emit("test", d)
+			}
+		}
+	})
+	b.Run("EmitMany", func(b *testing.B) {
+		sink := NewSyncEventSink(time.Millisecond)
+		sink.AddEventListener("test", NewEventHandler(func(Event) error { return nil }))
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			sink.(BatchSink).EmitMany("test", data)
+		}
+	})
+}
+
+func TestPruneExpired(t *testing.T) {
+	tests := []struct {
+		name string
+		handlers func() []EventHandler
+		advance time.Duration
+		want int
+	}{
+		{
+			"timed out",
+			func() []EventHandler {
+				return []EventHandler{WithTimeout(NewEventHandler(func(Event) error { return nil }), time.Minute)}
+			},
+			2 * time.Minute,
+			1,
+		},
+		{
+			"not yet timed out",
+			func() []EventHandler {
+				return []EventHandler{WithTimeout(NewEventHandler(func(Event) error { return nil }), time.Hour)}
+			},
+			time.Minute,
+			0,
+		},
+		{
+			"mixed",
+			func() []EventHandler {
+				return []EventHandler{
+					WithTimeout(NewEventHandler(func(Event) error { return nil }), time.Minute),
+					WithTimeout(NewEventHandler(func(Event) error { return nil }), time.Second),
+					NewEventHandler(func(Event) error { return nil }),
+				}
+			},
+			2 * time.Minute,
+			2,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			c := useFakeClock(t)
+			sink := NewSyncEventSink(time.Minute)
+			handlers := tc.handlers()
+			for _, h := range handlers {
+				sink.AddEventListener("quiet", h)
+			}
+			removed := RecordingHandler()
+			sink.AddEventListener(EventTypeHandlerRemoved, removed)
+			c.Advance(tc.advance)
+			if n := sink.(ListenerManager).PruneExpired(); n != tc.want {
+				t.Errorf("pruned %d listeners, want %d", n, tc.want)
+			}
+			if n := sink.(ListenerInspector).ListenerCount("quiet"); n != len(handlers) - tc.want {
+				t.Errorf("%d listeners left, want %d", n, len(handlers) - tc.want)
+			}
+			if n := len(removed.Calls()); n != tc.want {
+				t.Errorf("%d removal events, want %d", n, tc.want)
+			}
+			if n := sink.(ListenerManager).PruneExpired(); n != 0 {
+				t.Errorf("pruned %d more listeners the second time", n)
+			}
+		})
+	}
+}
+
+func TestSyncEventSink(t *testing.T) {
+	tests := []struct {
+		name string
+		listeners int
+		maxCalls int
+		fires int
+		wantCalls []int
+		wantListeners int
+	}{
+		{"one listener", 1, 0, 2, []int{0, 0}, 1},
+		{"registration order", 3, 0, 1, []int{0, 1, 2}, 3},
+		{"expired removed before return", 2, 1, 2, []int{0, 1}, 0},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			sink := NewSyncEventSink(time.Minute)
+			added := RecordingHandler()
+			removed := RecordingHandler()
+			sink.AddEventListener(EventTypeHandlerAdded, added)
+			sink.AddEventListener(EventTypeHandlerRemoved, removed)
+			calls := []int{}
+			for i := 0; i < tc.listeners; i++ {
+				n := i
+				sink.AddEventListener("test", WithMaxCalls(NewEventHandler(func(Event) error {
+					calls = append(calls, n)
+					return nil
+				}), tc.maxCalls))
+			}
+			// the adds of both meta-event listeners, including the
+			// listener-add one itself, and of the test listeners have all
+			// been handled already
+			if n := len(added.Calls()); n != tc.listeners + 2 {
+				t.Errorf("%d listener-add events handled, want %d", n, tc.listeners + 2)
+			}
+			for i := 0; i < tc.fires; i++ {
+				sink.Emit("test", float64(i))
+			}
+			if !reflect.DeepEqual(calls, tc.wantCalls) {
+				t.Errorf("calls %v, want %v", calls, tc.wantCalls)
+			}
+			if n := sink.(ListenerInspector).ListenerCount("test"); n != tc.wantListeners {
+				t.Errorf("%d listeners left, want %d", n, tc.wantListeners)
+			}
+			if n := len(removed.Calls()); n != tc.listeners - tc.wantListeners {
+				t.Errorf("%d listener-remove events handled, want %d", n, tc.listeners - tc.wantListeners)
+			}
+			if n := len(filterLog(sink.Log(), "test")); n != tc.fires {
+				t.Errorf("%d events logged, want %d", n, tc.fires)
+			}
+		})
+	}
+}
+
+func TestAddEventListenerIf(t *testing.T) {
+	above := func(min float64) func(Event) bool {
+		return func(ev Event) bool { return ev.(Valuer).GetValue() > min }
+	}
+	tests := []struct {
+		name string
+		pred func(Event) bool
+		remove func(h EventHandler) EventHandler
+		before []float64
+		after []float64
+		want []float64
+	}{
+		{"filtered", above(5), nil, []float64{1, 6, 3, 8}, nil, []float64{6, 8}},
+		{"removed by original", above(5), func(h EventHandler) EventHandler { return h }, []float64{6}, []float64{7, 8}, []float64{6}},
+		{"removed by reference", above(0), func(h EventHandler) EventHandler { return HandlerReference(h.ID()) }, []float64{1, 2}, []float64{3}, []float64{1, 2}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			sink := NewSyncEventSink(time.Minute)
+			rec := RecordingHandler()
+			sink.(ListenerManager).AddEventListenerIf("test", tc.pred, rec)
+			for _, val := range tc.before {
+				sink.Emit("test", val)
+			}
+			if tc.remove != nil {
+				sink.RemoveEventListener("test", tc.remove(rec))
+				if n := sink.(ListenerInspector).ListenerCount("test"); n != 0 {
+					t.Errorf("%d listeners after removal, want 0", n)
+				}
+			}
+			for _, val := range tc.after {
+				sink.Emit("test", val)
+			}
+			if got := logValues(rec.Calls()); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("handled %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestAddEventListenerTwice(t *testing.T) {
+	tests := []struct {
+		name string
+		add func(sink EventSink, h EventHandler)
+		want int
+	}{
+		{"once", func(sink EventSink, h EventHandler) { sink.AddEventListener("test", h) }, 1},
+		{"twice", func(sink EventSink, h EventHandler) {
+			sink.AddEventListener("test", h)
+			sink.AddEventListener("test", h)
+		}, 1},
+		{"by reference", func(sink EventSink, h EventHandler) {
+			sink.AddEventListener("test", h)
+			sink.AddEventListener("test", HandlerReference(h.ID()))
+		}, 1},
+		{"tagged", func(sink EventSink, h EventHandler) {
+			sink.AddEventListener("test", h)
+			sink.(ListenerManager).AddEventListenerTagged("test", "again", h)
+		}, 1},
+		{"after removal", func(sink EventSink, h EventHandler) {
+			sink.AddEventListener("test", h)
+			sink.RemoveEventListener("test", h)
+			sink.AddEventListener("test", h)
+		}, 1},
+		{"other type", func(sink EventSink, h EventHandler) {
+			sink.AddEventListener("test", h)
+			sink.AddEventListener("other", h)
+		}, 2},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			sink := NewSyncEventSink(time.Minute)
+			rec := RecordingHandler()
+			tc.add(sink, rec)
+			sink.Emit("test", 1.0)
+			sink.Emit("other", 2.0)
+			if n := len(rec.Calls()); n != tc.want {
+				t.Errorf("handler called %d times, want %d", n, tc.want)
+			}
+			if n := sink.(ListenerInspector).ListenerCount("test"); n != 1 {
+				t.Errorf("%d listeners for test, want 1", n)
+			}
+		})
+	}
+}
+
+func TestNilSafety(t *testing.T) {
+	tests := []struct {
+		name string
+		call func(sink EventSink)
+	}{
+		{"nil handler", func(sink EventSink) { sink.AddEventListener("test", nil) }},
+		{"nil tagged handler", func(sink EventSink) { sink.(ListenerManager).AddEventListenerTagged("test", "tag", nil) }},
+		{"nil filtered handler", func(sink EventSink) { sink.(ListenerManager).AddEventListenerIf("test", func(Event) bool { return true }, nil) }},
+		{"nil once when handler", func(sink EventSink) { sink.(ListenerManager).OnceWhen("test", nil, nil) }},
+		{"nil event", func(sink EventSink) { sink.Fire(nil) }},
+		{"nil events", func(sink EventSink) { sink.(BatchSink).FireMany([]Event{nil, nil}) }},
+		{"empty type", func(sink EventSink) { sink.Emit("", 1.0) }},
+		{"empty types", func(sink EventSink) { sink.(BatchSink).EmitMany("", []interface{}{1.0, 2.0}) }},
+	}
+	sinks := []struct {
+		name string
+		sink func() EventSink
+	}{
+		{"sync", func() EventSink { return NewSyncEventSink(time.Minute) }},
+		{"async", func() EventSink { return NewEventSink(time.Minute) }},
+		{"prefixed", func() EventSink { return NewPrefixedEventSource("p", NewSyncEventSink(time.Minute)) }},
+		{"mapped", func() EventSink { return NewMappedEventSource(map[string]string{"test": "sink-test"}, NewSyncEventSink(time.Minute)) }},
+	}
+	for _, sc := range sinks {
+		for _, tc := range tests {
+			t.Run(sc.name + "/" + tc.name, func(t *testing.T) {
+				sink := sc.sink()
+				rec := RecordingHandler()
+				sink.AddEventListener("", rec)
+				tc.call(sink)
+				if n := sink.(ListenerInspector).ListenerCount("test"); n != 0 {
+					t.Errorf("%d listeners added", n)
+				}
+				for _, ev := range sink.Log() {
+					if ev.GetType() == "" || ev.GetType() == "test" {
+						t.Errorf("logged %v", ev)
+					}
+				}
+				if rec.WaitForCalls(1, 10 * time.Millisecond) {
+					t.Errorf("listener for the empty type was called")
+				}
+			})
+		}
+	}
+}
+
+// seqs returns the sequence numbers of evs, in order.
+func seqs(evs []Event) []uint64 {
+	out := []uint64{}
+	for _, ev := range evs {
+		out = append(out, Seq(ev))
+	}
+	return out
+}
+
+func TestSeqConcurrent(t *testing.T) {
+	tests := []struct {
+		name string
+		sink func() EventSink
+		workers int
+		fires int
+	}{
+		{"sync", func() EventSink { return NewSyncEventSink(time.Hour) }, 8, 200},
+		{"async", func() EventSink { return NewEventSink(time.Hour) }, 8, 200},
+		{"ring", func() EventSink { return NewRingBufferEventSink(10000, time.Hour, SinkSync()) }, 4, 500},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			sink := tc.sink()
+			wg := &sync.WaitGroup{}
+			for i := 0; i < tc.workers; i++ {
+				wg.Add(1)
+				go func(i int) {
+					defer wg.Done()
+					for j := 0; j < tc.fires; j++ {
+						sink.Fire(NewEvent("test", float64(i * tc.fires + j)))
+					}
+				}(i)
+			}
+			wg.Wait()
+			logged := filterLog(sink.Log(), "test")
+			n := tc.workers * tc.fires
+			if len(logged) != n {
+				t.Fatalf("logged %d events, want %d", len(logged), n)
+			}
+			got := seqs(logged)
+			sort.Slice(got, func(i, j int) bool { return got[i] < got[j] })
+			for i := 1; i < len(got); i++ {
+				if got[i] == got[i-1] {
+					t.Fatalf("sequence number %d assigned twice", got[i])
+				}
+			}
+			// each worker's events are numbered in the order it fired them
+			bySeq := make([]uint64, n)
+			for _, ev := range logged {
+				bySeq[int(ev.(Valuer).GetValue())] = Seq(ev)
+			}
+			for i := 0; i < tc.workers; i++ {
+				for j := 1; j < tc.fires; j++ {
+					if prev, cur := bySeq[i * tc.fires + j - 1], bySeq[i * tc.fires + j]; cur <= prev {
+						t.Fatalf("worker %d: event %d has seq %d after %d", i, j, cur, prev)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestSeqSharedEvent(t *testing.T) {
+	tests := []struct {
+		name string
+		make func() EventSink
+	}{
+		{"sync", func() EventSink { return NewSyncEventSink(time.Hour) }},
+		{"async", func() EventSink { return NewEventSink(time.Hour) }},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			a, b := tc.make(), tc.make()
+			a.Emit("test", 0.0)
+			a.Emit("test", 0.0)
+			ev := NewEvent("test", 1.0)
+			wg := &sync.WaitGroup{}
+			for _, sink := range []EventSink{a, b} {
+				wg.Add(1)
+				go func(sink EventSink) {
+					defer wg.Done()
+					sink.Fire(ev)
+				}(sink)
+			}
+			wg.Wait()
+			if got := seqs(filterLog(a.Log(), "test")); !reflect.DeepEqual(got, []uint64{3, 2, 1}) {
+				t.Errorf("first sink seqs = %v, want [3 2 1]", got)
+			}
+			if got := seqs(filterLog(b.Log(), "test")); !reflect.DeepEqual(got, []uint64{1}) {
+				t.Errorf("second sink seqs = %v, want [1]", got)
+			}
+			if Seq(ev) != 0 {
+				t.Errorf("firing changed the caller's seq to %d", Seq(ev))
+			}
+		})
+	}
+}
+
+func TestSeqRenumbered(t *testing.T) {
+	tests := []struct {
+		name string
+		fire func(t *testing.T, sink EventSink)
+		want []uint64
+	}{
+		{"fired", func(t *testing.T, sink EventSink) {
+			sink.Emit("test", 1.0)
+			sink.Emit("test", 2.0)
+		}, []uint64{2, 1}},
+		{"refired", func(t *testing.T, sink EventSink) {
+			ev := NewEvent("test", 1.0)
+			sink.Fire(ev)
+			sink.Fire(ev)
+			if Seq(ev) != 0 {
+				t.Errorf("firing changed the caller's seq to %d", Seq(ev))
+			}
+		}, []uint64{2, 1}},
+		{"ingested", func(t *testing.T, sink EventSink) {
+			body := `[{"type": "test", "value": 1, "seq": 99}, {"type": "test", "value": 2, "seq": 98}]`
+			w := httptest.NewRecorder()
+			IngestHandler(sink).ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body)))
+			if w.Code != http.StatusAccepted {
+				t.Fatalf("ingest responded %d", w.Code)
+			}
+		}, []uint64{2, 1}},
+		{"replayed", func(t *testing.T, sink EventSink) {
+			lines := `{"type": "test", "value": 1, "seq": 7}` + "\n" + `{"type": "test", "value": 2, "seq": 8}` + "\n"
+			if err := ReplayFrom(strings.NewReader(lines), sink, 0); err != nil {
+				t.Fatalf("replay failed: %s", err)
+			}
+		}, []uint64{2, 1}},
+		{"restored", func(t *testing.T, sink EventSink) {
+			other := NewSyncEventSink(time.Hour)
+			for i := 0; i < 3; i++ {
+				other.Emit("test", float64(i))
+			}
+			sink.Emit("test", 9.0)
+			sink.(Snapshotter).Restore(other.(Snapshotter).Snapshot())
+			sink.Emit("test", 10.0)
+		}, []uint64{5, 4, 3, 2}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			sink := NewSyncEventSink(time.Hour)
+			tc.fire(t, sink)
+			if got := seqs(filterLog(sink.Log(), "test")); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("seqs = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestNestedPrefixedEventSource(t *testing.T) {
+	tests := []struct {
+		name string
+		prefixes []string
+		eventType string
+		wantType string
+	}{
+		{"single", []string{"a"}, "x", "a-x"},
+		{"trailing dash", []string{"a-"}, "x", "a-x"},
+		{"nested", []string{"inner", "outer"}, "x", "inner-outer-x"},
+		{"nested three deep", []string{"a", "b", "c"}, "x", "a-b-c-x"},
+		{"type containing the prefix", []string{"a"}, "a-x", "a-a-x"},
+		{"empty prefix", []string{""}, "x", "x"},
+		{"empty inner prefix", []string{"", "a"}, "x", "a-x"},
+		{"empty outer prefix", []string{"a", ""}, "x", "a-x"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			base := NewSyncEventSink(time.Minute)
+			var view EventSink = base
+			for _, prefix := range tc.prefixes {
+				view = NewPrefixedEventSource(prefix, view)
+			}
+			view.RegisterEventType(NewEvent(tc.eventType, 0.0))
+			view.Emit(tc.eventType, 1.0)
+			if got := filterLog(base.Log(), tc.wantType); len(got) != 1 {
+				t.Errorf("base sink logged %d %s events, want 1", len(got), tc.wantType)
+			}
+			if got := filterLog(view.Log(), tc.eventType); len(got) != 1 {
+				t.Errorf("view logged %d %s events, want 1", len(got), tc.eventType)
+			}
+			types := []string{}
+			for _, ev := range view.ListEventTypes() {
+				types = append(types, ev.GetType())
+			}
+			found := false
+			for _, eventType := range types {
+				found = found || eventType == tc.eventType
+			}
+			if !found {
+				t.Errorf("view lists types %v, want %s among them", types, tc.eventType)
+			}
+		})
+	}
+}
+
+func TestPrefixedEventSourceIsolation(t *testing.T) {
+	base := NewSyncEventSink(time.Minute)
+	kitchen := NewPrefixedEventSource("kitchen", base)
+	garage := NewPrefixedEventSource("garage", base)
+	nested := NewPrefixedEventSource("fridge", kitchen)
+	kitchen.Emit("temp", 20.0)
+	garage.Emit("temp", 5.0)
+	nested.Emit("temp", 4.0)
+	tests := []struct {
+		name string
+		sink EventSink
+		want []string
+	}{
+		{"kitchen", kitchen, []string{"fridge-temp", "temp"}},
+		{"garage", garage, []string{"temp"}},
+		{"nested", nested, []string{"temp"}},
+		{"base", base, []string{"garage-temp", "kitchen-fridge-temp", "kitchen-temp"}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := []string{}
+			for _, ev := range tc.sink.Log() {
+				got = append(got, ev.GetType())
+			}
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("logged types %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestFireWhileChangingListeners(t *testing.T) {
+	tests := []struct {
+		name string
+		sink func() EventSink
+		firers int
+		changers int
+		fires int
+	}{
+		{"sync", func() EventSink { return NewSyncEventSink(time.Hour) }, 4, 4, 200},
+		{"async", func() EventSink { return NewEventSink(time.Hour) }, 4, 4, 200},
+		{"ring", func() EventSink { return NewRingBufferEventSink(100, time.Hour, SinkSync()) }, 4, 4, 200},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			sink := tc.sink()
+			var steady int32
+			sink.AddEventListener("test", NewEventHandler(func(ev Event) error {
+				atomic.AddInt32(&steady, 1)
+				return nil
+			}))
+			wg := &sync.WaitGroup{}
+			for i := 0; i < tc.firers; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for j := 0; j < tc.fires; j++ {
+						sink.Emit("test", float64(j))
+					}
+				}()
+			}
+			for i := 0; i < tc.changers; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for j := 0; j < tc.fires; j++ {
+						h := NewEventHandler(func(ev Event) error { return nil })
+						sink.AddEventListener("test", h)
+						// removes itself while the event is being dispatched
+						sink.Once("test", NewEventHandler(func(ev Event) error { return nil }))
+						sink.RemoveEventListener("test", h)
+					}
+				}()
+			}
+			wg.Wait()
+			// one more event clears out the Once listeners still waiting
+			sink.Emit("test", 0.0)
+			want := int32(tc.firers * tc.fires + 1)
+			deadline := time.Now().Add(5 * time.Second)
+			for atomic.LoadInt32(&steady) < want && time.Now().Before(deadline) {
+				time.Sleep(time.Millisecond)
+			}
+			if n := atomic.LoadInt32(&steady); n != want {
+				t.Errorf("steady listener called %d times, want %d", n, want)
+			}
+			n := 0
+			for time.Now().Before(deadline) {
+				sink.(ListenerManager).PruneExpired()
+				if n = sink.(ListenerInspector).ListenerCount("test"); n == 1 {
+					break
+				}
+				time.Sleep(time.Millisecond)
+			}
+			if n != 1 {
+				t.Errorf("%d listeners left, want 1", n)
+			}
+		})
+	}
+}
+
+func TestRemoveEventListenerSync(t *testing.T) {
+	tests := []struct {
+		name string
+		make func() (view EventSink, base EventSink)
+	}{
+		{"async", func() (EventSink, EventSink) { s := NewEventSink(time.Hour); return s, s }},
+		{"ring", func() (EventSink, EventSink) { s := NewRingBufferEventSink(10, time.Hour); return s, s }},
+		{"sync", func() (EventSink, EventSink) { s := NewSyncEventSink(time.Hour); return s, s }},
+		{"prefixed", func() (EventSink, EventSink) { s := NewEventSink(time.Hour); return NewPrefixedEventSource("kitchen", s), s }},
+		{"mapped", func() (EventSink, EventSink) { s := NewEventSink(time.Hour); return NewMappedEventSource(map[string]string{"t": "test"}, s), s }},
+		{"logged", func() (EventSink, EventSink) { s := NewEventSink(time.Hour); return NewLoggedEventSink(s, io.Discard), s }},
+		{"scoped", func() (EventSink, EventSink) { s := NewEventSink(time.Hour); return NewScopedSink(context.Background(), s), s }},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			view, base := tc.make()
+			defer base.(Closer).Close()
+			var removed int32
+			base.AddEventListener(EventTypeHandlerRemoved, NewEventHandler(func(ev Event) error {
+				atomic.AddInt32(&removed, 1)
+				// the mutex is released, so the listener may change listeners
+				base.AddEventListener("after", RecordingHandler())
+				return nil
+			}))
+			h := newClosingHandler(nil)
+			view.AddEventListener("test", h)
+			view.(SyncSink).RemoveEventListenerSync("test", h)
+			if n := atomic.LoadInt32(&removed); n != 1 {
+				t.Errorf("removal listener ran %d times by the time RemoveEventListenerSync returned, want 1", n)
+			}
+			if n := atomic.LoadInt32(&h.closed); n != 1 {
+				t.Errorf("handler closed %d times, want 1", n)
+			}
+			if li, ok := view.(ListenerInspector); ok && li.ListenerCount("test") != 0 {
+				t.Errorf("%d listeners left, want 0", li.ListenerCount("test"))
+			}
+			if n := base.(ListenerInspector).ListenerCount("after"); n != 1 {
+				t.Errorf("removal listener added %d listeners, want 1", n)
+			}
+		})
+	}
+}
+
+func TestRemoveNilListener(t *testing.T) {
+	tests := []struct {
+		name string
+		make func() (view EventSink, base EventSink)
+	}{
+		{"async", func() (EventSink, EventSink) { s := NewEventSink(time.Hour); return s, s }},
+		{"sync", func() (EventSink, EventSink) { s := NewSyncEventSink(time.Hour); return s, s }},
+		{"prefixed", func() (EventSink, EventSink) { s := NewEventSink(time.Hour); return NewPrefixedEventSource("kitchen", s), s }},
+		{"mapped", func() (EventSink, EventSink) { s := NewEventSink(time.Hour); return NewMappedEventSource(map[string]string{"t": "test"}, s), s }},
+		{"logged", func() (EventSink, EventSink) { s := NewEventSink(time.Hour); return NewLoggedEventSink(s, io.Discard), s }},
+		{"scoped", func() (EventSink, EventSink) { s := NewEventSink(time.Hour); return NewScopedSink(context.Background(), s), s }},
+		{"broadcaster", func() (EventSink, EventSink) { s := NewEventSink(time.Hour); return NewBroadcaster(s, NewSyncEventSink(time.Hour)), s }},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			view, base := tc.make()
+			defer base.(Closer).Close()
+			h := RecordingHandler()
+			view.AddEventListener("test", h)
+			view.RemoveEventListener("test", nil)
+			removeEventListenerSync(view, "test", nil)
+			view.Emit("test", 1.0)
+			if !h.WaitForCalls(1, time.Second) {
+				t.Error("listener not called after removing nil")
+			}
+		})
+	}
+}
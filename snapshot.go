@@ -0,0 +1,113 @@
+package events
+
+import (
+	"encoding/json"
+	"sync/atomic"
+)
+
+// SinkState is the serializable state of a sink: its event type registry,
+// aliases, log, and the IDs of its listeners. Handlers themselves can't be
+// serialized, so after restoring a snapshot the caller must re-register
+// each handler, using NewEventHandlerWithID with the preserved ID if other
+// code refers to it by ID.
+type SinkState struct {
+	EventTypes []Event `json:"event_types"`
+	Aliases map[string][]string `json:"aliases,omitempty"`
+	Log []Event `json:"log"`
+	Listeners map[string][]int64 `json:"listeners,omitempty"`
+}
+
+type sinkStateJSON struct {
+	EventTypes []json.RawMessage `json:"event_types"`
+	Aliases map[string][]string `json:"aliases,omitempty"`
+	Log []json.RawMessage `json:"log"`
+	Listeners map[string][]int64 `json:"listeners,omitempty"`
+}
+
+func (state *SinkState) UnmarshalJSON(data []byte) error {
+	raw := &sinkStateJSON{}
+	err := json.Unmarshal(data, raw)
+	if err != nil {
+		return err
+	}
+	state.EventTypes, err = unmarshalEvents(raw.EventTypes)
+	if err != nil {
+		return err
+	}
+	state.Log, err = unmarshalEvents(raw.Log)
+	if err != nil {
+		return err
+	}
+	state.Aliases = raw.Aliases
+	state.Listeners = raw.Listeners
+	return nil
+}
+
+func unmarshalEvents(items []json.RawMessage) ([]Event, error) {
+	evs := make([]Event, len(items))
+	for i, item := range items {
+		ev, err := UnmarshalEvent(item)
+		if err != nil {
+			return nil, err
+		}
+		evs[i] = ev
+	}
+	return evs, nil
+}
+
+func (es *basicEventSink) Snapshot() *SinkState {
+	state := &SinkState{
+		EventTypes: es.ListEventTypes(),
+		Aliases: map[string][]string{},
+		Log: es.Log(),
+		Listeners: map[string][]int64{},
+	}
+	es.mutex.Lock()
+	defer es.mutex.Unlock()
+	for newType, oldTypes := range es.aliases {
+		state.Aliases[newType] = append([]string{}, oldTypes...)
+	}
+	for eventType, listeners := range es.listeners {
+		ids := make([]int64, len(listeners))
+		for i, h := range listeners {
+			ids[i] = h.ID()
+		}
+		state.Listeners[eventType] = ids
+	}
+	return state
+}
+
+// Restore replaces the sink's log with the snapshot's, and adds the
+// snapshot's event types and aliases to the sink's own. The restored
+// events are given new sequence numbers, following the sink's own, in the
+// order they were logged. Listeners are not restored; see SinkState.
+func (es *basicEventSink) Restore(state *SinkState) {
+	es.mutex.Lock()
+	for _, ev := range state.EventTypes {
+		es.registerLocked(ev)
+	}
+	for newType, oldTypes := range state.Aliases {
+		for _, oldType := range oldTypes {
+			es.aliasLocked(oldType, newType)
+		}
+	}
+	typeLogs := make([]eventLog, len(state.Log))
+	if es.typeLogs != nil {
+		for _, l := range es.typeLogs {
+			l.Clear()
+		}
+		for i, ev := range state.Log {
+			typeLogs[i] = es.typeLog(ev.GetType())
+		}
+	}
+	es.mutex.Unlock()
+	es.log.Clear()
+	// the log is most recent first, so replay it backwards
+	for i := len(state.Log) - 1; i >= 0; i-- {
+		ev := numbered(state.Log[i], atomic.AddUint64(&es.seq, 1))
+		es.log.Add(ev)
+		if typeLogs[i] != nil {
+			typeLogs[i].Add(ev)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package events
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSnapshotRestore(t *testing.T) {
+	tests := []struct {
+		name string
+		viaJSON bool
+		sink func() EventSink
+	}{
+		{"direct", false, func() EventSink { return NewSyncEventSink(time.Hour) }},
+		{"json", true, func() EventSink { return NewSyncEventSink(time.Hour) }},
+		{"type logs", true, func() EventSink { return NewSyncEventSink(time.Hour, SinkTypeLogs(0)) }},
+		{"ring", true, func() EventSink { return NewRingBufferEventSink(100, time.Hour, SinkSync()) }},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			src := NewSyncEventSink(time.Hour)
+			src.RegisterEventType(NewEvent("temp", 0.0))
+			src.RegisterEventType(NewEvent("door", ""))
+			src.(TypeManager).AliasEventType("old-temp", "temp")
+			listener := NewEventHandlerWithID(42, func(Event) error { return nil })
+			src.AddEventListener("temp", listener)
+			src.Emit("temp", 1.0)
+			src.Emit("door", "open")
+			src.Emit("temp", 2.0)
+			state := src.(Snapshotter).Snapshot()
+			if tc.viaJSON {
+				data, err := json.Marshal(state)
+				if err != nil {
+					t.Fatalf("can't marshal snapshot: %s", err)
+				}
+				state = &SinkState{}
+				if err := json.Unmarshal(data, state); err != nil {
+					t.Fatalf("can't unmarshal snapshot %s: %s", data, err)
+				}
+			}
+			if ids := state.Listeners["temp"]; !reflect.DeepEqual(ids, []int64{42}) {
+				t.Errorf("snapshot listeners for temp = %v, want [42]", ids)
+			}
+
+			dst := tc.sink()
+			dst.(Snapshotter).Restore(state)
+			if got := logValues(filterLog(dst.Log(), "temp")); !reflect.DeepEqual(got, []float64{2, 1}) {
+				t.Errorf("restored temp log = %v, want [2 1]", got)
+			}
+			if got := dst.(LogReader).LogForType("door"); len(got) != 1 || got[0].(MessageEvent).GetMessage() != "open" {
+				t.Errorf("restored door log = %v, want one open event", got)
+			}
+			types := map[string]bool{}
+			for _, ev := range dst.ListEventTypes() {
+				types[ev.GetType()] = true
+			}
+			if !types["door"] || !types["temp"] {
+				t.Errorf("restored types %v, want door and temp among them", types)
+			}
+
+			// the caller re-registers the handler under its preserved ID
+			calls := 0
+			for eventType, ids := range state.Listeners {
+				for _, id := range ids {
+					dst.AddEventListener(eventType, NewEventHandlerWithID(id, func(Event) error {
+						calls++
+						return nil
+					}))
+				}
+			}
+			dst.Emit("temp", 3.0)
+			if calls != 1 {
+				t.Errorf("re-registered handler called %d times, want 1", calls)
+			}
+			old := RecordingHandler()
+			dst.AddEventListener("old-temp", old)
+			dst.Emit("temp", 4.0)
+			if len(old.Calls()) != 1 {
+				t.Error("restored alias doesn't reach the old type's listeners")
+			}
+			dst.RemoveEventListener("temp", HandlerReference(42))
+			dst.Emit("temp", 5.0)
+			if calls != 2 {
+				t.Errorf("re-registered handler called %d times after removal, want 2", calls)
+			}
+		})
+	}
+}
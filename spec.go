@@ -0,0 +1,93 @@
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+// A ListenerSpec declares a chain of the common decorators, as applied by
+// EventHandlerBuilder, around a handler function named by Handler. Its
+// JSON form uses the same fields as a Webhook, so that
+//
+//	{"handler": "alert", "debounce": 60000000000, "min": 0, "max": 100}
+//
+// calls the function registered as "alert" for values in [0, 100], at
+// most once a minute.
+type ListenerSpec struct {
+	Handler string `json:"handler"`
+	Debounce *time.Duration `json:"debounce,omitempty"`
+	Direction *Direction `json:"direction,omitempty"`
+	TriggerValue *float64 `json:"trigger_value,omitempty"`
+	ResetValue *float64 `json:"reset_value,omitempty"`
+	Min *float64 `json:"min,omitempty"`
+	Max *float64 `json:"max,omitempty"`
+	MaxCalls int `json:"max_calls,omitempty"`
+	TTL time.Duration `json:"ttl,omitempty"`
+}
+
+// BuildHandler builds an EventHandler calling fn, with the decorators in
+// spec applied in the order described by EventHandlerBuilder. A direction
+// with both a trigger and a reset value adds a threshold; otherwise the
+// trigger and reset values are ignored.
+func BuildHandler(spec ListenerSpec, fn HandlerFunc) EventHandler {
+	return buildHandlerFor(spec, NewEventHandler(fn))
+}
+
+// buildHandlerFor is like BuildHandler, wrapping h.
+func buildHandlerFor(spec ListenerSpec, h EventHandler) EventHandler {
+	b := HandlerBuilderFor(h)
+	if spec.Debounce != nil {
+		b.Debounce(*spec.Debounce)
+	}
+	if spec.Min != nil && spec.Max != nil {
+		b.Range(*spec.Min, *spec.Max)
+	}
+	if spec.Direction != nil {
+		if spec.TriggerValue != nil && spec.ResetValue != nil {
+			b.Threshold(*spec.Direction, *spec.TriggerValue, *spec.ResetValue)
+		} else {
+			b.Direction(*spec.Direction)
+		}
+	}
+	return b.MaxCalls(spec.MaxCalls).Timeout(spec.TTL).Build()
+}
+
+// LoadListenerSpecs reads a JSON object mapping event types to lists of
+// listener specs.
+func LoadListenerSpecs(r io.Reader) (map[string][]*ListenerSpec, error) {
+	config := map[string][]*ListenerSpec{}
+	err := json.NewDecoder(r).Decode(&config)
+	if err != nil {
+		return nil, fmt.Errorf("can't parse listener specs: %w", err)
+	}
+	for eventType, specs := range config {
+		for i, spec := range specs {
+			if spec == nil {
+				return nil, fmt.Errorf("listener %d for event type %q: empty spec", i, eventType)
+			}
+		}
+	}
+	return config, nil
+}
+
+// AttachListeners adds a listener to sink for every spec in config,
+// binding each to the function in funcs named by its Handler. If any spec
+// names a function that isn't in funcs, it returns an error without
+// adding any listeners.
+func AttachListeners(sink EventSink, config map[string][]*ListenerSpec, funcs map[string]HandlerFunc) error {
+	for eventType, specs := range config {
+		for i, spec := range specs {
+			if _, ok := funcs[spec.Handler]; !ok {
+				return fmt.Errorf("listener %d for event type %q: unknown handler %q", i, eventType, spec.Handler)
+			}
+		}
+	}
+	for eventType, specs := range config {
+		for _, spec := range specs {
+			sink.AddEventListener(eventType, BuildHandler(*spec, funcs[spec.Handler]))
+		}
+	}
+	return nil
+}
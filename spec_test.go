@@ -0,0 +1,140 @@
+package events
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBuildHandler(t *testing.T) {
+	vals := []float64{1, 5, 12, 3, 8, 15, 9, 20, 2, 11, 6, 14}
+	tests := []struct {
+		name string
+		spec string
+		want []int
+	}{
+		{"nothing", `{"handler": "h"}`, []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11}},
+		{"range", `{"min": 4, "max": 12}`, []int{1, 2, 4, 6, 9, 10}},
+		{"min without max", `{"min": 4}`, []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11}},
+		{"threshold and max calls", `{"direction": "increasing", "trigger_value": 10, "reset_value": 4, "max_calls": 2}`, []int{2, 5}},
+		{"direction", `{"direction": "increasing"}`, []int{1, 2, 4, 5, 7, 9, 11}},
+		{"trigger without reset", `{"direction": "increasing", "trigger_value": 10}`, []int{1, 2, 4, 5, 7, 9, 11}},
+		{"direction and range", `{"direction": "increasing", "min": 0, "max": 15}`, []int{1, 2, 4, 5, 9, 11}},
+		{"range and debounce", `{"debounce": 3000000000, "min": 5, "max": 20}`, []int{1, 4, 7, 10}},
+		{
+			"everything",
+			`{"ttl": 9000000000, "max_calls": 3, "debounce": 2000000000, "min": 2, "max": 16, "direction": "increasing", "trigger_value": 8, "reset_value": 4}`,
+			[]int{2, 4, 9},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			spec := ListenerSpec{}
+			if err := json.Unmarshal([]byte(tc.spec), &spec); err != nil {
+				t.Fatalf("can't decode %s: %s", tc.spec, err)
+			}
+			got := timedIndexes(t, func(h EventHandler) EventHandler { return BuildHandler(spec, h.Call) }, vals...)
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("passed %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestBuildHandlerMatchesWebhook(t *testing.T) {
+	second := time.Second
+	min, max := 0.0, 10.0
+	hook := &Webhook{Debounce: &second, Min: &min, Max: &max, MaxCalls: 2}
+	want := Describe(hook.Handler())
+	got := Describe(BuildHandler(hook.Spec(), func(Event) error { return nil }))
+	if !reflect.DeepEqual(got[:len(got) - 1], want[:len(want) - 1]) {
+		t.Errorf("spec built %q, webhook built %q", got, want)
+	}
+}
+
+func TestLoadListenerSpecs(t *testing.T) {
+	tests := []struct {
+		name string
+		config string
+		wantErr bool
+		wantCount int
+	}{
+		{"empty", `{}`, false, 0},
+		{"specs", `{"temp": [{"handler": "alert", "min": 0, "max": 100}, {"handler": "log"}], "door": [{"handler": "log"}]}`, false, 3},
+		{"null spec", `{"temp": [null]}`, true, 0},
+		{"bad json", `{"temp": [`, true, 0},
+		{"wrong type", `{"temp": [{"max_calls": "two"}]}`, true, 0},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			config, err := LoadListenerSpecs(strings.NewReader(tc.config))
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("error = %v, want error %t", err, tc.wantErr)
+			}
+			n := 0
+			for _, specs := range config {
+				n += len(specs)
+			}
+			if n != tc.wantCount {
+				t.Errorf("loaded %d specs, want %d", n, tc.wantCount)
+			}
+		})
+	}
+}
+
+func TestAttachListeners(t *testing.T) {
+	config, err := LoadListenerSpecs(strings.NewReader(
+		`{"temp": [{"handler": "alert", "min": 10, "max": 20}, {"handler": "log", "max_calls": 1}], "door": [{"handler": "log"}]}`,
+	))
+	if err != nil {
+		t.Fatalf("can't load specs: %s", err)
+	}
+	tests := []struct {
+		name string
+		funcs []string
+		wantErr bool
+		wantCalls map[string][]string
+	}{
+		{"bound", []string{"alert", "log"}, false, map[string][]string{"alert": {"temp"}, "log": {"temp", "door", "door"}}},
+		{"unknown handler", []string{"log"}, true, map[string][]string{}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			sink := NewSyncEventSink(time.Hour)
+			calls := map[string][]string{}
+			funcs := map[string]HandlerFunc{}
+			for _, name := range tc.funcs {
+				name := name
+				funcs[name] = func(ev Event) error {
+					calls[name] = append(calls[name], ev.GetType())
+					return nil
+				}
+			}
+			err := AttachListeners(sink, config, funcs)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("error = %v, want error %t", err, tc.wantErr)
+			}
+			sink.Emit("temp", 15.0)
+			sink.Emit("temp", 25.0)
+			sink.Emit("door", "open")
+			sink.Emit("door", "closed")
+			if !reflect.DeepEqual(calls, tc.wantCalls) {
+				t.Errorf("calls = %v, want %v", calls, tc.wantCalls)
+			}
+		})
+	}
+}
+
+func TestAttachListenersUnknownHandlerAddsNothing(t *testing.T) {
+	config := map[string][]*ListenerSpec{"temp": {{Handler: "known"}, {Handler: "unknown"}}}
+	sink := NewSyncEventSink(time.Hour)
+	err := AttachListeners(sink, config, map[string]HandlerFunc{"known": func(Event) error { return nil }})
+	if err == nil || !strings.Contains(err.Error(), `"unknown"`) {
+		t.Errorf("error = %v, want one naming the unknown handler", err)
+	}
+	if n := sink.(ListenerInspector).ListenerCount("temp"); n != 0 {
+		t.Errorf("%d listeners added, want 0", n)
+	}
+}
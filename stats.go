@@ -0,0 +1,132 @@
+package events
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+type EventTypeStats struct {
+	Count int `json:"count"`
+	First time.Time `json:"first"`
+	Last time.Time `json:"last"`
+	ValueCount int `json:"value_count,omitempty"`
+	Min float64 `json:"min"`
+	Max float64 `json:"max"`
+	Mean float64 `json:"mean"`
+}
+
+func computeStats(log []Event) map[string]EventTypeStats {
+	stats := map[string]*EventTypeStats{}
+	sums := map[string]float64{}
+	for _, ev := range log {
+		eventType := ev.GetType()
+		st, ok := stats[eventType]
+		if !ok {
+			st = &EventTypeStats{
+				First: ev.GetTime(),
+				Last: ev.GetTime(),
+				Min: math.Inf(1),
+				Max: math.Inf(-1),
+			}
+			stats[eventType] = st
+		}
+		st.Count += 1
+		t := ev.GetTime()
+		if t.Before(st.First) {
+			st.First = t
+		}
+		if t.After(st.Last) {
+			st.Last = t
+		}
+		valEv, ok := ev.(ValueEvent)
+		if !ok {
+			continue
+		}
+		val := valEv.GetValue()
+		if math.IsNaN(val) {
+			continue
+		}
+		st.ValueCount += 1
+		sums[eventType] += val
+		if val < st.Min {
+			st.Min = val
+		}
+		if val > st.Max {
+			st.Max = val
+		}
+	}
+	out := make(map[string]EventTypeStats, len(stats))
+	for eventType, st := range stats {
+		if st.ValueCount == 0 {
+			st.Min = 0
+			st.Max = 0
+		} else {
+			st.Mean = sums[eventType] / float64(st.ValueCount)
+		}
+		out[eventType] = *st
+	}
+	return out
+}
+
+// Stats summarizes the events currently in the log, by event type, in one
+// pass over the log taken while holding the sink's mutex.
+func (es *basicEventSink) Stats() map[string]EventTypeStats {
+	es.mutex.Lock()
+	defer es.mutex.Unlock()
+	return computeStats(es.log.Slice())
+}
+
+func (es *PrefixedEventSource) Stats() map[string]EventTypeStats {
+	stats := map[string]EventTypeStats{}
+	ss, ok := es.EventSink.(StatsSink)
+	if !ok {
+		return stats
+	}
+	all := ss.Stats()
+	for eventType, st := range all {
+		if strings.HasPrefix(eventType, es.prefix) {
+			stats[strings.TrimPrefix(eventType, es.prefix)] = st
+		}
+	}
+	return stats
+}
+
+// Percentiles returns the requested percentiles, each between 0 and 100,
+// of the values of the logged events of the given type, interpolating
+// linearly between the closest values. It sorts the values on each call,
+// so costs O(n log n) in the number of logged events of the type. If
+// there are no values, the result is empty.
+func (es *basicEventSink) Percentiles(eventType string, ps ...float64) map[float64]float64 {
+	return percentiles(es.LogForType(eventType), ps)
+}
+
+func (es *PrefixedEventSource) Percentiles(eventType string, ps ...float64) map[float64]float64 {
+	if ss, ok := es.EventSink.(StatsSink); ok {
+		return ss.Percentiles(es.prefix+eventType, ps...)
+	}
+	return map[float64]float64{}
+}
+
+func percentiles(log []Event, ps []float64) map[float64]float64 {
+	vals := make([]float64, 0, len(log))
+	for _, ev := range log {
+		valEv, ok := ev.(ValueEvent)
+		if ok && !math.IsNaN(valEv.GetValue()) {
+			vals = append(vals, valEv.GetValue())
+		}
+	}
+	out := map[float64]float64{}
+	if len(vals) == 0 {
+		return out
+	}
+	sort.Float64s(vals)
+	for _, p := range ps {
+		rank := math.Max(0, math.Min(100, p)) / 100 * float64(len(vals) - 1)
+		lo := int(math.Floor(rank))
+		hi := int(math.Ceil(rank))
+		out[p] = vals[lo] + (vals[hi] - vals[lo]) * (rank - float64(lo))
+	}
+	return out
+}
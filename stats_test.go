@@ -0,0 +1,182 @@
+package events
+
+import (
+	"encoding/json"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestStats(t *testing.T) {
+	type emit struct {
+		eventType string
+		data interface{}
+	}
+	tests := []struct {
+		name string
+		emits []emit
+		want map[string]EventTypeStats
+	}{
+		{"empty", nil, map[string]EventTypeStats{}},
+		{
+			"values",
+			[]emit{{"temp", 10.0}, {"temp", 20.0}, {"temp", 30.0}},
+			map[string]EventTypeStats{"temp": {Count: 3, ValueCount: 3, Min: 10, Max: 30, Mean: 20}},
+		},
+		{
+			"mixed",
+			[]emit{{"temp", 10.0}, {"temp", "offline"}, {"temp", math.NaN()}, {"temp", 4.0}, {"door", "open"}},
+			map[string]EventTypeStats{
+				"temp": {Count: 4, ValueCount: 2, Min: 4, Max: 10, Mean: 7},
+				"door": {Count: 1},
+			},
+		},
+		{
+			"negative values",
+			[]emit{{"temp", -5.0}, {"temp", -1.0}},
+			map[string]EventTypeStats{"temp": {Count: 2, ValueCount: 2, Min: -5, Max: -1, Mean: -3}},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			c := useFakeClock(t)
+			start := c.Now()
+			sink := NewSyncEventSink(time.Hour)
+			for _, e := range tc.emits {
+				c.Advance(time.Second)
+				sink.Emit(e.eventType, e.data)
+			}
+			got := sink.(StatsSink).Stats()
+			if len(got) != len(tc.want) {
+				t.Fatalf("stats for %d types, want %d: %v", len(got), len(tc.want), got)
+			}
+			for eventType, want := range tc.want {
+				st := got[eventType]
+				first, last := st.First, st.Last
+				st.First, st.Last = time.Time{}, time.Time{}
+				if !reflect.DeepEqual(st, want) {
+					t.Errorf("stats for %q = %+v, want %+v", eventType, st, want)
+				}
+				if !first.After(start) || last.Before(first) {
+					t.Errorf("stats for %q span %s to %s", eventType, first, last)
+				}
+			}
+		})
+	}
+}
+
+func TestStatsJSON(t *testing.T) {
+	tests := []struct {
+		name string
+		stats EventTypeStats
+		want map[string]interface{}
+	}{
+		{"no values", EventTypeStats{Count: 1}, map[string]interface{}{"count": 1.0, "min": 0.0, "max": 0.0, "mean": 0.0}},
+		{"zero values", EventTypeStats{Count: 2, ValueCount: 2}, map[string]interface{}{"count": 2.0, "value_count": 2.0, "min": 0.0, "max": 0.0, "mean": 0.0}},
+		{"values", EventTypeStats{Count: 2, ValueCount: 2, Min: -1, Max: 3, Mean: 1}, map[string]interface{}{"count": 2.0, "value_count": 2.0, "min": -1.0, "max": 3.0, "mean": 1.0}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			js, err := json.Marshal(tc.stats)
+			if err != nil {
+				t.Fatalf("can't marshal stats: %s", err)
+			}
+			got := map[string]interface{}{}
+			if err := json.Unmarshal(js, &got); err != nil {
+				t.Fatalf("can't unmarshal %s: %s", js, err)
+			}
+			delete(got, "first")
+			delete(got, "last")
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("marshaled %s, want %v", js, tc.want)
+			}
+		})
+	}
+}
+
+func TestPrefixedStats(t *testing.T) {
+	sink := NewSyncEventSink(time.Hour)
+	kitchen := NewPrefixedEventSource("kitchen", sink)
+	garage := NewPrefixedEventSource("garage", sink)
+	kitchen.Emit("temp", 20.0)
+	kitchen.Emit("temp", 22.0)
+	garage.Emit("temp", 5.0)
+	sink.Emit("temp", 100.0)
+	tests := []struct {
+		name string
+		sink EventSink
+		wantTypes []string
+		wantMean float64
+	}{
+		{"kitchen", kitchen, []string{"temp"}, 21},
+		{"garage", garage, []string{"temp"}, 5},
+		{"unprefixed", sink, []string{"garage-temp", "kitchen-temp", "temp"}, 100},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := tc.sink.(StatsSink).Stats()
+			types := []string{}
+			for _, eventType := range tc.wantTypes {
+				if _, ok := got[eventType]; ok {
+					types = append(types, eventType)
+				}
+			}
+			if len(got) != len(tc.wantTypes) || len(types) != len(tc.wantTypes) {
+				t.Fatalf("stats = %v, want types %v", got, tc.wantTypes)
+			}
+			if mean := got["temp"].Mean; mean != tc.wantMean {
+				t.Errorf("mean temp = %g, want %g", mean, tc.wantMean)
+			}
+		})
+	}
+}
+
+func TestPercentiles(t *testing.T) {
+	hundred := make([]interface{}, 100)
+	for i := range hundred {
+		// 1 to 100, out of order
+		hundred[i] = float64((i * 37) % 100 + 1)
+	}
+	tests := []struct {
+		name string
+		data []interface{}
+		ps []float64
+		want map[float64]float64
+	}{
+		{"empty", nil, []float64{50}, map[float64]float64{}},
+		{"single", []interface{}{7.0}, []float64{0, 50, 99}, map[float64]float64{0: 7, 50: 7, 99: 7}},
+		{"interpolated", []interface{}{20.0, 10.0}, []float64{0, 25, 50, 100}, map[float64]float64{0: 10, 25: 12.5, 50: 15, 100: 20}},
+		{"odd count", []interface{}{3.0, 1.0, 2.0}, []float64{50, 75}, map[float64]float64{50: 2, 75: 2.5}},
+		{"uniform", hundred, []float64{0, 50, 95, 99, 100}, map[float64]float64{0: 1, 50: 50.5, 95: 95.05, 99: 99.01, 100: 100}},
+		{"clamped", []interface{}{1.0, 2.0}, []float64{-10, 150}, map[float64]float64{-10: 1, 150: 2}},
+		{"non-values skipped", []interface{}{1.0, math.NaN(), "offline", 3.0}, []float64{50}, map[float64]float64{50: 2}},
+		{"only messages", []interface{}{"offline"}, []float64{50}, map[float64]float64{}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			sink := NewSyncEventSink(time.Hour)
+			sink.(BatchSink).EmitMany("test", tc.data)
+			sink.Emit("other", 1000.0)
+			got := sink.(StatsSink).Percentiles("test", tc.ps...)
+			if len(got) != len(tc.want) {
+				t.Fatalf("percentiles = %v, want %v", got, tc.want)
+			}
+			for p, want := range tc.want {
+				if math.Abs(got[p] - want) > 1e-9 {
+					t.Errorf("p%g = %g, want %g", p, got[p], want)
+				}
+			}
+		})
+	}
+}
+
+func TestPrefixedPercentiles(t *testing.T) {
+	sink := NewSyncEventSink(time.Hour)
+	kitchen := NewPrefixedEventSource("kitchen", sink)
+	kitchen.(BatchSink).EmitMany("temp", []interface{}{10.0, 20.0, 30.0})
+	sink.Emit("temp", 1000.0)
+	if got := kitchen.(StatsSink).Percentiles("temp", 50); got[50] != 20 {
+		t.Errorf("kitchen p50 = %v, want 20", got)
+	}
+}
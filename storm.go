@@ -0,0 +1,73 @@
+package events
+
+import (
+	"time"
+)
+
+// DefaultStormCooldown is how long a sink waits, after reporting a storm
+// of one event type, before reporting that type again.
+const DefaultStormCooldown = time.Minute
+
+type StormMeta struct {
+	EventType string `json:"event_type"`
+	Rate float64 `json:"rate"`
+}
+
+type stormState struct {
+	start time.Time
+	count int
+	reported time.Time
+}
+
+// SinkStormDetection makes a sink watch for event types firing faster
+// than rate events per second, averaged over fixed windows of the given
+// length. When a type exceeds it, the sink fires an EventTypeStormDetected
+// event carrying a StormMeta with the type and its rate over the window.
+// Each type is reported at most once per cooldown; see SinkStormCooldown.
+// A rate <= 0 disables detection, and a window <= 0 means one second.
+func SinkStormDetection(rate float64, window time.Duration) SinkOption {
+	return func(es *basicEventSink) {
+		if window <= 0 {
+			window = time.Second
+		}
+		es.stormRate = rate
+		es.stormWindow = window
+	}
+}
+
+// SinkStormCooldown sets how long a sink waits before reporting another
+// storm of the same event type. It defaults to DefaultStormCooldown.
+func SinkStormCooldown(cooldown time.Duration) SinkOption {
+	return func(es *basicEventSink) {
+		es.stormCooldown = cooldown
+	}
+}
+
+// detectStorm counts an event of the given type, returning a storm event
+// to fire if the type has just exceeded the storm rate. The caller must
+// hold the mutex.
+func (es *basicEventSink) detectStorm(eventType string) Event {
+	if es.stormRate <= 0 || eventType == EventTypeStormDetected {
+		return nil
+	}
+	t := es.now()
+	st, ok := es.storms[eventType]
+	if !ok {
+		st = &stormState{start: t}
+		es.storms[eventType] = st
+	}
+	if t.Sub(st.start) >= es.stormWindow {
+		st.start = t
+		st.count = 0
+	}
+	st.count += 1
+	rate := float64(st.count) / es.stormWindow.Seconds()
+	if rate <= es.stormRate {
+		return nil
+	}
+	if !st.reported.IsZero() && t.Sub(st.reported) < es.stormCooldown {
+		return nil
+	}
+	st.reported = t
+	return NewEvent(EventTypeStormDetected, &StormMeta{eventType, rate})
+}
@@ -0,0 +1,76 @@
+package events
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestStormDetection(t *testing.T) {
+	type burst struct {
+		advance time.Duration
+		eventType string
+		count int
+		spacing time.Duration
+	}
+	tests := []struct {
+		name string
+		opts []SinkOption
+		bursts []burst
+		want []StormMeta
+	}{
+		{"below rate", []SinkOption{SinkStormDetection(10, time.Second)}, []burst{{0, "temp", 10, 0}}, []StormMeta{}},
+		{"burst", []SinkOption{SinkStormDetection(10, time.Second)}, []burst{{0, "temp", 11, 0}}, []StormMeta{{"temp", 11}}},
+		{"reported once", []SinkOption{SinkStormDetection(10, time.Second)}, []burst{{0, "temp", 100, 0}}, []StormMeta{{"temp", 11}}},
+		{"spread out", []SinkOption{SinkStormDetection(10, time.Second)}, []burst{{0, "temp", 50, 200 * time.Millisecond}}, []StormMeta{}},
+		{"longer window", []SinkOption{SinkStormDetection(10, 2 * time.Second)}, []burst{{0, "temp", 30, 50 * time.Millisecond}}, []StormMeta{{"temp", 10.5}}},
+		{"default window", []SinkOption{SinkStormDetection(10, 0)}, []burst{{0, "temp", 11, 0}}, []StormMeta{{"temp", 11}}},
+		{
+			"within cooldown",
+			[]SinkOption{SinkStormDetection(10, time.Second)},
+			[]burst{{0, "temp", 20, 0}, {2 * time.Second, "temp", 20, 0}},
+			[]StormMeta{{"temp", 11}},
+		},
+		{
+			"after cooldown",
+			[]SinkOption{SinkStormDetection(10, time.Second)},
+			[]burst{{0, "temp", 20, 0}, {DefaultStormCooldown, "temp", 20, 0}},
+			[]StormMeta{{"temp", 11}, {"temp", 11}},
+		},
+		{
+			"short cooldown",
+			[]SinkOption{SinkStormDetection(10, time.Second), SinkStormCooldown(time.Second)},
+			[]burst{{0, "temp", 20, 0}, {2 * time.Second, "temp", 20, 0}},
+			[]StormMeta{{"temp", 11}, {"temp", 11}},
+		},
+		{
+			"per type",
+			[]SinkOption{SinkStormDetection(10, time.Second)},
+			[]burst{{0, "temp", 20, 0}, {0, "door", 5, 0}, {0, "humidity", 12, 0}},
+			[]StormMeta{{"temp", 11}, {"humidity", 11}},
+		},
+		{"disabled", nil, []burst{{0, "temp", 100, 0}}, []StormMeta{}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			c := useFakeClock(t)
+			sink := NewSyncEventSink(time.Hour, tc.opts...)
+			rec := RecordingHandler()
+			sink.AddEventListener(EventTypeStormDetected, rec)
+			for _, b := range tc.bursts {
+				c.Advance(b.advance)
+				for i := 0; i < b.count; i++ {
+					sink.Emit(b.eventType, float64(i))
+					c.Advance(b.spacing)
+				}
+			}
+			got := []StormMeta{}
+			for _, ev := range rec.Calls() {
+				got = append(got, *ev.GetData().(*StormMeta))
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("storms = %+v, want %+v", got, tc.want)
+			}
+		})
+	}
+}
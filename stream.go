@@ -0,0 +1,78 @@
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+type StreamFormat int
+
+const (
+	StreamCompact = StreamFormat(iota)
+	StreamVerbose
+)
+
+// StreamEvents prints events of the given types to w, one per line, until
+// the returned function is called. If no types are given, it subscribes to
+// every type the sink knows about at the time of the call.
+func StreamEvents(sink EventSink, w io.Writer, eventTypes ...string) func() {
+	return StreamEventsFormat(sink, w, StreamCompact, eventTypes...)
+}
+
+// StreamEventsFormat is like StreamEvents, with a choice of output format.
+// StreamCompact prints the time, type and value or message of each event;
+// StreamVerbose also prints its correlation ID and data.
+func StreamEventsFormat(sink EventSink, w io.Writer, format StreamFormat, eventTypes ...string) func() {
+	if len(eventTypes) == 0 {
+		for _, ev := range sink.ListEventTypes() {
+			eventTypes = append(eventTypes, ev.GetType())
+		}
+	}
+	mutex := &sync.Mutex{}
+	h := NewEventHandler(func(ev Event) error {
+		line := FormatEvent(ev, format) + "\n"
+		mutex.Lock()
+		defer mutex.Unlock()
+		_, err := io.WriteString(w, line)
+		return err
+	})
+	for _, eventType := range eventTypes {
+		sink.AddEventListener(eventType, h)
+	}
+	return func() {
+		for _, eventType := range eventTypes {
+			sink.RemoveEventListener(eventType, h)
+		}
+	}
+}
+
+// FormatEvent renders ev on a single line in the given format.
+func FormatEvent(ev Event, format StreamFormat) string {
+	parts := []string{
+		ev.GetTime().Format(time.RFC3339Nano),
+		ev.GetType(),
+	}
+	switch tev := ev.(type) {
+	case ValueEvent:
+		parts = append(parts, fmt.Sprint(tev.GetValue()))
+	case MessageEvent:
+		parts = append(parts, fmt.Sprintf("%q", tev.GetMessage()))
+	}
+	if format == StreamVerbose {
+		corrID := CorrelationID(ev)
+		if corrID != "" {
+			parts = append(parts, "correlation_id="+corrID)
+		}
+		if ev.GetData() != nil {
+			data, err := json.Marshal(ev.GetData())
+			if err == nil {
+				parts = append(parts, string(data))
+			}
+		}
+	}
+	return strings.Join(parts, " ")
+}
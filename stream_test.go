@@ -0,0 +1,97 @@
+package events
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFormatEvent(t *testing.T) {
+	tests := []struct {
+		name string
+		ev func() Event
+		wantCompact string
+		wantVerbose string
+	}{
+		{
+			"value",
+			func() Event { return NewEvent("temp", 21.5) },
+			"2024-01-01T00:00:00Z temp 21.5",
+			"2024-01-01T00:00:00Z temp 21.5",
+		},
+		{
+			"message",
+			func() Event { return NewEvent("door", "open") },
+			`2024-01-01T00:00:00Z door "open"`,
+			`2024-01-01T00:00:00Z door "open"`,
+		},
+		{
+			"correlated message",
+			func() Event { return NewEvent("door", "open", EventCorrelation("req-1")) },
+			`2024-01-01T00:00:00Z door "open"`,
+			`2024-01-01T00:00:00Z door "open" correlation_id=req-1`,
+		},
+		{
+			"data",
+			func() Event { return NewEvent("raw", map[string]interface{}{"a": 1.0}) },
+			"2024-01-01T00:00:00Z raw",
+			`2024-01-01T00:00:00Z raw {"a":1}`,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			useFakeClock(t)
+			ev := tc.ev()
+			if got := FormatEvent(ev, StreamCompact); got != tc.wantCompact {
+				t.Errorf("compact = %q, want %q", got, tc.wantCompact)
+			}
+			if got := FormatEvent(ev, StreamVerbose); got != tc.wantVerbose {
+				t.Errorf("verbose = %q, want %q", got, tc.wantVerbose)
+			}
+		})
+	}
+}
+
+func TestStreamEvents(t *testing.T) {
+	tests := []struct {
+		name string
+		format StreamFormat
+		eventTypes []string
+		want []string
+	}{
+		{
+			"compact",
+			StreamCompact,
+			[]string{"temp", "door"},
+			[]string{"2024-01-01T00:00:00Z temp 21.5", `2024-01-01T00:00:00Z door "open"`},
+		},
+		{
+			"verbose",
+			StreamVerbose,
+			[]string{"door"},
+			[]string{`2024-01-01T00:00:00Z door "open" correlation_id=req-1`},
+		},
+		{"unsubscribed type", StreamCompact, []string{"window"}, []string{}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			useFakeClock(t)
+			sink := NewSyncEventSink(time.Minute)
+			buf := &bytes.Buffer{}
+			stop := StreamEventsFormat(sink, buf, tc.format, tc.eventTypes...)
+			sink.Emit("temp", 21.5)
+			sink.Fire(NewEvent("door", "open", EventCorrelation("req-1")))
+			stop()
+			sink.Emit("temp", 22.0)
+			got := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+			if buf.Len() == 0 {
+				got = []string{}
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("output = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package events
+
+import (
+	"time"
+)
+
+// SinkHandlerTimeout sets the default deadline for each handler call on a
+// sink; see SetTypeTimeout. A timeout <= 0, the default, means none.
+func SinkHandlerTimeout(timeout time.Duration) SinkOption {
+	return func(es *basicEventSink) {
+		es.handlerTimeout = timeout
+	}
+}
+
+// SetTypeTimeout sets the deadline for each call to a handler for the
+// given event type, overriding the sink's default (see
+// SinkHandlerTimeout). The handler is called through CallContext with a
+// context that is cancelled once d has passed, so a slow handler can give
+// up; a handler that ignores its context isn't interrupted, so wrap it
+// with WithHardTimeout to stop waiting for it. A d <= 0 reverts the type
+// to the sink's default.
+func (es *basicEventSink) SetTypeTimeout(eventType string, d time.Duration) {
+	es.mutex.Lock()
+	if d <= 0 {
+		delete(es.typeTimeouts, eventType)
+	} else {
+		es.typeTimeouts[eventType] = d
+	}
+	es.mutex.Unlock()
+}
+
+func (es *PrefixedEventSource) SetTypeTimeout(eventType string, d time.Duration) {
+	if tm, ok := es.EventSink.(TypeManager); ok {
+		tm.SetTypeTimeout(es.prefix+eventType, d)
+	}
+}
+
+func (es *MappedEventSource) SetTypeTimeout(eventType string, d time.Duration) {
+	if tm, ok := es.EventSink.(TypeManager); ok {
+		tm.SetTypeTimeout(es.sinkType(eventType), d)
+	}
+}
+
+// timeoutFor returns the handler timeout for eventType, or 0 if there is
+// none.
+func (es *basicEventSink) timeoutFor(eventType string) time.Duration {
+	es.mutex.Lock()
+	defer es.mutex.Unlock()
+	if d, ok := es.typeTimeouts[eventType]; ok {
+		return d
+	}
+	return es.handlerTimeout
+}
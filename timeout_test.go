@@ -0,0 +1,80 @@
+package events
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSetTypeTimeout(t *testing.T) {
+	type setting struct {
+		eventType string
+		d time.Duration
+	}
+	tests := []struct {
+		name string
+		sinkTimeout time.Duration
+		settings []setting
+		wantSlow bool
+		wantOther bool
+	}{
+		{"none", 0, nil, false, false},
+		{"type timeout", 0, []setting{{"slow", 10 * time.Millisecond}}, true, false},
+		{"sink default", 10 * time.Millisecond, nil, true, true},
+		{"type overrides default", 10 * time.Millisecond, []setting{{"other", time.Hour}}, true, false},
+		{"reverted to default", 0, []setting{{"slow", 10 * time.Millisecond}, {"slow", 0}}, false, false},
+		{"long type timeout", 0, []setting{{"slow", time.Hour}}, false, false},
+	}
+	sinks := []struct {
+		name string
+		make func(timeout time.Duration) EventSink
+	}{
+		{"sync", func(timeout time.Duration) EventSink { return NewSyncEventSink(time.Hour, SinkHandlerTimeout(timeout)) }},
+		{"async", func(timeout time.Duration) EventSink { return NewEventSink(time.Hour, SinkHandlerTimeout(timeout)) }},
+		{
+			"prefixed",
+			func(timeout time.Duration) EventSink {
+				return NewPrefixedEventSource("kitchen", NewSyncEventSink(time.Hour, SinkHandlerTimeout(timeout)))
+			},
+		},
+	}
+	for _, sc := range sinks {
+		for _, tc := range tests {
+			t.Run(sc.name + "/" + tc.name, func(t *testing.T) {
+				sink := sc.make(tc.sinkTimeout)
+				for _, st := range tc.settings {
+					sink.(TypeManager).SetTypeTimeout(st.eventType, st.d)
+				}
+				cancelled := map[string]bool{}
+				mutex := &sync.Mutex{}
+				wg := &sync.WaitGroup{}
+				h := NewContextEventHandler(func(ctx context.Context, ev Event) error {
+					defer wg.Done()
+					select {
+					case <-ctx.Done():
+						mutex.Lock()
+						cancelled[ev.GetType()] = true
+						mutex.Unlock()
+					case <-time.After(100 * time.Millisecond):
+					}
+					return nil
+				})
+				sink.AddEventListener("slow", h)
+				sink.AddEventListener("other", h)
+				wg.Add(2)
+				sink.Emit("slow", 1.0)
+				sink.Emit("other", 1.0)
+				wg.Wait()
+				mutex.Lock()
+				defer mutex.Unlock()
+				if got := cancelled["kitchen-slow"] || cancelled["slow"]; got != tc.wantSlow {
+					t.Errorf("slow handler cancelled = %t, want %t", got, tc.wantSlow)
+				}
+				if got := cancelled["kitchen-other"] || cancelled["other"]; got != tc.wantOther {
+					t.Errorf("other handler cancelled = %t, want %t", got, tc.wantOther)
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,230 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+type emaHandler struct {
+	EventHandler
+	alpha float64
+	avg float64
+	mutex *sync.Mutex
+}
+
+// WithEMA passes h value events carrying an exponential moving average of
+// the values received, rather than the raw values. Each new value v moves
+// the average to alpha*v + (1-alpha)*avg, so a smaller alpha smooths more.
+// alpha must be in (0,1], and WithEMA panics if it isn't, since no average
+// could be computed; with alpha == 1 the average is the latest value, so h
+// is returned unchanged. NaN values are ignored.
+func WithEMA(h EventHandler, alpha float64) EventHandler {
+	if !(alpha > 0 && alpha <= 1) {
+		panic(fmt.Sprintf("events: WithEMA alpha %v is outside (0,1]", alpha))
+	}
+	if alpha == 1 {
+		return h
+	}
+	return &emaHandler{h, alpha, math.NaN(), &sync.Mutex{}}
+}
+
+func (h *emaHandler) Call(ev Event) error {
+	return h.CallContext(context.Background(), ev)
+}
+
+func (h *emaHandler) CallContext(ctx context.Context, ev Event) error {
+	valEv, ok := ev.(ValueEvent)
+	if !ok {
+		return ErrIncompatibleEvent
+	}
+	val := valEv.GetValue()
+	if math.IsNaN(val) {
+		return ignored(ctx, "ema: value is NaN")
+	}
+	h.mutex.Lock()
+	if math.IsNaN(h.avg) {
+		h.avg = val
+	} else {
+		h.avg = h.alpha * val + (1 - h.alpha) * h.avg
+	}
+	avg := h.avg
+	h.mutex.Unlock()
+	return callContext(ctx, h.EventHandler, withValue(ev, avg))
+}
+
+func (h *emaHandler) Unwrap() EventHandler {
+	return h.EventHandler
+}
+
+type sample struct {
+	t time.Time
+	val float64
+}
+
+type windowedAverageHandler struct {
+	EventHandler
+	window time.Duration
+	samples []sample
+	mutex *sync.Mutex
+}
+
+// WithWindowedAverage passes h value events carrying the time-weighted
+// average of the values received over the trailing window, measured by
+// event time. Each value is weighted by how long it held before the next
+// one arrived, so irregularly spaced events are averaged fairly. The
+// handler keeps every event in the window, so its memory use grows with
+// the event rate times the window. NaN values are ignored.
+func WithWindowedAverage(h EventHandler, window time.Duration) EventHandler {
+	if window <= 0 {
+		return h
+	}
+	return &windowedAverageHandler{h, window, nil, &sync.Mutex{}}
+}
+
+func (h *windowedAverageHandler) Call(ev Event) error {
+	return h.CallContext(context.Background(), ev)
+}
+
+func (h *windowedAverageHandler) CallContext(ctx context.Context, ev Event) error {
+	valEv, ok := ev.(ValueEvent)
+	if !ok {
+		return ErrIncompatibleEvent
+	}
+	val := valEv.GetValue()
+	if math.IsNaN(val) {
+		return ignored(ctx, "windowed average: value is NaN")
+	}
+	h.mutex.Lock()
+	avg := h.add(sample{ev.GetTime(), val})
+	h.mutex.Unlock()
+	return callContext(ctx, h.EventHandler, withValue(ev, avg))
+}
+
+// add records s and returns the average over the window ending at the
+// latest sample. The caller must hold the mutex.
+func (h *windowedAverageHandler) add(s sample) float64 {
+	i := sort.Search(len(h.samples), func(i int) bool { return h.samples[i].t.After(s.t) })
+	h.samples = append(h.samples, sample{})
+	copy(h.samples[i+1:], h.samples[i:])
+	h.samples[i] = s
+	last := h.samples[len(h.samples)-1]
+	cutoff := last.t.Add(-h.window)
+	// keep the last sample before the window, since it holds into it
+	n := 0
+	for n < len(h.samples) - 1 && !h.samples[n+1].t.After(cutoff) {
+		n += 1
+	}
+	h.samples = h.samples[n:]
+	var area, weight float64
+	for i := 0; i < len(h.samples) - 1; i++ {
+		start := h.samples[i].t
+		if start.Before(cutoff) {
+			start = cutoff
+		}
+		dt := h.samples[i+1].t.Sub(start).Seconds()
+		if dt > 0 {
+			area += h.samples[i].val * dt
+			weight += dt
+		}
+	}
+	if weight == 0 {
+		return last.val
+	}
+	return area / weight
+}
+
+func (h *windowedAverageHandler) Unwrap() EventHandler {
+	return h.EventHandler
+}
+
+type clampHandler struct {
+	EventHandler
+	lo float64
+	hi float64
+}
+
+// WithClamp passes h value events with their values clamped to [lo, hi].
+// Unlike WithRange, values outside the range are passed on at the nearest
+// bound rather than dropped. If lo > hi, h is returned unchanged. NaN
+// values are ignored.
+func WithClamp(h EventHandler, lo, hi float64) EventHandler {
+	if lo > hi {
+		return h
+	}
+	return &clampHandler{h, lo, hi}
+}
+
+func (h *clampHandler) Call(ev Event) error {
+	return h.CallContext(context.Background(), ev)
+}
+
+func (h *clampHandler) CallContext(ctx context.Context, ev Event) error {
+	valEv, ok := ev.(ValueEvent)
+	if !ok {
+		return ErrIncompatibleEvent
+	}
+	val := valEv.GetValue()
+	if math.IsNaN(val) {
+		return ignored(ctx, "clamp: value is NaN")
+	}
+	if val < h.lo {
+		ev = withValue(ev, h.lo)
+	} else if val > h.hi {
+		ev = withValue(ev, h.hi)
+	}
+	return callContext(ctx, h.EventHandler, ev)
+}
+
+func (h *clampHandler) Unwrap() EventHandler {
+	return h.EventHandler
+}
+
+type normalizeHandler struct {
+	EventHandler
+	min float64
+	max float64
+}
+
+// WithNormalize passes h value events with their values scaled from
+// [min, max] to [0, 1], so that thresholds can be shared by sensors with
+// different scales. Values outside the range are clamped to 0 or 1. If
+// min == max, values below it become 0 and the rest 1; if min > max, h is
+// returned unchanged. NaN values are ignored.
+func WithNormalize(h EventHandler, min, max float64) EventHandler {
+	if min > max {
+		return h
+	}
+	return &normalizeHandler{h, min, max}
+}
+
+func (h *normalizeHandler) Call(ev Event) error {
+	return h.CallContext(context.Background(), ev)
+}
+
+func (h *normalizeHandler) CallContext(ctx context.Context, ev Event) error {
+	valEv, ok := ev.(ValueEvent)
+	if !ok {
+		return ErrIncompatibleEvent
+	}
+	val := valEv.GetValue()
+	if math.IsNaN(val) {
+		return ignored(ctx, "normalize: value is NaN")
+	}
+	var norm float64
+	if val < h.min {
+		norm = 0
+	} else if val >= h.max {
+		norm = 1
+	} else {
+		norm = (val - h.min) / (h.max - h.min)
+	}
+	return callContext(ctx, h.EventHandler, withValue(ev, norm))
+}
+
+func (h *normalizeHandler) Unwrap() EventHandler {
+	return h.EventHandler
+}
@@ -0,0 +1,252 @@
+package events
+
+import (
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestWithEMA(t *testing.T) {
+	tests := []struct {
+		name string
+		alpha float64
+		vals []float64
+		want []float64
+	}{
+		{"half", 0.5, []float64{10, 0, 10, 0}, []float64{10, 5, 7.5, 3.75}},
+		{"quarter", 0.25, []float64{0, 8, 8}, []float64{0, 2, 3.5}},
+		{"nan ignored", 0.5, []float64{4, math.NaN(), 8}, []float64{4, 6}},
+		{"leading nan", 0.5, []float64{math.NaN(), 4, 8}, []float64{4, 6}},
+		{"one", 1, []float64{10, 0, 10}, []float64{10, 0, 10}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := passedValues(func(h EventHandler) EventHandler { return WithEMA(h, tc.alpha) }, tc.vals...)
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("passed %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestWithEMAInvalidAlpha(t *testing.T) {
+	tests := []struct {
+		name string
+		alpha float64
+	}{
+		{"zero", 0},
+		{"negative", -0.5},
+		{"above one", 1.5},
+		{"nan", math.NaN()},
+		{"infinite", math.Inf(1)},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("WithEMA(h, %v) didn't panic", tc.alpha)
+				}
+			}()
+			WithEMA(RecordingHandler(), tc.alpha)
+		})
+	}
+}
+
+func TestWithEMABeforeThreshold(t *testing.T) {
+	spike := []float64{0, 0, 10, 0, 0, 10, 10, 10}
+	tests := []struct {
+		name string
+		alpha float64
+		want []float64
+	}{
+		{"raw", 1, []float64{10, 10}},
+		{"smoothed", 0.5, []float64{8.90625}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := passedValues(func(h EventHandler) EventHandler {
+				return WithEMA(WithThreshold(h, DirectionIncreasing, 8, 1), tc.alpha)
+			}, spike...)
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("passed %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestWithWindowedAverage(t *testing.T) {
+	type arrival struct {
+		at time.Duration
+		val float64
+	}
+	tests := []struct {
+		name string
+		window time.Duration
+		arrivals []arrival
+		want []float64
+	}{
+		{
+			"irregular",
+			10 * time.Second,
+			[]arrival{{0, 10}, {2 * time.Second, 20}, {3 * time.Second, 30}, {13 * time.Second, 0}, {18 * time.Second, 0}},
+			[]float64{10, 10, 40.0 / 3, 30, 15},
+		},
+		{
+			"evicted",
+			time.Second,
+			[]arrival{{0, 100}, {10 * time.Second, 1}, {11 * time.Second, 2}},
+			[]float64{100, 100, 1},
+		},
+		{
+			"same time",
+			time.Minute,
+			[]arrival{{0, 1}, {0, 3}},
+			[]float64{1, 3},
+		},
+		{
+			"nan ignored",
+			time.Minute,
+			[]arrival{{0, 4}, {time.Second, math.NaN()}, {2 * time.Second, 8}},
+			[]float64{4, 4},
+		},
+		{
+			"late arrival",
+			time.Minute,
+			[]arrival{{0, 10}, {4 * time.Second, 10}, {2 * time.Second, 40}},
+			[]float64{10, 10, 25},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			c := useFakeClock(t)
+			start := c.Now()
+			got := []float64{}
+			h := WithWindowedAverage(NewEventHandler(func(ev Event) error {
+				got = append(got, ev.(Valuer).GetValue())
+				return nil
+			}), tc.window)
+			for _, a := range tc.arrivals {
+				c.Set(start.Add(a.at))
+				h.Call(NewEvent("test", a.val))
+			}
+			if len(got) != len(tc.want) {
+				t.Fatalf("passed %v, want %v", got, tc.want)
+			}
+			for i := range got {
+				if math.Abs(got[i] - tc.want[i]) > 1e-9 {
+					t.Errorf("passed %v, want %v", got, tc.want)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestWithClamp(t *testing.T) {
+	tests := []struct {
+		name string
+		lo float64
+		hi float64
+		vals []float64
+		want []float64
+	}{
+		{"below", 0, 10, []float64{-5, -0.1}, []float64{0, 0}},
+		{"above", 0, 10, []float64{10.1, 1e9}, []float64{10, 10}},
+		{"within", 0, 10, []float64{0, 5, 10}, []float64{0, 5, 10}},
+		{"infinite", 0, 10, []float64{math.Inf(-1), math.Inf(1)}, []float64{0, 10}},
+		{"nan ignored", 0, 10, []float64{math.NaN(), 5}, []float64{5}},
+		{"single point", 3, 3, []float64{1, 3, 5}, []float64{3, 3, 3}},
+		{"inverted", 10, 0, []float64{-5, 5, 15}, []float64{-5, 5, 15}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := passedValues(func(h EventHandler) EventHandler { return WithClamp(h, tc.lo, tc.hi) }, tc.vals...)
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("passed %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestWithClampKeepsEvent(t *testing.T) {
+	tests := []struct {
+		name string
+		val float64
+		wantSame bool
+	}{
+		{"within", 5, true},
+		{"clamped", 50, false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var got Event
+			h := WithClamp(NewEventHandler(func(ev Event) error {
+				got = ev
+				return nil
+			}), 0, 10)
+			ev := NewEvent("test", tc.val, EventCorrelation("req-1"))
+			if err := h.Call(ev); err != nil {
+				t.Fatalf("call returned %v", err)
+			}
+			if (got == ev) != tc.wantSame {
+				t.Errorf("passed the original event: %t, want %t", got == ev, tc.wantSame)
+			}
+			if got.GetType() != "test" || !got.GetTime().Equal(ev.GetTime()) || CorrelationID(got) != "req-1" {
+				t.Errorf("passed %v, want a copy of %v", got, ev)
+			}
+		})
+	}
+}
+
+func TestWithNormalize(t *testing.T) {
+	tests := []struct {
+		name string
+		min float64
+		max float64
+		vals []float64
+		want []float64
+	}{
+		{"within", 0, 10, []float64{2.5, 5, 7.5}, []float64{0.25, 0.5, 0.75}},
+		{"bounds", 0, 10, []float64{0, 10}, []float64{0, 1}},
+		{"offset range", -20, 20, []float64{-20, -10, 0, 20}, []float64{0, 0.25, 0.5, 1}},
+		{"below", 0, 10, []float64{-1, -1e9}, []float64{0, 0}},
+		{"above", 0, 10, []float64{10.5, 1e9}, []float64{1, 1}},
+		{"infinite", 0, 10, []float64{math.Inf(-1), math.Inf(1)}, []float64{0, 1}},
+		{"nan ignored", 0, 10, []float64{math.NaN(), 5}, []float64{0.5}},
+		{"min equals max", 3, 3, []float64{1, 3, 5}, []float64{0, 1, 1}},
+		{"inverted", 10, 0, []float64{-5, 5, 15}, []float64{-5, 5, 15}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := passedValues(func(h EventHandler) EventHandler { return WithNormalize(h, tc.min, tc.max) }, tc.vals...)
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("passed %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestWithNormalizeBeforeThreshold(t *testing.T) {
+	// sensors on different scales share one threshold at 80%
+	tests := []struct {
+		name string
+		min float64
+		max float64
+		vals []float64
+		want []float64
+	}{
+		{"celsius", 0, 50, []float64{20, 41, 45, 10, 40}, []float64{0.82, 0.8}},
+		{"percent", 0, 100, []float64{50, 79, 80, 95}, []float64{0.8}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := passedValues(func(h EventHandler) EventHandler {
+				return WithNormalize(WithThreshold(h, DirectionIncreasing, 0.8, 0.5), tc.min, tc.max)
+			}, tc.vals...)
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("passed %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,93 @@
+package events
+
+import (
+	"context"
+	"math"
+	"sync"
+)
+
+// A UnitConverter is a registry of functions converting values between
+// units. It is safe for concurrent use.
+type UnitConverter struct {
+	conversions map[[2]string]func(float64) float64
+	mutex *sync.RWMutex
+}
+
+func NewUnitConverter() *UnitConverter {
+	return &UnitConverter{
+		conversions: map[[2]string]func(float64) float64{},
+		mutex: &sync.RWMutex{},
+	}
+}
+
+// Units is the registry used by WithUnitConversion.
+var Units = NewUnitConverter()
+
+// Register adds a conversion from one unit to another, replacing any
+// previous one. Conversions are one-way; register the inverse separately.
+func (c *UnitConverter) Register(from, to string, fn func(float64) float64) {
+	c.mutex.Lock()
+	c.conversions[[2]string{from, to}] = fn
+	c.mutex.Unlock()
+}
+
+// Convert converts val from one unit to another, reporting false if no
+// conversion is registered. Converting a unit to itself always succeeds.
+func (c *UnitConverter) Convert(val float64, from, to string) (float64, bool) {
+	if from == to {
+		return val, true
+	}
+	c.mutex.RLock()
+	fn, ok := c.conversions[[2]string{from, to}]
+	c.mutex.RUnlock()
+	if !ok {
+		return 0, false
+	}
+	return fn(val), true
+}
+
+type unitConversionHandler struct {
+	EventHandler
+	units *UnitConverter
+	from string
+	to string
+}
+
+// WithUnitConversion passes h value events with their values converted
+// from one unit to another using the conversions registered in Units. The
+// conversion is looked up for each event, so it may be registered after
+// the handler is created. Events are rejected with ErrIncompatibleEvent if
+// no conversion is registered. NaN values are ignored.
+func WithUnitConversion(h EventHandler, from, to string) EventHandler {
+	return Units.WithConversion(h, from, to)
+}
+
+// WithConversion is like WithUnitConversion, using the conversions
+// registered in c.
+func (c *UnitConverter) WithConversion(h EventHandler, from, to string) EventHandler {
+	return &unitConversionHandler{h, c, from, to}
+}
+
+func (h *unitConversionHandler) Call(ev Event) error {
+	return h.CallContext(context.Background(), ev)
+}
+
+func (h *unitConversionHandler) CallContext(ctx context.Context, ev Event) error {
+	valEv, ok := ev.(ValueEvent)
+	if !ok {
+		return ErrIncompatibleEvent
+	}
+	val := valEv.GetValue()
+	if math.IsNaN(val) {
+		return ignored(ctx, "unit conversion: value is NaN")
+	}
+	val, ok = h.units.Convert(val, h.from, h.to)
+	if !ok {
+		return ErrIncompatibleEvent
+	}
+	return callContext(ctx, h.EventHandler, withValue(ev, val))
+}
+
+func (h *unitConversionHandler) Unwrap() EventHandler {
+	return h.EventHandler
+}
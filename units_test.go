@@ -0,0 +1,71 @@
+package events
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestUnitConversion(t *testing.T) {
+	units := NewUnitConverter()
+	units.Register("F", "C", func(f float64) float64 { return (f - 32) * 5 / 9 })
+	units.Register("psi", "bar", func(p float64) float64 { return p * 0.0689476 })
+	tests := []struct {
+		name string
+		from string
+		to string
+		data interface{}
+		wantErr error
+		want float64
+	}{
+		{"fahrenheit to celsius", "F", "C", 212.0, nil, 100},
+		{"freezing", "F", "C", 32.0, nil, 0},
+		{"psi to bar", "psi", "bar", 100.0, nil, 6.89476},
+		{"same unit", "K", "K", 5.0, nil, 5},
+		{"one way only", "C", "F", 100.0, ErrIncompatibleEvent, 0},
+		{"unregistered", "m", "ft", 1.0, ErrIncompatibleEvent, 0},
+		{"message", "F", "C", "hot", ErrIncompatibleEvent, 0},
+		{"map", "F", "C", map[string]interface{}{"a": "b"}, ErrIncompatibleEvent, 0},
+		{"NaN", "F", "C", math.NaN(), ErrIgnored, 0},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := RecordingHandler()
+			err := units.WithConversion(rec, tc.from, tc.to).Call(NewEvent("temp", tc.data))
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tc.wantErr)
+			}
+			calls := rec.Calls()
+			if tc.wantErr != nil {
+				if len(calls) != 0 {
+					t.Errorf("handler called with %v", logValues(calls))
+				}
+				return
+			}
+			if len(calls) != 1 || calls[0].GetType() != "temp" {
+				t.Fatalf("handler called with %v", calls)
+			}
+			if got := calls[0].(Valuer).GetValue(); math.Abs(got - tc.want) > 1e-9 {
+				t.Errorf("converted to %g, want %g", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestUnitConversionRegisteredLater(t *testing.T) {
+	rec := RecordingHandler()
+	h := WithUnitConversion(rec, "test-km", "test-m")
+	if err := h.Call(NewEvent("distance", 1.5)); !errors.Is(err, ErrIncompatibleEvent) {
+		t.Errorf("error before registering = %v, want %v", err, ErrIncompatibleEvent)
+	}
+	Units.Register("test-km", "test-m", func(km float64) float64 { return km * 1000 })
+	if err := h.Call(NewEvent("distance", 1.5)); err != nil {
+		t.Fatalf("error after registering = %s", err)
+	}
+	if got := logValues(rec.Calls()); len(got) != 1 || got[0] != 1500 {
+		t.Errorf("converted to %v, want [1500]", got)
+	}
+	if v, ok := Units.Convert(2, "test-km", "test-m"); !ok || v != 2000 {
+		t.Errorf("Convert = %g, %t, want 2000, true", v, ok)
+	}
+}
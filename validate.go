@@ -0,0 +1,43 @@
+package events
+
+// A Validator checks that an event is well formed, returning an error
+// describing the problem if it isn't.
+type Validator func(Event) error
+
+type ValidationErrorMeta struct {
+	EventType string `json:"event_type"`
+	Error string `json:"error"`
+}
+
+// RegisterEventTypeWithValidator registers an event type like
+// RegisterEventType, and additionally requires every event of that type to
+// pass validator. Events that fail are neither logged nor dispatched, and
+// an EventTypeValidationError event is emitted in their place. Event types
+// registered without a validator, or never registered, are not checked.
+func (es *basicEventSink) RegisterEventTypeWithValidator(ev Event, validator Validator) {
+	es.mutex.Lock()
+	es.registerLocked(ev)
+	if validator == nil {
+		delete(es.validators, ev.GetType())
+	} else {
+		es.validators[ev.GetType()] = validator
+	}
+	es.mutex.Unlock()
+}
+
+// validate runs the validator registered for ev's type, if any. The
+// mutex is held only to look the validator up, not while it runs, so the
+// caller must not hold it.
+func (es *basicEventSink) validate(ev Event) error {
+	eventType := ev.GetType()
+	if eventType == EventTypeValidationError {
+		return nil
+	}
+	es.mutex.Lock()
+	validator, ok := es.validators[eventType]
+	es.mutex.Unlock()
+	if !ok {
+		return nil
+	}
+	return validator(ev)
+}
@@ -0,0 +1,103 @@
+package events
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// percentage rejects value events outside [0, 100].
+func percentage(ev Event) error {
+	vev, ok := ev.(ValueEvent)
+	if !ok {
+		return fmt.Errorf("not a value")
+	}
+	if v := vev.GetValue(); v < 0 || v > 100 {
+		return fmt.Errorf("%g out of range", v)
+	}
+	return nil
+}
+
+func TestRegisterEventTypeWithValidator(t *testing.T) {
+	tests := []struct {
+		name string
+		validator Validator
+		data []interface{}
+		wantHandled []float64
+		wantErrors []string
+	}{
+		{"all valid", percentage, []interface{}{0.0, 50.0, 100.0}, []float64{0, 50, 100}, []string{}},
+		{"out of range", percentage, []interface{}{50.0, 101.0, -1.0, 20.0}, []float64{50, 20}, []string{"101 out of range", "-1 out of range"}},
+		{"wrong kind", percentage, []interface{}{"high"}, []float64{}, []string{"not a value"}},
+		{"no validator", nil, []interface{}{500.0}, []float64{500}, []string{}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			sink := NewSyncEventSink(time.Minute)
+			sink.(TypeManager).RegisterEventTypeWithValidator(NewEvent("humidity", 0.0), tc.validator)
+			rec := RecordingHandler()
+			sink.AddEventListener("humidity", rec)
+			invalid := RecordingHandler()
+			sink.AddEventListener(EventTypeValidationError, invalid)
+			for _, d := range tc.data {
+				sink.Emit("humidity", d)
+			}
+			if got := logValues(rec.Calls()); !reflect.DeepEqual(got, tc.wantHandled) {
+				t.Errorf("handled %v, want %v", got, tc.wantHandled)
+			}
+			if got := logValues(sink.(LogReader).LogForType("humidity")); len(got) != len(tc.wantHandled) {
+				t.Errorf("logged %v, want %d valid events", got, len(tc.wantHandled))
+			}
+			errs := []string{}
+			for _, ev := range invalid.Calls() {
+				meta := ev.GetData().(*ValidationErrorMeta)
+				if meta.EventType != "humidity" {
+					t.Errorf("validation error for %q, want humidity", meta.EventType)
+				}
+				errs = append(errs, meta.Error)
+			}
+			if !reflect.DeepEqual(errs, tc.wantErrors) {
+				t.Errorf("validation errors %v, want %v", errs, tc.wantErrors)
+			}
+		})
+	}
+}
+
+func TestValidatorRunsUnlocked(t *testing.T) {
+	tests := []struct {
+		name string
+		make func() EventSink
+	}{
+		{"async", func() EventSink { return NewEventSink(time.Hour) }},
+		{"sync", func() EventSink { return NewSyncEventSink(time.Hour) }},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			sink := tc.make()
+			// a validator may read the sink, here to reject repeated values
+			sink.(TypeManager).RegisterEventTypeWithValidator(NewEvent("temp", 0.0), func(ev Event) error {
+				if prev, ok := sink.(LatestReader).Latest("temp"); ok && prev.(ValueEvent).GetValue() == ev.(ValueEvent).GetValue() {
+					return fmt.Errorf("repeated value")
+				}
+				return nil
+			})
+			done := make(chan struct{})
+			go func() {
+				sink.Emit("temp", 1.0)
+				sink.Emit("temp", 1.0)
+				sink.Emit("temp", 2.0)
+				close(done)
+			}()
+			select {
+			case <-done:
+			case <-time.After(time.Second):
+				t.Fatal("validator deadlocked the sink")
+			}
+			if got := logValues(filterLog(sink.Log(), "temp")); !reflect.DeepEqual(got, []float64{2, 1}) {
+				t.Errorf("logged %v, want [2 1]", got)
+			}
+			sink.(Closer).Close()
+		})
+	}
+}
@@ -2,8 +2,11 @@ package events
 
 import (
 	"bytes"
+	"compress/gzip"
+	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 	"net/http"
 	"net/url"
@@ -14,22 +17,137 @@ import (
 	"github.com/rclancey/encoding-form"
 )
 
+// ErrCircuitOpen is returned by a webhook that has stopped calling its URL
+// after repeated failures. See Webhook.BreakerThreshold.
+var ErrCircuitOpen = errors.New("webhook circuit open")
+
+// DefaultBreakerCooldown is how long a webhook's circuit stays open when
+// its BreakerCooldown isn't set.
+const DefaultBreakerCooldown = 30 * time.Second
+
+// DefaultWebhookTimeout is how long a webhook call may take, including
+// reading the response, when the webhook's Timeout isn't set.
+const DefaultWebhookTimeout = 30 * time.Second
+
 func WebhookFunc(method, uri string, headers http.Header) HandlerFunc {
-	h := headers.Clone()
-	h.Set("Content-Type", "application/json")
-	client := http.Client{}
-	mutex := &sync.Mutex{}
+	hook := &Webhook{
+		Method: method,
+		URL: uri,
+		Headers: headers,
+	}
+	return hook.Func()
+}
+
+func gzipBytes(data []byte) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	w := gzip.NewWriter(buf)
+	_, err := w.Write(data)
+	if err != nil {
+		return nil, err
+	}
+	err = w.Close()
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// renamedEvent marshals an event with its JSON fields renamed, including
+// those of the event nested under "Event" in a value, message or binary
+// event.
+type renamedEvent struct {
+	Event
+	fields map[string]string
+}
+
+func (ev *renamedEvent) MarshalJSON() ([]byte, error) {
+	data, err := json.Marshal(ev.Event)
+	if err != nil {
+		return nil, err
+	}
+	return renameFields(data, ev.fields)
+}
+
+func renameFields(data []byte, names map[string]string) ([]byte, error) {
+	fields := map[string]json.RawMessage{}
+	err := json.Unmarshal(data, &fields)
+	if err != nil {
+		return nil, err
+	}
+	renamed := make(map[string]json.RawMessage, len(fields))
+	for k, v := range fields {
+		if k == "Event" && len(v) > 0 && v[0] == '{' {
+			v, err = renameFields(v, names)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if name, ok := names[k]; ok {
+			k = name
+		}
+		renamed[k] = v
+	}
+	return json.Marshal(renamed)
+}
+
+// Func returns a function calling the webhook. See ContextFunc.
+func (hook *Webhook) Func() HandlerFunc {
+	fn := hook.ContextFunc()
 	return func(ev Event) error {
-		mutex.Lock()
-		defer mutex.Unlock()
+		return fn(context.Background(), ev)
+	}
+}
+
+// ContextFunc returns a function calling the webhook, whose request is
+// cancelled when its ctx is done, such as when a sink's handler timeout
+// (see SetTypeTimeout) has passed.
+func (hook *Webhook) ContextFunc() ContextHandlerFunc {
+	method := hook.Method
+	uri := hook.URL
+	compress := hook.Compress
+	validate := hook.ResponseValidator
+	fieldMap := hook.FieldMap
+	tokens := hook.TokenSource
+	h := hook.Headers.Clone()
+	if h == nil {
+		h = http.Header{}
+	}
+	if compress {
+		h.Set("Content-Encoding", "gzip")
+	}
+	timeout := hook.Timeout
+	if timeout <= 0 {
+		timeout = DefaultWebhookTimeout
+	}
+	client := http.Client{Timeout: timeout}
+	mutex := &sync.Mutex{}
+	send := func(ctx context.Context, ev Event) error {
 		var body io.Reader
 		var bodySize int
 		var u string
+		contentType := "application/json"
 		if method == http.MethodPost || method == http.MethodPut || method == http.MethodDelete || method == http.MethodPatch {
 			u = uri
-			data, err := json.Marshal(ev)
-			if err != nil {
-				return err
+			var data []byte
+			var err error
+			if bev, ok := ev.(BinaryEvent); ok {
+				data = bev.GetBytes()
+				contentType = "application/octet-stream"
+			} else {
+				var payload interface{} = ev
+				if len(fieldMap) > 0 {
+					payload = &renamedEvent{ev, fieldMap}
+				}
+				data, err = json.Marshal(payload)
+				if err != nil {
+					return err
+				}
+			}
+			if compress {
+				data, err = gzipBytes(data)
+				if err != nil {
+					return err
+				}
 			}
 			body = bytes.NewReader(data)
 			bodySize = len(data)
@@ -52,12 +170,22 @@ func WebhookFunc(method, uri string, headers http.Header) HandlerFunc {
 			xu.RawQuery = query.Encode()
 			u = xu.String()
 		}
-		req, err := http.NewRequest(method, u, body)
+		req, err := http.NewRequestWithContext(ctx, method, u, body)
 		if err != nil {
 			return err
 		}
 		req.Header = h.Clone()
-		if body != nil {
+		req.Header.Set("Content-Type", contentType)
+		if tokens != nil {
+			auth, err := tokens.AuthHeader()
+			if err != nil {
+				return fmt.Errorf("can't get webhook token: %w", err)
+			}
+			req.Header.Set("Authorization", auth)
+		}
+		if body == nil {
+			req.Header.Del("Content-Encoding")
+		} else {
 			req.Header.Set("Content-Length", strconv.Itoa(bodySize))
 		}
 		res, err := client.Do(req)
@@ -65,11 +193,69 @@ func WebhookFunc(method, uri string, headers http.Header) HandlerFunc {
 			return err
 		}
 		defer res.Body.Close()
+		if validate != nil {
+			resBody, err := io.ReadAll(res.Body)
+			if err != nil {
+				return err
+			}
+			return validate(res.StatusCode, resBody)
+		}
 		if res.StatusCode < 200 || res.StatusCode >= 400 {
 			return errors.New(res.Status)
 		}
 		return nil
 	}
+	threshold := hook.BreakerThreshold
+	cooldown := hook.BreakerCooldown
+	if cooldown <= 0 {
+		cooldown = DefaultBreakerCooldown
+	}
+	failures := 0
+	probing := false
+	var openUntil time.Time
+	// the mutex guards the breaker state only, so that a slow call doesn't
+	// hold up the others; while the circuit is open, a single probe is
+	// let through once the cooldown has passed
+	return func(ctx context.Context, ev Event) error {
+		mutex.Lock()
+		if threshold > 0 && failures >= threshold {
+			if probing || now().Before(openUntil) {
+				mutex.Unlock()
+				return ErrCircuitOpen
+			}
+			probing = true
+		}
+		mutex.Unlock()
+		err := send(ctx, ev)
+		mutex.Lock()
+		defer mutex.Unlock()
+		probing = false
+		if err != nil {
+			failures += 1
+			if threshold > 0 && failures >= threshold {
+				openUntil = now().Add(cooldown)
+			}
+			return err
+		}
+		failures = 0
+		return nil
+	}
+}
+
+// TokenSource supplies the credentials sent with each webhook request.
+// AuthHeader returns the value of the Authorization header, such as
+// "Bearer <token>", refreshing the token if it needs to. The oauth2events
+// package adapts an oauth2.TokenSource to a TokenSource.
+type TokenSource interface {
+	AuthHeader() (string, error)
+}
+
+// TokenSourceFunc adapts a function returning the Authorization header to
+// a TokenSource.
+type TokenSourceFunc func() (string, error)
+
+func (f TokenSourceFunc) AuthHeader() (string, error) {
+	return f()
 }
 
 type Webhook struct {
@@ -84,24 +270,53 @@ type Webhook struct {
 	Max *float64 `json:"max,omitempty"`
 	MaxCalls int `json:"max_calls,omitempty"`
 	TTL time.Duration `json:"ttl,omitempty"`
+	Compress bool `json:"compress,omitempty"`
+	// FieldMap renames the fields of the JSON payload, such as "type" to
+	// "metric" or "value" to "reading", both at the top level and in the
+	// event nested under "Event". Fields not in the map keep their usual
+	// names.
+	FieldMap map[string]string `json:"field_map,omitempty"`
+	// BreakerThreshold, if > 0, is the number of consecutive failed calls
+	// after which the webhook stops calling the URL and fails fast with
+	// ErrCircuitOpen instead. After BreakerCooldown (or
+	// DefaultBreakerCooldown if it is 0), the next event is sent as a
+	// probe: if it succeeds, the webhook resumes normal operation, and if
+	// it fails, the circuit stays open for another cooldown.
+	BreakerThreshold int `json:"breaker_threshold,omitempty"`
+	BreakerCooldown time.Duration `json:"breaker_cooldown,omitempty"`
+	// Timeout limits how long a call may take, including reading the
+	// response, after which it fails like any other call. If it is 0,
+	// DefaultWebhookTimeout is used.
+	Timeout time.Duration `json:"timeout,omitempty"`
+	// TokenSource, if set, supplies the Authorization header for each
+	// request, such as an OAuth2 bearer token from the oauth2events
+	// package. A failure to get a token fails the call, so it is reported
+	// like any other webhook error.
+	TokenSource TokenSource `json:"-"`
+	// ResponseValidator, if set, decides whether a webhook call succeeded
+	// from the response status and body, replacing the default check that
+	// the status is 2xx or 3xx.
+	ResponseValidator func(status int, body []byte) error `json:"-"`
 }
 
+// Handler builds an EventHandler for the webhook, applying its decorators
+// in the order described by EventHandlerBuilder.
 func (hook *Webhook) Handler() EventHandler {
-	h := NewEventHandler(WebhookFunc(hook.Method, hook.URL, hook.Headers))
-	if hook.Debounce != nil {
-		h = WithDebounce(h, *hook.Debounce)
-	}
-	if hook.Min != nil && hook.Max != nil {
-		h = WithRange(h, *hook.Min, *hook.Max)
-	}
-	if hook.Direction != nil {
-		if hook.TriggerValue != nil && hook.ResetValue != nil {
-			h = WithThreshold(h, *hook.Direction, *hook.TriggerValue, *hook.ResetValue)
-		} else {
-			h = WithDirection(h, *hook.Direction)
-		}
+	return buildHandlerFor(hook.Spec(), NewContextEventHandler(hook.ContextFunc()))
+}
+
+// Spec returns the webhook's decorator settings as a ListenerSpec.
+func (hook *Webhook) Spec() ListenerSpec {
+	return ListenerSpec{
+		Debounce: hook.Debounce,
+		Direction: hook.Direction,
+		TriggerValue: hook.TriggerValue,
+		ResetValue: hook.ResetValue,
+		Min: hook.Min,
+		Max: hook.Max,
+		MaxCalls: hook.MaxCalls,
+		TTL: hook.TTL,
 	}
-	return WithTimeout(WithMaxCalls(h, hook.MaxCalls), hook.TTL)
 }
 
 func (hook *Webhook) Equals(other *Webhook) bool {
@@ -113,3 +328,105 @@ func (hook *Webhook) Equals(other *Webhook) bool {
 	}
 	return true
 }
+
+// Validate checks that the webhook has a standard HTTP method, in upper
+// case, and an absolute http or https URL with a host.
+func (hook *Webhook) Validate() error {
+	if hook.Method == "" {
+		return errors.New("missing method")
+	}
+	if !webhookMethods[hook.Method] {
+		return fmt.Errorf("unknown method %q", hook.Method)
+	}
+	if hook.URL == "" {
+		return errors.New("missing url")
+	}
+	u, err := url.Parse(hook.URL)
+	if err != nil {
+		return fmt.Errorf("invalid url: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("invalid url %q: not an absolute http or https url", hook.URL)
+	}
+	if u.Host == "" {
+		return fmt.Errorf("invalid url %q: missing host", hook.URL)
+	}
+	return nil
+}
+
+// webhookMethods are the HTTP methods a webhook may use.
+var webhookMethods = map[string]bool{
+	http.MethodGet: true,
+	http.MethodHead: true,
+	http.MethodPost: true,
+	http.MethodPut: true,
+	http.MethodPatch: true,
+	http.MethodDelete: true,
+	http.MethodConnect: true,
+	http.MethodOptions: true,
+	http.MethodTrace: true,
+}
+
+// LoadWebhooks reads a JSON object mapping event types to lists of webhook
+// configurations, validating each webhook.
+func LoadWebhooks(r io.Reader) (map[string][]*Webhook, error) {
+	config := map[string][]*Webhook{}
+	err := json.NewDecoder(r).Decode(&config)
+	if err != nil {
+		return nil, fmt.Errorf("can't parse webhook config: %w", err)
+	}
+	for eventType, hooks := range config {
+		for i, hook := range hooks {
+			if hook == nil {
+				return nil, fmt.Errorf("webhook %d for event type %q: empty config", i, eventType)
+			}
+			err = hook.Validate()
+			if err != nil {
+				return nil, fmt.Errorf("webhook %d for event type %q: %w", i, eventType, err)
+			}
+		}
+	}
+	return config, nil
+}
+
+// AttachWebhooks adds a listener to sink for every webhook in config.
+func AttachWebhooks(sink EventSink, config map[string][]*Webhook) {
+	for eventType, hooks := range config {
+		for _, hook := range hooks {
+			sink.AddEventListener(eventType, hook.Handler())
+		}
+	}
+}
+
+// WebhookDefaults are fallback settings for webhooks that leave them
+// unset: a nil Debounce, or a zero TTL or MaxCalls.
+type WebhookDefaults struct {
+	Debounce *time.Duration `json:"debounce,omitempty"`
+	TTL time.Duration `json:"ttl,omitempty"`
+	MaxCalls int `json:"max_calls,omitempty"`
+}
+
+// AttachWebhooksWithDefaults is like AttachWebhooks, but webhooks that
+// leave a setting in defaults unset use the default instead. The webhooks
+// in config are not modified.
+func AttachWebhooksWithDefaults(sink EventSink, config map[string][]*Webhook, defaults WebhookDefaults) {
+	for eventType, hooks := range config {
+		for _, hook := range hooks {
+			sink.AddEventListener(eventType, hook.withDefaults(defaults).Handler())
+		}
+	}
+}
+
+func (hook *Webhook) withDefaults(defaults WebhookDefaults) *Webhook {
+	copied := *hook
+	if copied.Debounce == nil {
+		copied.Debounce = defaults.Debounce
+	}
+	if copied.TTL == 0 {
+		copied.TTL = defaults.TTL
+	}
+	if copied.MaxCalls == 0 {
+		copied.MaxCalls = defaults.MaxCalls
+	}
+	return &copied
+}
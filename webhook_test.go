@@ -0,0 +1,710 @@
+package events
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// capturedRequest is a request received by a test webhook server.
+type capturedRequest struct {
+	header http.Header
+	contentLength int64
+	body []byte
+}
+
+// webhookServer starts a server that records each request and responds
+// with status and body. The returned function lists the requests so far.
+func webhookServer(t *testing.T, status int, body string) (*httptest.Server, func() []capturedRequest) {
+	reqs := []capturedRequest{}
+	mutex := &sync.Mutex{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Errorf("can't read request body: %s", err)
+		}
+		mutex.Lock()
+		reqs = append(reqs, capturedRequest{r.Header.Clone(), r.ContentLength, data})
+		mutex.Unlock()
+		w.WriteHeader(status)
+		io.WriteString(w, body)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, func() []capturedRequest {
+		mutex.Lock()
+		defer mutex.Unlock()
+		return append([]capturedRequest{}, reqs...)
+	}
+}
+
+func TestWebhookCompress(t *testing.T) {
+	tests := []struct {
+		name string
+		compress bool
+		wantEncoding string
+	}{
+		{"plain", false, ""},
+		{"gzip", true, "gzip"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			srv, reqs := webhookServer(t, http.StatusOK, "")
+			hook := &Webhook{Method: http.MethodPost, URL: srv.URL, Compress: tc.compress}
+			err := hook.Func()(NewEvent("temperature", 21.5))
+			if err != nil {
+				t.Fatalf("webhook failed: %s", err)
+			}
+			got := reqs()
+			if len(got) != 1 {
+				t.Fatalf("got %d requests, want 1", len(got))
+			}
+			req := got[0]
+			if enc := req.header.Get("Content-Encoding"); enc != tc.wantEncoding {
+				t.Errorf("Content-Encoding = %q, want %q", enc, tc.wantEncoding)
+			}
+			if req.contentLength != int64(len(req.body)) {
+				t.Errorf("Content-Length = %d, want %d", req.contentLength, len(req.body))
+			}
+			payload := req.body
+			if tc.compress {
+				zr, err := gzip.NewReader(bytes.NewReader(req.body))
+				if err != nil {
+					t.Fatalf("body isn't gzipped: %s", err)
+				}
+				payload, err = io.ReadAll(zr)
+				if err != nil {
+					t.Fatalf("can't decompress body: %s", err)
+				}
+			}
+			ev, err := UnmarshalEvent(payload)
+			if err != nil {
+				t.Fatalf("can't decode payload %s: %s", payload, err)
+			}
+			if ev.GetType() != "temperature" || ev.(Valuer).GetValue() != 21.5 {
+				t.Errorf("payload = %s, want a temperature event with value 21.5", payload)
+			}
+		})
+	}
+}
+
+func TestWebhookCompressWithoutBody(t *testing.T) {
+	srv, reqs := webhookServer(t, http.StatusOK, "")
+	hook := &Webhook{Method: http.MethodGet, URL: srv.URL, Compress: true}
+	err := hook.Func()(NewEvent("temperature", map[string]interface{}{"room": "kitchen"}))
+	if err != nil {
+		t.Fatalf("webhook failed: %s", err)
+	}
+	if enc := reqs()[0].header.Get("Content-Encoding"); enc != "" {
+		t.Errorf("Content-Encoding = %q for a request without a body", enc)
+	}
+}
+
+func TestWebhookBinary(t *testing.T) {
+	tests := []struct {
+		name string
+		data interface{}
+		compress bool
+		wantType string
+	}{
+		{"binary", []byte{0, 1, 0xfe, 0xff}, false, "application/octet-stream"},
+		{"compressed binary", []byte{0, 1, 0xfe, 0xff}, true, "application/octet-stream"},
+		{"value", 21.5, false, "application/json"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			srv, reqs := webhookServer(t, http.StatusOK, "")
+			hook := &Webhook{Method: http.MethodPost, URL: srv.URL, Compress: tc.compress}
+			if err := hook.Func()(NewEvent("blob", tc.data)); err != nil {
+				t.Fatalf("webhook failed: %s", err)
+			}
+			req := reqs()[0]
+			if ct := req.header.Get("Content-Type"); ct != tc.wantType {
+				t.Errorf("Content-Type = %q, want %q", ct, tc.wantType)
+			}
+			payload := req.body
+			if tc.compress {
+				zr, err := gzip.NewReader(bytes.NewReader(req.body))
+				if err != nil {
+					t.Fatalf("body isn't gzipped: %s", err)
+				}
+				payload, _ = io.ReadAll(zr)
+			}
+			if raw, ok := tc.data.([]byte); ok && !bytes.Equal(payload, raw) {
+				t.Errorf("body = %v, want %v", payload, raw)
+			}
+		})
+	}
+}
+
+func TestWebhookResponseValidator(t *testing.T) {
+	okBody := func(status int, body []byte) error {
+		res := struct {
+			OK bool `json:"ok"`
+		}{}
+		if err := json.Unmarshal(body, &res); err != nil {
+			return err
+		}
+		if !res.OK {
+			return fmt.Errorf("endpoint failed with status %d: %s", status, body)
+		}
+		return nil
+	}
+	tests := []struct {
+		name string
+		status int
+		body string
+		validate func(int, []byte) error
+		wantErr bool
+	}{
+		{"ok body", http.StatusOK, `{"ok":true}`, okBody, false},
+		{"failed body", http.StatusOK, `{"ok":false}`, okBody, true},
+		{"bad json", http.StatusOK, `not json`, okBody, true},
+		{"error status accepted", http.StatusInternalServerError, `{"ok":true}`, okBody, false},
+		{"default ok", http.StatusOK, `{"ok":false}`, nil, false},
+		{"default error status", http.StatusInternalServerError, `{"ok":true}`, nil, true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			srv, _ := webhookServer(t, tc.status, tc.body)
+			hook := &Webhook{Method: http.MethodPost, URL: srv.URL, ResponseValidator: tc.validate}
+			err := hook.Func()(NewEvent("test", 1.0))
+			if (err != nil) != tc.wantErr {
+				t.Errorf("webhook returned %v, want error %t", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestWebhookFieldMap(t *testing.T) {
+	tests := []struct {
+		name string
+		data interface{}
+		fieldMap map[string]string
+		want string
+	}{
+		{
+			"no map",
+			21.5,
+			nil,
+			`{"Event": {"type": "temp", "time": "2024-01-01T00:00:00Z"}, "value": 21.5}`,
+		},
+		{
+			"value",
+			21.5,
+			map[string]string{"type": "metric", "value": "reading"},
+			`{"Event": {"metric": "temp", "time": "2024-01-01T00:00:00Z"}, "reading": 21.5}`,
+		},
+		{
+			"message",
+			"open",
+			map[string]string{"type": "metric", "message": "status", "time": "at"},
+			`{"Event": {"metric": "temp", "at": "2024-01-01T00:00:00Z"}, "status": "open"}`,
+		},
+		{
+			"unmapped fields",
+			"open",
+			map[string]string{"value": "reading"},
+			`{"Event": {"type": "temp", "time": "2024-01-01T00:00:00Z"}, "message": "open"}`,
+		},
+		{
+			"data keys untouched",
+			map[string]interface{}{"room": "kitchen", "type": "sensor"},
+			map[string]string{"type": "metric", "data": "payload"},
+			`{"metric": "temp", "time": "2024-01-01T00:00:00Z", "payload": {"room": "kitchen", "type": "sensor"}}`,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			useFakeClock(t)
+			srv, reqs := webhookServer(t, http.StatusOK, "")
+			hook := &Webhook{Method: http.MethodPost, URL: srv.URL, FieldMap: tc.fieldMap}
+			if err := hook.Func()(NewEvent("temp", tc.data)); err != nil {
+				t.Fatalf("webhook failed: %s", err)
+			}
+			var got, want interface{}
+			if err := json.Unmarshal(reqs()[0].body, &got); err != nil {
+				t.Fatalf("can't decode body %s: %s", reqs()[0].body, err)
+			}
+			json.Unmarshal([]byte(tc.want), &want)
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("body = %s, want %s", reqs()[0].body, tc.want)
+			}
+		})
+	}
+}
+
+func TestWebhookCircuitBreaker(t *testing.T) {
+	type step struct {
+		advance time.Duration
+		status int
+		want string
+	}
+	// want is "ok", "failed" or "open"
+	fail := http.StatusInternalServerError
+	ok := http.StatusOK
+	tests := []struct {
+		name string
+		threshold int
+		cooldown time.Duration
+		steps []step
+	}{
+		{"no breaker", 0, time.Minute, []step{{0, fail, "failed"}, {0, fail, "failed"}, {0, fail, "failed"}, {0, ok, "ok"}}},
+		{"below threshold", 3, time.Minute, []step{{0, fail, "failed"}, {0, fail, "failed"}, {0, ok, "ok"}, {0, fail, "failed"}, {0, fail, "failed"}, {0, ok, "ok"}}},
+		{
+			"opens and recovers",
+			2,
+			time.Minute,
+			[]step{
+				{0, fail, "failed"},
+				{0, fail, "failed"},
+				{0, ok, "open"},
+				{30 * time.Second, ok, "open"},
+				{31 * time.Second, ok, "ok"},
+				{0, fail, "failed"},
+				{0, ok, "ok"},
+			},
+		},
+		{
+			"failed probe reopens",
+			2,
+			time.Minute,
+			[]step{
+				{0, fail, "failed"},
+				{0, fail, "failed"},
+				{time.Minute, fail, "failed"},
+				{0, ok, "open"},
+				{59 * time.Second, ok, "open"},
+				{time.Second, ok, "ok"},
+			},
+		},
+		{
+			"default cooldown",
+			1,
+			0,
+			[]step{
+				{0, fail, "failed"},
+				{DefaultBreakerCooldown - time.Second, ok, "open"},
+				{time.Second, ok, "ok"},
+			},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			c := useFakeClock(t)
+			var status, requests int32
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				atomic.AddInt32(&requests, 1)
+				w.WriteHeader(int(atomic.LoadInt32(&status)))
+			}))
+			defer srv.Close()
+			hook := &Webhook{Method: http.MethodPost, URL: srv.URL, BreakerThreshold: tc.threshold, BreakerCooldown: tc.cooldown}
+			fn := hook.Func()
+			sent := int32(0)
+			for i, s := range tc.steps {
+				c.Advance(s.advance)
+				atomic.StoreInt32(&status, int32(s.status))
+				err := fn(NewEvent("temp", float64(i)))
+				got := "ok"
+				if errors.Is(err, ErrCircuitOpen) {
+					got = "open"
+				} else if err != nil {
+					got = "failed"
+				}
+				if got != s.want {
+					t.Errorf("step %d: %s (%v), want %s", i, got, err, s.want)
+				}
+				if got != "open" {
+					sent += 1
+				}
+				if n := atomic.LoadInt32(&requests); n != sent {
+					t.Errorf("step %d: %d requests, want %d", i, n, sent)
+				}
+			}
+		})
+	}
+}
+
+// mockTokenSource hands out its headers in turn, returning err instead
+// once they run out.
+type mockTokenSource struct {
+	headers []string
+	err error
+	calls int
+}
+
+func (ts *mockTokenSource) AuthHeader() (string, error) {
+	ts.calls += 1
+	if len(ts.headers) == 0 {
+		return "", ts.err
+	}
+	auth := ts.headers[0]
+	ts.headers = ts.headers[1:]
+	return auth, nil
+}
+
+func TestWebhookTimeout(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/hang" {
+			<-release
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(srv.Close)
+	t.Cleanup(func() { close(release) })
+	// want is "ok", "timeout" or "open"
+	tests := []struct {
+		name string
+		path string
+		threshold int
+		want []string
+	}{
+		{"answering", "/ok", 2, []string{"ok", "ok", "ok"}},
+		{"hanging", "/hang", 0, []string{"timeout", "timeout", "timeout"}},
+		{"hanging trips the breaker", "/hang", 2, []string{"timeout", "timeout", "open"}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			hook := &Webhook{Method: http.MethodPost, URL: srv.URL + tc.path, Timeout: 20 * time.Millisecond, BreakerThreshold: tc.threshold}
+			fn := hook.Func()
+			for i, want := range tc.want {
+				start := time.Now()
+				err := fn(NewEvent("test", 1.0))
+				got := "ok"
+				if errors.Is(err, ErrCircuitOpen) {
+					got = "open"
+				} else if isTimeout(err) {
+					got = "timeout"
+				} else if err != nil {
+					got = err.Error()
+				}
+				if got != want {
+					t.Errorf("call %d: %s, want %s", i, got, want)
+				}
+				if d := time.Since(start); d > time.Second {
+					t.Errorf("call %d took %s", i, d)
+				}
+			}
+		})
+	}
+}
+
+func isTimeout(err error) bool {
+	var te interface{ Timeout() bool }
+	return errors.As(err, &te) && te.Timeout()
+}
+
+func TestWebhookSlowCallDoesNotBlock(t *testing.T) {
+	release := make(chan struct{})
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// the first call hangs until the second has finished
+		if atomic.AddInt32(&calls, 1) == 1 {
+			<-release
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(srv.Close)
+	fn := (&Webhook{Method: http.MethodPost, URL: srv.URL, BreakerThreshold: 1}).Func()
+	first := make(chan error, 1)
+	go func() { first <- fn(NewEvent("test", 1.0)) }()
+	for atomic.LoadInt32(&calls) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	second := make(chan error, 1)
+	go func() { second <- fn(NewEvent("test", 2.0)) }()
+	select {
+	case err := <-second:
+		if err != nil {
+			t.Errorf("second call returned %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Error("second call blocked behind the first")
+	}
+	close(release)
+	if err := <-first; err != nil {
+		t.Errorf("first call returned %v", err)
+	}
+}
+
+func TestWebhookTypeTimeout(t *testing.T) {
+	cancelled := make(chan struct{}, 10)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// the server only notices the client going away once it has read
+		// the body
+		io.ReadAll(r.Body)
+		select {
+		case <-r.Context().Done():
+			cancelled <- struct{}{}
+		case <-time.After(200 * time.Millisecond):
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(srv.Close)
+	tests := []struct {
+		name string
+		typeTimeout time.Duration
+		wantErr error
+		wantCancelled bool
+	}{
+		{"no timeout", 0, nil, false},
+		{"short type timeout", 20 * time.Millisecond, context.DeadlineExceeded, true},
+		{"long type timeout", time.Hour, nil, false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			sink := NewSyncEventSink(time.Hour)
+			sink.(TypeManager).SetTypeTimeout("door", tc.typeTimeout)
+			AttachWebhooks(sink, map[string][]*Webhook{"door": {{Method: http.MethodPost, URL: srv.URL, Timeout: time.Hour}}})
+			errs := sink.(SyncSink).EmitSync("door", "open")
+			if tc.wantErr == nil {
+				if len(errs) != 0 {
+					t.Errorf("errors = %v, want none", errs)
+				}
+			} else if len(errs) != 1 || !errors.Is(errs[0], tc.wantErr) {
+				t.Errorf("errors = %v, want %v", errs, tc.wantErr)
+			}
+			select {
+			case <-cancelled:
+				if !tc.wantCancelled {
+					t.Error("request cancelled")
+				}
+			case <-time.After(500 * time.Millisecond):
+				if tc.wantCancelled {
+					t.Error("request not cancelled")
+				}
+			}
+		})
+	}
+}
+
+func TestWebhookTokenSource(t *testing.T) {
+	errToken := errors.New("token endpoint down")
+	tests := []struct {
+		name string
+		headers []string
+		calls int
+		wantHeaders []string
+		wantErrs int
+	}{
+		{"one token", []string{"Bearer a"}, 1, []string{"Bearer a"}, 0},
+		{"refreshed", []string{"Bearer a", "Bearer b", "Bearer c"}, 3, []string{"Bearer a", "Bearer b", "Bearer c"}, 0},
+		{"fetch fails", []string{"Bearer a"}, 3, []string{"Bearer a"}, 2},
+		{"no token", nil, 1, []string{}, 1},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			srv, reqs := webhookServer(t, http.StatusOK, "")
+			ts := &mockTokenSource{headers: tc.headers, err: errToken}
+			hook := &Webhook{Method: http.MethodPost, URL: srv.URL, TokenSource: ts}
+			fn := hook.Func()
+			errs := 0
+			for i := 0; i < tc.calls; i++ {
+				if err := fn(NewEvent("temp", float64(i))); err != nil {
+					if !errors.Is(err, errToken) {
+						t.Errorf("error = %v, want %v", err, errToken)
+					}
+					errs += 1
+				}
+			}
+			if errs != tc.wantErrs {
+				t.Errorf("%d calls failed, want %d", errs, tc.wantErrs)
+			}
+			if ts.calls != tc.calls {
+				t.Errorf("token fetched %d times, want %d", ts.calls, tc.calls)
+			}
+			got := []string{}
+			for _, req := range reqs() {
+				got = append(got, req.header.Get("Authorization"))
+			}
+			if !reflect.DeepEqual(got, tc.wantHeaders) {
+				t.Errorf("Authorization headers = %q, want %q", got, tc.wantHeaders)
+			}
+		})
+	}
+}
+
+func TestTokenSourceFunc(t *testing.T) {
+	srv, reqs := webhookServer(t, http.StatusOK, "")
+	ts := TokenSourceFunc(func() (string, error) { return "Bearer xyz", nil })
+	hook := &Webhook{Method: http.MethodPost, URL: srv.URL, TokenSource: ts}
+	if err := hook.Func()(NewEvent("temp", 1.0)); err != nil {
+		t.Fatalf("webhook failed: %s", err)
+	}
+	if auth := reqs()[0].header.Get("Authorization"); auth != "Bearer xyz" {
+		t.Errorf("Authorization = %q, want %q", auth, "Bearer xyz")
+	}
+}
+
+func TestWebhookWithoutTokenSource(t *testing.T) {
+	srv, reqs := webhookServer(t, http.StatusOK, "")
+	hook := &Webhook{Method: http.MethodPost, URL: srv.URL, Headers: http.Header{"Authorization": {"Basic xyz"}}}
+	if err := hook.Func()(NewEvent("temp", 1.0)); err != nil {
+		t.Fatalf("webhook failed: %s", err)
+	}
+	if auth := reqs()[0].header.Get("Authorization"); auth != "Basic xyz" {
+		t.Errorf("Authorization = %q, want the configured header", auth)
+	}
+}
+
+func TestLoadWebhooks(t *testing.T) {
+	tests := []struct {
+		name string
+		config string
+		wantHooks map[string]int
+		wantErr string
+	}{
+		{
+			"sample",
+			`{"temperature": [{"method": "POST", "url": "http://example.com/hot", "min": 30}, {"method": "GET", "url": "http://example.com/log"}], "door": [{"method": "PUT", "url": "http://example.com/door", "max_calls": 1}]}`,
+			map[string]int{"temperature": 2, "door": 1},
+			"",
+		},
+		{"empty", `{}`, map[string]int{}, ""},
+		{"bad json", `{"temperature": [`, nil, "can't parse webhook config"},
+		{"missing method", `{"temperature": [{"url": "http://example.com"}]}`, nil, `webhook 0 for event type "temperature": missing method`},
+		{"missing url", `{"temperature": [{"method": "POST", "url": "http://example.com"}, {"method": "POST"}]}`, nil, `webhook 1 for event type "temperature": missing url`},
+		{"invalid url", `{"door": [{"method": "POST", "url": "://example.com"}]}`, nil, `webhook 0 for event type "door": invalid url`},
+		{"null webhook", `{"door": [null]}`, nil, `webhook 0 for event type "door": empty config`},
+		{"unknown method", `{"door": [{"method": "FETCH", "url": "http://example.com"}]}`, nil, `webhook 0 for event type "door": unknown method "FETCH"`},
+		{"lower case method", `{"door": [{"method": "post", "url": "http://example.com"}]}`, nil, `webhook 0 for event type "door": unknown method "post"`},
+		{"relative url", `{"door": [{"method": "POST", "url": "/hooks/door"}]}`, nil, `webhook 0 for event type "door": invalid url "/hooks/door": not an absolute http or https url`},
+		{"other scheme", `{"door": [{"method": "POST", "url": "ftp://example.com/door"}]}`, nil, `webhook 0 for event type "door": invalid url "ftp://example.com/door": not an absolute http or https url`},
+		{"missing host", `{"door": [{"method": "POST", "url": "http:///door"}]}`, nil, `webhook 0 for event type "door": invalid url "http:///door": missing host`},
+		{"https", `{"door": [{"method": "DELETE", "url": "https://example.com:8443/door"}]}`, map[string]int{"door": 1}, ""},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			config, err := LoadWebhooks(strings.NewReader(tc.config))
+			if tc.wantErr != "" {
+				if err == nil || !strings.HasPrefix(err.Error(), tc.wantErr) {
+					t.Fatalf("error = %v, want %q", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("can't load config: %s", err)
+			}
+			got := map[string]int{}
+			for eventType, hooks := range config {
+				got[eventType] = len(hooks)
+			}
+			if !reflect.DeepEqual(got, tc.wantHooks) {
+				t.Errorf("loaded %v webhooks, want %v", got, tc.wantHooks)
+			}
+		})
+	}
+}
+
+func TestAttachWebhooks(t *testing.T) {
+	srv, reqs := webhookServer(t, http.StatusOK, "")
+	config, err := LoadWebhooks(strings.NewReader(fmt.Sprintf(
+		`{"temperature": [{"method": "POST", "url": %q, "min": 10, "max": 20}], "door": [{"method": "POST", "url": %q, "max_calls": 1}]}`,
+		srv.URL + "/temperature", srv.URL + "/door",
+	)))
+	if err != nil {
+		t.Fatalf("can't load config: %s", err)
+	}
+	sink := NewSyncEventSink(time.Minute)
+	AttachWebhooks(sink, config)
+	sink.Emit("temperature", 15.0)
+	sink.Emit("temperature", 25.0)
+	sink.Emit("door", "open")
+	sink.Emit("door", "closed")
+	got := reqs()
+	if len(got) != 2 {
+		t.Fatalf("got %d requests, want 2", len(got))
+	}
+	for i, want := range []interface{}{15.0, "open"} {
+		ev, err := UnmarshalEvent(got[i].body)
+		if err != nil {
+			t.Fatalf("request %d: can't decode %s: %s", i, got[i].body, err)
+		}
+		if p := eventPayload(ev); p != want {
+			t.Errorf("request %d carried %v, want %v", i, p, want)
+		}
+	}
+}
+
+func TestAttachWebhooksWithDefaults(t *testing.T) {
+	second := time.Second
+	minute := time.Minute
+	zero := time.Duration(0)
+	defaults := WebhookDefaults{Debounce: &second, TTL: time.Hour, MaxCalls: 2}
+	tests := []struct {
+		name string
+		hook Webhook
+		defaults WebhookDefaults
+		wantDebounce *time.Duration
+		wantTTL time.Duration
+		wantMaxCalls int
+	}{
+		{"no defaults", Webhook{}, WebhookDefaults{}, nil, 0, 0},
+		{"all unset", Webhook{}, defaults, &second, time.Hour, 2},
+		{"debounce set", Webhook{Debounce: &minute}, defaults, &minute, time.Hour, 2},
+		{"zero debounce kept", Webhook{Debounce: &zero}, defaults, &zero, time.Hour, 2},
+		{"ttl set", Webhook{TTL: time.Minute}, defaults, &second, time.Minute, 2},
+		{"max calls set", Webhook{MaxCalls: 5}, defaults, &second, time.Hour, 5},
+		{"all set", Webhook{Debounce: &minute, TTL: time.Minute, MaxCalls: 5}, defaults, &minute, time.Minute, 5},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			hook := tc.hook
+			got := hook.withDefaults(tc.defaults)
+			if got.Debounce != tc.wantDebounce {
+				t.Errorf("debounce = %v, want %v", got.Debounce, tc.wantDebounce)
+			}
+			if got.TTL != tc.wantTTL {
+				t.Errorf("ttl = %s, want %s", got.TTL, tc.wantTTL)
+			}
+			if got.MaxCalls != tc.wantMaxCalls {
+				t.Errorf("max calls = %d, want %d", got.MaxCalls, tc.wantMaxCalls)
+			}
+			if !reflect.DeepEqual(hook, tc.hook) {
+				t.Errorf("webhook changed to %+v, want %+v", hook, tc.hook)
+			}
+		})
+	}
+}
+
+func TestAttachWebhooksWithDefaultsCalls(t *testing.T) {
+	tests := []struct {
+		name string
+		maxCalls int
+		defaultMaxCalls int
+		want int
+	}{
+		{"unlimited", 0, 0, 5},
+		{"default", 0, 2, 2},
+		{"own limit", 3, 2, 3},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			srv, reqs := webhookServer(t, http.StatusOK, "")
+			hook := &Webhook{Method: http.MethodPost, URL: srv.URL, MaxCalls: tc.maxCalls}
+			sink := NewSyncEventSink(time.Minute)
+			AttachWebhooksWithDefaults(sink, map[string][]*Webhook{"door": {hook}}, WebhookDefaults{MaxCalls: tc.defaultMaxCalls})
+			for i := 0; i < 5; i++ {
+				sink.Emit("door", float64(i))
+			}
+			if n := len(reqs()); n != tc.want {
+				t.Errorf("got %d requests, want %d", n, tc.want)
+			}
+			if hook.MaxCalls != tc.maxCalls {
+				t.Errorf("webhook max calls changed to %d", hook.MaxCalls)
+			}
+		})
+	}
+}
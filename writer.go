@@ -0,0 +1,25 @@
+package events
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+)
+
+// NewWriterHandler returns a handler that writes each event to w as a line
+// of JSON. Writes are serialized, so the handler is safe for concurrent
+// use.
+func NewWriterHandler(w io.Writer) EventHandler {
+	mutex := &sync.Mutex{}
+	return NewEventHandler(func(ev Event) error {
+		data, err := json.Marshal(ev)
+		if err != nil {
+			return err
+		}
+		data = append(data, '\n')
+		mutex.Lock()
+		defer mutex.Unlock()
+		_, err = w.Write(data)
+		return err
+	})
+}
@@ -0,0 +1,91 @@
+package events
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestWriterHandler(t *testing.T) {
+	tests := []struct {
+		name string
+		data []interface{}
+	}{
+		{"none", nil},
+		{"values", []interface{}{1.5, 2.0}},
+		{"mixed", []interface{}{1.5, "door open", map[string]interface{}{"room": "kitchen"}}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			h := NewWriterHandler(buf)
+			want := make([]Event, len(tc.data))
+			for i, d := range tc.data {
+				want[i] = NewEvent("test", d)
+				if err := h.Call(want[i]); err != nil {
+					t.Fatalf("call failed: %s", err)
+				}
+			}
+			scanner := bufio.NewScanner(buf)
+			i := 0
+			for scanner.Scan() {
+				if i >= len(want) {
+					t.Fatalf("extra line %s", scanner.Text())
+				}
+				ev, err := UnmarshalEvent(scanner.Bytes())
+				if err != nil {
+					t.Fatalf("line %d isn't an event: %s", i, err)
+				}
+				if ev.GetType() != want[i].GetType() || !ev.GetTime().Equal(want[i].GetTime()) || !reflect.DeepEqual(eventPayload(ev), eventPayload(want[i])) {
+					t.Errorf("line %d = %s, want %+v", i, scanner.Text(), want[i])
+				}
+				i += 1
+			}
+			if i != len(want) {
+				t.Errorf("%d lines, want %d", i, len(want))
+			}
+		})
+	}
+}
+
+func TestWriterHandlerConcurrent(t *testing.T) {
+	buf := &bytes.Buffer{}
+	h := NewWriterHandler(buf)
+	wg := &sync.WaitGroup{}
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			h.Call(NewEvent("test", float64(i)))
+		}(i)
+	}
+	wg.Wait()
+	scanner := bufio.NewScanner(buf)
+	n := 0
+	for scanner.Scan() {
+		if _, err := UnmarshalEvent(scanner.Bytes()); err != nil {
+			t.Errorf("interleaved line %s: %s", scanner.Text(), err)
+		}
+		n += 1
+	}
+	if n != 50 {
+		t.Errorf("%d lines, want 50", n)
+	}
+}
+
+type failingWriter struct{}
+
+var errWrite = errors.New("write failed")
+
+func (failingWriter) Write([]byte) (int, error) {
+	return 0, errWrite
+}
+
+func TestWriterHandlerError(t *testing.T) {
+	if err := NewWriterHandler(failingWriter{}).Call(NewEvent("test", 1.0)); !errors.Is(err, errWrite) {
+		t.Errorf("call returned %v, want %v", err, errWrite)
+	}
+}